@@ -21,6 +21,7 @@ type EndPoint struct {
 	Method   string
 	Body     interface{}
 	Token    string
+	IfMatch  string
 }
 
 func main() {
@@ -49,6 +50,8 @@ func main() {
 				},
 			},
 			Token: token,
+			// Updates need If-Match; "*" skips the version check.
+			IfMatch: "*",
 		},
 		EndPoint{
 			EndPoint: "http://localhost:8080/todos",
@@ -118,6 +121,9 @@ func invokeApi(endpoint EndPoint, resultChan chan BenchmarkResult) {
 	if endpoint.Token != "" {
 		req.Header.Set("Authorization", endpoint.Token)
 	}
+	if endpoint.IfMatch != "" {
+		req.Header.Set("If-Match", endpoint.IfMatch)
+	}
 
 	// Execute the request
 	client := &http.Client{}
@@ -4,12 +4,37 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
 
 	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
 
-var DB *sql.DB
+// Supported values for the DB_DRIVER env variable.
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
+
+func Driver() string {
+	driver := os.Getenv("DB_DRIVER")
+	if driver == "" {
+		return Postgres
+	}
+	return driver
+}
+
+var placeholder = regexp.MustCompile(`\$(\d+)`)
+
+// Rebind turns $1 style placeholders into SQLite's numbered ?1 form so the
+// same query text and argument order work for both drivers.
+func Rebind(driver, query string) string {
+	if driver != SQLite {
+		return query
+	}
+	return placeholder.ReplaceAllString(query, "?${1}")
+}
 
 func createConnectionString() string {
 	host := os.Getenv("DB_HOST")
@@ -21,63 +46,62 @@ func createConnectionString() string {
 		host, port, user, password, dbname)
 }
 
-func InitDB() {
+func createSQLitePath() string {
+	path := os.Getenv("DB_PATH")
+	if path == "" {
+		path = "todo.db"
+	}
+	// Foreign keys are off by default in SQLite and the busy timeout keeps
+	// concurrent writers from failing straight away with SQLITE_BUSY.
+	// Immediate transactions take the write lock up front, which is what
+	// serialises two processes running migrations against the same file.
+	// The sqlite time format is one SQLite's own date functions understand.
+	return "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate&_time_format=sqlite"
+}
+
+// Open connects to the configured database without touching the schema.
+func Open(driver string) *sql.DB {
+	var connectionString string
+	switch driver {
+	case Postgres:
+		connectionString = createConnectionString()
+	case SQLite:
+		connectionString = createSQLitePath()
+	default:
+		panic("Unsupported database driver " + driver)
+	}
 
-	connectionString := createConnectionString()
-	fmt.Println(connectionString)
-	var err error //required. since := in the next line cause error in creating DB tables.
-	DB, err = sql.Open("postgres", connectionString)
+	db, err := sql.Open(driver, connectionString)
 	fmt.Println("Connecting to database", connectionString)
 	if err != nil {
 		panic("Unable to connect to the database1")
 	}
 
-	err = DB.Ping()
+	err = db.Ping()
 	if err != nil {
 		panic("Unable to connect to the database2")
 	}
 
-	DB.SetMaxOpenConns(10)
-	DB.SetMaxIdleConns(5)
-
-	createTables()
+	if driver == SQLite {
+		// SQLite allows a single writer; one connection avoids lock contention.
+		db.SetMaxOpenConns(1)
+	} else {
+		db.SetMaxOpenConns(10)
+		db.SetMaxIdleConns(5)
+	}
+	return db
 }
 
-func createTables() {
-
-	createUsersTable := `
-	CREATE TABLE IF NOT EXISTS users (
-		id SERIAL PRIMARY KEY,
-		email TEXT NOT NULL UNIQUE,
-		first_name TEXT NOT NULL,
-		last_name TEXT NOT NULL,
-		password TEXT NOT NULL,
-		is_active BOOLEAN DEFAULT TRUE,
-		created_at TIMESTAMPTZ DEFAULT NOW(),
-		updated_at TIMESTAMPTZ DEFAULT NOW()
-	)
-	`
-	_, err := DB.Exec(createUsersTable)
+// InitDB opens the database and applies any pending migrations.
+func InitDB(driver string) *sql.DB {
+	db := Open(driver)
+	applied, err := NewMigrator(db, driver).Up()
 	if err != nil {
 		fmt.Println(err)
-		panic("Unable to create users table")
+		panic("Unable to migrate the database")
 	}
-
-	createTodosTable := `
-	CREATE TABLE IF NOT EXISTS todos (
-		id SERIAL PRIMARY KEY,
-		title TEXT NOT NULL,
-		list JSONB,
-		is_active BOOLEAN DEFAULT TRUE,
-		created_at TIMESTAMPTZ DEFAULT NOW(),
-		updated_at TIMESTAMPTZ DEFAULT NOW(),
-		user_id INTEGER,
-		FOREIGN KEY(user_id) REFERENCES users(id)
-	)
-	`
-	_, err = DB.Exec(createTodosTable)
-	if err != nil {
-		fmt.Println(err)
-		panic("Unable to create todos table")
+	if applied > 0 {
+		fmt.Println("Applied", applied, "migration(s)")
 	}
+	return db
 }
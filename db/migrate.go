@@ -0,0 +1,225 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Script holds the SQL for one direction of a migration. SQLite falls back
+// to the Postgres text when both dialects accept the same statements.
+type Script struct {
+	Postgres string
+	SQLite   string
+}
+
+func (s Script) For(driver string) string {
+	if driver == SQLite && s.SQLite != "" {
+		return s.SQLite
+	}
+	return s.Postgres
+}
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      Script
+	Down    Script
+}
+
+type MigrationState struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies Migrations in version order and records them in the
+// schema_migrations table.
+type Migrator struct {
+	DB         *sql.DB
+	Driver     string
+	Migrations []Migration
+}
+
+// Arbitrary key shared by every instance so only one of them migrates at a time.
+const migrationLockKey = 72621009
+
+func NewMigrator(db *sql.DB, driver string) *Migrator {
+	return &Migrator{DB: db, Driver: driver, Migrations: migrations}
+}
+
+// Up applies every pending migration and returns how many ran.
+func (m *Migrator) Up() (int, error) {
+	count := 0
+	err := m.withLock(func(conn *sql.Conn) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.sorted() {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			ran, err := m.run(conn, migration, true)
+			if err != nil {
+				return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+			}
+			if ran {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down reverts the most recently applied migrations, newest first.
+func (m *Migrator) Down(steps int) (int, error) {
+	count := 0
+	err := m.withLock(func(conn *sql.Conn) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		sorted := m.sorted()
+		for i := len(sorted) - 1; i >= 0 && count < steps; i-- {
+			migration := sorted[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			ran, err := m.run(conn, migration, false)
+			if err != nil {
+				return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+			}
+			if ran {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (m *Migrator) Status() ([]MigrationState, error) {
+	var states []MigrationState
+	err := m.withLock(func(conn *sql.Conn) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.sorted() {
+			appliedAt, ok := applied[migration.Version]
+			states = append(states, MigrationState{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Applied:   ok,
+				AppliedAt: appliedAt,
+			})
+		}
+		return nil
+	})
+	return states, err
+}
+
+func (m *Migrator) sorted() []Migration {
+	sorted := append([]Migration(nil), m.Migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return sorted
+}
+
+// withLock runs fn on a dedicated connection holding the migration lock.
+// Postgres uses a session advisory lock; SQLite relies on the immediate
+// transactions opened in run, together with the re-check done there.
+func (m *Migrator) withLock(fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.Driver == Postgres {
+		_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey)
+		if err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockKey)
+	}
+
+	_, err = conn.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)
+	`)
+	if err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) applied(conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(context.Background(), "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		err = rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// run applies (up) or reverts (down) one migration in its own transaction.
+// It reports false when another process got there first.
+func (m *Migrator) run(conn *sql.Conn, migration Migration, up bool) (bool, error) {
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRowContext(ctx, Rebind(m.Driver, "SELECT COUNT(*) FROM schema_migrations WHERE version = $1"), migration.Version).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	if (exists > 0) == up {
+		return false, nil
+	}
+
+	script := migration.Down
+	if up {
+		script = migration.Up
+	}
+	if statements := script.For(m.Driver); statements != "" {
+		_, err = tx.ExecContext(ctx, statements)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, Rebind(m.Driver, "INSERT INTO schema_migrations(version, name, applied_at) VALUES ($1, $2, $3)"),
+			migration.Version, migration.Name, time.Now().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx, Rebind(m.Driver, "DELETE FROM schema_migrations WHERE version = $1"), migration.Version)
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,251 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func openTestSQLite(t *testing.T, path string) *sql.DB {
+	t.Setenv("DB_PATH", path)
+	conn := Open(SQLite)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func tableExists(t *testing.T, conn *sql.DB, name string) bool {
+	var count int
+	err := conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	assert.NoError(t, err)
+	return count > 0
+}
+
+var testMigrations = []Migration{
+	{
+		Version: 2,
+		Name:    "add notes",
+		Up:      Script{Postgres: "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)"},
+		Down:    Script{Postgres: "DROP TABLE notes"},
+	},
+	{
+		Version: 1,
+		Name:    "add tags",
+		Up:      Script{Postgres: "CREATE TABLE tags (id INTEGER PRIMARY KEY); CREATE TABLE tag_names (id INTEGER PRIMARY KEY)"},
+		Down:    Script{Postgres: "DROP TABLE tag_names; DROP TABLE tags"},
+	},
+}
+
+func TestMigratorUpDownStatus(t *testing.T) {
+	conn := openTestSQLite(t, filepath.Join(t.TempDir(), "todo.db"))
+	migrator := &Migrator{DB: conn, Driver: SQLite, Migrations: testMigrations}
+
+	states, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.Len(t, states, 2)
+	assert.Equal(t, int64(1), states[0].Version)
+	assert.False(t, states[0].Applied)
+
+	applied, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, applied)
+	assert.True(t, tableExists(t, conn, "tags"))
+	assert.True(t, tableExists(t, conn, "tag_names"))
+	assert.True(t, tableExists(t, conn, "notes"))
+
+	applied, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, applied)
+
+	states, err = migrator.Status()
+	assert.NoError(t, err)
+	assert.True(t, states[0].Applied)
+	assert.True(t, states[1].Applied)
+	assert.False(t, states[1].AppliedAt.IsZero())
+
+	reverted, err := migrator.Down(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, reverted)
+	assert.False(t, tableExists(t, conn, "notes"))
+	assert.True(t, tableExists(t, conn, "tags"))
+
+	reverted, err = migrator.Down(5)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, reverted)
+	assert.False(t, tableExists(t, conn, "tags"))
+}
+
+func TestMigratorFailedMigrationRollsBack(t *testing.T) {
+	conn := openTestSQLite(t, filepath.Join(t.TempDir(), "todo.db"))
+	migrator := &Migrator{DB: conn, Driver: SQLite, Migrations: []Migration{
+		{Version: 1, Name: "broken", Up: Script{Postgres: "CREATE TABLE half (id INTEGER); NOT SQL"}},
+	}}
+
+	_, err := migrator.Up()
+	assert.Error(t, err)
+	assert.False(t, tableExists(t, conn, "half"))
+
+	states, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.False(t, states[0].Applied)
+}
+
+func TestMigratorConcurrentUp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo.db")
+	first := openTestSQLite(t, path)
+	second := openTestSQLite(t, path)
+
+	var wg sync.WaitGroup
+	results := make([]int, 2)
+	errs := make([]error, 2)
+	for i, conn := range []*sql.DB{first, second} {
+		wg.Add(1)
+		go func(i int, conn *sql.DB) {
+			defer wg.Done()
+			results[i], errs[i] = NewMigrator(conn, SQLite).Up()
+		}(i, conn)
+	}
+	wg.Wait()
+
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	assert.Equal(t, len(migrations), results[0]+results[1])
+	assert.True(t, tableExists(t, first, "todos"))
+}
+
+func TestRebind(t *testing.T) {
+	query := "UPDATE todos SET title = $1 WHERE id = $10 AND user_id = $1"
+	assert.Equal(t, query, Rebind(Postgres, query))
+	assert.Equal(t, "UPDATE todos SET title = ?1 WHERE id = ?10 AND user_id = ?1", Rebind(SQLite, query))
+}
+
+func TestMigrationMovesChecklistItems(t *testing.T) {
+	conn := openTestSQLite(t, filepath.Join(t.TempDir(), "todo.db"))
+	migrator := &Migrator{DB: conn, Driver: SQLite, Migrations: migrations[:1]}
+	_, err := migrator.Up()
+	assert.NoError(t, err)
+
+	_, err = conn.Exec(`INSERT INTO users(email, first_name, last_name, password) VALUES ('a@b.c', 'A', 'B', 'x')`)
+	assert.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO todos(title, list, user_id) VALUES
+		('groceries', '[{"item":"milk","checked":false},{"item":"eggs","checked":true}]', 1),
+		('empty', NULL, 1)`)
+	assert.NoError(t, err)
+
+	migrator.Migrations = migrations[:2]
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	rows, err := conn.Query("SELECT todo_id, item, checked, position FROM todo_items ORDER BY todo_id, position")
+	assert.NoError(t, err)
+	type row struct {
+		TodoID   int64
+		Item     string
+		Checked  bool
+		Position int
+	}
+	var items []row
+	for rows.Next() {
+		var r row
+		assert.NoError(t, rows.Scan(&r.TodoID, &r.Item, &r.Checked, &r.Position))
+		items = append(items, r)
+	}
+	rows.Close()
+	assert.Equal(t, []row{{1, "milk", false, 0}, {1, "eggs", true, 1}}, items)
+
+	_, err = migrator.Down(1)
+	assert.NoError(t, err)
+	var list string
+	assert.NoError(t, conn.QueryRow("SELECT list FROM todos WHERE id = 1").Scan(&list))
+	assert.JSONEq(t, `[{"item":"milk","checked":false},{"item":"eggs","checked":true}]`, list)
+	assert.False(t, tableExists(t, conn, "todo_items"))
+}
+
+func TestMigrationSearchIndexesExistingRows(t *testing.T) {
+	conn := openTestSQLite(t, filepath.Join(t.TempDir(), "todo.db"))
+	migrator := &Migrator{DB: conn, Driver: SQLite, Migrations: migrations[:3]}
+	_, err := migrator.Up()
+	assert.NoError(t, err)
+
+	_, err = conn.Exec(`INSERT INTO users(email, first_name, last_name, password) VALUES ('a@b.c', 'A', 'B', 'x')`)
+	assert.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO todos(title, user_id) VALUES ('groceries', 1)`)
+	assert.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO todo_items(todo_id, item, position) VALUES (1, 'oat milk', 0)`)
+	assert.NoError(t, err)
+
+	migrator.Migrations = migrations[:4]
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	var id int64
+	assert.NoError(t, conn.QueryRow("SELECT rowid FROM todos_fts WHERE todos_fts MATCH 'grocery'").Scan(&id))
+	assert.Equal(t, int64(1), id)
+	assert.NoError(t, conn.QueryRow("SELECT rowid FROM todo_items_fts WHERE todo_items_fts MATCH 'milk'").Scan(&id))
+	assert.Equal(t, int64(1), id)
+
+	_, err = migrator.Down(1)
+	assert.NoError(t, err)
+	assert.False(t, tableExists(t, conn, "todos_fts"))
+	_, err = conn.Exec(`UPDATE todos SET title = 'shopping' WHERE id = 1`)
+	assert.NoError(t, err)
+}
+
+func TestMigrationsRoundTrip(t *testing.T) {
+	conn := openTestSQLite(t, filepath.Join(t.TempDir(), "todo.db"))
+	migrator := NewMigrator(conn, SQLite)
+	applied, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Equal(t, len(migrations), applied)
+
+	reverted, err := migrator.Down(len(migrations))
+	assert.NoError(t, err)
+	assert.Equal(t, len(migrations), reverted)
+	assert.False(t, tableExists(t, conn, "todos"))
+
+	applied, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.Equal(t, len(migrations), applied)
+}
+
+func TestMigrationRanksExistingRows(t *testing.T) {
+	conn := openTestSQLite(t, filepath.Join(t.TempDir(), "todo.db"))
+	migrator := &Migrator{DB: conn, Driver: SQLite, Migrations: migrations[:9]}
+	_, err := migrator.Up()
+	assert.NoError(t, err)
+
+	_, err = conn.Exec(`INSERT INTO users(email, first_name, last_name, password) VALUES ('a@b.c', 'A', 'B', 'x')`)
+	assert.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO todos(title, user_id) VALUES ('first', 1), ('second', 1)`)
+	assert.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO todo_items(todo_id, item, position) VALUES (1, 'c', 2), (1, 'a', 0), (1, 'b', 1)`)
+	assert.NoError(t, err)
+
+	migrator.Migrations = migrations[:10]
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	column := func(query string) []string {
+		rows, err := conn.Query(query)
+		assert.NoError(t, err)
+		defer rows.Close()
+		var values []string
+		for rows.Next() {
+			var value string
+			assert.NoError(t, rows.Scan(&value))
+			values = append(values, value)
+		}
+		return values
+	}
+	assert.Equal(t, []string{"first", "second"}, column("SELECT title FROM todos ORDER BY rank"))
+	assert.Equal(t, []string{"P2", "P2"}, column("SELECT priority FROM todos ORDER BY id"))
+	assert.Equal(t, []string{"a", "b", "c"}, column("SELECT item FROM todo_items ORDER BY rank"))
+
+	_, err = conn.Exec(`UPDATE todo_items SET rank = '0000000000W' WHERE item = 'c'`)
+	assert.NoError(t, err)
+	_, err = migrator.Down(1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "c", "b"}, column("SELECT item FROM todo_items ORDER BY position"))
+}
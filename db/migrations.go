@@ -0,0 +1,904 @@
+package db
+
+// migrations is the ordered schema history. Never edit a migration that has
+// shipped; add a new version instead.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create users and todos",
+		Up: Script{
+			Postgres: `
+			CREATE TABLE IF NOT EXISTS users (
+				id SERIAL PRIMARY KEY,
+				email TEXT NOT NULL UNIQUE,
+				first_name TEXT NOT NULL,
+				last_name TEXT NOT NULL,
+				password TEXT NOT NULL,
+				is_active BOOLEAN DEFAULT TRUE,
+				created_at TIMESTAMPTZ DEFAULT NOW(),
+				updated_at TIMESTAMPTZ DEFAULT NOW()
+			);
+			CREATE TABLE IF NOT EXISTS todos (
+				id SERIAL PRIMARY KEY,
+				title TEXT NOT NULL,
+				list JSONB,
+				is_active BOOLEAN DEFAULT TRUE,
+				created_at TIMESTAMPTZ DEFAULT NOW(),
+				updated_at TIMESTAMPTZ DEFAULT NOW(),
+				user_id INTEGER,
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);
+			`,
+			SQLite: `
+			CREATE TABLE IF NOT EXISTS users (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				email TEXT NOT NULL UNIQUE,
+				first_name TEXT NOT NULL,
+				last_name TEXT NOT NULL,
+				password TEXT NOT NULL,
+				is_active BOOLEAN DEFAULT TRUE,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE TABLE IF NOT EXISTS todos (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				title TEXT NOT NULL,
+				list TEXT CHECK (list IS NULL OR json_valid(list)),
+				is_active BOOLEAN DEFAULT TRUE,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				user_id INTEGER,
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			DROP TABLE IF EXISTS todos;
+			DROP TABLE IF EXISTS users;
+			`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "move checklist items into todo_items",
+		Up: Script{
+			Postgres: `
+			CREATE TABLE todo_items (
+				id SERIAL PRIMARY KEY,
+				todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+				item TEXT NOT NULL,
+				checked BOOLEAN NOT NULL DEFAULT FALSE,
+				position INTEGER NOT NULL
+			);
+			CREATE INDEX todo_items_todo_id_position_idx ON todo_items(todo_id, position);
+			INSERT INTO todo_items(todo_id, item, checked, position)
+			SELECT t.id,
+				COALESCE(e.value->>'item', ''),
+				COALESCE((e.value->>'checked')::BOOLEAN, FALSE),
+				e.ordinality - 1
+			FROM todos t, jsonb_array_elements(t.list) WITH ORDINALITY AS e(value, ordinality)
+			WHERE jsonb_typeof(t.list) = 'array';
+			ALTER TABLE todos DROP COLUMN list;
+			`,
+			SQLite: `
+			CREATE TABLE todo_items (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+				item TEXT NOT NULL,
+				checked BOOLEAN NOT NULL DEFAULT FALSE,
+				position INTEGER NOT NULL
+			);
+			CREATE INDEX todo_items_todo_id_position_idx ON todo_items(todo_id, position);
+			INSERT INTO todo_items(todo_id, item, checked, position)
+			SELECT t.id,
+				COALESCE(json_extract(e.value, '$.item'), ''),
+				COALESCE(json_extract(e.value, '$.checked'), 0),
+				e.key
+			FROM todos t, json_each(t.list) AS e
+			WHERE json_type(t.list) = 'array';
+			ALTER TABLE todos DROP COLUMN list;
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			ALTER TABLE todos ADD COLUMN list JSONB;
+			UPDATE todos SET list = (
+				SELECT jsonb_agg(jsonb_build_object('item', i.item, 'checked', i.checked) ORDER BY i.position)
+				FROM todo_items i WHERE i.todo_id = todos.id
+			);
+			DROP TABLE todo_items;
+			`,
+			SQLite: `
+			ALTER TABLE todos ADD COLUMN list TEXT;
+			UPDATE todos SET list = (
+				SELECT json_group_array(json_object('item', i.item, 'checked', json(CASE WHEN i.checked THEN 'true' ELSE 'false' END)))
+				FROM (SELECT * FROM todo_items WHERE todo_id = todos.id ORDER BY position) i
+			);
+			DROP TABLE todo_items;
+			`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "index todo listing",
+		Up: Script{
+			Postgres: `
+			CREATE INDEX todos_user_id_id_idx ON todos(user_id, id);
+			CREATE INDEX todos_user_id_created_at_idx ON todos(user_id, created_at, id);
+			CREATE INDEX todos_user_id_updated_at_idx ON todos(user_id, updated_at, id);
+			CREATE INDEX todos_user_id_title_idx ON todos(user_id, title, id);
+			CREATE INDEX todo_items_unchecked_idx ON todo_items(todo_id) WHERE NOT checked;
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			DROP INDEX todos_user_id_id_idx;
+			DROP INDEX todos_user_id_created_at_idx;
+			DROP INDEX todos_user_id_updated_at_idx;
+			DROP INDEX todos_user_id_title_idx;
+			DROP INDEX todo_items_unchecked_idx;
+			`,
+		},
+	},
+	{
+		Version: 4,
+		Name:    "full-text search",
+		Up: Script{
+			Postgres: `
+			ALTER TABLE todos ADD COLUMN search_vector TSVECTOR
+				GENERATED ALWAYS AS (setweight(to_tsvector('english', title), 'A')) STORED;
+			ALTER TABLE todo_items ADD COLUMN search_vector TSVECTOR
+				GENERATED ALWAYS AS (setweight(to_tsvector('english', item), 'B')) STORED;
+			CREATE INDEX todos_search_idx ON todos USING GIN (search_vector);
+			CREATE INDEX todo_items_search_idx ON todo_items USING GIN (search_vector);
+			`,
+			SQLite: `
+			CREATE VIRTUAL TABLE todos_fts USING fts5(title, content='todos', content_rowid='id', tokenize='porter unicode61');
+			CREATE VIRTUAL TABLE todo_items_fts USING fts5(item, content='todo_items', content_rowid='id', tokenize='porter unicode61');
+			INSERT INTO todos_fts(todos_fts) VALUES ('rebuild');
+			INSERT INTO todo_items_fts(todo_items_fts) VALUES ('rebuild');
+			CREATE TRIGGER todos_fts_insert AFTER INSERT ON todos BEGIN
+				INSERT INTO todos_fts(rowid, title) VALUES (new.id, new.title);
+			END;
+			CREATE TRIGGER todos_fts_delete AFTER DELETE ON todos BEGIN
+				INSERT INTO todos_fts(todos_fts, rowid, title) VALUES ('delete', old.id, old.title);
+			END;
+			CREATE TRIGGER todos_fts_update AFTER UPDATE OF title ON todos BEGIN
+				INSERT INTO todos_fts(todos_fts, rowid, title) VALUES ('delete', old.id, old.title);
+				INSERT INTO todos_fts(rowid, title) VALUES (new.id, new.title);
+			END;
+			CREATE TRIGGER todo_items_fts_insert AFTER INSERT ON todo_items BEGIN
+				INSERT INTO todo_items_fts(rowid, item) VALUES (new.id, new.item);
+			END;
+			CREATE TRIGGER todo_items_fts_delete AFTER DELETE ON todo_items BEGIN
+				INSERT INTO todo_items_fts(todo_items_fts, rowid, item) VALUES ('delete', old.id, old.item);
+			END;
+			CREATE TRIGGER todo_items_fts_update AFTER UPDATE OF item ON todo_items BEGIN
+				INSERT INTO todo_items_fts(todo_items_fts, rowid, item) VALUES ('delete', old.id, old.item);
+				INSERT INTO todo_items_fts(rowid, item) VALUES (new.id, new.item);
+			END;
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			DROP INDEX todos_search_idx;
+			DROP INDEX todo_items_search_idx;
+			ALTER TABLE todos DROP COLUMN search_vector;
+			ALTER TABLE todo_items DROP COLUMN search_vector;
+			`,
+			SQLite: `
+			DROP TRIGGER todos_fts_insert;
+			DROP TRIGGER todos_fts_delete;
+			DROP TRIGGER todos_fts_update;
+			DROP TRIGGER todo_items_fts_insert;
+			DROP TRIGGER todo_items_fts_delete;
+			DROP TRIGGER todo_items_fts_update;
+			DROP TABLE todos_fts;
+			DROP TABLE todo_items_fts;
+			`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add todo version",
+		Up: Script{
+			Postgres: `ALTER TABLE todos ADD COLUMN version INTEGER NOT NULL DEFAULT 1;`,
+		},
+		Down: Script{
+			Postgres: `ALTER TABLE todos DROP COLUMN version;`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "add todo trash",
+		Up: Script{
+			Postgres: `
+			ALTER TABLE todos ADD COLUMN deleted_at TIMESTAMPTZ;
+			CREATE INDEX todos_deleted_at_idx ON todos(deleted_at) WHERE deleted_at IS NOT NULL;
+			`,
+			SQLite: `
+			ALTER TABLE todos ADD COLUMN deleted_at TIMESTAMP;
+			CREATE INDEX todos_deleted_at_idx ON todos(deleted_at) WHERE deleted_at IS NOT NULL;
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			DROP INDEX todos_deleted_at_idx;
+			ALTER TABLE todos DROP COLUMN deleted_at;
+			`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "add due dates and reminders",
+		Up: Script{
+			Postgres: `
+			ALTER TABLE todos ADD COLUMN due_at TIMESTAMPTZ;
+			ALTER TABLE todos ADD COLUMN time_zone TEXT NOT NULL DEFAULT '';
+			ALTER TABLE todos ADD COLUMN reminded_due_at TIMESTAMPTZ;
+			ALTER TABLE todo_items ADD COLUMN due_at TIMESTAMPTZ;
+			ALTER TABLE todo_items ADD COLUMN reminded_due_at TIMESTAMPTZ;
+			CREATE INDEX todos_due_at_idx ON todos(due_at) WHERE due_at IS NOT NULL;
+			CREATE INDEX todo_items_due_at_idx ON todo_items(due_at) WHERE due_at IS NOT NULL;
+			`,
+			SQLite: `
+			ALTER TABLE todos ADD COLUMN due_at TIMESTAMP;
+			ALTER TABLE todos ADD COLUMN time_zone TEXT NOT NULL DEFAULT '';
+			ALTER TABLE todos ADD COLUMN reminded_due_at TIMESTAMP;
+			ALTER TABLE todo_items ADD COLUMN due_at TIMESTAMP;
+			ALTER TABLE todo_items ADD COLUMN reminded_due_at TIMESTAMP;
+			CREATE INDEX todos_due_at_idx ON todos(due_at) WHERE due_at IS NOT NULL;
+			CREATE INDEX todo_items_due_at_idx ON todo_items(due_at) WHERE due_at IS NOT NULL;
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			DROP INDEX todos_due_at_idx;
+			DROP INDEX todo_items_due_at_idx;
+			ALTER TABLE todos DROP COLUMN due_at;
+			ALTER TABLE todos DROP COLUMN time_zone;
+			ALTER TABLE todos DROP COLUMN reminded_due_at;
+			ALTER TABLE todo_items DROP COLUMN due_at;
+			ALTER TABLE todo_items DROP COLUMN reminded_due_at;
+			`,
+		},
+	},
+	{
+		Version: 8,
+		Name:    "add recurring todos",
+		Up: Script{
+			Postgres: `
+			ALTER TABLE todos ADD COLUMN recurrence TEXT NOT NULL DEFAULT '';
+			ALTER TABLE todos ADD COLUMN series_id BIGINT;
+			ALTER TABLE todos ADD COLUMN occurrence INTEGER NOT NULL DEFAULT 1;
+			ALTER TABLE todos ADD COLUMN next_id BIGINT;
+			CREATE INDEX todos_recurring_idx ON todos(id) WHERE recurrence <> '' AND next_id IS NULL;
+			`,
+			SQLite: `
+			ALTER TABLE todos ADD COLUMN recurrence TEXT NOT NULL DEFAULT '';
+			ALTER TABLE todos ADD COLUMN series_id INTEGER;
+			ALTER TABLE todos ADD COLUMN occurrence INTEGER NOT NULL DEFAULT 1;
+			ALTER TABLE todos ADD COLUMN next_id INTEGER;
+			CREATE INDEX todos_recurring_idx ON todos(id) WHERE recurrence <> '' AND next_id IS NULL;
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			DROP INDEX todos_recurring_idx;
+			ALTER TABLE todos DROP COLUMN recurrence;
+			ALTER TABLE todos DROP COLUMN series_id;
+			ALTER TABLE todos DROP COLUMN occurrence;
+			ALTER TABLE todos DROP COLUMN next_id;
+			`,
+		},
+	},
+	{
+		Version: 9,
+		Name:    "add tags",
+		Up: Script{
+			Postgres: `
+			CREATE TABLE tags (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id),
+				name TEXT NOT NULL,
+				color TEXT NOT NULL DEFAULT '',
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			);
+			CREATE UNIQUE INDEX tags_user_id_name_idx ON tags(user_id, lower(name));
+			CREATE TABLE todo_tags (
+				todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+				tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+				PRIMARY KEY (todo_id, tag_id)
+			);
+			CREATE INDEX todo_tags_tag_id_idx ON todo_tags(tag_id, todo_id);
+			`,
+			SQLite: `
+			CREATE TABLE tags (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL REFERENCES users(id),
+				name TEXT NOT NULL,
+				color TEXT NOT NULL DEFAULT '',
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE UNIQUE INDEX tags_user_id_name_idx ON tags(user_id, lower(name));
+			CREATE TABLE todo_tags (
+				todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+				tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+				PRIMARY KEY (todo_id, tag_id)
+			);
+			CREATE INDEX todo_tags_tag_id_idx ON todo_tags(tag_id, todo_id);
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			DROP TABLE todo_tags;
+			DROP TABLE tags;
+			`,
+		},
+	},
+	{
+		Version: 10,
+		Name:    "add priorities and ranks",
+		Up: Script{
+			Postgres: `
+			ALTER TABLE todos ADD COLUMN priority TEXT NOT NULL DEFAULT 'P2' CHECK (priority IN ('P0', 'P1', 'P2', 'P3'));
+			ALTER TABLE todos ADD COLUMN rank TEXT COLLATE "C" NOT NULL DEFAULT '';
+			UPDATE todos SET rank = lpad(id::TEXT, 10, '0') || 'V';
+			CREATE INDEX todos_user_id_rank_idx ON todos(user_id, rank, id);
+			CREATE INDEX todos_user_id_priority_idx ON todos(user_id, priority, id);
+			ALTER TABLE todo_items ADD COLUMN rank TEXT COLLATE "C" NOT NULL DEFAULT '';
+			UPDATE todo_items SET rank = lpad(position::TEXT, 10, '0') || 'V';
+			DROP INDEX todo_items_todo_id_position_idx;
+			ALTER TABLE todo_items DROP COLUMN position;
+			CREATE INDEX todo_items_todo_id_rank_idx ON todo_items(todo_id, rank);
+			`,
+			SQLite: `
+			ALTER TABLE todos ADD COLUMN priority TEXT NOT NULL DEFAULT 'P2' CHECK (priority IN ('P0', 'P1', 'P2', 'P3'));
+			ALTER TABLE todos ADD COLUMN rank TEXT NOT NULL DEFAULT '';
+			UPDATE todos SET rank = printf('%010dV', id);
+			CREATE INDEX todos_user_id_rank_idx ON todos(user_id, rank, id);
+			CREATE INDEX todos_user_id_priority_idx ON todos(user_id, priority, id);
+			ALTER TABLE todo_items ADD COLUMN rank TEXT NOT NULL DEFAULT '';
+			UPDATE todo_items SET rank = printf('%010dV', position);
+			DROP INDEX todo_items_todo_id_position_idx;
+			ALTER TABLE todo_items DROP COLUMN position;
+			CREATE INDEX todo_items_todo_id_rank_idx ON todo_items(todo_id, rank);
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			ALTER TABLE todo_items ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+			UPDATE todo_items SET position = (
+				SELECT COUNT(*) FROM todo_items o
+				WHERE o.todo_id = todo_items.todo_id AND (o.rank < todo_items.rank OR (o.rank = todo_items.rank AND o.id < todo_items.id))
+			);
+			DROP INDEX todo_items_todo_id_rank_idx;
+			CREATE INDEX todo_items_todo_id_position_idx ON todo_items(todo_id, position);
+			ALTER TABLE todo_items DROP COLUMN rank;
+			DROP INDEX todos_user_id_rank_idx;
+			DROP INDEX todos_user_id_priority_idx;
+			ALTER TABLE todos DROP COLUMN rank;
+			ALTER TABLE todos DROP COLUMN priority;
+			`,
+		},
+	},
+	{
+		Version: 11,
+		Name:    "add projects",
+		Up: Script{
+			Postgres: `
+			CREATE TABLE projects (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id),
+				parent_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				archived_at TIMESTAMPTZ,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			);
+			CREATE INDEX projects_user_id_idx ON projects(user_id);
+			CREATE INDEX projects_parent_id_idx ON projects(parent_id);
+			ALTER TABLE todos ADD COLUMN project_id INTEGER REFERENCES projects(id) ON DELETE SET NULL;
+			CREATE INDEX todos_project_id_idx ON todos(project_id, id);
+			`,
+			// SQLite cannot drop a column that has a foreign key, so
+			// todos.project_id goes without one there; deleting a project
+			// clears it in the store.
+			SQLite: `
+			CREATE TABLE projects (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL REFERENCES users(id),
+				parent_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				archived_at TIMESTAMP,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX projects_user_id_idx ON projects(user_id);
+			CREATE INDEX projects_parent_id_idx ON projects(parent_id);
+			ALTER TABLE todos ADD COLUMN project_id INTEGER;
+			CREATE INDEX todos_project_id_idx ON todos(project_id, id);
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			DROP INDEX todos_project_id_idx;
+			ALTER TABLE todos DROP COLUMN project_id;
+			DROP TABLE projects;
+			`,
+		},
+	},
+	{
+		Version: 12,
+		Name:    "add todo shares",
+		Up: Script{
+			Postgres: `
+			CREATE TABLE todo_shares (
+				todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				role TEXT NOT NULL CHECK (role IN ('viewer', 'editor')),
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				PRIMARY KEY (todo_id, user_id)
+			);
+			CREATE INDEX todo_shares_user_id_idx ON todo_shares(user_id, todo_id);
+			`,
+			SQLite: `
+			CREATE TABLE todo_shares (
+				todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				role TEXT NOT NULL CHECK (role IN ('viewer', 'editor')),
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (todo_id, user_id)
+			);
+			CREATE INDEX todo_shares_user_id_idx ON todo_shares(user_id, todo_id);
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			DROP TABLE todo_shares;
+			`,
+		},
+	},
+	{
+		Version: 13,
+		Name:    "add workspaces",
+		Up: Script{
+			Postgres: `
+			CREATE TABLE workspaces (
+				id SERIAL PRIMARY KEY,
+				name TEXT NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			);
+			CREATE TABLE workspace_members (
+				workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'member', 'guest')),
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				PRIMARY KEY (workspace_id, user_id)
+			);
+			CREATE INDEX workspace_members_user_id_idx ON workspace_members(user_id, workspace_id);
+			CREATE TABLE workspace_invites (
+				id SERIAL PRIMARY KEY,
+				workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+				email TEXT NOT NULL,
+				role TEXT NOT NULL CHECK (role IN ('admin', 'member', 'guest')),
+				token TEXT NOT NULL UNIQUE,
+				invited_by INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				accepted_at TIMESTAMPTZ
+			);
+			CREATE INDEX workspace_invites_workspace_id_idx ON workspace_invites(workspace_id);
+			CREATE INDEX workspace_invites_email_idx ON workspace_invites(lower(email));
+			ALTER TABLE todos ADD COLUMN workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE;
+			CREATE INDEX todos_workspace_id_idx ON todos(workspace_id, id);
+			`,
+			// As with todos.project_id, todos.workspace_id goes without a
+			// foreign key on SQLite; deleting a workspace deletes its todos
+			// in the store.
+			SQLite: `
+			CREATE TABLE workspaces (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE TABLE workspace_members (
+				workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'member', 'guest')),
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (workspace_id, user_id)
+			);
+			CREATE INDEX workspace_members_user_id_idx ON workspace_members(user_id, workspace_id);
+			CREATE TABLE workspace_invites (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+				email TEXT NOT NULL,
+				role TEXT NOT NULL CHECK (role IN ('admin', 'member', 'guest')),
+				token TEXT NOT NULL UNIQUE,
+				invited_by INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				accepted_at TIMESTAMP
+			);
+			CREATE INDEX workspace_invites_workspace_id_idx ON workspace_invites(workspace_id);
+			CREATE INDEX workspace_invites_email_idx ON workspace_invites(lower(email));
+			ALTER TABLE todos ADD COLUMN workspace_id INTEGER;
+			CREATE INDEX todos_workspace_id_idx ON todos(workspace_id, id);
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			DROP INDEX todos_workspace_id_idx;
+			ALTER TABLE todos DROP COLUMN workspace_id;
+			DROP TABLE workspace_invites;
+			DROP TABLE workspace_members;
+			DROP TABLE workspaces;
+			`,
+		},
+	},
+	{
+		Version: 14,
+		Name:    "add share links",
+		Up: Script{
+			Postgres: `
+			CREATE TABLE share_links (
+				id SERIAL PRIMARY KEY,
+				todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+				token TEXT NOT NULL UNIQUE,
+				created_by INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				expires_at TIMESTAMPTZ,
+				revoked_at TIMESTAMPTZ,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			);
+			CREATE INDEX share_links_todo_id_idx ON share_links(todo_id, id);
+			CREATE TABLE share_link_accesses (
+				id SERIAL PRIMARY KEY,
+				link_id INTEGER NOT NULL REFERENCES share_links(id) ON DELETE CASCADE,
+				ip TEXT NOT NULL,
+				user_agent TEXT NOT NULL,
+				accessed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			);
+			CREATE INDEX share_link_accesses_link_id_idx ON share_link_accesses(link_id, accessed_at);
+			`,
+			SQLite: `
+			CREATE TABLE share_links (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+				token TEXT NOT NULL UNIQUE,
+				created_by INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				expires_at TIMESTAMP,
+				revoked_at TIMESTAMP,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX share_links_todo_id_idx ON share_links(todo_id, id);
+			CREATE TABLE share_link_accesses (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				link_id INTEGER NOT NULL REFERENCES share_links(id) ON DELETE CASCADE,
+				ip TEXT NOT NULL,
+				user_agent TEXT NOT NULL,
+				accessed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX share_link_accesses_link_id_idx ON share_link_accesses(link_id, accessed_at);
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			DROP TABLE share_link_accesses;
+			DROP TABLE share_links;
+			`,
+		},
+	},
+	{
+		Version: 15,
+		Name:    "add todo history",
+		Up: Script{
+			// todo_history has no foreign keys so that it outlives the todos
+			// and users it mentions, and refuses updates and deletes.
+			Postgres: `
+			CREATE TABLE todo_history (
+				id SERIAL PRIMARY KEY,
+				todo_id INTEGER NOT NULL,
+				actor_id INTEGER NOT NULL,
+				action TEXT NOT NULL,
+				diff TEXT NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			);
+			CREATE INDEX todo_history_todo_id_idx ON todo_history(todo_id, id);
+			CREATE FUNCTION todo_history_append_only() RETURNS trigger AS $$
+			BEGIN
+				RAISE EXCEPTION 'todo_history is append-only';
+			END;
+			$$ LANGUAGE plpgsql;
+			CREATE TRIGGER todo_history_no_update BEFORE UPDATE ON todo_history
+				FOR EACH ROW EXECUTE FUNCTION todo_history_append_only();
+			CREATE TRIGGER todo_history_no_delete BEFORE DELETE ON todo_history
+				FOR EACH ROW EXECUTE FUNCTION todo_history_append_only();
+			CREATE TRIGGER todo_history_no_truncate BEFORE TRUNCATE ON todo_history
+				FOR EACH STATEMENT EXECUTE FUNCTION todo_history_append_only();
+			`,
+			SQLite: `
+			CREATE TABLE todo_history (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				todo_id INTEGER NOT NULL,
+				actor_id INTEGER NOT NULL,
+				action TEXT NOT NULL,
+				diff TEXT NOT NULL CHECK (json_valid(diff)),
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX todo_history_todo_id_idx ON todo_history(todo_id, id);
+			CREATE TRIGGER todo_history_no_update BEFORE UPDATE ON todo_history
+			BEGIN
+				SELECT RAISE(ABORT, 'todo_history is append-only');
+			END;
+			CREATE TRIGGER todo_history_no_delete BEFORE DELETE ON todo_history
+			BEGIN
+				SELECT RAISE(ABORT, 'todo_history is append-only');
+			END;
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			DROP TABLE todo_history;
+			DROP FUNCTION todo_history_append_only();
+			`,
+			SQLite: `
+			DROP TABLE todo_history;
+			`,
+		},
+	},
+	{
+		Version: 16,
+		Name:    "add todo versions and undo tokens",
+		Up: Script{
+			Postgres: `
+			CREATE TABLE todo_versions (
+				todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+				version INTEGER NOT NULL,
+				title TEXT NOT NULL,
+				list TEXT NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				PRIMARY KEY (todo_id, version)
+			);
+			CREATE TABLE undo_tokens (
+				token TEXT PRIMARY KEY,
+				todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				action TEXT NOT NULL,
+				version INTEGER NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				expires_at TIMESTAMPTZ NOT NULL
+			);
+			CREATE INDEX undo_tokens_expires_at_idx ON undo_tokens(expires_at);
+			`,
+			SQLite: `
+			CREATE TABLE todo_versions (
+				todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+				version INTEGER NOT NULL,
+				title TEXT NOT NULL,
+				list TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (todo_id, version)
+			);
+			CREATE TABLE undo_tokens (
+				token TEXT PRIMARY KEY,
+				todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				action TEXT NOT NULL,
+				version INTEGER NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				expires_at TIMESTAMP NOT NULL
+			);
+			CREATE INDEX undo_tokens_expires_at_idx ON undo_tokens(expires_at);
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			DROP TABLE undo_tokens;
+			DROP TABLE todo_versions;
+			`,
+		},
+	},
+	{
+		Version: 17,
+		Name:    "add refresh tokens",
+		Up: Script{
+			Postgres: `
+			CREATE TABLE refresh_tokens (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				family_id TEXT NOT NULL,
+				token_hash TEXT NOT NULL UNIQUE,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				expires_at TIMESTAMPTZ NOT NULL,
+				used_at TIMESTAMPTZ,
+				revoked_at TIMESTAMPTZ
+			);
+			CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens(family_id);
+			CREATE INDEX refresh_tokens_expires_at_idx ON refresh_tokens(expires_at);
+			`,
+			SQLite: `
+			CREATE TABLE refresh_tokens (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				family_id TEXT NOT NULL,
+				token_hash TEXT NOT NULL UNIQUE,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				expires_at TIMESTAMP NOT NULL,
+				used_at TIMESTAMP,
+				revoked_at TIMESTAMP
+			);
+			CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens(family_id);
+			CREATE INDEX refresh_tokens_expires_at_idx ON refresh_tokens(expires_at);
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			DROP TABLE refresh_tokens;
+			`,
+		},
+	},
+	{
+		Version: 18,
+		Name:    "add sessions",
+		Up: Script{
+			Postgres: `
+			CREATE TABLE sessions (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				family_id TEXT NOT NULL UNIQUE,
+				user_agent TEXT NOT NULL,
+				ip TEXT NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				last_used_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				revoked_at TIMESTAMPTZ
+			);
+			CREATE INDEX sessions_user_id_idx ON sessions(user_id, last_used_at);
+			CREATE TABLE access_tokens (
+				jti TEXT PRIMARY KEY,
+				session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+				user_agent TEXT NOT NULL,
+				ip TEXT NOT NULL,
+				issued_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				expires_at TIMESTAMPTZ NOT NULL
+			);
+			CREATE INDEX access_tokens_expires_at_idx ON access_tokens(expires_at);
+			`,
+			SQLite: `
+			CREATE TABLE sessions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				family_id TEXT NOT NULL UNIQUE,
+				user_agent TEXT NOT NULL,
+				ip TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				last_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				revoked_at TIMESTAMP
+			);
+			CREATE INDEX sessions_user_id_idx ON sessions(user_id, last_used_at);
+			CREATE TABLE access_tokens (
+				jti TEXT PRIMARY KEY,
+				session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+				user_agent TEXT NOT NULL,
+				ip TEXT NOT NULL,
+				issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				expires_at TIMESTAMP NOT NULL
+			);
+			CREATE INDEX access_tokens_expires_at_idx ON access_tokens(expires_at);
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			DROP TABLE access_tokens;
+			DROP TABLE sessions;
+			`,
+		},
+	},
+	{
+		Version: 19,
+		Name:    "add password resets",
+		Up: Script{
+			Postgres: `
+			CREATE TABLE password_resets (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				token_hash TEXT NOT NULL UNIQUE,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				expires_at TIMESTAMPTZ NOT NULL,
+				used_at TIMESTAMPTZ
+			);
+			CREATE INDEX password_resets_user_id_idx ON password_resets(user_id);
+			CREATE INDEX password_resets_expires_at_idx ON password_resets(expires_at);
+			`,
+			SQLite: `
+			CREATE TABLE password_resets (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				token_hash TEXT NOT NULL UNIQUE,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				expires_at TIMESTAMP NOT NULL,
+				used_at TIMESTAMP
+			);
+			CREATE INDEX password_resets_user_id_idx ON password_resets(user_id);
+			CREATE INDEX password_resets_expires_at_idx ON password_resets(expires_at);
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			DROP TABLE password_resets;
+			`,
+		},
+	},
+	{
+		Version: 20,
+		Name:    "add email verification",
+		Up: Script{
+			// Accounts from before verification existed count as verified.
+			Postgres: `
+			ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+			ALTER TABLE users ADD COLUMN verification_sent_at TIMESTAMPTZ;
+			UPDATE users SET email_verified_at = COALESCE(created_at, NOW());
+			`,
+			SQLite: `
+			ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+			ALTER TABLE users ADD COLUMN verification_sent_at TIMESTAMP;
+			UPDATE users SET email_verified_at = COALESCE(created_at, CURRENT_TIMESTAMP);
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			ALTER TABLE users DROP COLUMN verification_sent_at;
+			ALTER TABLE users DROP COLUMN email_verified_at;
+			`,
+		},
+	},
+	{
+		Version: 21,
+		Name:    "add totp",
+		Up: Script{
+			Postgres: `
+			CREATE TABLE totp_factors (
+				user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+				secret TEXT NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				confirmed_at TIMESTAMPTZ,
+				last_used_step BIGINT NOT NULL DEFAULT 0,
+				failed_attempts INTEGER NOT NULL DEFAULT 0,
+				failed_at TIMESTAMPTZ
+			);
+			CREATE TABLE recovery_codes (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				code_hash TEXT NOT NULL UNIQUE,
+				used_at TIMESTAMPTZ
+			);
+			CREATE INDEX recovery_codes_user_id_idx ON recovery_codes(user_id);
+			`,
+			SQLite: `
+			CREATE TABLE totp_factors (
+				user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+				secret TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				confirmed_at TIMESTAMP,
+				last_used_step INTEGER NOT NULL DEFAULT 0,
+				failed_attempts INTEGER NOT NULL DEFAULT 0,
+				failed_at TIMESTAMP
+			);
+			CREATE TABLE recovery_codes (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				code_hash TEXT NOT NULL UNIQUE,
+				used_at TIMESTAMP
+			);
+			CREATE INDEX recovery_codes_user_id_idx ON recovery_codes(user_id);
+			`,
+		},
+		Down: Script{
+			Postgres: `
+			DROP TABLE recovery_codes;
+			DROP TABLE totp_factors;
+			`,
+		},
+	},
+}
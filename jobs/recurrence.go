@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"project_todo/models"
+	"project_todo/utils"
+	"time"
+)
+
+const DefaultRecurrenceInterval = time.Minute
+
+// RecurrenceInterval is how often recurring todos are checked, from
+// RECURRENCE_INTERVAL. Zero stops new occurrences from being generated.
+func RecurrenceInterval() time.Duration {
+	return utils.OptionalDurationEnv("RECURRENCE_INTERVAL", DefaultRecurrenceInterval)
+}
+
+// GenerateRecurrences creates the next todo of each recurring series whose
+// current todo is completed or whose period is over: once straight away,
+// then every interval until ctx is done.
+func GenerateRecurrences(ctx context.Context, store models.RecurrenceStore, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		generateRecurrences(store, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func generateRecurrences(store models.RecurrenceStore, now time.Time) {
+	todos, err := store.PendingRecurrences()
+	if err != nil {
+		fmt.Println("Error loading recurring todos:", err)
+		return
+	}
+	for _, todo := range todos {
+		next := todo.NextOccurrence(now)
+		if next == nil {
+			continue
+		}
+		err = store.CreateNextOccurrence(todo, next)
+		if errors.Is(err, models.ErrVersionConflict) {
+			// Another instance got there first.
+			continue
+		}
+		if err != nil {
+			fmt.Println("Error creating next occurrence of todo", todo.ID, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"project_todo/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateRecurrences(t *testing.T) {
+	store := models.NewMemoryStore()
+	now := time.Now()
+	due := now.Add(time.Hour)
+	todo := &models.Todo{
+		Title:      "Weekly ops",
+		List:       []models.TodoItem{{Item: "patch servers"}},
+		IsActive:   true,
+		UserID:     1,
+		DueAt:      &due,
+		Recurrence: "FREQ=WEEKLY",
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	assert.NoError(t, store.CreateTodo(todo))
+
+	// Still open and within its week.
+	generateRecurrences(store, now)
+	pending, err := store.PendingRecurrences()
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{todo.ID}, todoIds(pending))
+
+	// Checking the last item completes it.
+	todo.List[0].Checked = true
+	assert.NoError(t, store.UpdateTodo(*todo, todo.UserID))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		GenerateRecurrences(ctx, store, time.Millisecond)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		fetched, err := store.GetTodoById(todo.ID)
+		return err == nil && fetched.NextID != 0
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	pending, err = store.PendingRecurrences()
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 1) {
+		next := pending[0]
+		assert.Equal(t, "Weekly ops", next.Title)
+		assert.False(t, next.List[0].Checked)
+		assert.Equal(t, due.AddDate(0, 0, 7).Unix(), next.DueAt.Unix())
+	}
+}
+
+func todoIds(todos []models.Todo) []int64 {
+	var ids []int64
+	for _, todo := range todos {
+		ids = append(ids, todo.ID)
+	}
+	return ids
+}
@@ -0,0 +1,77 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"project_todo/models"
+	"project_todo/notify"
+	"project_todo/utils"
+	"time"
+)
+
+const DefaultReminderInterval = time.Minute
+
+// ReminderLead is how long before the due date reminders go out, from
+// REMINDER_LEAD (a Go duration such as "15m"). It defaults to zero, so
+// reminders are sent once the due date is reached.
+func ReminderLead() time.Duration {
+	return utils.OptionalDurationEnv("REMINDER_LEAD", 0)
+}
+
+// ReminderInterval is how often due reminders are checked, from
+// REMINDER_INTERVAL. Zero turns reminders off.
+func ReminderInterval() time.Duration {
+	return utils.OptionalDurationEnv("REMINDER_INTERVAL", DefaultReminderInterval)
+}
+
+// SendReminders notifies owners of todos and items that are due within
+// lead: once straight away, then every interval until ctx is done.
+func SendReminders(ctx context.Context, store models.ReminderStore, notifier notify.Notifier, lead, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		sendReminders(store, notifier, lead)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendReminders marks a reminder as sent only once the notifier accepted
+// it, so failed deliveries are retried on the next run.
+func sendReminders(store models.ReminderStore, notifier notify.Notifier, lead time.Duration) {
+	reminders, err := store.DueReminders(time.Now().Add(lead))
+	if err != nil {
+		fmt.Println("Error loading reminders:", err)
+		return
+	}
+	for _, reminder := range reminders {
+		err = notifier.Notify(reminderNotification(reminder))
+		if err != nil {
+			fmt.Println("Error sending reminder for todo", reminder.TodoID, err)
+			continue
+		}
+		err = store.MarkReminded(reminder)
+		if err != nil {
+			fmt.Println("Error marking reminder for todo", reminder.TodoID, err)
+		}
+	}
+}
+
+func reminderNotification(r models.Reminder) notify.Notification {
+	due := r.DueAt.In(r.Location()).Format("Mon 2 Jan 2006 15:04 MST")
+	n := notify.Notification{UserID: r.UserID, Email: r.Email}
+	if r.ItemID != 0 {
+		n.Subject = "Reminder: " + r.Item
+		n.Body = fmt.Sprintf("%q on %q is due %s.", r.Item, r.Title, due)
+	} else {
+		n.Subject = "Reminder: " + r.Title
+		n.Body = fmt.Sprintf("%q is due %s.", r.Title, due)
+	}
+	return n
+}
@@ -0,0 +1,106 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"project_todo/models"
+	"project_todo/notify"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []notify.Notification
+	err  error
+}
+
+func (r *recordingNotifier) Notify(n notify.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+func (r *recordingNotifier) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sent)
+}
+
+func dueTodo(t *testing.T, store *models.MemoryStore, dueAt time.Time) {
+	user := &models.User{Email: "john@example.com"}
+	assert.NoError(t, store.CreateUser(user))
+	item := dueAt.Add(-time.Hour)
+	todo := &models.Todo{
+		Title:     "Taxes",
+		List:      []models.TodoItem{{Item: "receipts", DueAt: &item}},
+		IsActive:  true,
+		UserID:    user.ID,
+		DueAt:     &dueAt,
+		TimeZone:  "Europe/Berlin",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	assert.NoError(t, store.CreateTodo(todo))
+}
+
+func TestSendReminders(t *testing.T) {
+	store := models.NewMemoryStore()
+	dueTodo(t, store, time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC))
+	notifier := &recordingNotifier{}
+
+	sendReminders(store, notifier, 0)
+	if assert.Len(t, notifier.sent, 2) {
+		assert.Equal(t, "Reminder: receipts", notifier.sent[0].Subject)
+		assert.Equal(t, "Reminder: Taxes", notifier.sent[1].Subject)
+		assert.Equal(t, `"Taxes" is due Mon 15 Jan 2024 10:00 CET.`, notifier.sent[1].Body)
+		assert.Equal(t, "john@example.com", notifier.sent[1].Email)
+	}
+
+	// Each due date is only reminded once.
+	sendReminders(store, notifier, 0)
+	assert.Len(t, notifier.sent, 2)
+}
+
+func TestSendReminders_RetriesFailedDeliveries(t *testing.T) {
+	store := models.NewMemoryStore()
+	dueTodo(t, store, time.Now().Add(30*time.Minute))
+	notifier := &recordingNotifier{err: errors.New("smtp down")}
+
+	// Not due yet without a lead time.
+	sendReminders(store, notifier, 0)
+	reminders, err := store.DueReminders(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, reminders, 2)
+
+	sendReminders(store, notifier, time.Hour)
+	reminders, err = store.DueReminders(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, reminders, 2)
+
+	notifier.err = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		SendReminders(ctx, store, notifier, time.Hour, time.Millisecond)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return notifier.count() == 2 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+	assert.Equal(t, 2, notifier.count())
+}
+
+func TestReminderInterval(t *testing.T) {
+	t.Setenv("REMINDER_INTERVAL", "")
+	assert.Equal(t, DefaultReminderInterval, ReminderInterval())
+	t.Setenv("REMINDER_LEAD", "15m")
+	assert.Equal(t, 15*time.Minute, ReminderLead())
+}
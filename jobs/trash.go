@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"project_todo/models"
+	"project_todo/utils"
+	"time"
+)
+
+const (
+	DefaultTrashRetention = 30 * 24 * time.Hour
+	DefaultPurgeInterval  = time.Hour
+)
+
+// TrashRetention is how long trashed todos are kept, from TRASH_RETENTION
+// (a Go duration such as "720h"). Zero keeps them until they are deleted by
+// hand.
+func TrashRetention() time.Duration {
+	return utils.OptionalDurationEnv("TRASH_RETENTION", DefaultTrashRetention)
+}
+
+// PurgeInterval is how often the trash is checked, from TRASH_PURGE_INTERVAL.
+func PurgeInterval() time.Duration {
+	return utils.DurationEnv("TRASH_PURGE_INTERVAL", DefaultPurgeInterval)
+}
+
+// PurgeTrash permanently deletes todos that have been in the trash longer
+// than retention: once straight away, then every interval until ctx is done.
+func PurgeTrash(ctx context.Context, store models.TrashStore, retention, interval time.Duration) {
+	if retention <= 0 || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purgeTrash(store, retention)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func purgeTrash(store models.TrashStore, retention time.Duration) {
+	count, err := store.PurgeTrash(time.Now().Add(-retention))
+	if err != nil {
+		fmt.Println("Error purging trash:", err)
+		return
+	}
+	if count > 0 {
+		fmt.Println("Purged", count, "todo(s) from the trash")
+	}
+}
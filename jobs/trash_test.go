@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"project_todo/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func trashedTodo(t *testing.T, store *models.MemoryStore) int64 {
+	todo := &models.Todo{Title: "Old", UserID: 1, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	assert.NoError(t, store.CreateTodo(todo))
+	assert.NoError(t, store.TrashTodo(*todo, todo.UserID))
+	return todo.ID
+}
+
+func TestPurgeTrash(t *testing.T) {
+	store := models.NewMemoryStore()
+	id := trashedTodo(t, store)
+
+	// Still within the retention period.
+	purgeTrash(store, time.Hour)
+	_, err := store.GetTodoById(id)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		PurgeTrash(ctx, store, time.Nanosecond, time.Millisecond)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		_, err := store.GetTodoById(id)
+		return errors.Is(err, models.ErrNotFound)
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+}
+
+func TestPurgeTrash_DisabledByZeroRetention(t *testing.T) {
+	store := models.NewMemoryStore()
+	id := trashedTodo(t, store)
+
+	// Returns straight away without touching the trash.
+	PurgeTrash(context.Background(), store, 0, time.Millisecond)
+	_, err := store.GetTodoById(id)
+	assert.NoError(t, err)
+}
+
+func TestDurationEnv(t *testing.T) {
+	t.Setenv("TRASH_RETENTION", "")
+	assert.Equal(t, DefaultTrashRetention, TrashRetention())
+	t.Setenv("TRASH_RETENTION", "48h")
+	assert.Equal(t, 48*time.Hour, TrashRetention())
+	t.Setenv("TRASH_RETENTION", "two days")
+	assert.Equal(t, DefaultTrashRetention, TrashRetention())
+	t.Setenv("TRASH_PURGE_INTERVAL", "5m")
+	assert.Equal(t, 5*time.Minute, PurgeInterval())
+
+	// Zero turns purging off, but only where it means that.
+	t.Setenv("TRASH_RETENTION", "0")
+	assert.Equal(t, time.Duration(0), TrashRetention())
+	t.Setenv("TRASH_RETENTION", "-1h")
+	assert.Equal(t, DefaultTrashRetention, TrashRetention())
+	t.Setenv("TRASH_PURGE_INTERVAL", "0")
+	assert.Equal(t, DefaultPurgeInterval, PurgeInterval())
+}
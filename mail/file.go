@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// FileMailer appends each message to Path as one line of JSON.
+type FileMailer struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func NewFileMailer(path string) *FileMailer {
+	return &FileMailer{Path: path}
+}
+
+func (f *FileMailer) Send(m Message) error {
+	line, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Sent reads back the messages sent so far, oldest first.
+func (f *FileMailer) Sent() ([]Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.Open(f.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var messages []Message
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var m Message
+		err = json.Unmarshal(scanner.Bytes(), &m)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, scanner.Err()
+}
@@ -0,0 +1,51 @@
+// Package mail sends email to users. The server only depends on the Mailer
+// interface: SMTPMailer delivers mail, the others keep it local for
+// development and tests.
+package mail
+
+import (
+	"fmt"
+	"os"
+)
+
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+type Mailer interface {
+	Send(message Message) error
+}
+
+const DefaultFile = "mail.log"
+
+// FromEnv picks the mailer named by MAILER: "log" (the default) prints to
+// stdout, "file" appends JSON lines to MAILER_FILE and "smtp" sends through
+// SMTP_HOST (see NewSMTPMailer).
+func FromEnv() Mailer {
+	switch kind := os.Getenv("MAILER"); kind {
+	case "", "log":
+		return LogMailer{}
+	case "file":
+		path := os.Getenv("MAILER_FILE")
+		if path == "" {
+			path = DefaultFile
+		}
+		return NewFileMailer(path)
+	case "smtp":
+		return NewSMTPMailer(os.Getenv("SMTP_HOST"), os.Getenv("SMTP_PORT"), os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"), os.Getenv("MAIL_FROM"))
+	default:
+		fmt.Println("Unknown MAILER", kind, "- logging mail instead")
+		return LogMailer{}
+	}
+}
+
+// LogMailer prints mail instead of sending it.
+type LogMailer struct{}
+
+func (LogMailer) Send(m Message) error {
+	fmt.Printf("Mail to %s: %s\n%s\n", m.To, m.Subject, m.Body)
+	return nil
+}
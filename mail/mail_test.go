@@ -0,0 +1,54 @@
+package mail
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileMailer(t *testing.T) {
+	mailer := NewFileMailer(filepath.Join(t.TempDir(), "mail.log"))
+	sent, err := mailer.Sent()
+	assert.NoError(t, err)
+	assert.Empty(t, sent)
+
+	assert.NoError(t, mailer.Send(Message{To: "john@example.com", Subject: "First"}))
+	assert.NoError(t, mailer.Send(Message{To: "jane@example.com", Subject: "Second", Body: "Hello"}))
+	sent, err = mailer.Sent()
+	assert.NoError(t, err)
+	assert.Equal(t, []Message{
+		{To: "john@example.com", Subject: "First"},
+		{To: "jane@example.com", Subject: "Second", Body: "Hello"},
+	}, sent)
+}
+
+func TestSMTPMailerFormat(t *testing.T) {
+	mailer := NewSMTPMailer("smtp.example.com", "", "", "", "todos@example.com")
+	assert.Equal(t, DefaultSMTPPort, mailer.Port)
+	message := mailer.format(Message{To: "john@example.com", Subject: "Reset\r\nBcc: jim@example.com", Body: "Hi\nthere"})
+	assert.Equal(t, "From: todos@example.com\r\nTo: john@example.com\r\nSubject: ResetBcc: jim@example.com\r\n"+
+		"MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\nHi\r\nthere", string(message))
+
+	assert.Error(t, NewSMTPMailer("", "", "", "", "todos@example.com").Send(Message{To: "john@example.com"}))
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("MAILER", "")
+	assert.IsType(t, LogMailer{}, FromEnv())
+
+	t.Setenv("MAILER", "file")
+	t.Setenv("MAILER_FILE", "outbox.log")
+	mailer, ok := FromEnv().(*FileMailer)
+	assert.True(t, ok)
+	assert.Equal(t, "outbox.log", mailer.Path)
+
+	t.Setenv("MAILER", "smtp")
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	smtpMailer, ok := FromEnv().(*SMTPMailer)
+	assert.True(t, ok)
+	assert.Equal(t, "smtp.example.com", smtpMailer.Host)
+
+	t.Setenv("MAILER", "pigeon")
+	assert.IsType(t, LogMailer{}, FromEnv())
+}
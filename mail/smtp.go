@@ -0,0 +1,54 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+const DefaultSMTPPort = "587"
+
+// SMTPMailer sends plain text mail through an SMTP server, authenticating
+// with PLAIN auth when Username is set. net/smtp upgrades to TLS when the
+// server offers STARTTLS.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	if port == "" {
+		port = DefaultSMTPPort
+	}
+	return &SMTPMailer{Host: host, Port: port, Username: username, Password: password, From: from}
+}
+
+func (s *SMTPMailer) Send(m Message) error {
+	if s.Host == "" || s.From == "" {
+		return errors.New("SMTP_HOST and MAIL_FROM must be set to send mail")
+	}
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	return smtp.SendMail(net.JoinHostPort(s.Host, s.Port), auth, s.From, []string{m.To}, s.format(m))
+}
+
+// format renders the message with its headers. Line breaks are dropped
+// from header values so they can't smuggle in headers of their own.
+func (s *SMTPMailer) format(m Message) []byte {
+	header := strings.NewReplacer("\r", "", "\n", "")
+	var message strings.Builder
+	fmt.Fprintf(&message, "From: %s\r\n", header.Replace(s.From))
+	fmt.Fprintf(&message, "To: %s\r\n", header.Replace(m.To))
+	fmt.Fprintf(&message, "Subject: %s\r\n", header.Replace(m.Subject))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	message.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	return []byte(message.String())
+}
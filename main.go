@@ -1,9 +1,17 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"os"
 	"project_todo/db"
+	"project_todo/jobs"
+	"project_todo/mail"
+	"project_todo/models"
+	"project_todo/notify"
 	"project_todo/routes"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -15,11 +23,35 @@ func main() {
 		fmt.Println("Error loading .env file", err)
 	}
 
-	db.InitDB()
+	demo := flag.Bool("demo", false, "run with an in-memory store seeded with a demo user")
+	flag.Parse()
+
+	if flag.Arg(0) == "migrate" {
+		runMigrate(flag.Args()[1:])
+		return
+	}
+
+	if os.Getenv("APP_URL") == "" {
+		fmt.Println("APP_URL is not set: password reset and email verification mails can't be sent")
+	}
+
+	var store models.Store
+	if *demo {
+		store = newDemoStore()
+	} else {
+		driver := db.Driver()
+		store = models.NewSQLStore(db.InitDB(driver), driver)
+	}
+	go jobs.PurgeTrash(context.Background(), store, jobs.TrashRetention(), jobs.PurgeInterval())
+	go jobs.SendReminders(context.Background(), store, notify.FromEnv(), jobs.ReminderLead(), jobs.ReminderInterval())
+	go jobs.GenerateRecurrences(context.Background(), store, jobs.RecurrenceInterval())
+
 	server := gin.Default()
 
 	// Serve static files
 	server.Static("/static", "./static")
+	// shared.html is also the template for todos opened through share links
+	server.LoadHTMLFiles("./static/shared.html")
 
 	// Serve the HTML files based on the URL path
 	server.GET("/", func(c *gin.Context) {
@@ -34,11 +66,19 @@ func main() {
 		c.File("./static/login.html")
 	})
 
+	server.GET("/app-reset-password", func(c *gin.Context) {
+		c.File("./static/reset-password.html")
+	})
+
+	server.GET("/app-verify-email", func(c *gin.Context) {
+		c.File("./static/verify-email.html")
+	})
+
 	server.GET("/todolist", func(c *gin.Context) {
 		c.File("./static/todos.html")
 	})
 
-	routes.RegisterRoutes(server)
+	routes.RegisterRoutes(server, store, mail.FromEnv())
 
 	// Serve index.html as the default route
 	server.NoRoute(func(c *gin.Context) {
@@ -47,3 +87,26 @@ func main() {
 
 	server.Run(":8080") // localhost:8080
 }
+
+// newDemoStore returns an in-memory store with a single user to log in with.
+// Nothing is persisted; restarting the server resets the data.
+func newDemoStore() models.Store {
+	store := models.NewMemoryStore()
+	now := time.Now()
+	user := models.User{
+		Email:           "demo@example.com",
+		FirstName:       "Demo",
+		LastName:        "User",
+		Password:        "demo1234",
+		IsActive:        true,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		EmailVerifiedAt: &now,
+	}
+	err := user.Save(store)
+	if err != nil {
+		panic("Unable to seed the demo user")
+	}
+	fmt.Println("Demo mode: log in with", user.Email, "/", "demo1234")
+	return store
+}
@@ -1,24 +1,101 @@
 package middlewares
 
 import (
+	"errors"
 	"net/http"
+	"project_todo/models"
 	"project_todo/utils"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Authenticate(context *gin.Context) {
-	token := context.Request.Header.Get("Authorization")
-	if token == "" {
-		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized access"})
-		return
+// Revocations caches the session each access token was handed out in and
+// whether it has been revoked, so Authenticate doesn't go to the store on
+// every request. Entries are trusted for ttl; handlers call Reset after
+// revoking a session so that it stops working here straight away.
+type Revocations struct {
+	store   models.SessionStore
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]revocation
+}
+
+type revocation struct {
+	sessionId int64
+	revoked   bool
+	checkedAt time.Time
+}
+
+func NewRevocations(store models.SessionStore, ttl time.Duration) *Revocations {
+	return &Revocations{store: store, ttl: ttl, entries: make(map[string]revocation)}
+}
+
+// Check returns the session of the access token jti and whether it was
+// revoked. Tokens the store doesn't track count as revoked.
+func (r *Revocations) Check(jti string) (int64, bool, error) {
+	now := time.Now()
+	r.mu.Lock()
+	entry, ok := r.entries[jti]
+	r.mu.Unlock()
+	if ok && now.Sub(entry.checkedAt) < r.ttl {
+		return entry.sessionId, entry.revoked, nil
+	}
+
+	session, err := r.store.GetTokenSession(jti)
+	if errors.Is(err, models.ErrNotFound) {
+		return 0, true, nil
 	}
-	userId, err := utils.VerifyToken(token)
 	if err != nil {
-		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized access"})
-		return
+		return 0, false, err
+	}
+	entry = revocation{sessionId: session.ID, revoked: session.RevokedAt != nil, checkedAt: now}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for cached, old := range r.entries {
+		if now.Sub(old.checkedAt) >= r.ttl {
+			delete(r.entries, cached)
+		}
 	}
+	r.entries[jti] = entry
+	return entry.sessionId, entry.revoked, nil
+}
 
-	context.Set("userId", userId)
-	context.Next()
+// Reset forgets everything cached.
+func (r *Revocations) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = make(map[string]revocation)
+}
+
+// Authenticate lets requests with a valid access token through, unless the
+// session it was handed out in has been revoked. Handlers find the user
+// and session IDs under "userId" and "sessionId".
+func Authenticate(revocations *Revocations) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		token := context.Request.Header.Get("Authorization")
+		if token == "" {
+			context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized access"})
+			return
+		}
+		claims, err := utils.VerifyToken(token)
+		if err != nil {
+			context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized access"})
+			return
+		}
+		sessionId, revoked, err := revocations.Check(claims.JTI)
+		if err != nil {
+			context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Unable to check the session"})
+			return
+		}
+		if revoked {
+			context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized access"})
+			return
+		}
+
+		context.Set("userId", claims.UserID)
+		context.Set("sessionId", sessionId)
+		context.Next()
+	}
 }
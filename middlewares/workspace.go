@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"net/http"
+	"project_todo/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Workspace runs after Authenticate on routes under
+// /workspaces/:workspaceId. It resolves the workspace from the route, looks
+// up the user's role in it and lets the request through only if the role
+// allows action (see models.WorkspaceAllows). Handlers find the workspace
+// ID and role under "workspaceId" and "workspaceRole".
+func Workspace(store models.WorkspaceStore, action models.Action) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		workspaceId, err := strconv.ParseInt(context.Param("workspaceId"), 10, 64)
+		if err != nil {
+			context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Unable to parse workspace id"})
+			return
+		}
+		role, err := store.GetMemberRole(workspaceId, context.GetInt64("userId"))
+		if err != nil {
+			context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch workspace"})
+			return
+		}
+		// Non-members can't tell a workspace they are not in from one that
+		// does not exist.
+		if role == "" {
+			context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "Workspace not found"})
+			return
+		}
+		if !models.WorkspaceAllows(role, action) {
+			context.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "Your workspace role does not allow that"})
+			return
+		}
+
+		context.Set("workspaceId", workspaceId)
+		context.Set("workspaceRole", role)
+		context.Next()
+	}
+}
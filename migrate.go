@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"project_todo/db"
+	"strconv"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | status"
+
+// runMigrate handles `go run . migrate ...`. It exits the process on failure.
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		fmt.Println(migrateUsage)
+		os.Exit(2)
+	}
+
+	driver := db.Driver()
+	conn := db.Open(driver)
+	defer conn.Close()
+	migrator := db.NewMigrator(conn, driver)
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		exitOnError(err)
+		fmt.Println("Applied", applied, "migration(s)")
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				fmt.Println(migrateUsage)
+				os.Exit(2)
+			}
+		}
+		reverted, err := migrator.Down(steps)
+		exitOnError(err)
+		fmt.Println("Reverted", reverted, "migration(s)")
+	case "status":
+		states, err := migrator.Status()
+		exitOnError(err)
+		for _, state := range states {
+			status := "pending"
+			if state.Applied {
+				status = "applied " + state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%4d  %-40s %s\n", state.Version, state.Name, status)
+		}
+	default:
+		fmt.Println(migrateUsage)
+		os.Exit(2)
+	}
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
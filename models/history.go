@@ -0,0 +1,109 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// What a history entry records being done to a todo.
+const (
+	HistoryCreated  = "created"
+	HistoryUpdated  = "updated"
+	HistoryTrashed  = "trashed"
+	HistoryRestored = "restored"
+	HistoryDeleted  = "deleted"
+	HistoryReverted = "reverted"
+)
+
+// HistoryEntry is one change to a todo in its append-only history. ActorID
+// is the user who made it, or 0 for the server; ActorEmail is filled in by
+// GetHistory while that user still exists.
+type HistoryEntry struct {
+	ID         int64     `db:"id" json:"id"`
+	TodoID     int64     `db:"todo_id" json:"todoId"`
+	ActorID    int64     `db:"actor_id" json:"actorId"`
+	ActorEmail string    `db:"-" json:"actorEmail,omitempty"`
+	Action     string    `db:"action" json:"action"`
+	Diff       TodoDiff  `db:"diff" json:"diff"`
+	CreatedAt  time.Time `db:"created_at" json:"createdAt"`
+}
+
+// TodoDiff is what a change did to a todo's title and checklist. Items are
+// matched by ID; Reordered is set when items present before and after
+// changed places.
+type TodoDiff struct {
+	Title     *TitleChange `json:"title,omitempty"`
+	Added     []ItemState  `json:"added,omitempty"`
+	Removed   []ItemState  `json:"removed,omitempty"`
+	Changed   []ItemChange `json:"changed,omitempty"`
+	Reordered bool         `json:"reordered,omitempty"`
+}
+
+type TitleChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ItemState is a checklist item as the history shows it.
+type ItemState struct {
+	ID      int64      `json:"id"`
+	Item    string     `json:"item"`
+	Checked bool       `json:"checked"`
+	DueAt   *time.Time `json:"dueAt,omitempty"`
+}
+
+type ItemChange struct {
+	From ItemState `json:"from"`
+	To   ItemState `json:"to"`
+}
+
+func itemState(item TodoItem) ItemState {
+	return ItemState{ID: item.ID, Item: item.Item, Checked: item.Checked, DueAt: item.DueAt}
+}
+
+func (s ItemState) equal(other ItemState) bool {
+	sameDue := s.DueAt == nil && other.DueAt == nil ||
+		s.DueAt != nil && other.DueAt != nil && s.DueAt.Equal(*other.DueAt)
+	return s.Item == other.Item && s.Checked == other.Checked && sameDue
+}
+
+// DiffTodos compares two states of a todo. A nil before stands for a todo
+// that did not exist yet, a nil after for one that no longer exists.
+func DiffTodos(before, after *Todo) TodoDiff {
+	if before == nil {
+		before = &Todo{}
+	}
+	if after == nil {
+		after = &Todo{}
+	}
+	var diff TodoDiff
+	if before.Title != after.Title {
+		diff.Title = &TitleChange{From: before.Title, To: after.Title}
+	}
+
+	previous := make(map[int64]TodoItem)
+	for _, item := range before.List {
+		previous[item.ID] = item
+	}
+	var kept, keptBefore []int64
+	for _, item := range after.List {
+		old, ok := previous[item.ID]
+		if !ok {
+			diff.Added = append(diff.Added, itemState(item))
+			continue
+		}
+		kept = append(kept, item.ID)
+		if from, to := itemState(old), itemState(item); !from.equal(to) {
+			diff.Changed = append(diff.Changed, ItemChange{From: from, To: to})
+		}
+	}
+	for _, item := range before.List {
+		if slices.Contains(kept, item.ID) {
+			keptBefore = append(keptBefore, item.ID)
+		} else {
+			diff.Removed = append(diff.Removed, itemState(item))
+		}
+	}
+	diff.Reordered = !slices.Equal(kept, keptBefore)
+	return diff
+}
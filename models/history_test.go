@@ -0,0 +1,41 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffTodos(t *testing.T) {
+	due := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	before := &Todo{Title: "Groceries", List: []TodoItem{
+		{ID: 1, Item: "milk"},
+		{ID: 2, Item: "eggs"},
+		{ID: 3, Item: "bread", DueAt: &due},
+	}}
+
+	diff := DiffTodos(nil, before)
+	assert.Equal(t, &TitleChange{To: "Groceries"}, diff.Title)
+	assert.Len(t, diff.Added, 3)
+	assert.False(t, diff.Reordered)
+	assert.Len(t, DiffTodos(before, nil).Removed, 3)
+	assert.Equal(t, TodoDiff{}, DiffTodos(before, before))
+
+	later := due.Add(time.Hour)
+	after := &Todo{Title: "Weekly groceries", List: []TodoItem{
+		{ID: 3, Item: "bread", DueAt: &later},
+		{ID: 1, Item: "milk", Checked: true},
+		{ID: 4, Item: "butter"},
+	}}
+	diff = DiffTodos(before, after)
+	assert.Equal(t, &TitleChange{From: "Groceries", To: "Weekly groceries"}, diff.Title)
+	assert.Equal(t, []ItemState{{ID: 4, Item: "butter"}}, diff.Added)
+	assert.Equal(t, []ItemState{{ID: 2, Item: "eggs"}}, diff.Removed)
+	if assert.Len(t, diff.Changed, 2) {
+		assert.Equal(t, int64(3), diff.Changed[0].To.ID)
+		assert.Equal(t, &later, diff.Changed[0].To.DueAt)
+		assert.True(t, diff.Changed[1].To.Checked)
+	}
+	assert.True(t, diff.Reordered)
+}
@@ -0,0 +1,395 @@
+package models
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps everything in process memory. It mirrors the SQL
+// semantics the handlers rely on (auto-increment IDs, unique emails, todos
+// scoped by user) and is used by tests and the --demo mode.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	todos    map[int64]Todo
+	users    map[int64]User
+	tags     map[int64]Tag
+	projects map[int64]Project
+	// shares is keyed by todo ID, then by the ID of the user it is shared
+	// with.
+	shares     map[int64]map[int64]Share
+	lastTodoId int64
+	lastItemId int64
+	lastUserId int64
+	lastTagId  int64
+	// lastProjectId is the last ID handed to a project.
+	lastProjectId int64
+	workspaces    map[int64]Workspace
+	// members is keyed by workspace ID, then by user ID.
+	members         map[int64]map[int64]Member
+	invites         map[int64]Invite
+	lastWorkspaceId int64
+	lastInviteId    int64
+	links           map[int64]ShareLink
+	// accesses is keyed by link ID, oldest first.
+	accesses   map[int64][]LinkAccess
+	lastLinkId int64
+	// history is append-only, oldest first.
+	history       []HistoryEntry
+	lastHistoryId int64
+	// versions is keyed by todo ID, oldest first.
+	versions map[int64][]TodoVersion
+	undos    map[string]Undo
+	// refreshTokens is keyed by token ID.
+	refreshTokens      map[int64]RefreshToken
+	lastRefreshTokenId int64
+	sessions           map[int64]Session
+	lastSessionId      int64
+	// accessTokens is keyed by jti.
+	accessTokens map[string]IssuedToken
+	resets       map[int64]PasswordReset
+	lastResetId  int64
+	// totps is keyed by user ID and recoveryCodes by code hash.
+	totps              map[int64]TOTP
+	recoveryCodes      map[string]RecoveryCode
+	lastRecoveryCodeId int64
+	// reminded holds the due date each reminder was last sent for, keyed
+	// by todo and item ID (0 for the todo itself).
+	reminded map[[2]int64]time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		todos:         make(map[int64]Todo),
+		users:         make(map[int64]User),
+		tags:          make(map[int64]Tag),
+		projects:      make(map[int64]Project),
+		shares:        make(map[int64]map[int64]Share),
+		workspaces:    make(map[int64]Workspace),
+		members:       make(map[int64]map[int64]Member),
+		invites:       make(map[int64]Invite),
+		links:         make(map[int64]ShareLink),
+		accesses:      make(map[int64][]LinkAccess),
+		versions:      make(map[int64][]TodoVersion),
+		undos:         make(map[string]Undo),
+		refreshTokens: make(map[int64]RefreshToken),
+		sessions:      make(map[int64]Session),
+		accessTokens:  make(map[string]IssuedToken),
+		resets:        make(map[int64]PasswordReset),
+		totps:         make(map[int64]TOTP),
+		recoveryCodes: make(map[string]RecoveryCode),
+		reminded:      make(map[[2]int64]time.Time),
+	}
+}
+
+func (s *MemoryStore) CreateTodo(todo *Todo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastTodoId++
+	todo.ID = s.lastTodoId
+	todo.Version = 1
+	todo.Occurrence = max(todo.Occurrence, 1)
+	todo.NextID = 0
+	todo.Tags = nil
+	s.append(todo)
+	todo.List = s.syncItems(todo.ID, nil, todo.List)
+	s.todos[todo.ID] = copyTodo(*todo)
+	s.addHistory(todo.ID, todo.UserID, HistoryCreated, nil)
+	return nil
+}
+
+// append ranks the todo after all of the user's todos, or the workspace's,
+// and fills in the default priority, as insertTodo does. The caller holds
+// s.mu.
+func (s *MemoryStore) append(todo *Todo) {
+	last := ""
+	for _, existing := range s.todos {
+		sameList := existing.WorkspaceID == todo.WorkspaceID && (todo.WorkspaceID != 0 || existing.UserID == todo.UserID)
+		if sameList && existing.Rank > last {
+			last = existing.Rank
+		}
+	}
+	todo.Rank = RankBetween(last, "")
+	todo.Priority = priorityOrDefault(todo.Priority)
+}
+
+func (s *MemoryStore) GetAllTodos(q TodoQuery) (TodoPage, error) {
+	after, err := q.normalize()
+	if err != nil {
+		return TodoPage{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var todos []Todo
+	for _, todo := range s.todos {
+		share, shared := s.shares[todo.ID][q.UserID]
+		switch {
+		case q.WorkspaceID != 0:
+			if todo.WorkspaceID != q.WorkspaceID {
+				continue
+			}
+			shared = false
+		case todo.WorkspaceID != 0:
+			continue
+		case todo.UserID != q.UserID && (!q.IncludeShared || !shared):
+			continue
+		}
+		if q.matches(todo) && (after == nil || q.after(after, todo)) && (q.IncludeArchived || !s.inArchivedProject(todo)) {
+			todo = copyTodo(todo)
+			if shared && todo.UserID != q.UserID {
+				todo.Shared, todo.Role = true, share.Role
+			}
+			todos = append(todos, todo)
+		}
+	}
+	sort.Slice(todos, func(i, j int) bool { return q.less(todos[i], todos[j]) })
+	if len(todos) > q.Limit+1 {
+		todos = todos[:q.Limit+1]
+	}
+	return q.page(todos), nil
+}
+
+func (s *MemoryStore) SearchTodos(q SearchQuery) ([]SearchResult, error) {
+	q.normalize()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var todos []Todo
+	for _, todo := range s.todos {
+		if todo.UserID == q.UserID && todo.WorkspaceID == 0 && todo.DeletedAt == nil {
+			todos = append(todos, copyTodo(todo))
+		}
+	}
+	return q.search(todos), nil
+}
+
+func (s *MemoryStore) GetTodoById(id int64) (*Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	todo, ok := s.todos[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	todo = copyTodo(todo)
+	return &todo, nil
+}
+
+func (s *MemoryStore) UpdateTodo(todo Todo, actorId int64) error {
+	return s.updateTodo(todo, actorId, HistoryUpdated)
+}
+
+func (s *MemoryStore) RevertTodo(todo Todo, actorId int64) error {
+	return s.updateTodo(todo, actorId, HistoryReverted)
+}
+
+// updateTodo only touches the columns the SQL UPDATE sets, so CreatedAt and
+// UserID are kept from the stored row.
+func (s *MemoryStore) updateTodo(todo Todo, actorId int64, action string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.todos[todo.ID]
+	if !ok {
+		return nil
+	}
+	if todo.Version != 0 && todo.Version != existing.Version {
+		return ErrVersionConflict
+	}
+	before := copyTodo(existing)
+	s.saveVersion(existing, todo.UpdatedAt)
+	existing.Version++
+	existing.Title = todo.Title
+	existing.List = s.syncItems(todo.ID, existing.List, todo.List)
+	existing.IsActive = todo.IsActive
+	existing.UpdatedAt = todo.UpdatedAt
+	existing.DueAt = todo.DueAt
+	existing.TimeZone = todo.TimeZone
+	existing.Recurrence = todo.Recurrence
+	existing.Priority = priorityOrDefault(todo.Priority)
+	existing.ProjectID = todo.ProjectID
+	s.todos[todo.ID] = copyTodo(existing)
+	s.addHistory(todo.ID, actorId, action, &before)
+	return nil
+}
+
+func (s *MemoryStore) DeleteTodo(todo Todo, actorId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.todos[todo.ID]
+	if !ok {
+		return nil
+	}
+	if todo.Version != 0 && todo.Version != existing.Version {
+		return ErrVersionConflict
+	}
+	s.drop(todo.ID)
+	s.addHistory(todo.ID, actorId, HistoryDeleted, &existing)
+	return nil
+}
+
+// drop deletes the todo with its shares, links, versions and undo tokens.
+// The caller holds s.mu.
+func (s *MemoryStore) drop(todoId int64) {
+	delete(s.todos, todoId)
+	delete(s.shares, todoId)
+	delete(s.versions, todoId)
+	for token, undo := range s.undos {
+		if undo.TodoID == todoId {
+			delete(s.undos, token)
+		}
+	}
+	for linkId, link := range s.links {
+		if link.TodoID == todoId {
+			delete(s.links, linkId)
+			delete(s.accesses, linkId)
+		}
+	}
+}
+
+func (s *MemoryStore) MoveTodo(userId int64, move Move) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, ok := s.todos[move.ID]
+	if !ok || todo.UserID != userId || todo.WorkspaceID != 0 || todo.DeletedAt != nil {
+		return ErrNotFound
+	}
+	if move.Version != 0 && todo.Version != move.Version {
+		return ErrVersionConflict
+	}
+	var siblings []Todo
+	for _, existing := range s.todos {
+		if existing.UserID == userId && existing.WorkspaceID == 0 && existing.DeletedAt == nil && existing.ID != move.ID {
+			siblings = append(siblings, existing)
+		}
+	}
+	byRank := TodoQuery{SortBy: SortByRank}
+	sort.Slice(siblings, func(i, j int) bool { return byRank.less(siblings[i], siblings[j]) })
+	ids := make([]int64, len(siblings))
+	ranks := make([]string, len(siblings))
+	for i, sibling := range siblings {
+		ids[i], ranks[i] = sibling.ID, sibling.Rank
+	}
+
+	rank, err := moveRank(ids, ranks, move)
+	if err != nil {
+		return err
+	}
+	before := copyTodo(todo)
+	todo.Rank = rank
+	todo.UpdatedAt = time.Now()
+	s.saveVersion(todo, todo.UpdatedAt)
+	todo.Version++
+	s.todos[todo.ID] = todo
+	s.addHistory(todo.ID, userId, HistoryUpdated, &before)
+	return nil
+}
+
+func (s *MemoryStore) CreateUser(user *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Email == user.Email {
+			return ErrDuplicate
+		}
+	}
+	s.lastUserId++
+	user.ID = s.lastUserId
+	s.users[user.ID] = *user
+	return nil
+}
+
+func (s *MemoryStore) GetUserByEmail(email string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) GetUserById(id int64) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (s *MemoryStore) UpdatePassword(userId int64, hashedPassword string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userId]
+	if !ok {
+		return ErrNotFound
+	}
+	user.Password = hashedPassword
+	user.UpdatedAt = time.Now()
+	s.users[userId] = user
+	return nil
+}
+
+func (s *MemoryStore) VerifyEmail(userId int64, email string, verifiedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userId]
+	if !ok || user.Email != email || user.EmailVerifiedAt != nil {
+		return ErrNotFound
+	}
+	user.EmailVerifiedAt = &verifiedAt
+	user.IsActive = true
+	user.UpdatedAt = verifiedAt
+	s.users[userId] = user
+	return nil
+}
+
+func (s *MemoryStore) MarkVerificationSent(userId int64, sentAt, since time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userId]
+	if !ok || user.EmailVerifiedAt != nil || (user.VerificationSentAt != nil && user.VerificationSentAt.After(since)) {
+		return false, nil
+	}
+	user.VerificationSentAt = &sentAt
+	s.users[userId] = user
+	return true, nil
+}
+
+func copyTodo(todo Todo) Todo {
+	if todo.List != nil {
+		todo.List = append([]TodoItem(nil), todo.List...)
+		for i := range todo.List {
+			todo.List[i].DueAt = copyTime(todo.List[i].DueAt)
+		}
+	}
+	if todo.Tags != nil {
+		todo.Tags = append([]Tag(nil), todo.Tags...)
+	}
+	todo.DeletedAt = copyTime(todo.DeletedAt)
+	todo.DueAt = copyTime(todo.DueAt)
+	return todo
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := *t
+	return &copied
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// addHistory mirrors SQLStore.addHistory, diffing before against the todo
+// as stored now. The caller holds s.mu.
+func (s *MemoryStore) addHistory(todoId, actorId int64, action string, before *Todo) {
+	var after *Todo
+	if todo, ok := s.todos[todoId]; ok {
+		todo = copyTodo(todo)
+		after = &todo
+	}
+	s.lastHistoryId++
+	s.history = append(s.history, HistoryEntry{
+		ID:        s.lastHistoryId,
+		TodoID:    todoId,
+		ActorID:   actorId,
+		Action:    action,
+		Diff:      DiffTodos(before, after),
+		CreatedAt: time.Now(),
+	})
+}
+
+func (s *MemoryStore) GetHistory(todoId int64) ([]HistoryEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var history []HistoryEntry
+	for i := len(s.history) - 1; i >= 0; i-- {
+		entry := s.history[i]
+		if entry.TodoID == todoId {
+			entry.ActorEmail = s.users[entry.ActorID].Email
+			history = append(history, entry)
+		}
+	}
+	return history, nil
+}
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// syncItems mirrors SQLStore.syncItems: known IDs are kept, new entries get
+// fresh IDs and the list follows the slice order, keeping the ranks of
+// items that stayed in order. The caller holds s.mu.
+func (s *MemoryStore) syncItems(todoId int64, stored, items []TodoItem) []TodoItem {
+	existing := make(map[int64]string, len(stored))
+	for _, item := range stored {
+		existing[item.ID] = item.Rank
+	}
+	current := make([]string, len(items))
+	for i, item := range items {
+		current[i] = existing[item.ID]
+	}
+	ranks := assignRanks(current)
+	for i := range items {
+		item := &items[i]
+		item.TodoID = todoId
+		item.Position = i
+		item.Rank = ranks[i]
+		if _, ok := existing[item.ID]; !ok {
+			s.lastItemId++
+			item.ID = s.lastItemId
+		}
+		delete(existing, item.ID)
+	}
+	return items
+}
+
+func findItem(list []TodoItem, itemId int64) int {
+	for i, item := range list {
+		if item.ID == itemId {
+			return i
+		}
+	}
+	return -1
+}
+
+func itemRanks(list []TodoItem) ([]int64, []string) {
+	ids := make([]int64, len(list))
+	ranks := make([]string, len(list))
+	for i, item := range list {
+		ids[i], ranks[i] = item.ID, item.Rank
+	}
+	return ids, ranks
+}
+
+// setList stores list, already in rank order, on the todo with positions
+// renumbered from zero, and records the change as made by actorId.
+func (s *MemoryStore) setList(todo Todo, list []TodoItem, actorId int64) {
+	before := copyTodo(todo)
+	for i := range list {
+		list[i].Position = i
+	}
+	todo.List = list
+	todo.UpdatedAt = time.Now()
+	s.saveVersion(before, todo.UpdatedAt)
+	todo.Version++
+	s.todos[todo.ID] = todo
+	s.addHistory(todo.ID, actorId, HistoryUpdated, &before)
+}
+
+func (s *MemoryStore) CreateItem(item *TodoItem, version, actorId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, ok := s.todos[item.TodoID]
+	if !ok {
+		return ErrNotFound
+	}
+	if version != 0 && todo.Version != version {
+		return ErrVersionConflict
+	}
+	list := append([]TodoItem(nil), todo.List...)
+	if item.Position < 0 || item.Position > len(list) {
+		item.Position = len(list)
+	}
+	_, ranks := itemRanks(list)
+	item.Rank = rankAt(ranks, item.Position)
+	s.lastItemId++
+	item.ID = s.lastItemId
+	list = slices.Insert(list, item.Position, *item)
+	s.setList(todo, list, actorId)
+	return nil
+}
+
+func (s *MemoryStore) GetItem(todoId, itemId int64) (*TodoItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	todo, ok := s.todos[todoId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	i := findItem(todo.List, itemId)
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	item := todo.List[i]
+	return &item, nil
+}
+
+func (s *MemoryStore) UpdateItem(item TodoItem, version, actorId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, ok := s.todos[item.TodoID]
+	if !ok {
+		return ErrNotFound
+	}
+	if version != 0 && todo.Version != version {
+		return ErrVersionConflict
+	}
+	i := findItem(todo.List, item.ID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	item.Rank = todo.List[i].Rank
+	list := append([]TodoItem(nil), todo.List[:i]...)
+	list = append(list, todo.List[i+1:]...)
+	if item.Position < 0 || item.Position > len(list) {
+		item.Position = len(list)
+	}
+	if item.Position != i {
+		_, ranks := itemRanks(list)
+		item.Rank = rankAt(ranks, item.Position)
+	}
+	list = slices.Insert(list, item.Position, item)
+	s.setList(todo, list, actorId)
+	return nil
+}
+
+func (s *MemoryStore) DeleteItem(item TodoItem, version, actorId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, ok := s.todos[item.TodoID]
+	if !ok {
+		return ErrNotFound
+	}
+	if version != 0 && todo.Version != version {
+		return ErrVersionConflict
+	}
+	i := findItem(todo.List, item.ID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	list := append([]TodoItem(nil), todo.List[:i]...)
+	list = append(list, todo.List[i+1:]...)
+	s.setList(todo, list, actorId)
+	return nil
+}
+
+func (s *MemoryStore) MoveItem(todoId int64, move Move, version, actorId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, ok := s.todos[todoId]
+	if !ok {
+		return ErrNotFound
+	}
+	if version != 0 && todo.Version != version {
+		return ErrVersionConflict
+	}
+	i := findItem(todo.List, move.ID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	item := todo.List[i]
+	list := append([]TodoItem(nil), todo.List[:i]...)
+	list = append(list, todo.List[i+1:]...)
+	ids, ranks := itemRanks(list)
+	rank, err := moveRank(ids, ranks, move)
+	if err != nil {
+		return err
+	}
+	item.Rank = rank
+	at, _ := slices.BinarySearchFunc(list, rank, func(other TodoItem, rank string) int { return strings.Compare(other.Rank, rank) })
+	list = slices.Insert(list, at, item)
+	s.setList(todo, list, actorId)
+	return nil
+}
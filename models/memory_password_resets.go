@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+func (s *MemoryStore) CreatePasswordReset(reset *PasswordReset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, existing := range s.resets {
+		if existing.TokenHash == reset.TokenHash {
+			return ErrDuplicate
+		}
+		if existing.UserID == reset.UserID || existing.Expired(reset.CreatedAt) {
+			delete(s.resets, id)
+		}
+	}
+	s.lastResetId++
+	reset.ID = s.lastResetId
+	reset.UsedAt = nil
+	s.resets[reset.ID] = *reset
+	return nil
+}
+
+func (s *MemoryStore) UsePasswordReset(hash string, usedAt time.Time) (*PasswordReset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, reset := range s.resets {
+		if reset.TokenHash == hash && reset.UsedAt == nil {
+			reset.UsedAt = &usedAt
+			s.resets[id] = reset
+			return &reset, nil
+		}
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,124 @@
+package models
+
+import "time"
+
+// inArchivedProject is the memory version of the archived projects check
+// in SQLStore.GetAllTodos. The caller holds s.mu.
+func (s *MemoryStore) inArchivedProject(todo Todo) bool {
+	project, ok := s.projects[todo.ProjectID]
+	return ok && project.ArchivedAt != nil
+}
+
+// subtree returns the IDs of the project and every project under it. The
+// caller holds s.mu.
+func (s *MemoryStore) subtree(project Project) []int64 {
+	var projects []Project
+	for _, existing := range s.projects {
+		if existing.UserID == project.UserID {
+			projects = append(projects, existing)
+		}
+	}
+	return Subtree(projects, project.ID)
+}
+
+func (s *MemoryStore) CreateProject(project *Project) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastProjectId++
+	project.ID = s.lastProjectId
+	s.projects[project.ID] = *project
+	return nil
+}
+
+func (s *MemoryStore) GetProjects(userId int64) ([]Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var projects []Project
+	for _, project := range s.projects {
+		if project.UserID == userId {
+			project.ArchivedAt = copyTime(project.ArchivedAt)
+			projects = append(projects, project)
+		}
+	}
+	sortProjects(projects)
+	return projects, nil
+}
+
+func (s *MemoryStore) GetProject(id int64) (*Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	project, ok := s.projects[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	project.ArchivedAt = copyTime(project.ArchivedAt)
+	return &project, nil
+}
+
+func (s *MemoryStore) UpdateProject(project Project) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.projects[project.ID]
+	if !ok {
+		return nil
+	}
+	existing.Name = project.Name
+	existing.ParentID = project.ParentID
+	existing.UpdatedAt = project.UpdatedAt
+	s.projects[project.ID] = existing
+	return nil
+}
+
+func (s *MemoryStore) ArchiveProject(project Project, archived bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, id := range s.subtree(project) {
+		existing := s.projects[id]
+		existing.ArchivedAt = nil
+		if archived {
+			existing.ArchivedAt = copyTime(&now)
+		}
+		existing.UpdatedAt = now
+		s.projects[id] = existing
+	}
+	return nil
+}
+
+func (s *MemoryStore) DeleteProject(project Project, keepTodos bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	ids := s.subtree(project)
+	inSubtree := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		inSubtree[id] = true
+		delete(s.projects, id)
+	}
+	for id, todo := range s.todos {
+		if !inSubtree[todo.ProjectID] {
+			continue
+		}
+		before := copyTodo(todo)
+		todo.ProjectID = 0
+		trashed := !keepTodos && todo.DeletedAt == nil
+		if keepTodos {
+			todo.UpdatedAt = now
+		} else if trashed {
+			todo.DeletedAt = copyTime(&now)
+		}
+		s.saveVersion(before, now)
+		todo.Version++
+		s.todos[id] = todo
+		if trashed {
+			s.addHistory(id, project.UserID, HistoryTrashed, &before)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package models
+
+import "sort"
+
+func (s *MemoryStore) PendingRecurrences() ([]Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var todos []Todo
+	for _, todo := range s.todos {
+		if todo.Recurrence != "" && todo.NextID == 0 && todo.DeletedAt == nil {
+			todos = append(todos, copyTodo(todo))
+		}
+	}
+	sort.Slice(todos, func(i, j int) bool { return todos[i].ID < todos[j].ID })
+	return todos, nil
+}
+
+func (s *MemoryStore) CreateNextOccurrence(current Todo, next *Todo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.todos[current.ID]
+	if !ok || existing.NextID != 0 {
+		return ErrVersionConflict
+	}
+	s.lastTodoId++
+	next.ID = s.lastTodoId
+	next.Version = 1
+	next.Occurrence = max(next.Occurrence, 1)
+	next.NextID = 0
+	s.append(next)
+	next.List = s.syncItems(next.ID, nil, next.List)
+	s.todos[next.ID] = copyTodo(*next)
+	existing.NextID = next.ID
+	s.todos[current.ID] = existing
+	s.addHistory(next.ID, 0, HistoryCreated, nil)
+	return nil
+}
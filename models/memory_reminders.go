@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+func (s *MemoryStore) DueReminders(before time.Time) ([]Reminder, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pending := func(key [2]int64, dueAt *time.Time) bool {
+		if dueAt == nil || dueAt.After(before) {
+			return false
+		}
+		reminded, ok := s.reminded[key]
+		return !ok || !reminded.Equal(*dueAt)
+	}
+
+	var reminders []Reminder
+	for _, todo := range s.todos {
+		if !todo.IsActive || todo.DeletedAt != nil {
+			continue
+		}
+		base := Reminder{TodoID: todo.ID, UserID: todo.UserID, Email: s.users[todo.UserID].Email, Title: todo.Title, TimeZone: todo.TimeZone}
+		if pending([2]int64{todo.ID, 0}, todo.DueAt) {
+			r := base
+			r.DueAt = *todo.DueAt
+			reminders = append(reminders, r)
+		}
+		for _, item := range todo.List {
+			if !item.Checked && pending([2]int64{todo.ID, item.ID}, item.DueAt) {
+				r := base
+				r.ItemID, r.Item, r.DueAt = item.ID, item.Item, *item.DueAt
+				reminders = append(reminders, r)
+			}
+		}
+	}
+	sortReminders(reminders)
+	return reminders, nil
+}
+
+func (s *MemoryStore) MarkReminded(r Reminder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reminded[[2]int64{r.TodoID, r.ItemID}] = r.DueAt
+	return nil
+}
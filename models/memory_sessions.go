@@ -0,0 +1,115 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+func (s *MemoryStore) CreateSession(session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.sessions {
+		if existing.FamilyID == session.FamilyID {
+			return ErrDuplicate
+		}
+	}
+	s.lastSessionId++
+	session.ID = s.lastSessionId
+	session.RevokedAt = nil
+	s.sessions[session.ID] = *session
+	return nil
+}
+
+func (s *MemoryStore) GetSessionByFamily(familyId string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, session := range s.sessions {
+		if session.FamilyID == familyId {
+			return &session, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) GetSessions(userId int64, since time.Time) ([]Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var sessions []Session
+	for _, session := range s.sessions {
+		if session.UserID == userId && session.RevokedAt == nil && session.LastUsedAt.After(since) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastUsedAt.Equal(sessions[j].LastUsedAt) {
+			return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+		}
+		return sessions[i].ID > sessions[j].ID
+	})
+	return sessions, nil
+}
+
+func (s *MemoryStore) RevokeSession(userId, sessionId int64, revokedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[sessionId]
+	if !ok || session.UserID != userId {
+		return ErrNotFound
+	}
+	s.revokeFamily(session.FamilyID, revokedAt)
+	return nil
+}
+
+func (s *MemoryStore) RevokeSessions(userId int64, revokedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, token := range s.refreshTokens {
+		if token.UserID == userId && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+			s.refreshTokens[id] = token
+		}
+	}
+	for id, session := range s.sessions {
+		if session.UserID == userId && session.RevokedAt == nil {
+			session.RevokedAt = &revokedAt
+			s.sessions[id] = session
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) RecordToken(token IssuedToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[token.SessionID]
+	if !ok {
+		return ErrNotFound
+	}
+	for jti, existing := range s.accessTokens {
+		if !token.IssuedAt.Before(existing.ExpiresAt) {
+			delete(s.accessTokens, jti)
+		}
+	}
+	s.accessTokens[token.JTI] = token
+	session.UserAgent, session.IP, session.LastUsedAt = token.UserAgent, token.IP, token.IssuedAt
+	s.sessions[session.ID] = session
+	return nil
+}
+
+func (s *MemoryStore) GetTokenSession(jti string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.accessTokens[jti]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	session := s.sessions[token.SessionID]
+	return &session, nil
+}
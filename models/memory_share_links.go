@@ -0,0 +1,92 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+func (s *MemoryStore) CreateShareLink(link *ShareLink) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.links {
+		if existing.Token == link.Token {
+			return ErrDuplicate
+		}
+	}
+	s.lastLinkId++
+	link.ID = s.lastLinkId
+	link.RevokedAt = nil
+	link.Accesses = 0
+	s.links[link.ID] = *link
+	return nil
+}
+
+func (s *MemoryStore) GetShareLinks(todoId int64) ([]ShareLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var links []ShareLink
+	for _, link := range s.links {
+		if link.TodoID == todoId {
+			link.Accesses = len(s.accesses[link.ID])
+			links = append(links, link)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if !links[i].CreatedAt.Equal(links[j].CreatedAt) {
+			return links[i].CreatedAt.After(links[j].CreatedAt)
+		}
+		return links[i].ID > links[j].ID
+	})
+	return links, nil
+}
+
+func (s *MemoryStore) GetShareLink(token string) (*ShareLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, link := range s.links {
+		if link.Token == token {
+			return &link, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) RevokeShareLink(todoId, linkId int64, revokedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[linkId]
+	if !ok || link.TodoID != todoId {
+		return ErrNotFound
+	}
+	if link.RevokedAt == nil {
+		link.RevokedAt = &revokedAt
+		s.links[linkId] = link
+	}
+	return nil
+}
+
+func (s *MemoryStore) LogLinkAccess(access LinkAccess) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.links[access.LinkID]; !ok {
+		return ErrNotFound
+	}
+	s.accesses[access.LinkID] = append(s.accesses[access.LinkID], access)
+	return nil
+}
+
+func (s *MemoryStore) GetLinkAccesses(linkId int64) ([]LinkAccess, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var accesses []LinkAccess
+	for i := len(s.accesses[linkId]) - 1; i >= 0; i-- {
+		accesses = append(accesses, s.accesses[linkId][i])
+	}
+	return accesses, nil
+}
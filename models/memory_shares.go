@@ -0,0 +1,56 @@
+package models
+
+import "sort"
+
+func (s *MemoryStore) ShareTodo(share *Share) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	shares := s.shares[share.TodoID]
+	if shares == nil {
+		shares = make(map[int64]Share)
+		s.shares[share.TodoID] = shares
+	}
+	if existing, ok := shares[share.UserID]; ok {
+		share.CreatedAt = existing.CreatedAt
+	}
+	share.Email = ""
+	shares[share.UserID] = *share
+	return nil
+}
+
+func (s *MemoryStore) GetShares(todoId int64) ([]Share, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var shares []Share
+	for userId, share := range s.shares[todoId] {
+		share.Email = s.users[userId].Email
+		shares = append(shares, share)
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if !shares[i].CreatedAt.Equal(shares[j].CreatedAt) {
+			return shares[i].CreatedAt.Before(shares[j].CreatedAt)
+		}
+		return shares[i].UserID < shares[j].UserID
+	})
+	return shares, nil
+}
+
+func (s *MemoryStore) GetShareRole(todoId, userId int64) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.shares[todoId][userId].Role, nil
+}
+
+func (s *MemoryStore) UnshareTodo(todoId, userId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.shares[todoId][userId]; !ok {
+		return ErrNotFound
+	}
+	delete(s.shares[todoId], userId)
+	return nil
+}
@@ -0,0 +1,201 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Stored todos keep their own copy of each tag, so every change to a tag
+// is written to the todos carrying it.
+
+// nameTaken reports whether another of the user's tags has the name. The
+// caller holds s.mu.
+func (s *MemoryStore) nameTaken(tag Tag) bool {
+	for _, existing := range s.tags {
+		if existing.ID != tag.ID && existing.UserID == tag.UserID && strings.EqualFold(existing.Name, tag.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// retag applies change to the tags of every todo carrying tagId, bumps
+// their versions and records the change as made by actorId. The caller
+// holds s.mu.
+func (s *MemoryStore) retag(tagId, actorId int64, change func(tags []Tag, i int) []Tag) {
+	for id, todo := range s.todos {
+		i := findTag(todo.Tags, tagId)
+		if i < 0 {
+			continue
+		}
+		before := copyTodo(todo)
+		todo = copyTodo(todo)
+		todo.Tags = change(todo.Tags, i)
+		if len(todo.Tags) == 0 {
+			todo.Tags = nil
+		}
+		sortTags(todo.Tags)
+		todo.UpdatedAt = time.Now()
+		s.saveVersion(todo, todo.UpdatedAt)
+		todo.Version++
+		s.todos[id] = todo
+		s.addHistory(id, actorId, HistoryUpdated, &before)
+	}
+}
+
+func findTag(tags []Tag, tagId int64) int {
+	for i, tag := range tags {
+		if tag.ID == tagId {
+			return i
+		}
+	}
+	return -1
+}
+
+func removeTag(tags []Tag, i int) []Tag {
+	return append(tags[:i], tags[i+1:]...)
+}
+
+func (s *MemoryStore) CreateTag(tag *Tag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.nameTaken(*tag) {
+		return ErrDuplicate
+	}
+	s.lastTagId++
+	tag.ID = s.lastTagId
+	s.tags[tag.ID] = *tag
+	return nil
+}
+
+func (s *MemoryStore) GetTags(userId int64) ([]Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tags []Tag
+	for _, tag := range s.tags {
+		if tag.UserID == userId {
+			tags = append(tags, tag)
+		}
+	}
+	sortTags(tags)
+	return tags, nil
+}
+
+func (s *MemoryStore) GetTag(id int64) (*Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tag, ok := s.tags[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &tag, nil
+}
+
+func (s *MemoryStore) UpdateTag(tag Tag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.tags[tag.ID]
+	if !ok {
+		return nil
+	}
+	tag.UserID = existing.UserID
+	if s.nameTaken(tag) {
+		return ErrDuplicate
+	}
+	s.tags[tag.ID] = tag
+	s.retag(tag.ID, tag.UserID, func(tags []Tag, i int) []Tag {
+		tags[i] = tag
+		return tags
+	})
+	return nil
+}
+
+func (s *MemoryStore) DeleteTag(tag Tag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.retag(tag.ID, tag.UserID, removeTag)
+	delete(s.tags, tag.ID)
+	return nil
+}
+
+func (s *MemoryStore) MergeTags(source, target Tag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target, ok := s.tags[target.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	s.retag(source.ID, source.UserID, func(tags []Tag, i int) []Tag {
+		if findTag(tags, target.ID) >= 0 {
+			return removeTag(tags, i)
+		}
+		tags[i] = target
+		return tags
+	})
+	delete(s.tags, source.ID)
+	return nil
+}
+
+func (s *MemoryStore) AttachTags(todoId int64, tagIds []int64, actorId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, ok := s.todos[todoId]
+	if !ok {
+		return ErrNotFound
+	}
+	before := copyTodo(todo)
+	todo = copyTodo(todo)
+	added := false
+	for _, tagId := range tagIds {
+		tag, ok := s.tags[tagId]
+		if !ok {
+			return ErrNotFound
+		}
+		if findTag(todo.Tags, tagId) < 0 {
+			todo.Tags = append(todo.Tags, tag)
+			added = true
+		}
+	}
+	if added {
+		sortTags(todo.Tags)
+		todo.UpdatedAt = time.Now()
+		s.saveVersion(todo, todo.UpdatedAt)
+		todo.Version++
+		s.todos[todoId] = todo
+		s.addHistory(todoId, actorId, HistoryUpdated, &before)
+	}
+	return nil
+}
+
+func (s *MemoryStore) DetachTag(todoId, tagId, actorId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, ok := s.todos[todoId]
+	if !ok {
+		return ErrNotFound
+	}
+	i := findTag(todo.Tags, tagId)
+	if i < 0 {
+		return ErrNotFound
+	}
+	before := copyTodo(todo)
+	todo = copyTodo(todo)
+	todo.Tags = removeTag(todo.Tags, i)
+	if len(todo.Tags) == 0 {
+		todo.Tags = nil
+	}
+	todo.UpdatedAt = time.Now()
+	s.saveVersion(todo, todo.UpdatedAt)
+	todo.Version++
+	s.todos[todoId] = todo
+	s.addHistory(todoId, actorId, HistoryUpdated, &before)
+	return nil
+}
@@ -0,0 +1,95 @@
+package models
+
+import "testing"
+
+func TestMemoryStoreTodos(t *testing.T) {
+	testTodoStore(t, NewMemoryStore())
+}
+
+func TestMemoryStoreUsers(t *testing.T) {
+	testUserStore(t, NewMemoryStore())
+}
+
+func TestMemoryStoreItems(t *testing.T) {
+	testItemStore(t, NewMemoryStore())
+}
+
+func TestMemoryStoreTodoQueries(t *testing.T) {
+	testTodoQueries(t, NewMemoryStore())
+}
+
+func TestMemoryStoreSearchTodos(t *testing.T) {
+	testSearchTodos(t, NewMemoryStore())
+}
+
+func TestMemoryStoreTodoVersions(t *testing.T) {
+	testTodoVersions(t, NewMemoryStore())
+}
+
+func TestMemoryStoreTrash(t *testing.T) {
+	testTrash(t, NewMemoryStore())
+}
+
+func TestMemoryStoreDueDates(t *testing.T) {
+	testDueDates(t, NewMemoryStore())
+}
+
+func TestMemoryStoreReminders(t *testing.T) {
+	testReminders(t, NewMemoryStore())
+}
+
+func TestMemoryStoreRecurrence(t *testing.T) {
+	testRecurrence(t, NewMemoryStore())
+}
+
+func TestMemoryStoreTags(t *testing.T) {
+	testTags(t, NewMemoryStore())
+}
+
+func TestMemoryStoreRanking(t *testing.T) {
+	testRanking(t, NewMemoryStore())
+}
+
+func TestMemoryStoreProjects(t *testing.T) {
+	testProjects(t, NewMemoryStore())
+}
+
+func TestMemoryStoreShares(t *testing.T) {
+	testShares(t, NewMemoryStore())
+}
+
+func TestMemoryStoreShareLinks(t *testing.T) {
+	testShareLinks(t, NewMemoryStore())
+}
+
+func TestMemoryStoreHistory(t *testing.T) {
+	testHistory(t, NewMemoryStore())
+}
+
+func TestMemoryStoreVersions(t *testing.T) {
+	testVersions(t, NewMemoryStore())
+}
+
+func TestMemoryStoreRefreshTokens(t *testing.T) {
+	testRefreshTokens(t, NewMemoryStore())
+}
+
+func TestMemoryStoreSessions(t *testing.T) {
+	testSessions(t, NewMemoryStore())
+}
+
+func TestMemoryStoreEmailVerification(t *testing.T) {
+	testEmailVerification(t, NewMemoryStore())
+}
+
+func TestMemoryStorePasswordResets(t *testing.T) {
+	testPasswordResets(t, NewMemoryStore())
+}
+
+func TestMemoryStoreTOTP(t *testing.T) {
+	testTOTP(t, NewMemoryStore())
+}
+
+func TestMemoryStoreWorkspaces(t *testing.T) {
+	testWorkspaces(t, NewMemoryStore())
+}
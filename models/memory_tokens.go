@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+func (s *MemoryStore) CreateRefreshToken(token *RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, existing := range s.refreshTokens {
+		if existing.Expired(token.CreatedAt) {
+			delete(s.refreshTokens, id)
+		} else if existing.TokenHash == token.TokenHash {
+			return ErrDuplicate
+		}
+	}
+	s.lastRefreshTokenId++
+	token.ID = s.lastRefreshTokenId
+	token.UsedAt, token.RevokedAt = nil, nil
+	s.refreshTokens[token.ID] = *token
+	return nil
+}
+
+func (s *MemoryStore) GetRefreshToken(hash string) (*RefreshToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, token := range s.refreshTokens {
+		if token.TokenHash == hash {
+			return &token, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) UseRefreshToken(id int64, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.refreshTokens[id]
+	if !ok || token.UsedAt != nil || token.RevokedAt != nil {
+		return ErrNotFound
+	}
+	token.UsedAt = &usedAt
+	s.refreshTokens[id] = token
+	return nil
+}
+
+func (s *MemoryStore) RevokeRefreshFamily(familyId string, revokedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revokeFamily(familyId, revokedAt)
+	return nil
+}
+
+// revokeFamily revokes the refresh tokens and the session of a family. The
+// caller holds s.mu.
+func (s *MemoryStore) revokeFamily(familyId string, revokedAt time.Time) {
+	for id, token := range s.refreshTokens {
+		if token.FamilyID == familyId && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+			s.refreshTokens[id] = token
+		}
+	}
+	for id, session := range s.sessions {
+		if session.FamilyID == familyId && session.RevokedAt == nil {
+			session.RevokedAt = &revokedAt
+			s.sessions[id] = session
+		}
+	}
+}
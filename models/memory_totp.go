@@ -0,0 +1,145 @@
+package models
+
+import "time"
+
+func (s *MemoryStore) CreateTOTP(factor *TOTP) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.totps[factor.UserID]
+	if ok && existing.Confirmed() {
+		return ErrDuplicate
+	}
+	created := *factor
+	created.ConfirmedAt = nil
+	created.LastUsedStep = 0
+	created.FailedAttempts = 0
+	created.FailedAt = nil
+	s.totps[factor.UserID] = created
+	return nil
+}
+
+func (s *MemoryStore) GetTOTP(userId int64) (*TOTP, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	factor, ok := s.totps[userId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &factor, nil
+}
+
+func (s *MemoryStore) ConfirmTOTP(userId, step int64, confirmedAt time.Time, codeHashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	factor, ok := s.totps[userId]
+	if !ok || factor.Confirmed() {
+		return ErrNotFound
+	}
+	for _, hash := range codeHashes {
+		code, taken := s.recoveryCodes[hash]
+		if taken && code.UserID != userId {
+			return ErrDuplicate
+		}
+	}
+	for hash, code := range s.recoveryCodes {
+		if code.UserID == userId {
+			delete(s.recoveryCodes, hash)
+		}
+	}
+	for _, hash := range codeHashes {
+		s.lastRecoveryCodeId++
+		s.recoveryCodes[hash] = RecoveryCode{ID: s.lastRecoveryCodeId, UserID: userId, CodeHash: hash}
+	}
+	factor.ConfirmedAt = &confirmedAt
+	factor.LastUsedStep = step
+	factor.FailedAttempts = 0
+	factor.FailedAt = nil
+	s.totps[userId] = factor
+	return nil
+}
+
+func (s *MemoryStore) UseTOTPStep(userId, step int64, lockout TOTPLockout, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	factor, ok := s.totps[userId]
+	if ok && factor.Locked(lockout, now) {
+		return ErrLocked
+	}
+	if !ok || !factor.Confirmed() || factor.LastUsedStep >= step {
+		return ErrNotFound
+	}
+	factor.LastUsedStep = step
+	factor.FailedAttempts = 0
+	factor.FailedAt = nil
+	s.totps[userId] = factor
+	return nil
+}
+
+func (s *MemoryStore) FailTOTP(userId int64, lockout TOTPLockout, failedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	factor, ok := s.totps[userId]
+	if !ok {
+		return ErrNotFound
+	}
+	if factor.Locked(lockout, failedAt) {
+		return ErrLocked
+	}
+	factor.FailedAttempts++
+	factor.FailedAt = &failedAt
+	s.totps[userId] = factor
+	return nil
+}
+
+func (s *MemoryStore) UseRecoveryCode(userId int64, hash string, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code, ok := s.recoveryCodes[hash]
+	if !ok || code.UserID != userId || code.UsedAt != nil {
+		return ErrNotFound
+	}
+	code.UsedAt = &usedAt
+	s.recoveryCodes[hash] = code
+	if factor, ok := s.totps[userId]; ok {
+		factor.FailedAttempts = 0
+		factor.FailedAt = nil
+		s.totps[userId] = factor
+	}
+	return nil
+}
+
+func (s *MemoryStore) CountRecoveryCodes(userId int64) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, code := range s.recoveryCodes {
+		if code.UserID == userId && code.UsedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *MemoryStore) DeleteTOTP(userId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.totps[userId]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.totps, userId)
+	for hash, code := range s.recoveryCodes {
+		if code.UserID == userId {
+			delete(s.recoveryCodes, hash)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+func (s *MemoryStore) TrashTodo(todo Todo, actorId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.todos[todo.ID]
+	if !ok || existing.DeletedAt != nil {
+		return nil
+	}
+	if todo.Version != 0 && todo.Version != existing.Version {
+		return ErrVersionConflict
+	}
+	before := copyTodo(existing)
+	now := time.Now()
+	s.saveVersion(existing, now)
+	existing.DeletedAt = &now
+	existing.Version++
+	s.todos[todo.ID] = existing
+	s.addHistory(todo.ID, actorId, HistoryTrashed, &before)
+	return nil
+}
+
+func (s *MemoryStore) RestoreTodo(todo Todo, actorId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.todos[todo.ID]
+	if !ok || existing.DeletedAt == nil {
+		return nil
+	}
+	before := copyTodo(existing)
+	existing.DeletedAt = nil
+	existing.UpdatedAt = time.Now()
+	s.saveVersion(existing, existing.UpdatedAt)
+	existing.Version++
+	s.todos[todo.ID] = existing
+	s.addHistory(todo.ID, actorId, HistoryRestored, &before)
+	return nil
+}
+
+func (s *MemoryStore) GetTrash(userId int64) ([]Todo, error) {
+	return s.trash(func(todo Todo) bool { return todo.UserID == userId && todo.WorkspaceID == 0 }), nil
+}
+
+func (s *MemoryStore) GetWorkspaceTrash(workspaceId int64) ([]Todo, error) {
+	return s.trash(func(todo Todo) bool { return todo.WorkspaceID == workspaceId }), nil
+}
+
+// trash lists the trashed todos for which match returns true, most
+// recently trashed first.
+func (s *MemoryStore) trash(match func(todo Todo) bool) []Todo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var todos []Todo
+	for _, todo := range s.todos {
+		if todo.DeletedAt != nil && match(todo) {
+			todos = append(todos, copyTodo(todo))
+		}
+	}
+	sort.Slice(todos, func(i, j int) bool {
+		if !todos[i].DeletedAt.Equal(*todos[j].DeletedAt) {
+			return todos[i].DeletedAt.After(*todos[j].DeletedAt)
+		}
+		return todos[i].ID > todos[j].ID
+	})
+	return todos
+}
+
+func (s *MemoryStore) EmptyTrash(userId int64) (int64, error) {
+	return s.purge(userId, func(todo Todo) bool { return todo.UserID == userId && todo.WorkspaceID == 0 }), nil
+}
+
+func (s *MemoryStore) PurgeTrash(deletedBefore time.Time) (int64, error) {
+	return s.purge(0, func(todo Todo) bool { return todo.DeletedAt.Before(deletedBefore) }), nil
+}
+
+// purge deletes the trashed todos for which match returns true on behalf
+// of actorId.
+func (s *MemoryStore) purge(actorId int64, match func(todo Todo) bool) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for id, todo := range s.todos {
+		if todo.DeletedAt != nil && match(todo) {
+			s.drop(id)
+			s.addHistory(id, actorId, HistoryDeleted, &todo)
+			count++
+		}
+	}
+	return count
+}
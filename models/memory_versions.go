@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// saveVersion snapshots existing before its version is bumped. The caller
+// holds s.mu.
+func (s *MemoryStore) saveVersion(existing Todo, replacedAt time.Time) {
+	versions := s.versions[existing.ID]
+	if len(versions) > 0 && versions[len(versions)-1].Version == existing.Version {
+		return
+	}
+	list := copyTodo(existing).List
+	for i := range list {
+		list[i].TodoID, list[i].Rank = 0, ""
+	}
+	version := TodoVersion{TodoID: existing.ID, Version: existing.Version, Title: existing.Title, List: list, CreatedAt: replacedAt}
+	s.versions[existing.ID] = append(versions, version)
+}
+
+func (s *MemoryStore) GetVersions(todoId int64) ([]TodoVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var versions []TodoVersion
+	for i := len(s.versions[todoId]) - 1; i >= 0; i-- {
+		version := s.versions[todoId][i]
+		version.List = copyTodo(Todo{List: version.List}).List
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+func (s *MemoryStore) GetVersion(todoId, version int64) (*TodoVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, found := range s.versions[todoId] {
+		if found.Version == version {
+			found.List = copyTodo(Todo{List: found.List}).List
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) CreateUndo(undo Undo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for token, existing := range s.undos {
+		if existing.Expired(undo.CreatedAt) {
+			delete(s.undos, token)
+		}
+	}
+	s.undos[undo.Token] = undo
+	return nil
+}
+
+func (s *MemoryStore) TakeUndo(token string, userId int64) (*Undo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	undo, ok := s.undos[token]
+	if !ok || undo.UserID != userId {
+		return nil, ErrNotFound
+	}
+	delete(s.undos, token)
+	return &undo, nil
+}
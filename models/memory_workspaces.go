@@ -0,0 +1,230 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+func (s *MemoryStore) CreateWorkspace(workspace *Workspace, ownerId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastWorkspaceId++
+	workspace.ID = s.lastWorkspaceId
+	workspace.Role = WorkspaceOwner
+	stored := *workspace
+	stored.Role = ""
+	s.workspaces[workspace.ID] = stored
+	s.members[workspace.ID] = map[int64]Member{
+		ownerId: {WorkspaceID: workspace.ID, UserID: ownerId, Role: WorkspaceOwner, CreatedAt: workspace.CreatedAt},
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetWorkspaces(userId int64) ([]Workspace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var workspaces []Workspace
+	for id, members := range s.members {
+		if member, ok := members[userId]; ok {
+			workspace := s.workspaces[id]
+			workspace.Role = member.Role
+			workspaces = append(workspaces, workspace)
+		}
+	}
+	sort.Slice(workspaces, func(i, j int) bool {
+		a, b := strings.ToLower(workspaces[i].Name), strings.ToLower(workspaces[j].Name)
+		if a != b {
+			return a < b
+		}
+		return workspaces[i].ID < workspaces[j].ID
+	})
+	return workspaces, nil
+}
+
+func (s *MemoryStore) GetWorkspace(id int64) (*Workspace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	workspace, ok := s.workspaces[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &workspace, nil
+}
+
+func (s *MemoryStore) UpdateWorkspace(workspace Workspace) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.workspaces[workspace.ID]
+	if !ok {
+		return nil
+	}
+	existing.Name = workspace.Name
+	existing.UpdatedAt = workspace.UpdatedAt
+	s.workspaces[workspace.ID] = existing
+	return nil
+}
+
+func (s *MemoryStore) DeleteWorkspace(id, actorId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for todoId, todo := range s.todos {
+		if todo.WorkspaceID == id {
+			s.drop(todoId)
+			s.addHistory(todoId, actorId, HistoryDeleted, &todo)
+		}
+	}
+	for inviteId, invite := range s.invites {
+		if invite.WorkspaceID == id {
+			delete(s.invites, inviteId)
+		}
+	}
+	delete(s.members, id)
+	delete(s.workspaces, id)
+	return nil
+}
+
+func (s *MemoryStore) GetMembers(workspaceId int64) ([]Member, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var members []Member
+	for userId, member := range s.members[workspaceId] {
+		user := s.users[userId]
+		member.Email, member.FirstName, member.LastName = user.Email, user.FirstName, user.LastName
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if !members[i].CreatedAt.Equal(members[j].CreatedAt) {
+			return members[i].CreatedAt.Before(members[j].CreatedAt)
+		}
+		return members[i].UserID < members[j].UserID
+	})
+	return members, nil
+}
+
+func (s *MemoryStore) GetMemberRole(workspaceId, userId int64) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.members[workspaceId][userId].Role, nil
+}
+
+func (s *MemoryStore) SetMemberRole(workspaceId, userId int64, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	member, ok := s.members[workspaceId][userId]
+	if !ok {
+		return ErrNotFound
+	}
+	member.Role = role
+	s.members[workspaceId][userId] = member
+	return nil
+}
+
+func (s *MemoryStore) RemoveMember(workspaceId, userId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.members[workspaceId][userId]; !ok {
+		return ErrNotFound
+	}
+	delete(s.members[workspaceId], userId)
+	return nil
+}
+
+func (s *MemoryStore) CreateInvite(invite *Invite) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastInviteId++
+	invite.ID = s.lastInviteId
+	invite.AcceptedAt = nil
+	s.invites[invite.ID] = *invite
+	return nil
+}
+
+// pendingInvites returns the pending invites for which match returns true,
+// oldest first. The caller holds s.mu.
+func (s *MemoryStore) pendingInvites(match func(invite Invite) bool) []Invite {
+	var invites []Invite
+	for _, invite := range s.invites {
+		if invite.AcceptedAt == nil && match(invite) {
+			invites = append(invites, invite)
+		}
+	}
+	sort.Slice(invites, func(i, j int) bool {
+		if !invites[i].CreatedAt.Equal(invites[j].CreatedAt) {
+			return invites[i].CreatedAt.Before(invites[j].CreatedAt)
+		}
+		return invites[i].ID < invites[j].ID
+	})
+	return invites
+}
+
+func (s *MemoryStore) GetInvites(workspaceId int64) ([]Invite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.pendingInvites(func(invite Invite) bool { return invite.WorkspaceID == workspaceId }), nil
+}
+
+func (s *MemoryStore) GetInvitesForEmail(email string) ([]Invite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	invites := s.pendingInvites(func(invite Invite) bool { return strings.EqualFold(invite.Email, email) })
+	for i := range invites {
+		invites[i].WorkspaceName = s.workspaces[invites[i].WorkspaceID].Name
+	}
+	return invites, nil
+}
+
+func (s *MemoryStore) GetInvite(token string) (*Invite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, invite := range s.invites {
+		if invite.Token == token {
+			invite.AcceptedAt = copyTime(invite.AcceptedAt)
+			return &invite, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) AcceptInvite(invite Invite, userId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	members := s.members[invite.WorkspaceID]
+	if members == nil {
+		return nil
+	}
+	if _, ok := members[userId]; !ok {
+		members[userId] = Member{WorkspaceID: invite.WorkspaceID, UserID: userId, Role: invite.Role, CreatedAt: now}
+	}
+	stored := s.invites[invite.ID]
+	stored.AcceptedAt = &now
+	s.invites[invite.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) DeleteInvite(workspaceId, inviteId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invite, ok := s.invites[inviteId]
+	if !ok || invite.WorkspaceID != workspaceId {
+		return ErrNotFound
+	}
+	delete(s.invites, inviteId)
+	return nil
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// PasswordReset lets the user set a new password once, by presenting the
+// token whose hash is stored, until ExpiresAt.
+type PasswordReset struct {
+	ID        int64      `db:"id" json:"id"`
+	UserID    int64      `db:"user_id" json:"userId"`
+	TokenHash string     `db:"token_hash" json:"-"`
+	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expiresAt"`
+	UsedAt    *time.Time `db:"used_at" json:"usedAt,omitempty"`
+}
+
+func (r PasswordReset) Expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
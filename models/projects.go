@@ -0,0 +1,45 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Project groups a user's todos. Projects nest through ParentID (zero for
+// a top-level project); archiving or deleting a project does the same to
+// every project under it.
+type Project struct {
+	ID         int64      `db:"id" json:"id"`
+	UserID     int64      `db:"user_id" json:"-"`
+	ParentID   int64      `db:"parent_id" json:"parentId,omitempty"`
+	Name       string     `db:"name" json:"name"`
+	ArchivedAt *time.Time `db:"archived_at" json:"archivedAt,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt  time.Time  `db:"updated_at" json:"updatedAt"`
+}
+
+// Subtree returns the ID of the project rootId and of every project under
+// it, given all of the user's projects.
+func Subtree(projects []Project, rootId int64) []int64 {
+	children := make(map[int64][]int64, len(projects))
+	for _, project := range projects {
+		children[project.ParentID] = append(children[project.ParentID], project.ID)
+	}
+	ids := []int64{rootId}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids
+}
+
+// sortProjects orders projects the way the SQL backends return them.
+func sortProjects(projects []Project) {
+	sort.Slice(projects, func(i, j int) bool {
+		a, b := strings.ToLower(projects[i].Name), strings.ToLower(projects[j].Name)
+		if a != b {
+			return a < b
+		}
+		return projects[i].ID < projects[j].ID
+	})
+}
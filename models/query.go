@@ -0,0 +1,283 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Sort keys accepted by TodoQuery.SortBy.
+const (
+	SortById        = "id"
+	SortByCreatedAt = "createdAt"
+	SortByUpdatedAt = "updatedAt"
+	SortByTitle     = "title"
+	SortByRank      = "rank"
+	SortByPriority  = "priority"
+)
+
+const (
+	DefaultTodoLimit = 50
+	MaxTodoLimit     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TodoQuery describes one page of a user's todos. Nil filters are ignored;
+// the From bounds are inclusive and the To bounds exclusive.
+type TodoQuery struct {
+	UserID        int64
+	Limit         int
+	Cursor        string
+	IsActive      *bool
+	CreatedFrom   *time.Time
+	CreatedTo     *time.Time
+	UpdatedFrom   *time.Time
+	UpdatedTo     *time.Time
+	TitleContains string
+	HasUnchecked  *bool
+	// DueFrom and DueTo match todos due in the range, or with an unchecked
+	// item due in it.
+	DueFrom *time.Time
+	DueTo   *time.Time
+	// Tags matches todos carrying every one of UserID's tags with the
+	// given names, or any of them with AnyTag. Names are compared ignoring
+	// case.
+	Tags   []string
+	AnyTag bool
+	// Priorities keeps todos with one of the given priorities.
+	Priorities []string
+	// ProjectIDs keeps todos in one of the given projects. Todos in
+	// archived projects are left out unless IncludeArchived is set.
+	ProjectIDs      []int64
+	IncludeArchived bool
+	// WorkspaceID lists the workspace's todos instead of UserID's own;
+	// personal listings leave workspace todos out.
+	WorkspaceID int64
+	// IncludeShared also returns todos other users shared with UserID,
+	// marked through Todo.Shared and Todo.Role.
+	IncludeShared bool
+	// SortBy defaults to SortByRank, the user's manual order.
+	SortBy     string
+	Descending bool
+}
+
+type TodoPage struct {
+	Todos      []Todo
+	NextCursor string
+}
+
+// cursor is the decoded form of TodoPage.NextCursor. It remembers the sort
+// it was issued for so it cannot be replayed against a different ordering.
+type cursor struct {
+	SortBy     string `json:"s"`
+	Descending bool   `json:"d"`
+	Key        string `json:"k,omitempty"`
+	ID         int64  `json:"id"`
+}
+
+func isValidSort(sortBy string) bool {
+	switch sortBy {
+	case SortById, SortByCreatedAt, SortByUpdatedAt, SortByTitle, SortByRank, SortByPriority:
+		return true
+	}
+	return false
+}
+
+// normalize fills in defaults and decodes the cursor, if any.
+func (q *TodoQuery) normalize() (*cursor, error) {
+	if q.SortBy == "" {
+		q.SortBy = SortByRank
+	}
+	if !isValidSort(q.SortBy) {
+		return nil, errors.New("unknown sort " + q.SortBy)
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultTodoLimit
+	}
+	if q.Limit > MaxTodoLimit {
+		q.Limit = MaxTodoLimit
+	}
+	if q.Cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	err = json.Unmarshal(raw, &c)
+	if err != nil || c.SortBy != q.SortBy || c.Descending != q.Descending {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+func (q TodoQuery) sortKey(todo Todo) string {
+	switch q.SortBy {
+	case SortByCreatedAt:
+		return todo.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case SortByUpdatedAt:
+		return todo.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	case SortByTitle:
+		return todo.Title
+	case SortByRank:
+		return todo.Rank
+	case SortByPriority:
+		return todo.Priority
+	}
+	return ""
+}
+
+func (q TodoQuery) cursorAfter(todo Todo) string {
+	raw, _ := json.Marshal(cursor{SortBy: q.SortBy, Descending: q.Descending, Key: q.sortKey(todo), ID: todo.ID})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// page trims one extra fetched row off todos and turns it into a cursor.
+func (q TodoQuery) page(todos []Todo) TodoPage {
+	if len(todos) <= q.Limit {
+		return TodoPage{Todos: todos}
+	}
+	todos = todos[:q.Limit]
+	return TodoPage{Todos: todos, NextCursor: q.cursorAfter(todos[len(todos)-1])}
+}
+
+// matches applies the filters in Go; MemoryStore uses it and it documents
+// what the SQL in SQLStore.GetAllTodos has to do. The caller checks the
+// todo is visible to q.UserID.
+func (q TodoQuery) matches(todo Todo) bool {
+	if todo.DeletedAt != nil {
+		return false
+	}
+	if q.IsActive != nil && todo.IsActive != *q.IsActive {
+		return false
+	}
+	if !inRange(todo.CreatedAt, q.CreatedFrom, q.CreatedTo) || !inRange(todo.UpdatedAt, q.UpdatedFrom, q.UpdatedTo) {
+		return false
+	}
+	if q.TitleContains != "" && !strings.Contains(strings.ToLower(todo.Title), strings.ToLower(q.TitleContains)) {
+		return false
+	}
+	if q.HasUnchecked != nil {
+		unchecked := false
+		for _, item := range todo.List {
+			if !item.Checked {
+				unchecked = true
+				break
+			}
+		}
+		if unchecked != *q.HasUnchecked {
+			return false
+		}
+	}
+	if (q.DueFrom != nil || q.DueTo != nil) && !q.isDue(todo) {
+		return false
+	}
+	if len(q.Tags) > 0 && !q.hasTags(todo) {
+		return false
+	}
+	if len(q.Priorities) > 0 && !slices.Contains(q.Priorities, todo.Priority) {
+		return false
+	}
+	if len(q.ProjectIDs) > 0 && !slices.Contains(q.ProjectIDs, todo.ProjectID) {
+		return false
+	}
+	return true
+}
+
+func (q TodoQuery) hasTags(todo Todo) bool {
+	carried := make(map[string]bool, len(todo.Tags))
+	for _, tag := range todo.Tags {
+		if tag.UserID == q.UserID {
+			carried[strings.ToLower(tag.Name)] = true
+		}
+	}
+	for _, name := range tagNames(q.Tags) {
+		if carried[name] && q.AnyTag {
+			return true
+		}
+		if !carried[name] && !q.AnyTag {
+			return false
+		}
+	}
+	return !q.AnyTag
+}
+
+// isDue reports whether the todo, or one of its unchecked items, is due
+// between DueFrom and DueTo.
+func (q TodoQuery) isDue(todo Todo) bool {
+	if todo.DueAt != nil && inRange(*todo.DueAt, q.DueFrom, q.DueTo) {
+		return true
+	}
+	for _, item := range todo.List {
+		if !item.Checked && item.DueAt != nil && inRange(*item.DueAt, q.DueFrom, q.DueTo) {
+			return true
+		}
+	}
+	return false
+}
+
+func inRange(t time.Time, from, to *time.Time) bool {
+	if from != nil && t.Before(*from) {
+		return false
+	}
+	if to != nil && !t.Before(*to) {
+		return false
+	}
+	return true
+}
+
+// less orders two todos by the sort key with the ID as tie-breaker.
+func (q TodoQuery) less(a, b Todo) bool {
+	var cmp int
+	switch q.SortBy {
+	case SortByCreatedAt:
+		cmp = a.CreatedAt.Compare(b.CreatedAt)
+	case SortByUpdatedAt:
+		cmp = a.UpdatedAt.Compare(b.UpdatedAt)
+	case SortByTitle, SortByRank, SortByPriority:
+		cmp = strings.Compare(q.sortKey(a), q.sortKey(b))
+	}
+	if cmp == 0 {
+		cmp = compareInt64(a.ID, b.ID)
+	}
+	if q.Descending {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+// after reports whether todo sorts after the cursor position.
+func (q TodoQuery) after(c *cursor, todo Todo) bool {
+	cmp := strings.Compare(q.sortKey(todo), c.Key)
+	if q.SortBy == SortByCreatedAt || q.SortBy == SortByUpdatedAt {
+		at, _ := time.Parse(time.RFC3339Nano, c.Key)
+		if q.SortBy == SortByCreatedAt {
+			cmp = todo.CreatedAt.Compare(at)
+		} else {
+			cmp = todo.UpdatedAt.Compare(at)
+		}
+	}
+	if cmp == 0 {
+		cmp = compareInt64(todo.ID, c.ID)
+	}
+	if q.Descending {
+		return cmp < 0
+	}
+	return cmp > 0
+}
+
+func compareInt64(a, b int64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
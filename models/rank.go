@@ -0,0 +1,191 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// Ranks order todos, and items within a todo, as plain strings compared
+// byte by byte (Postgres uses the "C" collation for them). A move gives the
+// moved row a key between its new neighbours, so no other row is rewritten.
+// Keys use the digits below in ASCII order and never end in the lowest
+// digit, which guarantees there is always room for another key between two
+// different ones.
+const rankDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+var ErrInvalidMove = errors.New("invalid move")
+
+// RankBetween returns a key that sorts strictly between a and b. An empty a
+// means "before everything" and an empty b "after everything". It panics
+// if a does not sort before b.
+func RankBetween(a, b string) string {
+	if b != "" && a >= b {
+		panic("models: rank " + a + " does not sort before " + b)
+	}
+	// Skip the common prefix; a is padded with the lowest digit.
+	n := 0
+	for n < len(b) && rankDigit(a, n) == strings.IndexByte(rankDigits, b[n]) {
+		n++
+	}
+	if n > 0 {
+		rest := ""
+		if n < len(a) {
+			rest = a[n:]
+		}
+		return b[:n] + RankBetween(rest, b[n:])
+	}
+
+	low := rankDigit(a, 0)
+	high := len(rankDigits)
+	if b != "" {
+		high = strings.IndexByte(rankDigits, b[0])
+	}
+	if high-low > 1 {
+		return string(rankDigits[(low+high)/2])
+	}
+	// The first digits are adjacent: b's first digit alone still sorts
+	// after a if b is longer, otherwise extend a.
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(rankDigits[low]) + RankBetween(rest, "")
+}
+
+func rankDigit(key string, i int) int {
+	if i >= len(key) {
+		return 0
+	}
+	return strings.IndexByte(rankDigits, key[i])
+}
+
+// spreadRanks returns n increasing keys between a and b, bisecting so the
+// keys stay short.
+func spreadRanks(a, b string, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+	middle := n / 2
+	key := RankBetween(a, b)
+	ranks := spreadRanks(a, key, middle)
+	ranks = append(ranks, key)
+	return append(ranks, spreadRanks(key, b, n-middle-1)...)
+}
+
+// assignRanks gives every slot a key in slot order. current holds each
+// slot's stored key, or "" for new rows. Stored keys are kept where they
+// are already in order, so only moved and new rows get new keys.
+func assignRanks(current []string) []string {
+	ranks := make([]string, len(current))
+	kept := make([]bool, len(current))
+	last := ""
+	for i, rank := range current {
+		if rank != "" && rank > last {
+			ranks[i], kept[i], last = rank, true, rank
+		}
+	}
+	for i := 0; i < len(ranks); {
+		if kept[i] {
+			i++
+			continue
+		}
+		j := i
+		for j < len(ranks) && !kept[j] {
+			j++
+		}
+		low, high := "", ""
+		if i > 0 {
+			low = ranks[i-1]
+		}
+		if j < len(ranks) {
+			high = ranks[j]
+		}
+		copy(ranks[i:j], spreadRanks(low, high, j-i))
+		i = j
+	}
+	return ranks
+}
+
+// Move places a todo, or an item within its todo, right after AfterID or
+// right before BeforeID. With both set it goes between the two, which must
+// be in that order. MoveTodo only moves the todo while it is still at
+// Version, which comes from If-Match rather than the body; zero skips the
+// check.
+type Move struct {
+	ID       int64 `json:"id"`
+	AfterID  int64 `json:"after"`
+	BeforeID int64 `json:"before"`
+	Version  int64 `json:"-"`
+}
+
+// rankWithin returns a key between low and high, which must be in order.
+func rankWithin(low, high string) (string, error) {
+	if high != "" && low >= high {
+		return "", ErrInvalidMove
+	}
+	return RankBetween(low, high), nil
+}
+
+// firstAbove returns the first of the sorted ranks after low, or "".
+func firstAbove(ranks []string, low string) string {
+	for _, rank := range ranks {
+		if rank > low {
+			return rank
+		}
+	}
+	return ""
+}
+
+// lastBelow returns the last of the sorted ranks before high, or "".
+func lastBelow(ranks []string, high string) string {
+	for i := len(ranks) - 1; i >= 0; i-- {
+		if ranks[i] < high {
+			return ranks[i]
+		}
+	}
+	return ""
+}
+
+// rankAt returns the key for a new entry inserted at index among the
+// sorted ranks.
+func rankAt(ranks []string, index int) string {
+	low := ""
+	if index > 0 {
+		low = ranks[index-1]
+	}
+	return RankBetween(low, firstAbove(ranks[index:], low))
+}
+
+// moveRank returns the key for move among its siblings' IDs and sorted
+// ranks, which leave out the moved entry itself.
+func moveRank(ids []int64, ranks []string, move Move) (string, error) {
+	if (move.AfterID == 0 && move.BeforeID == 0) || move.AfterID == move.ID || move.BeforeID == move.ID {
+		return "", ErrInvalidMove
+	}
+	low, high := "", ""
+	if move.AfterID != 0 {
+		i := slices.Index(ids, move.AfterID)
+		if i < 0 {
+			return "", ErrNotFound
+		}
+		low = ranks[i]
+	}
+	if move.BeforeID != 0 {
+		i := slices.Index(ids, move.BeforeID)
+		if i < 0 {
+			return "", ErrNotFound
+		}
+		high = ranks[i]
+	}
+	if move.BeforeID == 0 {
+		high = firstAbove(ranks, low)
+	}
+	if move.AfterID == 0 {
+		low = lastBelow(ranks, high)
+	}
+	return rankWithin(low, high)
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRankBetween(t *testing.T) {
+	between := func(a, b string) string {
+		key := RankBetween(a, b)
+		assert.Greater(t, key, a, "%q < %q", a, key)
+		if b != "" {
+			assert.Less(t, key, b, "%q < %q", key, b)
+		}
+		assert.NotEqual(t, byte('0'), key[len(key)-1], key)
+		return key
+	}
+	assert.Equal(t, "V", between("", ""))
+	assert.Equal(t, "k", between("V", ""))
+	assert.Equal(t, "F", between("", "V"))
+	assert.Equal(t, "zV", between("z", ""))
+	assert.Equal(t, "A0V", between("A", "A1"))
+	// Keys written by the migration for existing rows.
+	assert.Equal(t, "000000001", between("0000000009V", "0000000010V"))
+	between("0000000009V", "000000001")
+
+	// Always inserting at the same spot keeps finding room.
+	low, high := "V", "W"
+	for i := 0; i < 200; i++ {
+		high = between(low, high)
+	}
+	for i := 0; i < 200; i++ {
+		low = between(low, high)
+	}
+	assert.Panics(t, func() { RankBetween("b", "a") })
+	assert.Panics(t, func() { RankBetween("a", "a") })
+}
+
+func TestAssignRanks(t *testing.T) {
+	// Items that stay in order keep their keys; the moved and new ones are
+	// given keys in between.
+	ranks := assignRanks([]string{"V", "", "G", "k", ""})
+	assert.Equal(t, "V", ranks[0])
+	assert.Equal(t, "k", ranks[3])
+	assert.True(t, ranks[0] < ranks[1] && ranks[1] < ranks[2] && ranks[2] < ranks[3] && ranks[3] < ranks[4], ranks)
+
+	ranks = assignRanks(make([]string, 100))
+	for i := 1; i < len(ranks); i++ {
+		assert.Less(t, ranks[i-1], ranks[i])
+		assert.LessOrEqual(t, len(ranks[i]), 3)
+	}
+}
+
+func TestMoveRank(t *testing.T) {
+	ids := []int64{1, 2, 3}
+	ranks := []string{"F", "V", "k"}
+	rank, err := moveRank(ids, ranks, Move{ID: 4, AfterID: 1})
+	assert.NoError(t, err)
+	assert.True(t, "F" < rank && rank < "V")
+	rank, err = moveRank(ids, ranks, Move{ID: 4, BeforeID: 1})
+	assert.NoError(t, err)
+	assert.Less(t, rank, "F")
+	rank, err = moveRank(ids, ranks, Move{ID: 4, AfterID: 3})
+	assert.NoError(t, err)
+	assert.Greater(t, rank, "k")
+	rank, err = moveRank(ids, ranks, Move{ID: 4, AfterID: 1, BeforeID: 3})
+	assert.NoError(t, err)
+	assert.True(t, "F" < rank && rank < "k")
+
+	_, err = moveRank(ids, ranks, Move{ID: 4, AfterID: 3, BeforeID: 1})
+	assert.ErrorIs(t, err, ErrInvalidMove)
+	_, err = moveRank(ids, ranks, Move{ID: 4})
+	assert.ErrorIs(t, err, ErrInvalidMove)
+	_, err = moveRank(ids, ranks, Move{ID: 4, AfterID: 9})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
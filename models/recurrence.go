@@ -0,0 +1,315 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence frequencies. Rules are a subset of RFC 5545 RRULE: FREQ,
+// INTERVAL, BYDAY (weekly rules only), BYMONTHDAY (monthly rules only),
+// COUNT and UNTIL.
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+)
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence")
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	// Count limits the series to that many todos, including the first.
+	Count int
+	Until *time.Time
+}
+
+func invalidRule(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidRecurrence, fmt.Sprintf(format, args...))
+}
+
+// ParseRule reads "daily", "weekly", "monthly" or an RRULE such as
+// "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", with or without the "RRULE:" prefix.
+func ParseRule(text string) (Rule, error) {
+	text = strings.ToUpper(strings.TrimSpace(text))
+	switch text {
+	case Daily, Weekly, Monthly:
+		return Rule{Freq: text, Interval: 1}, nil
+	}
+
+	rule := Rule{Interval: 1}
+	for _, part := range strings.Split(strings.TrimPrefix(text, "RRULE:"), ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return rule, invalidRule("malformed part %q", part)
+		}
+		var err error
+		switch name {
+		case "FREQ":
+			rule.Freq = value
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(value)
+			if err == nil && rule.Interval < 1 {
+				err = errors.New("must be positive")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(value)
+			if err == nil && rule.Count < 1 {
+				err = errors.New("must be positive")
+			}
+		case "UNTIL":
+			until, parseErr := parseUntil(value)
+			rule.Until, err = &until, parseErr
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return rule, invalidRule("unknown BYDAY %q", day)
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				number, err := strconv.Atoi(day)
+				if err != nil || number == 0 || number < -31 || number > 31 {
+					return rule, invalidRule("unknown BYMONTHDAY %q", day)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, number)
+			}
+		default:
+			return rule, invalidRule("unsupported part %s", name)
+		}
+		if err != nil {
+			return rule, invalidRule("%s %s", name, err)
+		}
+	}
+
+	switch {
+	case rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly:
+		return rule, invalidRule("FREQ must be DAILY, WEEKLY or MONTHLY")
+	case len(rule.ByDay) > 0 && rule.Freq != Weekly:
+		return rule, invalidRule("BYDAY needs FREQ=WEEKLY")
+	case len(rule.ByMonthDay) > 0 && rule.Freq != Monthly:
+		return rule, invalidRule("BYMONTHDAY needs FREQ=MONTHLY")
+	case rule.Count > 0 && rule.Until != nil:
+		return rule, invalidRule("COUNT and UNTIL cannot be combined")
+	}
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		until, err := time.Parse(layout, value)
+		if err == nil {
+			if layout == "20060102" {
+				until = until.Add(24*time.Hour - time.Second)
+			}
+			return until, nil
+		}
+	}
+	return time.Time{}, errors.New("must look like 20240131 or 20240131T170000Z")
+}
+
+// String is the rule as an RRULE value, which is how todos store it.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, weekday := range r.ByDay {
+			days[i] = strings.ToUpper(weekday.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence after from, keeping from's time of day
+// in location. Weeks start on Monday. Like RFC 5545, months that lack the
+// requested day are skipped. It returns false once the series has ended;
+// occurrence is the number of from within the series.
+func (r Rule) Next(from time.Time, occurrence int, location *time.Location) (time.Time, bool) {
+	if r.Count > 0 && occurrence >= r.Count {
+		return time.Time{}, false
+	}
+	from = from.In(location)
+	var next time.Time
+	switch r.Freq {
+	case Daily:
+		next = from.AddDate(0, 0, r.Interval)
+	case Weekly:
+		next = r.nextWeekly(from)
+	case Monthly:
+		next = r.nextMonthly(from)
+	}
+	if next.IsZero() || (r.Until != nil && next.After(*r.Until)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+func (r Rule) nextWeekly(from time.Time) time.Time {
+	if len(r.ByDay) == 0 {
+		return from.AddDate(0, 0, 7*r.Interval)
+	}
+	week := startOfWeek(from)
+	for day := 1; day <= 7*r.Interval+7; day++ {
+		next := from.AddDate(0, 0, day)
+		weeks := int(startOfWeek(next).Sub(week).Hours()+12) / (24 * 7)
+		if weeks%r.Interval == 0 && hasWeekday(r.ByDay, next.Weekday()) {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
+
+func hasWeekday(days []time.Weekday, weekday time.Weekday) bool {
+	for _, day := range days {
+		if day == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) nextMonthly(from time.Time) time.Time {
+	days := r.ByMonthDay
+	if len(days) == 0 {
+		days = []int{from.Day()}
+	}
+	hour, minute, second := from.Clock()
+	// A day such as the 31st may be missing for several months in a row,
+	// but never for a whole year.
+	for months := 0; months <= 12*r.Interval; months += r.Interval {
+		first := time.Date(from.Year(), from.Month()+time.Month(months), 1, hour, minute, second, from.Nanosecond(), from.Location())
+		length := first.AddDate(0, 1, -1).Day()
+		var candidates []int
+		for _, day := range days {
+			if day < 0 {
+				day = length + day + 1
+			}
+			if day >= 1 && day <= length {
+				candidates = append(candidates, day)
+			}
+		}
+		sort.Ints(candidates)
+		for _, day := range candidates {
+			next := first.AddDate(0, 0, day-1)
+			if next.After(from) {
+				return next
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// NextOccurrence returns the todo that follows this one in its series, with
+// the same title, items and tags, or nil while it is not yet due. A new one
+// is due once this one is completed (made inactive or all of its items
+// checked) or when its period is over. Occurrences that passed without
+// being generated are skipped.
+func (t Todo) NextOccurrence(now time.Time) *Todo {
+	if t.Recurrence == "" || t.NextID != 0 {
+		return nil
+	}
+	rule, err := ParseRule(t.Recurrence)
+	if err != nil {
+		return nil
+	}
+	location := t.Location()
+
+	current := t.CreatedAt
+	if t.DueAt != nil {
+		current = *t.DueAt
+	}
+	occurrence := max(t.Occurrence, 1)
+	next, ok := rule.Next(current, occurrence, location)
+	if !ok || (!t.isCompleted() && next.After(now)) {
+		return nil
+	}
+	for !next.After(now) {
+		following, ok := rule.Next(next, occurrence+1, location)
+		if !ok {
+			break
+		}
+		next = following
+		occurrence++
+	}
+
+	seriesId := t.SeriesID
+	if seriesId == 0 {
+		seriesId = t.ID
+	}
+	shift := next.Sub(current)
+	list := make([]TodoItem, len(t.List))
+	for i, item := range t.List {
+		list[i] = TodoItem{Item: item.Item}
+		if item.DueAt != nil {
+			due := item.DueAt.Add(shift)
+			list[i].DueAt = &due
+		}
+	}
+	return &Todo{
+		Title:       t.Title,
+		List:        list,
+		IsActive:    true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		UserID:      t.UserID,
+		DueAt:       &next,
+		TimeZone:    t.TimeZone,
+		Recurrence:  t.Recurrence,
+		SeriesID:    seriesId,
+		Occurrence:  occurrence + 1,
+		Tags:        append([]Tag(nil), t.Tags...),
+		Priority:    t.Priority,
+		ProjectID:   t.ProjectID,
+		WorkspaceID: t.WorkspaceID,
+	}
+}
+
+func (t Todo) isCompleted() bool {
+	if !t.IsActive {
+		return true
+	}
+	if len(t.List) == 0 {
+		return false
+	}
+	for _, item := range t.List {
+		if !item.Checked {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("weekly")
+	assert.NoError(t, err)
+	assert.Equal(t, "FREQ=WEEKLY", rule.String())
+
+	rule, err = ParseRule("RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=5")
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Weekday{time.Monday, time.Thursday}, rule.ByDay)
+	assert.Equal(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=5", rule.String())
+
+	rule, err = ParseRule("freq=monthly;bymonthday=1,-1;until=20241231")
+	assert.NoError(t, err)
+	assert.Equal(t, "FREQ=MONTHLY;BYMONTHDAY=1,-1;UNTIL=20241231T235959Z", rule.String())
+
+	for _, text := range []string{
+		"", "yearly", "FREQ=HOURLY", "FREQ=DAILY;BYDAY=MO", "FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=WEEKLY;BYDAY=XX", "FREQ=DAILY;INTERVAL=0", "FREQ=DAILY;COUNT=2;UNTIL=20240101", "FREQ=DAILY;WKST=MO",
+	} {
+		_, err = ParseRule(text)
+		assert.ErrorIs(t, err, ErrInvalidRecurrence, text)
+	}
+}
+
+func TestRuleNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	next := func(text string, from time.Time, occurrence int) time.Time {
+		rule, err := ParseRule(text)
+		assert.NoError(t, err)
+		at, ok := rule.Next(from, occurrence, berlin)
+		if !ok {
+			return time.Time{}
+		}
+		return at
+	}
+	// Friday 9:00 in Berlin, the day before the switch to summer time.
+	friday := time.Date(2024, 3, 29, 9, 0, 0, 0, berlin)
+
+	assert.Equal(t, time.Date(2024, 3, 30, 9, 0, 0, 0, berlin), next("daily", friday, 1))
+	assert.Equal(t, time.Date(2024, 4, 5, 9, 0, 0, 0, berlin), next("weekly", friday, 1))
+	assert.Equal(t, time.Date(2024, 4, 1, 9, 0, 0, 0, berlin), next("FREQ=WEEKLY;BYDAY=MO,FR", friday, 1))
+	assert.Equal(t, time.Date(2024, 4, 8, 9, 0, 0, 0, berlin), next("FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", friday, 1))
+	assert.Equal(t, time.Date(2024, 4, 29, 9, 0, 0, 0, berlin), next("monthly", friday, 1))
+	assert.Equal(t, time.Date(2024, 3, 31, 9, 0, 0, 0, berlin), next("FREQ=MONTHLY;BYMONTHDAY=-1", friday, 1))
+
+	// January 31st skips to March 31st, as February has no 31st.
+	assert.Equal(t, time.Date(2024, 3, 31, 9, 0, 0, 0, berlin), next("monthly", time.Date(2024, 1, 31, 9, 0, 0, 0, berlin), 1))
+
+	assert.True(t, next("FREQ=DAILY;COUNT=3", friday, 3).IsZero())
+	assert.False(t, next("FREQ=DAILY;COUNT=3", friday, 2).IsZero())
+	assert.True(t, next("FREQ=DAILY;UNTIL=20240329", friday, 1).IsZero())
+}
+
+func TestNextOccurrence(t *testing.T) {
+	due := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	itemDue := due.Add(-time.Hour)
+	todo := Todo{
+		ID:         7,
+		Title:      "Ops runbook",
+		List:       []TodoItem{{ID: 1, Item: "rotate logs", Checked: true, DueAt: &itemDue}, {ID: 2, Item: "check backups"}},
+		IsActive:   true,
+		UserID:     3,
+		DueAt:      &due,
+		Recurrence: "FREQ=WEEKLY",
+		Occurrence: 1,
+	}
+
+	// Not completed and the week is not over yet.
+	assert.Nil(t, todo.NextOccurrence(due.Add(24*time.Hour)))
+
+	// Completing it generates the next one straight away.
+	done := todo
+	done.IsActive = false
+	next := done.NextOccurrence(due.Add(24 * time.Hour))
+	if assert.NotNil(t, next) {
+		assert.Equal(t, due.AddDate(0, 0, 7), *next.DueAt)
+		assert.Equal(t, int64(7), next.SeriesID)
+		assert.Equal(t, 2, next.Occurrence)
+		assert.True(t, next.IsActive)
+		assert.Equal(t, []TodoItem{{Item: "rotate logs", DueAt: ptr(itemDue.AddDate(0, 0, 7))}, {Item: "check backups"}}, next.List)
+	}
+
+	// Once the period is over the next one is due either way; missed weeks
+	// are skipped.
+	next = todo.NextOccurrence(due.AddDate(0, 0, 15))
+	if assert.NotNil(t, next) {
+		assert.Equal(t, due.AddDate(0, 0, 21), *next.DueAt)
+		assert.Equal(t, 4, next.Occurrence)
+	}
+
+	todo.NextID = 8
+	assert.Nil(t, todo.NextOccurrence(due.AddDate(0, 0, 15)))
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
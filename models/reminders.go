@@ -0,0 +1,41 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// Reminder is a due todo, or a due item when ItemID is set, together with
+// the owner to notify.
+type Reminder struct {
+	TodoID   int64
+	ItemID   int64
+	UserID   int64
+	Email    string
+	Title    string
+	Item     string
+	DueAt    time.Time
+	TimeZone string
+}
+
+// Location is the time zone the due date was set in, falling back to UTC.
+func (r Reminder) Location() *time.Location {
+	location, err := time.LoadLocation(r.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+func sortReminders(reminders []Reminder) {
+	sort.SliceStable(reminders, func(i, j int) bool {
+		a, b := reminders[i], reminders[j]
+		if !a.DueAt.Equal(b.DueAt) {
+			return a.DueAt.Before(b.DueAt)
+		}
+		if a.TodoID != b.TodoID {
+			return a.TodoID < b.TodoID
+		}
+		return a.ItemID < b.ItemID
+	})
+}
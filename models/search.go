@@ -0,0 +1,155 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const DefaultSearchLimit = 20
+
+// Snippets wrap the matched words in these markers. The surrounding text
+// is returned as stored, not HTML-escaped.
+const (
+	highlightStart = "<mark>"
+	highlightStop  = "</mark>"
+)
+
+// SearchQuery is a full-text search over one user's todo titles and
+// checklist items. A title or item matches when it contains every word of
+// Text.
+type SearchQuery struct {
+	UserID int64
+	Text   string
+	Limit  int
+}
+
+type ItemSnippet struct {
+	ItemID  int64  `json:"itemId"`
+	Snippet string `json:"snippet"`
+}
+
+// SearchResult is a matching todo with its rank (higher is better) and the
+// title and matching items with the search words highlighted.
+type SearchResult struct {
+	Todo         Todo          `json:"todo"`
+	Rank         float64       `json:"rank"`
+	TitleSnippet string        `json:"titleSnippet"`
+	ItemSnippets []ItemSnippet `json:"itemSnippets,omitempty"`
+}
+
+func (q *SearchQuery) normalize() {
+	if q.Limit <= 0 {
+		q.Limit = DefaultSearchLimit
+	}
+	if q.Limit > MaxTodoLimit {
+		q.Limit = MaxTodoLimit
+	}
+}
+
+// searchTerms splits text into lower-cased words, dropping punctuation.
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func isSearchWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// matchesWord reports whether word starts with one of terms. Prefix
+// matching stands in for the stemming the SQL backends do.
+func matchesWord(word string, terms []string) bool {
+	word = strings.ToLower(word)
+	for _, term := range terms {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAll reports whether every term matches some word of text.
+func matchesAll(text string, terms []string) bool {
+	words := searchTerms(text)
+	for _, term := range terms {
+		found := false
+		for _, word := range words {
+			if strings.HasPrefix(word, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// highlight wraps every word of text that matches one of terms.
+func highlight(text string, terms []string) string {
+	var b strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !isSearchWord(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && isSearchWord(runes[j]) {
+			j++
+		}
+		word := string(runes[i:j])
+		if matchesWord(word, terms) {
+			b.WriteString(highlightStart + word + highlightStop)
+		} else {
+			b.WriteString(word)
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// search ranks todos the way the SQL backends weight them: a matching title
+// counts for 1 and each matching item for 0.4.
+func (q SearchQuery) search(todos []Todo) []SearchResult {
+	terms := searchTerms(q.Text)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var results []SearchResult
+	for _, todo := range todos {
+		result := SearchResult{Todo: todo, TitleSnippet: todo.Title}
+		if matchesAll(todo.Title, terms) {
+			result.Rank += 1
+			result.TitleSnippet = highlight(todo.Title, terms)
+		}
+		for _, item := range todo.List {
+			if matchesAll(item.Item, terms) {
+				result.Rank += 0.4
+				result.ItemSnippets = append(result.ItemSnippets, ItemSnippet{ItemID: item.ID, Snippet: highlight(item.Item, terms)})
+			}
+		}
+		if result.Rank > 0 {
+			results = append(results, result)
+		}
+	}
+	sortResults(results)
+	if len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results
+}
+
+func sortResults(results []SearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Todo.ID < results[j].Todo.ID
+	})
+}
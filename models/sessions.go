@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Session is one login of a user, from POST /login until it is logged out,
+// revoked or left unused for longer than refresh tokens last. Its refresh
+// tokens share FamilyID. UserAgent and IP are those of the last token
+// handed out in it.
+type Session struct {
+	ID         int64      `db:"id" json:"id"`
+	UserID     int64      `db:"user_id" json:"userId"`
+	FamilyID   string     `db:"family_id" json:"-"`
+	UserAgent  string     `db:"user_agent" json:"userAgent"`
+	IP         string     `db:"ip" json:"ip"`
+	CreatedAt  time.Time  `db:"created_at" json:"createdAt"`
+	LastUsedAt time.Time  `db:"last_used_at" json:"lastUsedAt"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revokedAt,omitempty"`
+	// Current marks the session the request was made in.
+	Current bool `db:"-" json:"current"`
+}
+
+// IssuedToken is an access token handed out in a session, tracked by its
+// jti so that revoking the session revokes the token too.
+type IssuedToken struct {
+	JTI       string    `db:"jti" json:"jti"`
+	SessionID int64     `db:"session_id" json:"sessionId"`
+	UserAgent string    `db:"user_agent" json:"userAgent"`
+	IP        string    `db:"ip" json:"ip"`
+	IssuedAt  time.Time `db:"issued_at" json:"issuedAt"`
+	ExpiresAt time.Time `db:"expires_at" json:"expiresAt"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// ShareLink lets anyone holding Token view a todo without logging in,
+// until it expires or is revoked. Accesses is filled in by GetShareLinks.
+type ShareLink struct {
+	ID        int64      `db:"id" json:"id"`
+	TodoID    int64      `db:"todo_id" json:"todoId"`
+	Token     string     `db:"token" json:"token"`
+	CreatedBy int64      `db:"created_by" json:"createdBy"`
+	ExpiresAt *time.Time `db:"expires_at" json:"expiresAt,omitempty"`
+	RevokedAt *time.Time `db:"revoked_at" json:"revokedAt,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
+	Accesses  int        `db:"-" json:"accesses"`
+}
+
+func (l ShareLink) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// LinkAccess records one view of a todo through a share link.
+type LinkAccess struct {
+	LinkID     int64     `db:"link_id" json:"linkId"`
+	IP         string    `db:"ip" json:"ip"`
+	UserAgent  string    `db:"user_agent" json:"userAgent"`
+	AccessedAt time.Time `db:"accessed_at" json:"accessedAt"`
+}
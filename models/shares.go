@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Roles a user can have on a todo. The owner is the todo's UserID; the
+// other two are granted through a Share.
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// Share gives another user access to a todo. Email is filled in by
+// GetShares.
+type Share struct {
+	TodoID    int64     `db:"todo_id" json:"todoId"`
+	UserID    int64     `db:"user_id" json:"userId"`
+	Email     string    `db:"email" json:"email"`
+	Role      string    `db:"role" json:"role"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
+
+// Action is something a user may try to do with a todo.
+type Action int
+
+const (
+	// ActionView reads the todo and its items.
+	ActionView Action = iota
+	// ActionEdit changes the todo or its items.
+	ActionEdit
+	// ActionOrganize changes the owner's tags on the todo.
+	ActionOrganize
+	// ActionDelete moves the todo to the trash.
+	ActionDelete
+	// ActionShare grants or revokes access for other users.
+	ActionShare
+	// ActionManageWorkspace renames a workspace and manages its members
+	// and invites.
+	ActionManageWorkspace
+	// ActionDeleteWorkspace deletes a workspace with all of its todos.
+	ActionDeleteWorkspace
+)
+
+// Allows is the sharing policy: owners may do anything, editors may view
+// and edit, viewers may only view. An empty role, for users the todo is
+// not shared with, allows nothing.
+func Allows(role string, action Action) bool {
+	switch role {
+	case RoleOwner:
+		return action <= ActionShare
+	case RoleEditor:
+		return action == ActionView || action == ActionEdit
+	case RoleViewer:
+		return action == ActionView
+	}
+	return false
+}
+
+func IsValidShareRole(role string) bool {
+	return role == RoleEditor || role == RoleViewer
+}
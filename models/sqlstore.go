@@ -0,0 +1,596 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"project_todo/db"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLStore is the Store backed by the tables created in db. Queries are
+// written with Postgres placeholders and rebound for SQLite. Timestamps are
+// written in UTC so SQLite's text timestamps compare in time order.
+type SQLStore struct {
+	DB     *sql.DB
+	Driver string
+}
+
+func NewSQLStore(conn *sql.DB, driver string) *SQLStore {
+	return &SQLStore{DB: conn, Driver: driver}
+}
+
+func (s *SQLStore) rebind(query string) string {
+	return db.Rebind(s.Driver, query)
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// withTx runs fn in a transaction. With SQLite there is a single connection,
+// so everything inside fn must go through tx.
+func (s *SQLStore) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const todoColumns = "id, title, is_active, created_at, updated_at, user_id, version, deleted_at, due_at, time_zone, recurrence, COALESCE(series_id, 0), occurrence, COALESCE(next_id, 0), priority, rank, COALESCE(project_id, 0), COALESCE(workspace_id, 0)"
+
+func (s *SQLStore) CreateTodo(t *Todo) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		err := s.insertTodo(tx, t)
+		if err != nil {
+			return err
+		}
+		return s.addHistory(tx, t.ID, t.UserID, HistoryCreated, nil)
+	})
+}
+
+// insertTodo adds the todo at the end of the user's manual order, or of
+// the workspace's for workspace todos.
+func (s *SQLStore) insertTodo(tx *sql.Tx, t *Todo) error {
+	scope := "SELECT COALESCE(MAX(rank), '') FROM todos WHERE user_id = $1 AND workspace_id IS NULL"
+	owner := t.UserID
+	if t.WorkspaceID != 0 {
+		scope, owner = "SELECT COALESCE(MAX(rank), '') FROM todos WHERE workspace_id = $1", t.WorkspaceID
+	}
+	var last string
+	err := tx.QueryRow(s.rebind(scope), owner).Scan(&last)
+	if err != nil {
+		return err
+	}
+	t.Rank = RankBetween(last, "")
+	t.Priority = priorityOrDefault(t.Priority)
+
+	query := `
+	INSERT INTO todos(title, is_active, created_at, updated_at, user_id, version, due_at, time_zone, recurrence, series_id, occurrence, priority, rank, project_id, workspace_id)
+	VALUES ($1, $2, $3, $4, $5, 1, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id, version, occurrence
+	`
+	err = tx.QueryRow(s.rebind(query), t.Title, t.IsActive, t.CreatedAt.UTC(), t.UpdatedAt.UTC(), t.UserID, utc(t.DueAt), t.TimeZone,
+		t.Recurrence, nullId(t.SeriesID), max(t.Occurrence, 1), t.Priority, t.Rank, nullId(t.ProjectID), nullId(t.WorkspaceID)).Scan(&t.ID, &t.Version, &t.Occurrence)
+	if err != nil {
+		fmt.Println("Error inserting todo:", err)
+		return err
+	}
+	return s.syncItems(tx, t.ID, t.List)
+}
+
+var sortColumns = map[string]string{
+	SortById:        "id",
+	SortByCreatedAt: "created_at",
+	SortByUpdatedAt: "updated_at",
+	SortByTitle:     "title",
+	SortByRank:      "rank",
+	SortByPriority:  "priority",
+}
+
+// conditions collects WHERE clauses; each ? in a clause is numbered in turn.
+type conditions struct {
+	clauses []string
+	args    []any
+}
+
+func (c *conditions) add(clause string, args ...any) {
+	for _, arg := range args {
+		c.args = append(c.args, arg)
+		clause = strings.Replace(clause, "?", fmt.Sprintf("$%d", len(c.args)), 1)
+	}
+	c.clauses = append(c.clauses, clause)
+}
+
+func (c *conditions) where() string {
+	return strings.Join(c.clauses, " AND ")
+}
+
+// dueRange is the condition for column falling in [from, to), with a ? for
+// each bound that is set.
+func dueRange(column string, from, to *time.Time) (string, []any) {
+	clauses := []string{column + " IS NOT NULL"}
+	var args []any
+	if from != nil {
+		clauses = append(clauses, column+" >= ?")
+		args = append(args, from.UTC())
+	}
+	if to != nil {
+		clauses = append(clauses, column+" < ?")
+		args = append(args, to.UTC())
+	}
+	return "(" + strings.Join(clauses, " AND ") + ")", args
+}
+
+// likePattern escapes LIKE wildcards so the text is matched literally.
+func likePattern(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(text) + "%"
+}
+
+func (s *SQLStore) GetAllTodos(q TodoQuery) (TodoPage, error) {
+	after, err := q.normalize()
+	if err != nil {
+		return TodoPage{}, err
+	}
+
+	var c conditions
+	switch {
+	case q.WorkspaceID != 0:
+		c.add("workspace_id = ?", q.WorkspaceID)
+	case q.IncludeShared:
+		c.add("workspace_id IS NULL")
+		c.add("(user_id = ? OR id IN (SELECT todo_id FROM todo_shares WHERE user_id = ?))", q.UserID, q.UserID)
+	default:
+		c.add("workspace_id IS NULL")
+		c.add("user_id = ?", q.UserID)
+	}
+	c.add("deleted_at IS NULL")
+	if q.IsActive != nil {
+		c.add("is_active = ?", *q.IsActive)
+	}
+	if q.CreatedFrom != nil {
+		c.add("created_at >= ?", q.CreatedFrom.UTC())
+	}
+	if q.CreatedTo != nil {
+		c.add("created_at < ?", q.CreatedTo.UTC())
+	}
+	if q.UpdatedFrom != nil {
+		c.add("updated_at >= ?", q.UpdatedFrom.UTC())
+	}
+	if q.UpdatedTo != nil {
+		c.add("updated_at < ?", q.UpdatedTo.UTC())
+	}
+	if q.TitleContains != "" {
+		if s.Driver == db.SQLite {
+			// LIKE is already case-insensitive for ASCII in SQLite.
+			c.add(`title LIKE ? ESCAPE '\'`, likePattern(q.TitleContains))
+		} else {
+			c.add(`title ILIKE ? ESCAPE '\'`, likePattern(q.TitleContains))
+		}
+	}
+	if q.DueFrom != nil || q.DueTo != nil {
+		todoDue, args := dueRange("due_at", q.DueFrom, q.DueTo)
+		itemDue, _ := dueRange("i.due_at", q.DueFrom, q.DueTo)
+		c.add("("+todoDue+" OR EXISTS (SELECT 1 FROM todo_items i WHERE i.todo_id = todos.id AND NOT i.checked AND "+itemDue+"))",
+			append(args, args...)...)
+	}
+	if len(q.Tags) > 0 {
+		names := tagNames(q.Tags)
+		args := make([]any, len(names))
+		marks := make([]string, len(names))
+		for i, name := range names {
+			args[i] = name
+			marks[i] = "?"
+		}
+		tagged := "SELECT tt.todo_id FROM todo_tags tt JOIN tags g ON g.id = tt.tag_id WHERE lower(g.name) IN (" + strings.Join(marks, ", ") + ") AND g.user_id = ?"
+		args = append(args, q.UserID)
+		if !q.AnyTag {
+			tagged += fmt.Sprintf(" GROUP BY tt.todo_id HAVING COUNT(DISTINCT lower(g.name)) = %d", len(names))
+		}
+		c.add("id IN ("+tagged+")", args...)
+	}
+	if len(q.Priorities) > 0 {
+		args := make([]any, len(q.Priorities))
+		marks := make([]string, len(q.Priorities))
+		for i, priority := range q.Priorities {
+			args[i] = priority
+			marks[i] = "?"
+		}
+		c.add("priority IN ("+strings.Join(marks, ", ")+")", args...)
+	}
+	if len(q.ProjectIDs) > 0 {
+		args := make([]any, len(q.ProjectIDs))
+		marks := make([]string, len(q.ProjectIDs))
+		for i, projectId := range q.ProjectIDs {
+			args[i] = projectId
+			marks[i] = "?"
+		}
+		c.add("project_id IN ("+strings.Join(marks, ", ")+")", args...)
+	}
+	if !q.IncludeArchived {
+		c.add("(project_id IS NULL OR project_id IN (SELECT id FROM projects WHERE archived_at IS NULL))")
+	}
+	if q.HasUnchecked != nil {
+		exists := "EXISTS (SELECT 1 FROM todo_items i WHERE i.todo_id = todos.id AND NOT i.checked)"
+		if !*q.HasUnchecked {
+			exists = "NOT " + exists
+		}
+		c.add(exists)
+	}
+
+	column := sortColumns[q.SortBy]
+	direction, comparison := "ASC", ">"
+	if q.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if after != nil {
+		if q.SortBy == SortById {
+			c.add("id "+comparison+" ?", after.ID)
+		} else {
+			var key any = after.Key
+			if q.SortBy == SortByCreatedAt || q.SortBy == SortByUpdatedAt {
+				at, err := time.Parse(time.RFC3339Nano, after.Key)
+				if err != nil {
+					return TodoPage{}, ErrInvalidCursor
+				}
+				key = at.UTC()
+			}
+			c.add("("+column+" "+comparison+" ? OR ("+column+" = ? AND id "+comparison+" ?))", key, key, after.ID)
+		}
+	}
+
+	order := "id " + direction
+	if column != "id" {
+		order = column + " " + direction + ", " + order
+	}
+	query := fmt.Sprintf("SELECT %s FROM todos WHERE %s ORDER BY %s LIMIT %d", todoColumns, c.where(), order, q.Limit+1)
+	rows, err := s.DB.Query(s.rebind(query), c.args...)
+	if err != nil {
+		return TodoPage{}, err
+	}
+	var todos []Todo
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			rows.Close()
+			return TodoPage{}, err
+		}
+		todos = append(todos, *todo)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return TodoPage{}, err
+	}
+
+	page := q.page(todos)
+	err = s.loadRelated(s.DB, page.Todos)
+	if err != nil || !q.IncludeShared || q.WorkspaceID != 0 {
+		return page, err
+	}
+	return page, s.markShared(q.UserID, page.Todos)
+}
+
+func (s *SQLStore) GetTodoById(id int64) (*Todo, error) {
+	return s.getTodo(s.DB, id)
+}
+
+func (s *SQLStore) getTodo(q querier, id int64) (*Todo, error) {
+	query := "SELECT " + todoColumns + " FROM todos where id = $1"
+	todo, err := scanTodo(q.QueryRow(s.rebind(query), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		fmt.Println("Error in fetching todo", err)
+		return nil, err
+	}
+	todos := []Todo{*todo}
+	err = s.loadRelated(q, todos)
+	if err != nil {
+		return nil, err
+	}
+	return &todos[0], nil
+}
+
+// queryTodos runs query, which selects todoColumns, and fills in the items
+// and tags of the todos it returns.
+func (s *SQLStore) queryTodos(q querier, query string, args ...any) ([]Todo, error) {
+	rows, err := q.Query(s.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	var todos []Todo
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		todos = append(todos, *todo)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return todos, s.loadRelated(q, todos)
+}
+
+func (s *SQLStore) UpdateTodo(t Todo, actorId int64) error {
+	return s.updateTodo(t, actorId, HistoryUpdated)
+}
+
+func (s *SQLStore) RevertTodo(t Todo, actorId int64) error {
+	return s.updateTodo(t, actorId, HistoryReverted)
+}
+
+// updateTodo writes t and records it in the history as action.
+func (s *SQLStore) updateTodo(t Todo, actorId int64, action string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		before, err := s.getTodo(tx, t.ID)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		query := `
+		UPDATE todos
+		SET title =$1, is_active=$2, updated_at=$3, version = version + 1, due_at = $6, time_zone = $7, recurrence = $8, priority = $9,
+			project_id = $10
+		WHERE id = $4 AND ($5 = 0 OR version = $5)
+		`
+		result, err := tx.Exec(s.rebind(query), t.Title, t.IsActive, t.UpdatedAt.UTC(), t.ID, t.Version, utc(t.DueAt), t.TimeZone, t.Recurrence,
+			priorityOrDefault(t.Priority), nullId(t.ProjectID))
+		if err != nil {
+			fmt.Println("error in updating todo")
+			return err
+		}
+		err = checkVersion(result, t.Version)
+		if err != nil {
+			return err
+		}
+		err = s.insertVersion(tx, *before, t.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		err = s.syncItems(tx, t.ID, t.List)
+		if err != nil {
+			return err
+		}
+		return s.addHistory(tx, t.ID, actorId, action, before)
+	})
+}
+
+func (s *SQLStore) DeleteTodo(t Todo, actorId int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		before, err := s.getTodo(tx, t.ID)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(s.rebind("DELETE FROM todo_items WHERE todo_id = $1"), t.ID)
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(s.rebind("DELETE FROM todos WHERE id = $1 AND ($2 = 0 OR version = $2)"), t.ID, t.Version)
+		if err != nil {
+			return err
+		}
+		err = checkVersion(result, t.Version)
+		if err != nil {
+			return err
+		}
+		return s.addHistory(tx, t.ID, actorId, HistoryDeleted, before)
+	})
+}
+
+// MoveTodo rewrites the rank of the moved todo only. Neighbours missing
+// from the user's (untrashed, personal) todos give ErrNotFound.
+func (s *SQLStore) MoveTodo(userId int64, move Move) error {
+	if (move.AfterID == 0 && move.BeforeID == 0) || move.AfterID == move.ID || move.BeforeID == move.ID {
+		return ErrInvalidMove
+	}
+	return s.withTx(func(tx *sql.Tx) error {
+		rankOf := func(id int64) (string, error) {
+			var rank string
+			query := "SELECT rank FROM todos WHERE id = $1 AND user_id = $2 AND workspace_id IS NULL AND deleted_at IS NULL"
+			err := tx.QueryRow(s.rebind(query), id, userId).Scan(&rank)
+			if errors.Is(err, sql.ErrNoRows) {
+				return "", ErrNotFound
+			}
+			return rank, err
+		}
+		_, err := rankOf(move.ID)
+		if err != nil {
+			return err
+		}
+
+		var low, high string
+		if move.AfterID != 0 {
+			if low, err = rankOf(move.AfterID); err != nil {
+				return err
+			}
+		}
+		if move.BeforeID != 0 {
+			if high, err = rankOf(move.BeforeID); err != nil {
+				return err
+			}
+		}
+		siblings := "FROM todos WHERE user_id = $1 AND workspace_id IS NULL AND deleted_at IS NULL AND id <> $2"
+		if move.BeforeID == 0 {
+			err = tx.QueryRow(s.rebind("SELECT COALESCE(MIN(rank), '') "+siblings+" AND rank > $3"), userId, move.ID, low).Scan(&high)
+		} else if move.AfterID == 0 {
+			err = tx.QueryRow(s.rebind("SELECT COALESCE(MAX(rank), '') "+siblings+" AND rank < $3"), userId, move.ID, high).Scan(&low)
+		}
+		if err != nil {
+			return err
+		}
+		rank, err := rankWithin(low, high)
+		if err != nil {
+			return err
+		}
+		before, err := s.getTodo(tx, move.ID)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		err = s.insertVersion(tx, *before, now)
+		if err != nil {
+			return err
+		}
+		query := "UPDATE todos SET rank = $1, updated_at = $2, version = version + 1 WHERE id = $3 AND ($4 = 0 OR version = $4)"
+		result, err := tx.Exec(s.rebind(query), rank, now, move.ID, move.Version)
+		if err != nil {
+			return err
+		}
+		err = checkVersion(result, move.Version)
+		if err != nil {
+			return err
+		}
+		return s.addHistory(tx, move.ID, userId, HistoryUpdated, before)
+	})
+}
+
+// checkVersion turns a versioned write that matched no row into
+// ErrVersionConflict. Callers have already made sure the todo exists.
+func checkVersion(result sql.Result, version int64) error {
+	if version == 0 {
+		return nil
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+const userColumns = "id, email, first_name, last_name, password, is_active, created_at, updated_at, email_verified_at, verification_sent_at"
+
+func (s *SQLStore) CreateUser(u *User) error {
+	query := `INSERT INTO users(email, first_name, last_name, password, is_active, created_at, updated_at, email_verified_at)
+	VALUES ($1,$2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	err := s.DB.QueryRow(s.rebind(query), u.Email, u.FirstName, u.LastName, u.Password, u.IsActive, u.CreatedAt.UTC(), u.UpdatedAt.UTC(),
+		utc(u.EmailVerifiedAt)).Scan(&u.ID)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (s *SQLStore) GetUserByEmail(email string) (*User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE email = $1"
+	return scanUser(s.DB.QueryRow(s.rebind(query), email))
+}
+
+func (s *SQLStore) GetUserById(id int64) (*User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE id = $1"
+	return scanUser(s.DB.QueryRow(s.rebind(query), id))
+}
+
+func scanUser(row rowScanner) (*User, error) {
+	var u User
+	err := row.Scan(&u.ID, &u.Email, &u.FirstName, &u.LastName, &u.Password, &u.IsActive, &u.CreatedAt, &u.UpdatedAt,
+		&u.EmailVerifiedAt, &u.VerificationSentAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (s *SQLStore) UpdatePassword(userId int64, hashedPassword string) error {
+	query := "UPDATE users SET password = $1, updated_at = $2 WHERE id = $3"
+	return s.expectRow(s.DB.Exec(s.rebind(query), hashedPassword, time.Now().UTC(), userId))
+}
+
+func (s *SQLStore) VerifyEmail(userId int64, email string, verifiedAt time.Time) error {
+	query := `
+	UPDATE users SET email_verified_at = $1, is_active = TRUE, updated_at = $1
+	WHERE id = $2 AND email = $3 AND email_verified_at IS NULL`
+	return s.expectRow(s.DB.Exec(s.rebind(query), verifiedAt.UTC(), userId, email))
+}
+
+func (s *SQLStore) MarkVerificationSent(userId int64, sentAt, since time.Time) (bool, error) {
+	query := `
+	UPDATE users SET verification_sent_at = $1
+	WHERE id = $2 AND email_verified_at IS NULL AND (verification_sent_at IS NULL OR verification_sent_at <= $3)`
+	err := s.expectRow(s.DB.Exec(s.rebind(query), sentAt.UTC(), userId, since.UTC()))
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		code := sqliteErr.Code()
+		return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
+
+// placeholders returns "$from, $from+1, ..." for n arguments.
+func placeholders(from, n int) string {
+	marks := make([]string, n)
+	for i := range marks {
+		marks[i] = fmt.Sprintf("$%d", from+i)
+	}
+	return strings.Join(marks, ", ")
+}
+
+// nullId writes a zero ID as NULL.
+func nullId(id int64) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// utc converts an optional timestamp for writing; nil is written as NULL.
+func utc(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTodo(row rowScanner) (*Todo, error) {
+	var todo Todo
+	err := row.Scan(&todo.ID, &todo.Title, &todo.IsActive, &todo.CreatedAt, &todo.UpdatedAt, &todo.UserID, &todo.Version, &todo.DeletedAt, &todo.DueAt, &todo.TimeZone,
+		&todo.Recurrence, &todo.SeriesID, &todo.Occurrence, &todo.NextID, &todo.Priority, &todo.Rank, &todo.ProjectID, &todo.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
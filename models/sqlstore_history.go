@@ -0,0 +1,59 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// addHistory appends to todoId's history what action did to it, diffing
+// before, nil for a new todo, against the todo as tx sees it now.
+func (s *SQLStore) addHistory(tx *sql.Tx, todoId, actorId int64, action string, before *Todo) error {
+	after, err := s.getTodo(tx, todoId)
+	if errors.Is(err, ErrNotFound) {
+		after, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.insertHistory(tx, todoId, actorId, action, DiffTodos(before, after))
+}
+
+func (s *SQLStore) insertHistory(tx *sql.Tx, todoId, actorId int64, action string, todoDiff TodoDiff) error {
+	diff, err := json.Marshal(todoDiff)
+	if err != nil {
+		return err
+	}
+	query := "INSERT INTO todo_history(todo_id, actor_id, action, diff, created_at) VALUES ($1, $2, $3, $4, $5)"
+	_, err = tx.Exec(s.rebind(query), todoId, actorId, action, string(diff), time.Now().UTC())
+	return err
+}
+
+func (s *SQLStore) GetHistory(todoId int64) ([]HistoryEntry, error) {
+	query := `
+	SELECT h.id, h.todo_id, h.actor_id, COALESCE(u.email, ''), h.action, h.diff, h.created_at
+	FROM todo_history h LEFT JOIN users u ON u.id = h.actor_id
+	WHERE h.todo_id = $1
+	ORDER BY h.id DESC`
+	rows, err := s.DB.Query(s.rebind(query), todoId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var history []HistoryEntry
+	for rows.Next() {
+		var entry HistoryEntry
+		var diff string
+		err = rows.Scan(&entry.ID, &entry.TodoID, &entry.ActorID, &entry.ActorEmail, &entry.Action, &diff, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal([]byte(diff), &entry.Diff)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
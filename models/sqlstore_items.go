@@ -0,0 +1,296 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+)
+
+const itemColumns = "id, todo_id, item, checked, rank, due_at"
+
+func scanItem(row rowScanner) (*TodoItem, error) {
+	var item TodoItem
+	err := row.Scan(&item.ID, &item.TodoID, &item.Item, &item.Checked, &item.Rank, &item.DueAt)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// loadItems fills in List for each of the given todos.
+func (s *SQLStore) loadItems(q querier, todos []Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	ids := make([]any, len(todos))
+	index := make(map[int64]int, len(todos))
+	for i, todo := range todos {
+		ids[i] = todo.ID
+		index[todo.ID] = i
+	}
+
+	query := "SELECT " + itemColumns + " FROM todo_items WHERE todo_id IN (" + placeholders(1, len(ids)) + ") ORDER BY todo_id, rank, id"
+	rows, err := q.Query(s.rebind(query), ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return err
+		}
+		todo := &todos[index[item.TodoID]]
+		item.Position = len(todo.List)
+		todo.List = append(todo.List, *item)
+	}
+	return rows.Err()
+}
+
+// itemRanks returns the IDs and ranks of the todo's items in list order.
+func (s *SQLStore) itemRanks(tx *sql.Tx, todoId int64) ([]int64, []string, error) {
+	rows, err := tx.Query(s.rebind("SELECT id, rank FROM todo_items WHERE todo_id = $1 ORDER BY rank, id"), todoId)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	var ids []int64
+	var ranks []string
+	for rows.Next() {
+		var id int64
+		var rank string
+		err = rows.Scan(&id, &rank)
+		if err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, id)
+		ranks = append(ranks, rank)
+	}
+	return ids, ranks, rows.Err()
+}
+
+// syncItems makes the stored checklist match items. Entries whose ID
+// already belongs to the todo are updated in place, the rest are inserted,
+// and stored items missing from the slice are removed. The list order
+// follows the slice; items that kept their relative order keep their rank.
+// IDs and ranks are written back into items.
+func (s *SQLStore) syncItems(tx *sql.Tx, todoId int64, items []TodoItem) error {
+	ids, ranks, err := s.itemRanks(tx, todoId)
+	if err != nil {
+		return err
+	}
+	existing := make(map[int64]string, len(ids))
+	for i, id := range ids {
+		existing[id] = ranks[i]
+	}
+	current := make([]string, len(items))
+	for i, item := range items {
+		current[i] = existing[item.ID]
+	}
+	ranks = assignRanks(current)
+
+	for i := range items {
+		item := &items[i]
+		item.TodoID = todoId
+		item.Position = i
+		item.Rank = ranks[i]
+		if _, ok := existing[item.ID]; ok {
+			delete(existing, item.ID)
+			_, err = tx.Exec(s.rebind("UPDATE todo_items SET item = $1, checked = $2, rank = $3, due_at = $4 WHERE id = $5"),
+				item.Item, item.Checked, item.Rank, utc(item.DueAt), item.ID)
+		} else {
+			err = tx.QueryRow(s.rebind("INSERT INTO todo_items(todo_id, item, checked, rank, due_at) VALUES ($1, $2, $3, $4, $5) RETURNING id"),
+				todoId, item.Item, item.Checked, item.Rank, utc(item.DueAt)).Scan(&item.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for id := range existing {
+		_, err = tx.Exec(s.rebind("DELETE FROM todo_items WHERE id = $1"), id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// touchTodo bumps the version of a todo whose tags changed and records the
+// change in its history as made by actorId.
+func (s *SQLStore) touchTodo(tx *sql.Tx, todoId, actorId int64) error {
+	before, err := s.getTodo(tx, todoId)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	err = s.insertVersion(tx, *before, now)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(s.rebind("UPDATE todos SET updated_at = $1, version = version + 1 WHERE id = $2"), now, todoId)
+	if err != nil {
+		return err
+	}
+	return s.addHistory(tx, todoId, actorId, HistoryUpdated, before)
+}
+
+// itemsTodo loads the todo whose items are about to change, failing with
+// ErrVersionConflict unless it is still at version.
+func (s *SQLStore) itemsTodo(tx *sql.Tx, todoId, version int64) (*Todo, error) {
+	todo, err := s.getTodo(tx, todoId)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && todo.Version != version {
+		return nil, ErrVersionConflict
+	}
+	return todo, nil
+}
+
+// itemsChanged bumps the version of the todo whose items changed, saving
+// before, the todo as it was, as the version replaced, and records the
+// change in its history. The bump only applies while the todo is still at
+// before.Version, so a concurrent write in between is a conflict.
+func (s *SQLStore) itemsChanged(tx *sql.Tx, actorId int64, before *Todo) error {
+	now := time.Now().UTC()
+	err := s.insertVersion(tx, *before, now)
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec(s.rebind("UPDATE todos SET updated_at = $1, version = version + 1 WHERE id = $2 AND version = $3"), now, before.ID, before.Version)
+	if err != nil {
+		return err
+	}
+	err = checkVersion(result, before.Version)
+	if err != nil {
+		return err
+	}
+	return s.addHistory(tx, before.ID, actorId, HistoryUpdated, before)
+}
+
+func (s *SQLStore) CreateItem(item *TodoItem, version, actorId int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		before, err := s.itemsTodo(tx, item.TodoID, version)
+		if err != nil {
+			return err
+		}
+		_, ranks, err := s.itemRanks(tx, item.TodoID)
+		if err != nil {
+			return err
+		}
+		if item.Position < 0 || item.Position > len(ranks) {
+			item.Position = len(ranks)
+		}
+		item.Rank = rankAt(ranks, item.Position)
+		err = tx.QueryRow(s.rebind("INSERT INTO todo_items(todo_id, item, checked, rank, due_at) VALUES ($1, $2, $3, $4, $5) RETURNING id"),
+			item.TodoID, item.Item, item.Checked, item.Rank, utc(item.DueAt)).Scan(&item.ID)
+		if err != nil {
+			return err
+		}
+		return s.itemsChanged(tx, actorId, before)
+	})
+}
+
+func (s *SQLStore) GetItem(todoId, itemId int64) (*TodoItem, error) {
+	return s.getItem(s.DB, todoId, itemId)
+}
+
+// getItem also fills in the item's Position.
+func (s *SQLStore) getItem(q querier, todoId, itemId int64) (*TodoItem, error) {
+	query := "SELECT " + itemColumns + " FROM todo_items WHERE id = $1 AND todo_id = $2"
+	item, err := scanItem(q.QueryRow(s.rebind(query), itemId, todoId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	query = "SELECT COUNT(*) FROM todo_items WHERE todo_id = $1 AND (rank < $2 OR (rank = $2 AND id < $3))"
+	err = q.QueryRow(s.rebind(query), todoId, item.Rank, item.ID).Scan(&item.Position)
+	return item, err
+}
+
+// UpdateItem only gives the item a new rank when its position changes.
+func (s *SQLStore) UpdateItem(item TodoItem, version, actorId int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		before, err := s.itemsTodo(tx, item.TodoID, version)
+		if err != nil {
+			return err
+		}
+		ids, ranks, err := s.itemRanks(tx, item.TodoID)
+		if err != nil {
+			return err
+		}
+		current := slices.Index(ids, item.ID)
+		if current < 0 {
+			return ErrNotFound
+		}
+		rank := ranks[current]
+		others := slices.Delete(ranks, current, current+1)
+		if item.Position < 0 || item.Position > len(others) {
+			item.Position = len(others)
+		}
+		if item.Position != current {
+			rank = rankAt(others, item.Position)
+		}
+
+		_, err = tx.Exec(s.rebind("UPDATE todo_items SET item = $1, checked = $2, rank = $3, due_at = $4 WHERE id = $5"),
+			item.Item, item.Checked, rank, utc(item.DueAt), item.ID)
+		if err != nil {
+			return err
+		}
+		return s.itemsChanged(tx, actorId, before)
+	})
+}
+
+func (s *SQLStore) DeleteItem(item TodoItem, version, actorId int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		before, err := s.itemsTodo(tx, item.TodoID, version)
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(s.rebind("DELETE FROM todo_items WHERE id = $1 AND todo_id = $2"), item.ID, item.TodoID)
+		if err != nil {
+			return err
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+		return s.itemsChanged(tx, actorId, before)
+	})
+}
+
+// MoveItem rewrites the rank of the moved item only.
+func (s *SQLStore) MoveItem(todoId int64, move Move, version, actorId int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		before, err := s.itemsTodo(tx, todoId, version)
+		if err != nil {
+			return err
+		}
+		ids, ranks, err := s.itemRanks(tx, todoId)
+		if err != nil {
+			return err
+		}
+		current := slices.Index(ids, move.ID)
+		if current < 0 {
+			return ErrNotFound
+		}
+		ids = slices.Delete(ids, current, current+1)
+		ranks = slices.Delete(ranks, current, current+1)
+		rank, err := moveRank(ids, ranks, move)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(s.rebind("UPDATE todo_items SET rank = $1 WHERE id = $2"), rank, move.ID)
+		if err != nil {
+			return err
+		}
+		return s.itemsChanged(tx, actorId, before)
+	})
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+func (s *SQLStore) CreatePasswordReset(reset *PasswordReset) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		query := "DELETE FROM password_resets WHERE user_id = $1 OR expires_at <= $2"
+		_, err := tx.Exec(s.rebind(query), reset.UserID, reset.CreatedAt.UTC())
+		if err != nil {
+			return err
+		}
+		query = `
+		INSERT INTO password_resets(user_id, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4) RETURNING id`
+		err = tx.QueryRow(s.rebind(query), reset.UserID, reset.TokenHash, reset.CreatedAt.UTC(), reset.ExpiresAt.UTC()).Scan(&reset.ID)
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return err
+	})
+}
+
+func (s *SQLStore) UsePasswordReset(hash string, usedAt time.Time) (*PasswordReset, error) {
+	var reset PasswordReset
+	query := `
+	UPDATE password_resets SET used_at = $1 WHERE token_hash = $2 AND used_at IS NULL
+	RETURNING id, user_id, token_hash, created_at, expires_at, used_at`
+	err := s.DB.QueryRow(s.rebind(query), usedAt.UTC(), hash).Scan(&reset.ID, &reset.UserID, &reset.TokenHash, &reset.CreatedAt,
+		&reset.ExpiresAt, &reset.UsedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
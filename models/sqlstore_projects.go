@@ -0,0 +1,111 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const projectColumns = "id, user_id, COALESCE(parent_id, 0), name, archived_at, created_at, updated_at"
+
+// projectSubtree selects the IDs of project $1 and every project under it.
+const projectSubtree = `WITH RECURSIVE subtree(id) AS (
+	SELECT id FROM projects WHERE id = $1
+	UNION ALL
+	SELECT p.id FROM projects p JOIN subtree s ON p.parent_id = s.id
+) SELECT id FROM subtree`
+
+func scanProject(row rowScanner) (*Project, error) {
+	var project Project
+	err := row.Scan(&project.ID, &project.UserID, &project.ParentID, &project.Name, &project.ArchivedAt, &project.CreatedAt, &project.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (s *SQLStore) CreateProject(project *Project) error {
+	query := "INSERT INTO projects(user_id, parent_id, name, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+	return s.DB.QueryRow(s.rebind(query), project.UserID, nullId(project.ParentID), project.Name,
+		project.CreatedAt.UTC(), project.UpdatedAt.UTC()).Scan(&project.ID)
+}
+
+func (s *SQLStore) GetProjects(userId int64) ([]Project, error) {
+	rows, err := s.DB.Query(s.rebind("SELECT "+projectColumns+" FROM projects WHERE user_id = $1 ORDER BY lower(name), id"), userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var projects []Project
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, *project)
+	}
+	return projects, rows.Err()
+}
+
+func (s *SQLStore) GetProject(id int64) (*Project, error) {
+	project, err := scanProject(s.DB.QueryRow(s.rebind("SELECT "+projectColumns+" FROM projects WHERE id = $1"), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return project, err
+}
+
+func (s *SQLStore) UpdateProject(project Project) error {
+	query := "UPDATE projects SET name = $1, parent_id = $2, updated_at = $3 WHERE id = $4"
+	_, err := s.DB.Exec(s.rebind(query), project.Name, nullId(project.ParentID), project.UpdatedAt.UTC(), project.ID)
+	return err
+}
+
+func (s *SQLStore) ArchiveProject(project Project, archived bool) error {
+	now := time.Now().UTC()
+	var archivedAt any
+	if archived {
+		archivedAt = now
+	}
+	query := "UPDATE projects SET archived_at = $2, updated_at = $3 WHERE id IN (" + projectSubtree + ")"
+	_, err := s.DB.Exec(s.rebind(query), project.ID, archivedAt, now)
+	return err
+}
+
+func (s *SQLStore) DeleteProject(project Project, keepTodos bool) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		set := "deleted_at = COALESCE(deleted_at, $2)"
+		if keepTodos {
+			set = "updated_at = $2"
+		}
+		now := time.Now().UTC()
+		err := s.saveVersions(tx, now, "project_id IN ("+projectSubtree+")", project.ID)
+		if err != nil {
+			return err
+		}
+		var trashed []Todo
+		if !keepTodos {
+			query := "SELECT " + todoColumns + " FROM todos WHERE deleted_at IS NULL AND project_id IN (" + projectSubtree + ")"
+			trashed, err = s.queryTodos(tx, query, project.ID)
+			if err != nil {
+				return err
+			}
+		}
+		query := "UPDATE todos SET project_id = NULL, " + set + ", version = version + 1 WHERE project_id IN (" + projectSubtree + ")"
+		_, err = tx.Exec(s.rebind(query), project.ID, now)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(s.rebind("DELETE FROM projects WHERE id IN ("+projectSubtree+")"), project.ID)
+		if err != nil {
+			return err
+		}
+		for _, todo := range trashed {
+			err = s.addHistory(tx, todo.ID, project.UserID, HistoryTrashed, &todo)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,35 @@
+package models
+
+import "database/sql"
+
+func (s *SQLStore) PendingRecurrences() ([]Todo, error) {
+	query := "SELECT " + todoColumns + " FROM todos WHERE recurrence <> '' AND next_id IS NULL AND deleted_at IS NULL ORDER BY id"
+	return s.queryTodos(s.DB, query)
+}
+
+func (s *SQLStore) CreateNextOccurrence(current Todo, next *Todo) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		err := s.insertTodo(tx, next)
+		if err != nil {
+			return err
+		}
+		for _, tag := range next.Tags {
+			_, err = tx.Exec(s.rebind("INSERT INTO todo_tags(todo_id, tag_id) VALUES ($1, $2)"), next.ID, tag.ID)
+			if err != nil {
+				return err
+			}
+		}
+		result, err := tx.Exec(s.rebind("UPDATE todos SET next_id = $1 WHERE id = $2 AND next_id IS NULL"), next.ID, current.ID)
+		if err != nil {
+			return err
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrVersionConflict
+		}
+		return s.addHistory(tx, next.ID, 0, HistoryCreated, nil)
+	})
+}
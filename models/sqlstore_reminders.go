@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Todos and items are read with separate queries: SQLite loses the column
+// types of a UNION, and with them the conversion back to time.Time.
+const (
+	todoReminders = `
+	SELECT t.id, t.user_id, u.email, t.title, t.due_at, t.time_zone
+	FROM todos t JOIN users u ON u.id = t.user_id
+	WHERE t.due_at IS NOT NULL AND t.due_at <= $1 AND t.is_active AND t.deleted_at IS NULL
+		AND (t.reminded_due_at IS NULL OR t.reminded_due_at <> t.due_at)
+	`
+	itemReminders = `
+	SELECT t.id, i.id, t.user_id, u.email, t.title, i.item, i.due_at, t.time_zone
+	FROM todo_items i JOIN todos t ON t.id = i.todo_id JOIN users u ON u.id = t.user_id
+	WHERE i.due_at IS NOT NULL AND i.due_at <= $1 AND NOT i.checked AND t.is_active AND t.deleted_at IS NULL
+		AND (i.reminded_due_at IS NULL OR i.reminded_due_at <> i.due_at)
+	`
+)
+
+func (s *SQLStore) DueReminders(before time.Time) ([]Reminder, error) {
+	var reminders []Reminder
+	rows, err := s.DB.Query(s.rebind(todoReminders), before.UTC())
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var r Reminder
+		err = rows.Scan(&r.TodoID, &r.UserID, &r.Email, &r.Title, &r.DueAt, &r.TimeZone)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		reminders = append(reminders, r)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.DB.Query(s.rebind(itemReminders), before.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r Reminder
+		err = rows.Scan(&r.TodoID, &r.ItemID, &r.UserID, &r.Email, &r.Title, &r.Item, &r.DueAt, &r.TimeZone)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	sortReminders(reminders)
+	return reminders, nil
+}
+
+func (s *SQLStore) MarkReminded(r Reminder) error {
+	if r.ItemID != 0 {
+		_, err := s.DB.Exec(s.rebind("UPDATE todo_items SET reminded_due_at = $1 WHERE id = $2 AND todo_id = $3"), r.DueAt.UTC(), r.ItemID, r.TodoID)
+		return err
+	}
+	_, err := s.DB.Exec(s.rebind("UPDATE todos SET reminded_due_at = $1 WHERE id = $2"), r.DueAt.UTC(), r.TodoID)
+	return err
+}
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"project_todo/db"
+	"strings"
+)
+
+const headlineOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", HighlightAll=true"
+
+// Postgres ranks with ts_rank over the weighted search_vector columns
+// (titles A, items B); SQLite uses bm25 over the FTS5 tables with the same
+// 1 : 0.4 weighting. Both rank a todo by the sum over its title and items.
+const postgresSearch = `
+WITH q AS (SELECT to_tsquery('english', $2) AS query),
+hits AS (
+	SELECT t.id AS todo_id, ts_rank(t.search_vector, q.query) AS rank
+	FROM todos t, q
+	WHERE t.user_id = $1 AND t.workspace_id IS NULL AND t.deleted_at IS NULL AND t.search_vector @@ q.query
+	UNION ALL
+	SELECT i.todo_id, ts_rank(i.search_vector, q.query)
+	FROM todo_items i JOIN todos t ON t.id = i.todo_id, q
+	WHERE t.user_id = $1 AND t.workspace_id IS NULL AND t.deleted_at IS NULL AND i.search_vector @@ q.query
+),
+ranked AS (
+	SELECT todo_id, SUM(rank) AS rank FROM hits
+	GROUP BY todo_id ORDER BY rank DESC, todo_id LIMIT $3
+)
+SELECT t.id, t.title, t.is_active, t.created_at, t.updated_at, t.user_id, t.version, t.due_at, t.time_zone,
+	t.recurrence, COALESCE(t.series_id, 0), t.occurrence, COALESCE(t.next_id, 0), t.priority, t.rank, COALESCE(t.project_id, 0), COALESCE(t.workspace_id, 0), r.rank,
+	CASE WHEN t.search_vector @@ q.query THEN ts_headline('english', t.title, q.query, $4) ELSE t.title END
+FROM ranked r JOIN todos t ON t.id = r.todo_id, q
+ORDER BY r.rank DESC, t.id
+`
+
+const sqliteSearch = `
+WITH hits AS (
+	SELECT t.id AS todo_id, -bm25(todos_fts) AS rank
+	FROM todos_fts JOIN todos t ON t.id = todos_fts.rowid
+	WHERE todos_fts MATCH $2 AND t.user_id = $1 AND t.workspace_id IS NULL AND t.deleted_at IS NULL
+	UNION ALL
+	SELECT i.todo_id, -0.4 * bm25(todo_items_fts)
+	FROM todo_items_fts JOIN todo_items i ON i.id = todo_items_fts.rowid JOIN todos t ON t.id = i.todo_id
+	WHERE todo_items_fts MATCH $2 AND t.user_id = $1 AND t.workspace_id IS NULL AND t.deleted_at IS NULL
+),
+ranked AS (
+	SELECT todo_id, SUM(rank) AS rank FROM hits
+	GROUP BY todo_id ORDER BY rank DESC, todo_id LIMIT $3
+)
+SELECT t.id, t.title, t.is_active, t.created_at, t.updated_at, t.user_id, t.version, t.due_at, t.time_zone,
+	t.recurrence, COALESCE(t.series_id, 0), t.occurrence, COALESCE(t.next_id, 0), t.priority, t.rank, COALESCE(t.project_id, 0), COALESCE(t.workspace_id, 0), r.rank,
+	COALESCE((SELECT highlight(todos_fts, 0, $4, $5) FROM todos_fts WHERE todos_fts MATCH $2 AND rowid = t.id), t.title)
+FROM ranked r JOIN todos t ON t.id = r.todo_id
+ORDER BY r.rank DESC, t.id
+`
+
+// matchExpression turns the search words into a prefix query in the
+// driver's syntax. Only letters and digits survive searchTerms, so the
+// words can be embedded without further escaping.
+func (s *SQLStore) matchExpression(terms []string) string {
+	if s.Driver == db.SQLite {
+		quoted := make([]string, len(terms))
+		for i, term := range terms {
+			quoted[i] = `"` + term + `"*`
+		}
+		return strings.Join(quoted, " ")
+	}
+	return strings.Join(terms, ":* & ") + ":*"
+}
+
+func (s *SQLStore) SearchTodos(q SearchQuery) ([]SearchResult, error) {
+	q.normalize()
+	terms := searchTerms(q.Text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	match := s.matchExpression(terms)
+
+	query, args := postgresSearch, []any{q.UserID, match, q.Limit, headlineOptions}
+	if s.Driver == db.SQLite {
+		query, args = sqliteSearch, []any{q.UserID, match, q.Limit, highlightStart, highlightStop}
+	}
+	rows, err := s.DB.Query(s.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	var results []SearchResult
+	for rows.Next() {
+		var result SearchResult
+		todo := &result.Todo
+		err = rows.Scan(&todo.ID, &todo.Title, &todo.IsActive, &todo.CreatedAt, &todo.UpdatedAt, &todo.UserID, &todo.Version, &todo.DueAt, &todo.TimeZone,
+			&todo.Recurrence, &todo.SeriesID, &todo.Occurrence, &todo.NextID, &todo.Priority, &todo.Rank, &todo.ProjectID, &todo.WorkspaceID, &result.Rank, &result.TitleSnippet)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	todos := make([]Todo, len(results))
+	for i, result := range results {
+		todos[i] = result.Todo
+	}
+	err = s.loadRelated(s.DB, todos)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[int64]int, len(results))
+	for i := range results {
+		results[i].Todo = todos[i]
+		index[todos[i].ID] = i
+	}
+	return results, s.loadItemSnippets(match, index, results)
+}
+
+// loadItemSnippets adds the highlighted matching items to each result.
+func (s *SQLStore) loadItemSnippets(match string, index map[int64]int, results []SearchResult) error {
+	args := []any{match}
+	ids := make([]any, 0, len(results))
+	for _, result := range results {
+		ids = append(ids, result.Todo.ID)
+	}
+
+	var query string
+	if s.Driver == db.SQLite {
+		args = append(args, highlightStart, highlightStop)
+		query = `SELECT i.id, i.todo_id, highlight(todo_items_fts, 0, $2, $3)
+		FROM todo_items_fts JOIN todo_items i ON i.id = todo_items_fts.rowid
+		WHERE todo_items_fts MATCH $1 AND i.todo_id IN (` + placeholders(4, len(ids)) + `)
+		ORDER BY i.todo_id, i.rank, i.id`
+	} else {
+		args = append(args, headlineOptions)
+		query = `WITH q AS (SELECT to_tsquery('english', $1) AS query)
+		SELECT i.id, i.todo_id, ts_headline('english', i.item, q.query, $2)
+		FROM todo_items i, q
+		WHERE i.search_vector @@ q.query AND i.todo_id IN (` + placeholders(3, len(ids)) + `)
+		ORDER BY i.todo_id, i.rank, i.id`
+	}
+	args = append(args, ids...)
+
+	rows, err := s.DB.Query(s.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var snippet ItemSnippet
+		var todoId int64
+		err = rows.Scan(&snippet.ItemID, &todoId, &snippet.Snippet)
+		if err != nil {
+			return err
+		}
+		result := &results[index[todoId]]
+		result.ItemSnippets = append(result.ItemSnippets, snippet)
+	}
+	return rows.Err()
+}
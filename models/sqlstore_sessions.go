@@ -0,0 +1,121 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const sessionColumns = "s.id, s.user_id, s.family_id, s.user_agent, s.ip, s.created_at, s.last_used_at, s.revoked_at"
+
+func scanSession(row rowScanner) (*Session, error) {
+	var session Session
+	err := row.Scan(&session.ID, &session.UserID, &session.FamilyID, &session.UserAgent, &session.IP, &session.CreatedAt,
+		&session.LastUsedAt, &session.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *SQLStore) CreateSession(session *Session) error {
+	query := `
+	INSERT INTO sessions(user_id, family_id, user_agent, ip, created_at, last_used_at)
+	VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	err := s.DB.QueryRow(s.rebind(query), session.UserID, session.FamilyID, session.UserAgent, session.IP,
+		session.CreatedAt.UTC(), session.LastUsedAt.UTC()).Scan(&session.ID)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (s *SQLStore) GetSessionByFamily(familyId string) (*Session, error) {
+	query := "SELECT " + sessionColumns + " FROM sessions s WHERE s.family_id = $1"
+	session, err := scanSession(s.DB.QueryRow(s.rebind(query), familyId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return session, err
+}
+
+func (s *SQLStore) GetSessions(userId int64, since time.Time) ([]Session, error) {
+	query := `
+	SELECT ` + sessionColumns + `
+	FROM sessions s
+	WHERE s.user_id = $1 AND s.revoked_at IS NULL AND s.last_used_at > $2
+	ORDER BY s.last_used_at DESC, s.id DESC`
+	rows, err := s.DB.Query(s.rebind(query), userId, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var sessions []Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *SQLStore) RevokeSession(userId, sessionId int64, revokedAt time.Time) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		var familyId string
+		query := "SELECT family_id FROM sessions WHERE id = $1 AND user_id = $2"
+		err := tx.QueryRow(s.rebind(query), sessionId, userId).Scan(&familyId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return s.revokeFamily(tx, familyId, revokedAt)
+	})
+}
+
+func (s *SQLStore) RevokeSessions(userId int64, revokedAt time.Time) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		query := "UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL"
+		_, err := tx.Exec(s.rebind(query), revokedAt.UTC(), userId)
+		if err != nil {
+			return err
+		}
+		query = "UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL"
+		_, err = tx.Exec(s.rebind(query), revokedAt.UTC(), userId)
+		return err
+	})
+}
+
+func (s *SQLStore) RecordToken(token IssuedToken) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(s.rebind("DELETE FROM access_tokens WHERE expires_at <= $1"), token.IssuedAt.UTC())
+		if err != nil {
+			return err
+		}
+		query := `
+		INSERT INTO access_tokens(jti, session_id, user_agent, ip, issued_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+		_, err = tx.Exec(s.rebind(query), token.JTI, token.SessionID, token.UserAgent, token.IP, token.IssuedAt.UTC(),
+			token.ExpiresAt.UTC())
+		if err != nil {
+			return err
+		}
+		query = "UPDATE sessions SET user_agent = $1, ip = $2, last_used_at = $3 WHERE id = $4"
+		return s.expectRow(tx.Exec(s.rebind(query), token.UserAgent, token.IP, token.IssuedAt.UTC(), token.SessionID))
+	})
+}
+
+func (s *SQLStore) GetTokenSession(jti string) (*Session, error) {
+	query := `
+	SELECT ` + sessionColumns + `
+	FROM access_tokens t JOIN sessions s ON s.id = t.session_id
+	WHERE t.jti = $1`
+	session, err := scanSession(s.DB.QueryRow(s.rebind(query), jti))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return session, err
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+func (s *SQLStore) CreateShareLink(link *ShareLink) error {
+	query := `
+	INSERT INTO share_links(todo_id, token, created_by, expires_at, created_at)
+	VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	var expiresAt *time.Time
+	if link.ExpiresAt != nil {
+		utc := link.ExpiresAt.UTC()
+		expiresAt = &utc
+	}
+	return s.DB.QueryRow(s.rebind(query), link.TodoID, link.Token, link.CreatedBy, expiresAt, link.CreatedAt.UTC()).Scan(&link.ID)
+}
+
+func (s *SQLStore) GetShareLinks(todoId int64) ([]ShareLink, error) {
+	query := `
+	SELECT l.id, l.todo_id, l.token, l.created_by, l.expires_at, l.revoked_at, l.created_at,
+		(SELECT COUNT(*) FROM share_link_accesses a WHERE a.link_id = l.id)
+	FROM share_links l
+	WHERE l.todo_id = $1
+	ORDER BY l.created_at DESC, l.id DESC`
+	rows, err := s.DB.Query(s.rebind(query), todoId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var links []ShareLink
+	for rows.Next() {
+		var link ShareLink
+		err = rows.Scan(&link.ID, &link.TodoID, &link.Token, &link.CreatedBy, &link.ExpiresAt, &link.RevokedAt, &link.CreatedAt, &link.Accesses)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+func (s *SQLStore) GetShareLink(token string) (*ShareLink, error) {
+	var link ShareLink
+	query := "SELECT id, todo_id, token, created_by, expires_at, revoked_at, created_at FROM share_links WHERE token = $1"
+	err := s.DB.QueryRow(s.rebind(query), token).Scan(&link.ID, &link.TodoID, &link.Token, &link.CreatedBy, &link.ExpiresAt,
+		&link.RevokedAt, &link.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (s *SQLStore) RevokeShareLink(todoId, linkId int64, revokedAt time.Time) error {
+	query := "UPDATE share_links SET revoked_at = COALESCE(revoked_at, $1) WHERE todo_id = $2 AND id = $3"
+	return s.expectRow(s.DB.Exec(s.rebind(query), revokedAt.UTC(), todoId, linkId))
+}
+
+func (s *SQLStore) LogLinkAccess(access LinkAccess) error {
+	query := "INSERT INTO share_link_accesses(link_id, ip, user_agent, accessed_at) VALUES ($1, $2, $3, $4)"
+	_, err := s.DB.Exec(s.rebind(query), access.LinkID, access.IP, access.UserAgent, access.AccessedAt.UTC())
+	return err
+}
+
+func (s *SQLStore) GetLinkAccesses(linkId int64) ([]LinkAccess, error) {
+	query := "SELECT link_id, ip, user_agent, accessed_at FROM share_link_accesses WHERE link_id = $1 ORDER BY accessed_at DESC, id DESC"
+	rows, err := s.DB.Query(s.rebind(query), linkId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var accesses []LinkAccess
+	for rows.Next() {
+		var access LinkAccess
+		err = rows.Scan(&access.LinkID, &access.IP, &access.UserAgent, &access.AccessedAt)
+		if err != nil {
+			return nil, err
+		}
+		accesses = append(accesses, access)
+	}
+	return accesses, rows.Err()
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+)
+
+func (s *SQLStore) ShareTodo(share *Share) error {
+	query := `
+	INSERT INTO todo_shares(todo_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)
+	ON CONFLICT (todo_id, user_id) DO UPDATE SET role = excluded.role
+	RETURNING created_at`
+	return s.DB.QueryRow(s.rebind(query), share.TodoID, share.UserID, share.Role, share.CreatedAt.UTC()).Scan(&share.CreatedAt)
+}
+
+func (s *SQLStore) GetShares(todoId int64) ([]Share, error) {
+	query := `
+	SELECT sh.todo_id, sh.user_id, u.email, sh.role, sh.created_at
+	FROM todo_shares sh JOIN users u ON u.id = sh.user_id
+	WHERE sh.todo_id = $1
+	ORDER BY sh.created_at, sh.user_id`
+	rows, err := s.DB.Query(s.rebind(query), todoId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var shares []Share
+	for rows.Next() {
+		var share Share
+		err = rows.Scan(&share.TodoID, &share.UserID, &share.Email, &share.Role, &share.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+func (s *SQLStore) GetShareRole(todoId, userId int64) (string, error) {
+	var role string
+	err := s.DB.QueryRow(s.rebind("SELECT role FROM todo_shares WHERE todo_id = $1 AND user_id = $2"), todoId, userId).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+func (s *SQLStore) UnshareTodo(todoId, userId int64) error {
+	result, err := s.DB.Exec(s.rebind("DELETE FROM todo_shares WHERE todo_id = $1 AND user_id = $2"), todoId, userId)
+	if err != nil {
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err == nil && count == 0 {
+		err = ErrNotFound
+	}
+	return err
+}
+
+// markShared sets Shared and Role on the todos userId does not own.
+func (s *SQLStore) markShared(userId int64, todos []Todo) error {
+	index := make(map[int64]int)
+	var ids []any
+	for i, todo := range todos {
+		if todo.UserID != userId {
+			index[todo.ID] = i
+			ids = append(ids, todo.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	query := "SELECT todo_id, role FROM todo_shares WHERE user_id = $1 AND todo_id IN (" + placeholders(2, len(ids)) + ")"
+	rows, err := s.DB.Query(s.rebind(query), append([]any{userId}, ids...)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var todoId int64
+		var role string
+		err = rows.Scan(&todoId, &role)
+		if err != nil {
+			return err
+		}
+		todo := &todos[index[todoId]]
+		todo.Shared, todo.Role = true, role
+	}
+	return rows.Err()
+}
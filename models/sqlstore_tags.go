@@ -0,0 +1,214 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const tagColumns = "id, user_id, name, color"
+
+func scanTag(row rowScanner) (*Tag, error) {
+	var tag Tag
+	err := row.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.Color)
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// loadRelated fills in the items and tags of each of the given todos.
+func (s *SQLStore) loadRelated(q querier, todos []Todo) error {
+	err := s.loadItems(q, todos)
+	if err != nil {
+		return err
+	}
+	return s.loadTags(q, todos)
+}
+
+// loadTags fills in every tag attached to the todos, whoever owns it: a
+// workspace todo carries the tags its members attached, and everyone who
+// can view the todo sees them, like its title.
+func (s *SQLStore) loadTags(q querier, todos []Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	ids := make([]any, len(todos))
+	index := make(map[int64]int, len(todos))
+	for i, todo := range todos {
+		ids[i] = todo.ID
+		index[todo.ID] = i
+	}
+
+	query := `SELECT tt.todo_id, g.id, g.user_id, g.name, g.color
+	FROM todo_tags tt JOIN tags g ON g.id = tt.tag_id
+	WHERE tt.todo_id IN (` + placeholders(1, len(ids)) + `)
+	ORDER BY tt.todo_id, lower(g.name), g.id`
+	rows, err := q.Query(s.rebind(query), ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var todoId int64
+		var tag Tag
+		err = rows.Scan(&todoId, &tag.ID, &tag.UserID, &tag.Name, &tag.Color)
+		if err != nil {
+			return err
+		}
+		todo := &todos[index[todoId]]
+		todo.Tags = append(todo.Tags, tag)
+	}
+	return rows.Err()
+}
+
+// touchTagged bumps the version of every todo carrying the tag and records
+// the change in their histories as made by actorId.
+func (s *SQLStore) touchTagged(tx *sql.Tx, tagId, actorId int64) error {
+	tagged, err := s.queryTodos(tx, "SELECT "+todoColumns+" FROM todos WHERE id IN (SELECT todo_id FROM todo_tags WHERE tag_id = $1)", tagId)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for _, todo := range tagged {
+		err = s.insertVersion(tx, todo, now)
+		if err != nil {
+			return err
+		}
+	}
+	query := "UPDATE todos SET updated_at = $1, version = version + 1 WHERE id IN (SELECT todo_id FROM todo_tags WHERE tag_id = $2)"
+	_, err = tx.Exec(s.rebind(query), now, tagId)
+	if err != nil {
+		return err
+	}
+	for _, todo := range tagged {
+		err = s.addHistory(tx, todo.ID, actorId, HistoryUpdated, &todo)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) CreateTag(tag *Tag) error {
+	query := "INSERT INTO tags(user_id, name, color, created_at) VALUES ($1, $2, $3, $4) RETURNING id"
+	err := s.DB.QueryRow(s.rebind(query), tag.UserID, tag.Name, tag.Color, time.Now().UTC()).Scan(&tag.ID)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (s *SQLStore) GetTags(userId int64) ([]Tag, error) {
+	rows, err := s.DB.Query(s.rebind("SELECT "+tagColumns+" FROM tags WHERE user_id = $1 ORDER BY lower(name), id"), userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tags []Tag
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, *tag)
+	}
+	return tags, rows.Err()
+}
+
+func (s *SQLStore) GetTag(id int64) (*Tag, error) {
+	tag, err := scanTag(s.DB.QueryRow(s.rebind("SELECT "+tagColumns+" FROM tags WHERE id = $1"), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return tag, err
+}
+
+func (s *SQLStore) UpdateTag(tag Tag) error {
+	err := s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(s.rebind("UPDATE tags SET name = $1, color = $2 WHERE id = $3"), tag.Name, tag.Color, tag.ID)
+		if err != nil {
+			return err
+		}
+		return s.touchTagged(tx, tag.ID, tag.UserID)
+	})
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (s *SQLStore) DeleteTag(tag Tag) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		err := s.touchTagged(tx, tag.ID, tag.UserID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(s.rebind("DELETE FROM todo_tags WHERE tag_id = $1"), tag.ID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(s.rebind("DELETE FROM tags WHERE id = $1"), tag.ID)
+		return err
+	})
+}
+
+func (s *SQLStore) MergeTags(source, target Tag) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		err := s.touchTagged(tx, source.ID, source.UserID)
+		if err != nil {
+			return err
+		}
+		query := `INSERT INTO todo_tags(todo_id, tag_id)
+		SELECT todo_id, $1 FROM todo_tags WHERE tag_id = $2
+		AND todo_id NOT IN (SELECT todo_id FROM todo_tags WHERE tag_id = $1)`
+		_, err = tx.Exec(s.rebind(query), target.ID, source.ID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(s.rebind("DELETE FROM todo_tags WHERE tag_id = $1"), source.ID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(s.rebind("DELETE FROM tags WHERE id = $1"), source.ID)
+		return err
+	})
+}
+
+func (s *SQLStore) AttachTags(todoId int64, tagIds []int64, actorId int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		var added int64
+		for _, tagId := range tagIds {
+			result, err := tx.Exec(s.rebind("INSERT INTO todo_tags(todo_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"), todoId, tagId)
+			if err != nil {
+				return err
+			}
+			count, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			added += count
+		}
+		if added == 0 {
+			return nil
+		}
+		return s.touchTodo(tx, todoId, actorId)
+	})
+}
+
+func (s *SQLStore) DetachTag(todoId, tagId, actorId int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(s.rebind("DELETE FROM todo_tags WHERE todo_id = $1 AND tag_id = $2"), todoId, tagId)
+		if err != nil {
+			return err
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+		return s.touchTodo(tx, todoId, actorId)
+	})
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"path/filepath"
+	"project_todo/db"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSQLiteStore(t *testing.T) *SQLStore {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "todo.db"))
+	conn := db.InitDB(db.SQLite)
+	t.Cleanup(func() { conn.Close() })
+	return NewSQLStore(conn, db.SQLite)
+}
+
+func TestSQLiteStoreTodos(t *testing.T) {
+	testTodoStore(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreUsers(t *testing.T) {
+	testUserStore(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreItems(t *testing.T) {
+	testItemStore(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreTodoQueries(t *testing.T) {
+	testTodoQueries(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreSearchTodos(t *testing.T) {
+	testSearchTodos(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreTodoVersions(t *testing.T) {
+	testTodoVersions(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreTrash(t *testing.T) {
+	testTrash(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreDueDates(t *testing.T) {
+	testDueDates(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreReminders(t *testing.T) {
+	testReminders(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreRecurrence(t *testing.T) {
+	testRecurrence(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreTags(t *testing.T) {
+	testTags(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreRanking(t *testing.T) {
+	testRanking(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreProjects(t *testing.T) {
+	testProjects(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreShares(t *testing.T) {
+	testShares(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreShareLinks(t *testing.T) {
+	testShareLinks(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreHistory(t *testing.T) {
+	testHistory(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreHistoryIsAppendOnly(t *testing.T) {
+	store := newSQLiteStore(t)
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+	assert.NoError(t, store.CreateTodo(&Todo{Title: "Groceries", UserID: user.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()}))
+	_, err := store.DB.Exec("UPDATE todo_history SET actor_id = 2")
+	assert.Error(t, err)
+	_, err = store.DB.Exec("DELETE FROM todo_history")
+	assert.Error(t, err)
+}
+
+func TestSQLiteStoreVersions(t *testing.T) {
+	testVersions(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreRefreshTokens(t *testing.T) {
+	testRefreshTokens(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreSessions(t *testing.T) {
+	testSessions(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreEmailVerification(t *testing.T) {
+	testEmailVerification(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStorePasswordResets(t *testing.T) {
+	testPasswordResets(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreTOTP(t *testing.T) {
+	testTOTP(t, newSQLiteStore(t))
+}
+
+func TestSQLiteStoreWorkspaces(t *testing.T) {
+	testWorkspaces(t, newSQLiteStore(t))
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+func (s *SQLStore) CreateRefreshToken(token *RefreshToken) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(s.rebind("DELETE FROM refresh_tokens WHERE expires_at <= $1"), token.CreatedAt.UTC())
+		if err != nil {
+			return err
+		}
+		query := `
+		INSERT INTO refresh_tokens(user_id, family_id, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`
+		err = tx.QueryRow(s.rebind(query), token.UserID, token.FamilyID, token.TokenHash, token.CreatedAt.UTC(),
+			token.ExpiresAt.UTC()).Scan(&token.ID)
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return err
+	})
+}
+
+func (s *SQLStore) GetRefreshToken(hash string) (*RefreshToken, error) {
+	var token RefreshToken
+	query := `
+	SELECT id, user_id, family_id, token_hash, created_at, expires_at, used_at, revoked_at
+	FROM refresh_tokens WHERE token_hash = $1`
+	err := s.DB.QueryRow(s.rebind(query), hash).Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &token.CreatedAt,
+		&token.ExpiresAt, &token.UsedAt, &token.RevokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (s *SQLStore) UseRefreshToken(id int64, usedAt time.Time) error {
+	query := "UPDATE refresh_tokens SET used_at = $1 WHERE id = $2 AND used_at IS NULL AND revoked_at IS NULL"
+	return s.expectRow(s.DB.Exec(s.rebind(query), usedAt.UTC(), id))
+}
+
+func (s *SQLStore) RevokeRefreshFamily(familyId string, revokedAt time.Time) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		return s.revokeFamily(tx, familyId, revokedAt)
+	})
+}
+
+func (s *SQLStore) revokeFamily(tx *sql.Tx, familyId string, revokedAt time.Time) error {
+	query := "UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL"
+	_, err := tx.Exec(s.rebind(query), revokedAt.UTC(), familyId)
+	if err != nil {
+		return err
+	}
+	query = "UPDATE sessions SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL"
+	_, err = tx.Exec(s.rebind(query), revokedAt.UTC(), familyId)
+	return err
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+func (s *SQLStore) CreateTOTP(factor *TOTP) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		query := "DELETE FROM totp_factors WHERE user_id = $1 AND confirmed_at IS NULL"
+		_, err := tx.Exec(s.rebind(query), factor.UserID)
+		if err != nil {
+			return err
+		}
+		query = "INSERT INTO totp_factors(user_id, secret, created_at) VALUES ($1, $2, $3)"
+		_, err = tx.Exec(s.rebind(query), factor.UserID, factor.Secret, factor.CreatedAt.UTC())
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return err
+	})
+}
+
+func (s *SQLStore) GetTOTP(userId int64) (*TOTP, error) {
+	var f TOTP
+	query := `
+	SELECT user_id, secret, created_at, confirmed_at, last_used_step, failed_attempts, failed_at
+	FROM totp_factors WHERE user_id = $1`
+	err := s.DB.QueryRow(s.rebind(query), userId).Scan(&f.UserID, &f.Secret, &f.CreatedAt, &f.ConfirmedAt, &f.LastUsedStep,
+		&f.FailedAttempts, &f.FailedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (s *SQLStore) ConfirmTOTP(userId, step int64, confirmedAt time.Time, codeHashes []string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		query := `
+		UPDATE totp_factors SET confirmed_at = $1, last_used_step = $2, failed_attempts = 0, failed_at = NULL
+		WHERE user_id = $3 AND confirmed_at IS NULL`
+		err := s.expectRow(tx.Exec(s.rebind(query), confirmedAt.UTC(), step, userId))
+		if err != nil {
+			return err
+		}
+		return s.replaceRecoveryCodes(tx, userId, codeHashes)
+	})
+}
+
+func (s *SQLStore) replaceRecoveryCodes(tx *sql.Tx, userId int64, codeHashes []string) error {
+	_, err := tx.Exec(s.rebind("DELETE FROM recovery_codes WHERE user_id = $1"), userId)
+	if err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		_, err = tx.Exec(s.rebind("INSERT INTO recovery_codes(user_id, code_hash) VALUES ($1, $2)"), userId, hash)
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notLocked matches factors that are not locked out, given the maximum
+// attempts and the start of the lockout window as the next two arguments.
+func notLocked(n int) string {
+	return fmt.Sprintf("(failed_attempts < $%d OR failed_at IS NULL OR failed_at <= $%d)", n, n+1)
+}
+
+// lockedOrNotFound tells why a TOTP update matched no row: ErrLocked if
+// the user's factor is locked out at now, ErrNotFound otherwise.
+func (s *SQLStore) lockedOrNotFound(userId int64, lockout TOTPLockout, now time.Time) error {
+	factor, err := s.GetTOTP(userId)
+	if err != nil {
+		return err
+	}
+	if factor.Locked(lockout, now) {
+		return ErrLocked
+	}
+	return ErrNotFound
+}
+
+func (s *SQLStore) UseTOTPStep(userId, step int64, lockout TOTPLockout, now time.Time) error {
+	query := `
+	UPDATE totp_factors SET last_used_step = $1, failed_attempts = 0, failed_at = NULL
+	WHERE user_id = $2 AND confirmed_at IS NOT NULL AND last_used_step < $1 AND ` + notLocked(3)
+	err := s.expectRow(s.DB.Exec(s.rebind(query), step, userId, lockout.MaxAttempts, now.Add(-lockout.Duration).UTC()))
+	if errors.Is(err, ErrNotFound) {
+		return s.lockedOrNotFound(userId, lockout, now)
+	}
+	return err
+}
+
+func (s *SQLStore) FailTOTP(userId int64, lockout TOTPLockout, failedAt time.Time) error {
+	query := `
+	UPDATE totp_factors SET failed_attempts = failed_attempts + 1, failed_at = $1
+	WHERE user_id = $2 AND ` + notLocked(3)
+	err := s.expectRow(s.DB.Exec(s.rebind(query), failedAt.UTC(), userId, lockout.MaxAttempts, failedAt.Add(-lockout.Duration).UTC()))
+	if errors.Is(err, ErrNotFound) {
+		return s.lockedOrNotFound(userId, lockout, failedAt)
+	}
+	return err
+}
+
+func (s *SQLStore) UseRecoveryCode(userId int64, hash string, usedAt time.Time) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		query := "UPDATE recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL"
+		err := s.expectRow(tx.Exec(s.rebind(query), usedAt.UTC(), userId, hash))
+		if err != nil {
+			return err
+		}
+		query = "UPDATE totp_factors SET failed_attempts = 0, failed_at = NULL WHERE user_id = $1"
+		_, err = tx.Exec(s.rebind(query), userId)
+		return err
+	})
+}
+
+func (s *SQLStore) CountRecoveryCodes(userId int64) (int, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL"
+	err := s.DB.QueryRow(s.rebind(query), userId).Scan(&count)
+	return count, err
+}
+
+func (s *SQLStore) DeleteTOTP(userId int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		err := s.expectRow(tx.Exec(s.rebind("DELETE FROM totp_factors WHERE user_id = $1"), userId))
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(s.rebind("DELETE FROM recovery_codes WHERE user_id = $1"), userId)
+		return err
+	})
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+func (s *SQLStore) TrashTodo(t Todo, actorId int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		before, err := s.getTodo(tx, t.ID)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		query := "UPDATE todos SET deleted_at = $1, version = version + 1 WHERE id = $2 AND deleted_at IS NULL AND ($3 = 0 OR version = $3)"
+		result, err := tx.Exec(s.rebind(query), now, t.ID, t.Version)
+		if err != nil {
+			return err
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return checkVersion(result, t.Version)
+		}
+		err = s.insertVersion(tx, *before, now)
+		if err != nil {
+			return err
+		}
+		return s.addHistory(tx, t.ID, actorId, HistoryTrashed, before)
+	})
+}
+
+func (s *SQLStore) RestoreTodo(t Todo, actorId int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		before, err := s.getTodo(tx, t.ID)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		query := "UPDATE todos SET deleted_at = NULL, updated_at = $1, version = version + 1 WHERE id = $2 AND deleted_at IS NOT NULL"
+		result, err := tx.Exec(s.rebind(query), now, t.ID)
+		if err != nil {
+			return err
+		}
+		count, err := result.RowsAffected()
+		if err != nil || count == 0 {
+			return err
+		}
+		err = s.insertVersion(tx, *before, now)
+		if err != nil {
+			return err
+		}
+		return s.addHistory(tx, t.ID, actorId, HistoryRestored, before)
+	})
+}
+
+func (s *SQLStore) GetTrash(userId int64) ([]Todo, error) {
+	return s.trash("user_id = $1 AND workspace_id IS NULL", userId)
+}
+
+func (s *SQLStore) GetWorkspaceTrash(workspaceId int64) ([]Todo, error) {
+	return s.trash("workspace_id = $1", workspaceId)
+}
+
+// trash lists the trashed todos matching where, most recently trashed
+// first.
+func (s *SQLStore) trash(where string, arg any) ([]Todo, error) {
+	query := "SELECT " + todoColumns + " FROM todos WHERE " + where + " AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC"
+	return s.queryTodos(s.DB, query, arg)
+}
+
+func (s *SQLStore) EmptyTrash(userId int64) (int64, error) {
+	return s.purge(userId, "user_id = $1 AND workspace_id IS NULL AND deleted_at IS NOT NULL", userId)
+}
+
+// PurgeTrash records the deletes as made by actor 0, the server itself.
+func (s *SQLStore) PurgeTrash(deletedBefore time.Time) (int64, error) {
+	return s.purge(0, "deleted_at < $1", deletedBefore.UTC())
+}
+
+// purge permanently deletes the todos matching where, items first, on
+// behalf of actorId.
+func (s *SQLStore) purge(actorId int64, where string, arg any) (int64, error) {
+	var count int64
+	err := s.withTx(func(tx *sql.Tx) error {
+		purged, err := s.queryTodos(tx, "SELECT "+todoColumns+" FROM todos WHERE "+where, arg)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(s.rebind("DELETE FROM todo_items WHERE todo_id IN (SELECT id FROM todos WHERE "+where+")"), arg)
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(s.rebind("DELETE FROM todos WHERE "+where), arg)
+		if err != nil {
+			return err
+		}
+		count, err = result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		for _, todo := range purged {
+			err = s.insertHistory(tx, todo.ID, actorId, HistoryDeleted, DiffTodos(&todo, nil))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return count, err
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// saveVersions snapshots every todo matching where as it is before their
+// versions are bumped.
+func (s *SQLStore) saveVersions(tx *sql.Tx, replacedAt time.Time, where string, args ...any) error {
+	todos, err := s.queryTodos(tx, "SELECT "+todoColumns+" FROM todos WHERE "+where, args...)
+	if err != nil {
+		return err
+	}
+	for _, todo := range todos {
+		err = s.insertVersion(tx, todo, replacedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertVersion snapshots todo as given, for callers that loaded it before
+// bumping its version.
+func (s *SQLStore) insertVersion(tx *sql.Tx, todo Todo, replacedAt time.Time) error {
+	list, err := json.Marshal(todo.List)
+	if err != nil {
+		return err
+	}
+	query := `
+	INSERT INTO todo_versions(todo_id, version, title, list, created_at) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (todo_id, version) DO NOTHING`
+	_, err = tx.Exec(s.rebind(query), todo.ID, todo.Version, todo.Title, string(list), replacedAt.UTC())
+	return err
+}
+
+func scanVersion(row rowScanner) (*TodoVersion, error) {
+	var version TodoVersion
+	var list string
+	err := row.Scan(&version.TodoID, &version.Version, &version.Title, &list, &version.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal([]byte(list), &version.List)
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+func (s *SQLStore) GetVersions(todoId int64) ([]TodoVersion, error) {
+	query := "SELECT todo_id, version, title, list, created_at FROM todo_versions WHERE todo_id = $1 ORDER BY version DESC"
+	rows, err := s.DB.Query(s.rebind(query), todoId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var versions []TodoVersion
+	for rows.Next() {
+		version, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, *version)
+	}
+	return versions, rows.Err()
+}
+
+func (s *SQLStore) GetVersion(todoId, version int64) (*TodoVersion, error) {
+	query := "SELECT todo_id, version, title, list, created_at FROM todo_versions WHERE todo_id = $1 AND version = $2"
+	found, err := scanVersion(s.DB.QueryRow(s.rebind(query), todoId, version))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return found, err
+}
+
+func (s *SQLStore) CreateUndo(undo Undo) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(s.rebind("DELETE FROM undo_tokens WHERE expires_at <= $1"), undo.CreatedAt.UTC())
+		if err != nil {
+			return err
+		}
+		query := `
+		INSERT INTO undo_tokens(token, todo_id, user_id, action, version, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+		_, err = tx.Exec(s.rebind(query), undo.Token, undo.TodoID, undo.UserID, undo.Action, undo.Version, undo.CreatedAt.UTC(),
+			undo.ExpiresAt.UTC())
+		return err
+	})
+}
+
+func (s *SQLStore) TakeUndo(token string, userId int64) (*Undo, error) {
+	var undo Undo
+	query := `
+	DELETE FROM undo_tokens WHERE token = $1 AND user_id = $2
+	RETURNING token, todo_id, user_id, action, version, created_at, expires_at`
+	err := s.DB.QueryRow(s.rebind(query), token, userId).Scan(&undo.Token, &undo.TodoID, &undo.UserID, &undo.Action, &undo.Version,
+		&undo.CreatedAt, &undo.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &undo, nil
+}
@@ -0,0 +1,230 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const inviteColumns = "id, workspace_id, email, role, token, invited_by, created_at, accepted_at"
+
+func scanInvite(row rowScanner) (*Invite, error) {
+	var invite Invite
+	err := row.Scan(&invite.ID, &invite.WorkspaceID, &invite.Email, &invite.Role, &invite.Token, &invite.InvitedBy, &invite.CreatedAt, &invite.AcceptedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (s *SQLStore) CreateWorkspace(workspace *Workspace, ownerId int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		query := "INSERT INTO workspaces(name, created_at, updated_at) VALUES ($1, $2, $3) RETURNING id"
+		err := tx.QueryRow(s.rebind(query), workspace.Name, workspace.CreatedAt.UTC(), workspace.UpdatedAt.UTC()).Scan(&workspace.ID)
+		if err != nil {
+			return err
+		}
+		query = "INSERT INTO workspace_members(workspace_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)"
+		_, err = tx.Exec(s.rebind(query), workspace.ID, ownerId, WorkspaceOwner, workspace.CreatedAt.UTC())
+		workspace.Role = WorkspaceOwner
+		return err
+	})
+}
+
+func (s *SQLStore) GetWorkspaces(userId int64) ([]Workspace, error) {
+	query := `
+	SELECT w.id, w.name, m.role, w.created_at, w.updated_at
+	FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.id
+	WHERE m.user_id = $1
+	ORDER BY lower(w.name), w.id`
+	rows, err := s.DB.Query(s.rebind(query), userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var workspaces []Workspace
+	for rows.Next() {
+		var workspace Workspace
+		err = rows.Scan(&workspace.ID, &workspace.Name, &workspace.Role, &workspace.CreatedAt, &workspace.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, workspace)
+	}
+	return workspaces, rows.Err()
+}
+
+func (s *SQLStore) GetWorkspace(id int64) (*Workspace, error) {
+	var workspace Workspace
+	query := "SELECT id, name, created_at, updated_at FROM workspaces WHERE id = $1"
+	err := s.DB.QueryRow(s.rebind(query), id).Scan(&workspace.ID, &workspace.Name, &workspace.CreatedAt, &workspace.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+func (s *SQLStore) UpdateWorkspace(workspace Workspace) error {
+	_, err := s.DB.Exec(s.rebind("UPDATE workspaces SET name = $1, updated_at = $2 WHERE id = $3"), workspace.Name, workspace.UpdatedAt.UTC(), workspace.ID)
+	return err
+}
+
+func (s *SQLStore) DeleteWorkspace(id, actorId int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		deleted, err := s.queryTodos(tx, "SELECT "+todoColumns+" FROM todos WHERE workspace_id = $1", id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(s.rebind("DELETE FROM todos WHERE workspace_id = $1"), id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(s.rebind("DELETE FROM workspaces WHERE id = $1"), id)
+		if err != nil {
+			return err
+		}
+		for _, todo := range deleted {
+			err = s.insertHistory(tx, todo.ID, actorId, HistoryDeleted, DiffTodos(&todo, nil))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) GetMembers(workspaceId int64) ([]Member, error) {
+	query := `
+	SELECT m.workspace_id, m.user_id, u.email, u.first_name, u.last_name, m.role, m.created_at
+	FROM workspace_members m JOIN users u ON u.id = m.user_id
+	WHERE m.workspace_id = $1
+	ORDER BY m.created_at, m.user_id`
+	rows, err := s.DB.Query(s.rebind(query), workspaceId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var members []Member
+	for rows.Next() {
+		var member Member
+		err = rows.Scan(&member.WorkspaceID, &member.UserID, &member.Email, &member.FirstName, &member.LastName, &member.Role, &member.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func (s *SQLStore) GetMemberRole(workspaceId, userId int64) (string, error) {
+	var role string
+	query := "SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2"
+	err := s.DB.QueryRow(s.rebind(query), workspaceId, userId).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+func (s *SQLStore) SetMemberRole(workspaceId, userId int64, role string) error {
+	query := "UPDATE workspace_members SET role = $1 WHERE workspace_id = $2 AND user_id = $3"
+	return s.expectRow(s.DB.Exec(s.rebind(query), role, workspaceId, userId))
+}
+
+func (s *SQLStore) RemoveMember(workspaceId, userId int64) error {
+	query := "DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2"
+	return s.expectRow(s.DB.Exec(s.rebind(query), workspaceId, userId))
+}
+
+// expectRow turns an Exec that touched no rows into ErrNotFound.
+func (s *SQLStore) expectRow(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err == nil && count == 0 {
+		err = ErrNotFound
+	}
+	return err
+}
+
+func (s *SQLStore) CreateInvite(invite *Invite) error {
+	query := `
+	INSERT INTO workspace_invites(workspace_id, email, role, token, invited_by, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	return s.DB.QueryRow(s.rebind(query), invite.WorkspaceID, invite.Email, invite.Role, invite.Token, invite.InvitedBy,
+		invite.CreatedAt.UTC()).Scan(&invite.ID)
+}
+
+func (s *SQLStore) GetInvites(workspaceId int64) ([]Invite, error) {
+	query := "SELECT " + inviteColumns + " FROM workspace_invites WHERE workspace_id = $1 AND accepted_at IS NULL ORDER BY created_at, id"
+	rows, err := s.DB.Query(s.rebind(query), workspaceId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var invites []Invite
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, *invite)
+	}
+	return invites, rows.Err()
+}
+
+func (s *SQLStore) GetInvitesForEmail(email string) ([]Invite, error) {
+	query := `
+	SELECT i.id, i.workspace_id, i.email, i.role, i.token, i.invited_by, i.created_at, i.accepted_at, w.name
+	FROM workspace_invites i JOIN workspaces w ON w.id = i.workspace_id
+	WHERE lower(i.email) = lower($1) AND i.accepted_at IS NULL
+	ORDER BY i.created_at, i.id`
+	rows, err := s.DB.Query(s.rebind(query), email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var invites []Invite
+	for rows.Next() {
+		var invite Invite
+		err = rows.Scan(&invite.ID, &invite.WorkspaceID, &invite.Email, &invite.Role, &invite.Token, &invite.InvitedBy, &invite.CreatedAt,
+			&invite.AcceptedAt, &invite.WorkspaceName)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+	return invites, rows.Err()
+}
+
+func (s *SQLStore) GetInvite(token string) (*Invite, error) {
+	invite, err := scanInvite(s.DB.QueryRow(s.rebind("SELECT "+inviteColumns+" FROM workspace_invites WHERE token = $1"), token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return invite, err
+}
+
+func (s *SQLStore) AcceptInvite(invite Invite, userId int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		now := time.Now().UTC()
+		query := `
+		INSERT INTO workspace_members(workspace_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (workspace_id, user_id) DO NOTHING`
+		_, err := tx.Exec(s.rebind(query), invite.WorkspaceID, userId, invite.Role, now)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(s.rebind("UPDATE workspace_invites SET accepted_at = $1 WHERE id = $2"), now, invite.ID)
+		return err
+	})
+}
+
+func (s *SQLStore) DeleteInvite(workspaceId, inviteId int64) error {
+	query := "DELETE FROM workspace_invites WHERE workspace_id = $1 AND id = $2"
+	return s.expectRow(s.DB.Exec(s.rebind(query), workspaceId, inviteId))
+}
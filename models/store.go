@@ -0,0 +1,356 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound  = errors.New("record not found")
+	ErrDuplicate = errors.New("record already exists")
+	// ErrVersionConflict means the record changed since the version the
+	// caller based its write on.
+	ErrVersionConflict = errors.New("record has been modified")
+)
+
+// TodoStore persists todos. Ownership checks are left to the callers in routes.
+type TodoStore interface {
+	CreateTodo(todo *Todo) error
+	// GetAllTodos returns one page of the user's todos matching the query.
+	GetAllTodos(query TodoQuery) (TodoPage, error)
+	GetTodoById(id int64) (*Todo, error)
+	// DeleteTodo removes the todo for good; see TrashStore for soft deletes.
+	// UpdateTodo and DeleteTodo only apply when the stored version still
+	// equals todo.Version, otherwise they return ErrVersionConflict. A zero
+	// Version skips the check. Updates bump the version by one.
+	//
+	// Every version bump, here and in the other stores, first saves the
+	// todo's title and checklist as a TodoVersion in the same transaction,
+	// so the saved versions have no gaps.
+	//
+	// Every change to a todo made through a store, here and in the other
+	// stores, is added to its history in the same transaction, as made by
+	// actorId. Changes the server makes on its own, such as purging the
+	// trash or creating the next occurrence of a recurring todo, have
+	// actor 0. CreateTodo records the todo's UserID as the actor.
+	UpdateTodo(todo Todo, actorId int64) error
+	// RevertTodo is UpdateTodo recorded as a revert.
+	RevertTodo(todo Todo, actorId int64) error
+	DeleteTodo(todo Todo, actorId int64) error
+	// SearchTodos returns the user's best matching todos, best first.
+	SearchTodos(query SearchQuery) ([]SearchResult, error)
+	// MoveTodo gives one of the user's todos a new rank among its
+	// neighbours and bumps its version, recording the user as the actor.
+	// Moves that name no neighbour, or neighbours out of order, return
+	// ErrInvalidMove; a todo no longer at move.Version returns
+	// ErrVersionConflict.
+	MoveTodo(userId int64, move Move) error
+}
+
+// ItemStore manages single checklist items. Items are kept in rank order,
+// so a position change only rewrites the moved item, and every change bumps
+// the parent todo's updated_at and version. Like UpdateTodo, the writes only
+// apply when the todo's stored version still equals version, otherwise they
+// return ErrVersionConflict; a zero version skips the check.
+type ItemStore interface {
+	// CreateItem inserts at item.Position; a negative or too large
+	// position appends the item.
+	CreateItem(item *TodoItem, version, actorId int64) error
+	GetItem(todoId, itemId int64) (*TodoItem, error)
+	UpdateItem(item TodoItem, version, actorId int64) error
+	DeleteItem(item TodoItem, version, actorId int64) error
+	// MoveItem is MoveTodo for the items of one todo.
+	MoveItem(todoId int64, move Move, version, actorId int64) error
+}
+
+// TrashStore moves todos in and out of the trash. Trashed todos are left
+// out of GetAllTodos and SearchTodos but GetTodoById still returns them.
+type TrashStore interface {
+	// TrashTodo applies the same version check as DeleteTodo.
+	TrashTodo(todo Todo, actorId int64) error
+	RestoreTodo(todo Todo, actorId int64) error
+	// GetTrash returns the user's trashed todos, most recently trashed first.
+	GetTrash(userId int64) ([]Todo, error)
+	// EmptyTrash permanently deletes the user's trashed todos.
+	EmptyTrash(userId int64) (int64, error)
+	// PurgeTrash permanently deletes every todo trashed before the given time.
+	PurgeTrash(deletedBefore time.Time) (int64, error)
+}
+
+// UserStore persists users. Passwords reaching the store are already hashed.
+type UserStore interface {
+	CreateUser(user *User) error
+	GetUserByEmail(email string) (*User, error)
+	GetUserById(id int64) (*User, error)
+	// UpdatePassword stores an already hashed password.
+	UpdatePassword(userId int64, hashedPassword string) error
+	// VerifyEmail marks the user's email as verified and activates them,
+	// as long as the user still has that email. It returns ErrNotFound if
+	// there is no such user or they were already verified.
+	VerifyEmail(userId int64, email string, verifiedAt time.Time) error
+	// MarkVerificationSent records that a verification email was sent at
+	// sentAt, unless one was already sent after since; it returns false
+	// then, and for users who are verified already.
+	MarkVerificationSent(userId int64, sentAt, since time.Time) (bool, error)
+}
+
+// PasswordResetStore keeps the tokens handed out by POST /password/forgot.
+type PasswordResetStore interface {
+	// CreatePasswordReset saves the reset, replacing the user's earlier
+	// ones, and drops those that expired by its CreatedAt.
+	CreatePasswordReset(reset *PasswordReset) error
+	// UsePasswordReset marks the reset with the given token hash used and
+	// returns it, expired or not. It returns ErrNotFound if the reset is
+	// unknown or was used already, so a token works only once.
+	UsePasswordReset(hash string, usedAt time.Time) (*PasswordReset, error)
+}
+
+// TOTPStore keeps users' authenticator secrets and recovery codes.
+type TOTPStore interface {
+	// CreateTOTP saves an unconfirmed factor, replacing the user's earlier
+	// unconfirmed one. It returns ErrDuplicate if the user has a confirmed
+	// factor already.
+	CreateTOTP(factor *TOTP) error
+	GetTOTP(userId int64) (*TOTP, error)
+	// ConfirmTOTP confirms the user's factor with the code of step and
+	// replaces their recovery codes with codeHashes. It returns ErrNotFound
+	// if the user has no unconfirmed factor.
+	ConfirmTOTP(userId, step int64, confirmedAt time.Time, codeHashes []string) error
+	// UseTOTPStep records that the code of step was used and clears failed
+	// attempts. It returns ErrLocked if the factor is locked out at now, and
+	// ErrNotFound if the user has no confirmed factor or a code of step or
+	// later was used already.
+	UseTOTPStep(userId, step int64, lockout TOTPLockout, now time.Time) error
+	// FailTOTP counts a wrong code, unless the factor is locked out at
+	// failedAt already, which returns ErrLocked.
+	FailTOTP(userId int64, lockout TOTPLockout, failedAt time.Time) error
+	// UseRecoveryCode marks the user's recovery code with the given hash
+	// used and clears failed attempts. It returns ErrNotFound if the code is
+	// unknown or was used already.
+	UseRecoveryCode(userId int64, hash string, usedAt time.Time) error
+	// CountRecoveryCodes returns how many of the user's recovery codes are
+	// left.
+	CountRecoveryCodes(userId int64) (int, error)
+	// DeleteTOTP removes the user's factor and recovery codes.
+	DeleteTOTP(userId int64) error
+}
+
+// ReminderStore finds due todos and items that still need a reminder. A
+// reminder is pending once its due date has passed until MarkReminded is
+// called for that due date; moving the due date makes it pending again.
+// Trashed and inactive todos and checked items are skipped.
+type ReminderStore interface {
+	// DueReminders returns the pending reminders due at or before the
+	// given time, earliest first.
+	DueReminders(before time.Time) ([]Reminder, error)
+	MarkReminded(reminder Reminder) error
+}
+
+// RecurrenceStore moves recurring todos on to their next occurrence.
+type RecurrenceStore interface {
+	// PendingRecurrences returns the recurring todos, outside the trash,
+	// that have no next occurrence yet.
+	PendingRecurrences() ([]Todo, error)
+	// CreateNextOccurrence stores next and links current to it. It returns
+	// ErrVersionConflict if current already has a next occurrence.
+	CreateNextOccurrence(current Todo, next *Todo) error
+}
+
+// TagStore manages a user's tags and which todos carry them. Every change
+// that alters the tags a todo shows, including renaming, recolouring,
+// merging and deleting a tag, bumps the version of each affected todo in
+// the same transaction. Changes to a tag itself are recorded as made by
+// the tag's UserID.
+type TagStore interface {
+	// CreateTag returns ErrDuplicate if the user already has a tag with
+	// that name; so does UpdateTag.
+	CreateTag(tag *Tag) error
+	// GetTags returns the user's tags ordered by name.
+	GetTags(userId int64) ([]Tag, error)
+	GetTag(id int64) (*Tag, error)
+	UpdateTag(tag Tag) error
+	DeleteTag(tag Tag) error
+	// MergeTags moves every todo from source to target and deletes source.
+	MergeTags(source, target Tag) error
+	// AttachTags adds the tags to the todo; ones it already has are kept.
+	AttachTags(todoId int64, tagIds []int64, actorId int64) error
+	DetachTag(todoId, tagId, actorId int64) error
+}
+
+// ProjectStore manages a user's projects. Ownership and nesting rules are
+// checked by the callers in routes.
+type ProjectStore interface {
+	CreateProject(project *Project) error
+	// GetProjects returns all of the user's projects, archived ones
+	// included, ordered by name.
+	GetProjects(userId int64) ([]Project, error)
+	GetProject(id int64) (*Project, error)
+	// UpdateProject renames the project or moves it to another parent.
+	UpdateProject(project Project) error
+	// ArchiveProject archives, or unarchives, the project and every
+	// project under it. Todos in archived projects are left out of
+	// GetAllTodos unless the query includes them.
+	ArchiveProject(project Project, archived bool) error
+	// DeleteProject deletes the project and every project under it. Their
+	// todos move to the trash, or with keepTodos out of any project; either
+	// way their versions are bumped. The project's owner is recorded as
+	// having trashed them.
+	DeleteProject(project Project, keepTodos bool) error
+}
+
+// ShareStore manages who else can see or edit a todo. The policy for what
+// each role may do is Allows.
+type ShareStore interface {
+	// ShareTodo grants share.UserID share.Role on the todo, replacing the
+	// role they had. CreatedAt is kept from the first grant.
+	ShareTodo(share *Share) error
+	// GetShares returns the todo's shares with the users' emails, oldest
+	// first.
+	GetShares(todoId int64) ([]Share, error)
+	// GetShareRole returns the user's role on the todo, or "" when it is
+	// not shared with them.
+	GetShareRole(todoId, userId int64) (string, error)
+	// UnshareTodo returns ErrNotFound when the todo is not shared with the
+	// user.
+	UnshareTodo(todoId, userId int64) error
+}
+
+// ShareLinkStore manages public links to todos and the log of who used
+// them.
+type ShareLinkStore interface {
+	CreateShareLink(link *ShareLink) error
+	// GetShareLinks returns the todo's links, revoked ones included, newest
+	// first, with how often each was used.
+	GetShareLinks(todoId int64) ([]ShareLink, error)
+	// GetShareLink looks a link up by token, whether or not it is still
+	// usable.
+	GetShareLink(token string) (*ShareLink, error)
+	// RevokeShareLink returns ErrNotFound when the todo has no such link.
+	// Revoking a revoked link keeps the first RevokedAt.
+	RevokeShareLink(todoId, linkId int64, revokedAt time.Time) error
+	LogLinkAccess(access LinkAccess) error
+	// GetLinkAccesses returns the link's access log, newest first.
+	GetLinkAccesses(linkId int64) ([]LinkAccess, error)
+}
+
+// HistoryStore reads the append-only change history the other stores keep
+// of todos. Entries are never changed or deleted, not even with their todo.
+type HistoryStore interface {
+	// GetHistory returns the todo's history, newest first.
+	GetHistory(todoId int64) ([]HistoryEntry, error)
+}
+
+// VersionStore reads the snapshots saved on every version bump and stores
+// undo tokens.
+type VersionStore interface {
+	// GetVersions returns the todo's saved versions, newest first.
+	GetVersions(todoId int64) ([]TodoVersion, error)
+	GetVersion(todoId, version int64) (*TodoVersion, error)
+	// CreateUndo saves the token and drops the ones that expired by its
+	// CreatedAt.
+	CreateUndo(undo Undo) error
+	// TakeUndo deletes and returns userId's undo token, which may have
+	// expired. Tokens of other users give ErrNotFound.
+	TakeUndo(token string, userId int64) (*Undo, error)
+}
+
+// TokenStore keeps the refresh tokens handed out at login.
+type TokenStore interface {
+	// CreateRefreshToken saves the token and drops the ones that expired
+	// by its CreatedAt.
+	CreateRefreshToken(token *RefreshToken) error
+	// GetRefreshToken looks a token up by its hash, used and revoked ones
+	// included.
+	GetRefreshToken(hash string) (*RefreshToken, error)
+	// UseRefreshToken marks the token as swapped for a new one. It returns
+	// ErrNotFound if the token was used or revoked already, so only one of
+	// two concurrent refreshes with the same token succeeds.
+	UseRefreshToken(id int64, usedAt time.Time) error
+	// RevokeRefreshFamily revokes all tokens of the family and the session
+	// they belong to.
+	RevokeRefreshFamily(familyId string, revokedAt time.Time) error
+}
+
+// SessionStore keeps track of logins and the access tokens handed out in
+// them.
+type SessionStore interface {
+	CreateSession(session *Session) error
+	// GetSessionByFamily returns the session of a refresh token family.
+	GetSessionByFamily(familyId string) (*Session, error)
+	// GetSessions returns the user's sessions that are not revoked and
+	// were used since the given time, most recently used first.
+	GetSessions(userId int64, since time.Time) ([]Session, error)
+	// RevokeSession revokes one of the user's sessions with its refresh
+	// tokens. Other users' sessions give ErrNotFound.
+	RevokeSession(userId, sessionId int64, revokedAt time.Time) error
+	// RevokeSessions revokes all of the user's sessions.
+	RevokeSessions(userId int64, revokedAt time.Time) error
+	// RecordToken tracks an access token and marks its session used, with
+	// the token's user agent and IP. Tokens that expired by its IssuedAt
+	// are dropped.
+	RecordToken(token IssuedToken) error
+	// GetTokenSession returns the session an access token was handed out
+	// in, revoked or not, or ErrNotFound for tokens it doesn't track.
+	GetTokenSession(jti string) (*Session, error)
+}
+
+// WorkspaceStore manages workspaces, their members and invites. Role
+// rules are checked by the callers in routes and middlewares.
+type WorkspaceStore interface {
+	// CreateWorkspace creates the workspace with ownerId as its owner.
+	CreateWorkspace(workspace *Workspace, ownerId int64) error
+	// GetWorkspaces returns the workspaces userId is a member of, with
+	// their role, ordered by name.
+	GetWorkspaces(userId int64) ([]Workspace, error)
+	GetWorkspace(id int64) (*Workspace, error)
+	UpdateWorkspace(workspace Workspace) error
+	// DeleteWorkspace deletes the workspace with its todos, members and
+	// invites.
+	DeleteWorkspace(id, actorId int64) error
+	// GetWorkspaceTrash is GetTrash for the workspace's todos.
+	GetWorkspaceTrash(workspaceId int64) ([]Todo, error)
+
+	// GetMembers returns the members ordered by when they joined.
+	GetMembers(workspaceId int64) ([]Member, error)
+	// GetMemberRole returns "" when userId is not a member.
+	GetMemberRole(workspaceId, userId int64) (string, error)
+	// SetMemberRole and RemoveMember return ErrNotFound for non-members.
+	SetMemberRole(workspaceId, userId int64, role string) error
+	RemoveMember(workspaceId, userId int64) error
+
+	CreateInvite(invite *Invite) error
+	// GetInvites returns the workspace's pending invites, oldest first.
+	GetInvites(workspaceId int64) ([]Invite, error)
+	// GetInvitesForEmail returns the pending invites for email, ignoring
+	// case, with their workspace names.
+	GetInvitesForEmail(email string) ([]Invite, error)
+	// GetInvite looks a pending or accepted invite up by token.
+	GetInvite(token string) (*Invite, error)
+	// AcceptInvite marks the invite accepted and makes userId a member with
+	// the invite's role. Existing members keep the role they have.
+	AcceptInvite(invite Invite, userId int64) error
+	// DeleteInvite returns ErrNotFound when the workspace has no such
+	// invite.
+	DeleteInvite(workspaceId, inviteId int64) error
+}
+
+// Store is everything the server needs from a storage backend.
+type Store interface {
+	TodoStore
+	ItemStore
+	TrashStore
+	UserStore
+	PasswordResetStore
+	TOTPStore
+	ReminderStore
+	RecurrenceStore
+	TagStore
+	ProjectStore
+	ShareStore
+	ShareLinkStore
+	HistoryStore
+	VersionStore
+	TokenStore
+	SessionStore
+	WorkspaceStore
+}
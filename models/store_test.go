@@ -0,0 +1,1634 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The helpers below hold the behaviour every Store implementation must share.
+
+func testTodoStore(t *testing.T, store Store) {
+
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed", IsActive: true}
+	assert.NoError(t, store.CreateUser(user))
+	assert.Equal(t, int64(1), user.ID)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	todo := &Todo{
+		Title:     "Groceries",
+		List:      []TodoItem{{Item: "milk"}, {Item: "eggs", Checked: true}},
+		IsActive:  true,
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    user.ID,
+	}
+	assert.NoError(t, store.CreateTodo(todo))
+	assert.Equal(t, int64(1), todo.ID)
+
+	fetched, err := store.GetTodoById(todo.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, todo.Title, fetched.Title)
+	assert.Equal(t, todo.List, fetched.List)
+	assert.True(t, fetched.IsActive)
+	assert.True(t, now.Equal(fetched.CreatedAt))
+
+	fetched.Title = "Weekly groceries"
+	fetched.List = append(fetched.List, TodoItem{Item: "bread"})
+	assert.NoError(t, store.UpdateTodo(*fetched, user.ID))
+
+	page, err := store.GetAllTodos(TodoQuery{UserID: user.ID})
+	assert.NoError(t, err)
+	assert.Len(t, page.Todos, 1)
+	assert.Equal(t, "Weekly groceries", page.Todos[0].Title)
+	assert.Len(t, page.Todos[0].List, 3)
+	assert.Empty(t, page.NextCursor)
+
+	page, err = store.GetAllTodos(TodoQuery{UserID: user.ID + 1})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Todos)
+
+	fetched, err = store.GetTodoById(todo.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, store.DeleteTodo(*fetched, user.ID))
+	_, err = store.GetTodoById(todo.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func testTodoVersions(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+
+	now := time.Now()
+	todo := &Todo{Title: "Groceries", List: []TodoItem{{Item: "milk"}}, IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: user.ID}
+	assert.NoError(t, store.CreateTodo(todo))
+	assert.Equal(t, int64(1), todo.Version)
+
+	stale := *todo
+	todo.Title = "Weekly groceries"
+	assert.NoError(t, store.UpdateTodo(*todo, user.ID))
+	fetched, err := store.GetTodoById(todo.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), fetched.Version)
+
+	stale.Title = "Lost update"
+	assert.ErrorIs(t, store.UpdateTodo(stale, user.ID), ErrVersionConflict)
+	assert.ErrorIs(t, store.DeleteTodo(stale, user.ID), ErrVersionConflict)
+	fetched, err = store.GetTodoById(todo.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Weekly groceries", fetched.Title)
+	assert.Len(t, fetched.List, 1)
+
+	// Item changes count as changes to the todo.
+	assert.NoError(t, store.CreateItem(&TodoItem{TodoID: todo.ID, Item: "eggs", Position: -1}, 0, user.ID))
+	fetched, err = store.GetTodoById(todo.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), fetched.Version)
+
+	// A zero version skips the check.
+	unversioned := *fetched
+	unversioned.Version = 0
+	assert.NoError(t, store.UpdateTodo(unversioned, user.ID))
+	fetched, err = store.GetTodoById(todo.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), fetched.Version)
+
+	assert.NoError(t, store.DeleteTodo(*fetched, user.ID))
+	_, err = store.GetTodoById(todo.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func testUserStore(t *testing.T, store Store) {
+
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+
+	duplicate := &User{Email: "john@example.com", FirstName: "Jane", LastName: "Doe", Password: "hashed"}
+	assert.ErrorIs(t, store.CreateUser(duplicate), ErrDuplicate)
+
+	fetched, err := store.GetUserByEmail("john@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, fetched.ID)
+	assert.Equal(t, "hashed", fetched.Password)
+
+	_, err = store.GetUserByEmail("nobody@example.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	fetched, err = store.GetUserById(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "john@example.com", fetched.Email)
+	_, err = store.GetUserById(user.ID + 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func testEmailVerification(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+	now := time.Now().Truncate(time.Second)
+	verified := &User{Email: "jane@example.com", FirstName: "Jane", LastName: "Doe", Password: "hashed", EmailVerifiedAt: &now}
+	assert.NoError(t, store.CreateUser(verified))
+	fetched, err := store.GetUserById(verified.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, fetched.EmailVerifiedAt) {
+		assert.True(t, now.Equal(*fetched.EmailVerifiedAt))
+	}
+
+	// Verification mails are throttled: another one can only go out once
+	// the last was sent before since.
+	sent, err := store.MarkVerificationSent(user.ID, now, now)
+	assert.NoError(t, err)
+	assert.True(t, sent)
+	sent, err = store.MarkVerificationSent(user.ID, now.Add(time.Second), now.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, sent)
+	sent, err = store.MarkVerificationSent(user.ID, now.Add(time.Minute), now)
+	assert.NoError(t, err)
+	assert.True(t, sent)
+	fetched, err = store.GetUserByEmail("john@example.com")
+	assert.NoError(t, err)
+	assert.Nil(t, fetched.EmailVerifiedAt)
+	assert.False(t, fetched.IsActive)
+	if assert.NotNil(t, fetched.VerificationSentAt) {
+		assert.True(t, now.Add(time.Minute).Equal(*fetched.VerificationSentAt))
+	}
+	sent, err = store.MarkVerificationSent(verified.ID, now, now)
+	assert.NoError(t, err)
+	assert.False(t, sent)
+
+	// Verifying needs the email the user still has, and works once.
+	assert.ErrorIs(t, store.VerifyEmail(user.ID, "old@example.com", now), ErrNotFound)
+	assert.NoError(t, store.VerifyEmail(user.ID, "john@example.com", now.Add(time.Hour)))
+	assert.ErrorIs(t, store.VerifyEmail(user.ID, "john@example.com", now.Add(time.Hour)), ErrNotFound)
+	assert.ErrorIs(t, store.VerifyEmail(user.ID+100, "john@example.com", now), ErrNotFound)
+	fetched, err = store.GetUserById(user.ID)
+	assert.NoError(t, err)
+	assert.True(t, fetched.IsActive)
+	if assert.NotNil(t, fetched.EmailVerifiedAt) {
+		assert.True(t, now.Add(time.Hour).Equal(*fetched.EmailVerifiedAt))
+	}
+	sent, err = store.MarkVerificationSent(user.ID, now.Add(2*time.Hour), now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.False(t, sent)
+}
+
+func testItemStore(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+	todo := &Todo{Title: "Packing", List: []TodoItem{{Item: "tent"}, {Item: "stove"}}, UserID: user.ID}
+	assert.NoError(t, store.CreateTodo(todo))
+
+	items := func() []string {
+		fetched, err := store.GetTodoById(todo.ID)
+		assert.NoError(t, err)
+		var names []string
+		for i, item := range fetched.List {
+			assert.Equal(t, i, item.Position)
+			names = append(names, item.Item)
+		}
+		return names
+	}
+
+	appended := &TodoItem{TodoID: todo.ID, Item: "lamp", Position: -1}
+	assert.NoError(t, store.CreateItem(appended, 0, user.ID))
+	assert.Equal(t, 2, appended.Position)
+
+	first := &TodoItem{TodoID: todo.ID, Item: "map", Position: 0}
+	assert.NoError(t, store.CreateItem(first, 0, user.ID))
+	assert.Equal(t, []string{"map", "tent", "stove", "lamp"}, items())
+
+	fetched, err := store.GetItem(todo.ID, appended.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "lamp", fetched.Item)
+	_, err = store.GetItem(todo.ID+1, appended.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	fetched.Checked = true
+	fetched.Position = 1
+	assert.NoError(t, store.UpdateItem(*fetched, 0, user.ID))
+	assert.Equal(t, []string{"map", "lamp", "tent", "stove"}, items())
+
+	fetched.Position = 3
+	assert.NoError(t, store.UpdateItem(*fetched, 0, user.ID))
+	assert.Equal(t, []string{"map", "tent", "stove", "lamp"}, items())
+	fetched, err = store.GetItem(todo.ID, appended.ID)
+	assert.NoError(t, err)
+	assert.True(t, fetched.Checked)
+
+	assert.NoError(t, store.DeleteItem(*first, 0, user.ID))
+	assert.Equal(t, []string{"tent", "stove", "lamp"}, items())
+	assert.ErrorIs(t, store.DeleteItem(*first, 0, user.ID), ErrNotFound)
+
+	// Item writes check the todo's version like UpdateTodo does.
+	current, err := store.GetTodoById(todo.ID)
+	assert.NoError(t, err)
+	stale := current.Version - 1
+	assert.ErrorIs(t, store.CreateItem(&TodoItem{TodoID: todo.ID, Item: "rope", Position: -1}, stale, user.ID), ErrVersionConflict)
+	assert.ErrorIs(t, store.UpdateItem(*fetched, stale, user.ID), ErrVersionConflict)
+	assert.ErrorIs(t, store.DeleteItem(*fetched, stale, user.ID), ErrVersionConflict)
+	assert.ErrorIs(t, store.MoveItem(todo.ID, Move{ID: fetched.ID, BeforeID: current.List[0].ID}, stale, user.ID), ErrVersionConflict)
+	assert.Equal(t, []string{"tent", "stove", "lamp"}, items())
+	assert.NoError(t, store.MoveItem(todo.ID, Move{ID: fetched.ID, BeforeID: current.List[0].ID}, current.Version, user.ID))
+	assert.Equal(t, []string{"lamp", "tent", "stove"}, items())
+	assert.NoError(t, store.MoveItem(todo.ID, Move{ID: fetched.ID, AfterID: current.List[1].ID}, 0, user.ID))
+
+	// A full update keeps the IDs of items it still lists.
+	current, err = store.GetTodoById(todo.ID)
+	assert.NoError(t, err)
+	keptId := current.List[2].ID
+	current.List = []TodoItem{current.List[2], {Item: "rope"}}
+	assert.NoError(t, store.UpdateTodo(*current, user.ID))
+	current, err = store.GetTodoById(todo.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, keptId, current.List[0].ID)
+	assert.Equal(t, []string{"lamp", "rope"}, items())
+}
+
+func testTodoQueries(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+
+	base := time.Date(2024, time.August, 1, 9, 0, 0, 0, time.UTC)
+	seed := []struct {
+		title     string
+		day       int
+		active    bool
+		unchecked bool
+	}{
+		{"Groceries", 3, true, true},
+		{"Packing list", 1, true, false},
+		{"groceries for party", 5, false, true},
+		{"Taxes", 2, true, false},
+		{"Garden", 4, true, true},
+	}
+	for _, entry := range seed {
+		at := base.AddDate(0, 0, entry.day)
+		todo := &Todo{Title: entry.title, IsActive: entry.active, CreatedAt: at, UpdatedAt: at, UserID: user.ID,
+			List: []TodoItem{{Item: "first", Checked: true}, {Item: "second", Checked: !entry.unchecked}}}
+		assert.NoError(t, store.CreateTodo(todo))
+	}
+
+	titles := func(q TodoQuery) []string {
+		q.UserID = user.ID
+		var all []string
+		for {
+			page, err := store.GetAllTodos(q)
+			assert.NoError(t, err)
+			for _, todo := range page.Todos {
+				all = append(all, todo.Title)
+			}
+			if page.NextCursor == "" {
+				return all
+			}
+			q.Cursor = page.NextCursor
+		}
+	}
+	bool := func(b bool) *bool { return &b }
+	day := func(d int) *time.Time { at := base.AddDate(0, 0, d); return &at }
+
+	assert.Equal(t, []string{"Groceries", "Packing list", "groceries for party", "Taxes", "Garden"}, titles(TodoQuery{Limit: 2}))
+	assert.Equal(t, []string{"groceries for party", "Garden", "Groceries", "Taxes", "Packing list"},
+		titles(TodoQuery{Limit: 2, SortBy: SortByCreatedAt, Descending: true}))
+	assert.Equal(t, []string{"Garden", "Groceries", "Packing list", "Taxes", "groceries for party"},
+		titles(TodoQuery{Limit: 3, SortBy: SortByTitle}))
+	assert.Equal(t, []string{"Groceries", "groceries for party"}, titles(TodoQuery{TitleContains: "GROCER"}))
+	assert.Equal(t, []string{"groceries for party"}, titles(TodoQuery{IsActive: bool(false)}))
+	assert.Equal(t, []string{"Groceries", "groceries for party", "Garden"}, titles(TodoQuery{HasUnchecked: bool(true)}))
+	assert.Equal(t, []string{"Packing list", "Taxes"}, titles(TodoQuery{HasUnchecked: bool(false)}))
+	assert.Equal(t, []string{"Groceries", "Taxes"}, titles(TodoQuery{CreatedFrom: day(2), CreatedTo: day(4)}))
+	assert.Equal(t, []string{"Garden"}, titles(TodoQuery{UpdatedFrom: day(4), IsActive: bool(true)}))
+	// LIKE wildcards in the search text are matched literally.
+	assert.Empty(t, titles(TodoQuery{TitleContains: "a_e"}))
+
+	page, err := store.GetAllTodos(TodoQuery{UserID: user.ID, Limit: 2})
+	assert.NoError(t, err)
+	_, err = store.GetAllTodos(TodoQuery{UserID: user.ID, Cursor: page.NextCursor, SortBy: SortByTitle})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	_, err = store.GetAllTodos(TodoQuery{UserID: user.ID, Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func testSearchTodos(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+	other := &User{Email: "jane@example.com", FirstName: "Jane", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(other))
+
+	now := time.Now()
+	create := func(userId int64, title string, items ...string) *Todo {
+		todo := &Todo{Title: title, IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: userId}
+		for _, item := range items {
+			todo.List = append(todo.List, TodoItem{Item: item})
+		}
+		assert.NoError(t, store.CreateTodo(todo))
+		return todo
+	}
+	camping := create(user.ID, "Camping trip", "tent", "sleeping bag", "camping stove")
+	groceries := create(user.ID, "Groceries", "milk", "eggs")
+	create(user.ID, "Chores", "clean the stove")
+	create(user.ID, "Stove repair")
+	create(other.ID, "Camping gear", "tent")
+
+	results, err := store.SearchTodos(SearchQuery{UserID: user.ID, Text: "camping"})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, camping.ID, results[0].Todo.ID)
+	assert.Len(t, results[0].Todo.List, 3)
+	assert.Equal(t, "<mark>Camping</mark> trip", results[0].TitleSnippet)
+	assert.Equal(t, []ItemSnippet{{ItemID: camping.List[2].ID, Snippet: "<mark>camping</mark> stove"}}, results[0].ItemSnippets)
+
+	// A title match outranks an item match.
+	results, err = store.SearchTodos(SearchQuery{UserID: user.ID, Text: "Stove"})
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, "<mark>Stove</mark> repair", results[0].TitleSnippet)
+	for _, result := range results[1:] {
+		if result.Todo.Title == "Chores" {
+			assert.Equal(t, "Chores", result.TitleSnippet)
+			assert.Equal(t, "clean the <mark>stove</mark>", result.ItemSnippets[0].Snippet)
+		}
+	}
+
+	// Every word has to match within one title or item; words match as prefixes.
+	results, err = store.SearchTodos(SearchQuery{UserID: user.ID, Text: "groc"})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, groceries.ID, results[0].Todo.ID)
+
+	results, err = store.SearchTodos(SearchQuery{UserID: user.ID, Text: "sleeping tent"})
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+
+	results, err = store.SearchTodos(SearchQuery{UserID: user.ID, Text: `"sleeping" (bag*`})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	results, err = store.SearchTodos(SearchQuery{UserID: user.ID, Text: "tent", Limit: 1})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	results, err = store.SearchTodos(SearchQuery{UserID: user.ID, Text: " -- "})
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+
+	// The index follows updates and deletes.
+	groceries.Title = "Shopping"
+	assert.NoError(t, store.UpdateTodo(*groceries, user.ID))
+	results, err = store.SearchTodos(SearchQuery{UserID: user.ID, Text: "groceries"})
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+	assert.NoError(t, store.DeleteTodo(*camping, user.ID))
+	results, err = store.SearchTodos(SearchQuery{UserID: user.ID, Text: "tent"})
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func testTrash(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+	other := &User{Email: "jane@example.com", FirstName: "Jane", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(other))
+
+	now := time.Now()
+	create := func(userId int64, title string) *Todo {
+		todo := &Todo{Title: title, List: []TodoItem{{Item: title + " item"}}, IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: userId}
+		assert.NoError(t, store.CreateTodo(todo))
+		return todo
+	}
+	first := create(user.ID, "First")
+	second := create(user.ID, "Second")
+	create(user.ID, "Kept")
+	theirs := create(other.ID, "Theirs")
+
+	stale := *first
+	first.Title = "First edited"
+	assert.NoError(t, store.UpdateTodo(*first, user.ID))
+	assert.ErrorIs(t, store.TrashTodo(stale, user.ID), ErrVersionConflict)
+
+	fetched, err := store.GetTodoById(first.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, store.TrashTodo(*fetched, user.ID))
+	assert.NoError(t, store.TrashTodo(Todo{ID: second.ID}, user.ID))
+	assert.NoError(t, store.TrashTodo(Todo{ID: theirs.ID}, other.ID))
+
+	fetched, err = store.GetTodoById(first.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, fetched.DeletedAt)
+	assert.Equal(t, int64(3), fetched.Version)
+
+	page, err := store.GetAllTodos(TodoQuery{UserID: user.ID})
+	assert.NoError(t, err)
+	assert.Len(t, page.Todos, 1)
+	assert.Equal(t, "Kept", page.Todos[0].Title)
+	results, err := store.SearchTodos(SearchQuery{UserID: user.ID, Text: "item"})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	trash, err := store.GetTrash(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, trash, 2)
+	assert.Len(t, trash[0].List, 1)
+	assert.NotNil(t, trash[0].DeletedAt)
+
+	assert.NoError(t, store.RestoreTodo(Todo{ID: first.ID}, user.ID))
+	fetched, err = store.GetTodoById(first.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, fetched.DeletedAt)
+	assert.Equal(t, int64(4), fetched.Version)
+	assert.Equal(t, []string{"First item"}, []string{fetched.List[0].Item})
+
+	count, err := store.EmptyTrash(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	_, err = store.GetTodoById(second.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	trash, err = store.GetTrash(user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, trash)
+
+	// Only todos trashed before the cut-off are purged.
+	count, err = store.PurgeTrash(time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+	count, err = store.PurgeTrash(time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	_, err = store.GetTodoById(theirs.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = store.GetTodoById(first.ID)
+	assert.NoError(t, err)
+}
+
+func testDueDates(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+
+	now := time.Now().UTC().Truncate(time.Second)
+	at := func(d time.Duration) *time.Time {
+		due := now.Add(d)
+		return &due
+	}
+	create := func(title string, dueAt *time.Time, list ...TodoItem) *Todo {
+		todo := &Todo{Title: title, List: list, IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: user.ID, DueAt: dueAt, TimeZone: "Europe/Berlin"}
+		assert.NoError(t, store.CreateTodo(todo))
+		return todo
+	}
+	overdue := create("Overdue", at(-time.Hour))
+	create("Tomorrow", at(24*time.Hour))
+	create("Item overdue", nil, TodoItem{Item: "late", DueAt: at(-2 * time.Hour)}, TodoItem{Item: "later", DueAt: at(48 * time.Hour)})
+	create("Checked item", nil, TodoItem{Item: "done", Checked: true, DueAt: at(-time.Hour)})
+	create("No due date", nil)
+
+	fetched, err := store.GetTodoById(overdue.ID)
+	assert.NoError(t, err)
+	assert.True(t, overdue.DueAt.Equal(*fetched.DueAt))
+	assert.Equal(t, "Europe/Berlin", fetched.TimeZone)
+
+	titles := func(q TodoQuery) []string {
+		q.UserID = user.ID
+		page, err := store.GetAllTodos(q)
+		assert.NoError(t, err)
+		var titles []string
+		for _, todo := range page.Todos {
+			titles = append(titles, todo.Title)
+		}
+		return titles
+	}
+	assert.Equal(t, []string{"Overdue", "Item overdue"}, titles(TodoQuery{DueTo: &now}))
+	assert.Equal(t, []string{"Tomorrow"}, titles(TodoQuery{DueFrom: &now, DueTo: at(36 * time.Hour)}))
+	assert.Equal(t, []string{"Tomorrow", "Item overdue"}, titles(TodoQuery{DueFrom: &now}))
+
+	fetched.DueAt = nil
+	fetched.List = []TodoItem{{Item: "step", DueAt: at(time.Hour)}}
+	assert.NoError(t, store.UpdateTodo(*fetched, user.ID))
+	fetched, err = store.GetTodoById(overdue.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, fetched.DueAt)
+	assert.True(t, at(time.Hour).Equal(*fetched.List[0].DueAt))
+
+	item := &TodoItem{TodoID: overdue.ID, Item: "another", Position: -1, DueAt: at(2 * time.Hour)}
+	assert.NoError(t, store.CreateItem(item, 0, user.ID))
+	item.DueAt = at(3 * time.Hour)
+	assert.NoError(t, store.UpdateItem(*item, 0, user.ID))
+	stored, err := store.GetItem(overdue.ID, item.ID)
+	assert.NoError(t, err)
+	assert.True(t, at(3*time.Hour).Equal(*stored.DueAt))
+}
+
+func testReminders(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+
+	now := time.Now().UTC().Truncate(time.Second)
+	at := func(d time.Duration) *time.Time {
+		due := now.Add(d)
+		return &due
+	}
+	create := func(title string, active bool, dueAt *time.Time, list ...TodoItem) *Todo {
+		todo := &Todo{Title: title, List: list, IsActive: active, CreatedAt: now, UpdatedAt: now, UserID: user.ID, DueAt: dueAt}
+		assert.NoError(t, store.CreateTodo(todo))
+		return todo
+	}
+	due := create("Due", true, at(-time.Hour), TodoItem{Item: "first", DueAt: at(-2 * time.Hour)}, TodoItem{Item: "done", Checked: true, DueAt: at(-time.Hour)})
+	create("Future", true, at(time.Hour))
+	create("Inactive", false, at(-time.Hour))
+	trashed := create("Trashed", true, at(-time.Hour))
+	assert.NoError(t, store.TrashTodo(Todo{ID: trashed.ID}, user.ID))
+
+	reminders, err := store.DueReminders(now)
+	assert.NoError(t, err)
+	if assert.Len(t, reminders, 2) {
+		assert.Equal(t, due.List[0].ID, reminders[0].ItemID)
+		assert.Equal(t, "first", reminders[0].Item)
+		assert.Equal(t, "Due", reminders[0].Title)
+		assert.Equal(t, "john@example.com", reminders[0].Email)
+		assert.Equal(t, int64(0), reminders[1].ItemID)
+		assert.True(t, due.DueAt.Equal(reminders[1].DueAt))
+	}
+
+	for _, reminder := range reminders {
+		assert.NoError(t, store.MarkReminded(reminder))
+	}
+	reminders, err = store.DueReminders(now)
+	assert.NoError(t, err)
+	assert.Empty(t, reminders)
+
+	// Moving the due date makes the reminder pending again.
+	fetched, err := store.GetTodoById(due.ID)
+	assert.NoError(t, err)
+	fetched.DueAt = at(-time.Minute)
+	assert.NoError(t, store.UpdateTodo(*fetched, user.ID))
+	reminders, err = store.DueReminders(now)
+	assert.NoError(t, err)
+	if assert.Len(t, reminders, 1) {
+		assert.Equal(t, due.ID, reminders[0].TodoID)
+		assert.Equal(t, int64(0), reminders[0].ItemID)
+	}
+}
+
+func testRecurrence(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+
+	now := time.Now().UTC().Truncate(time.Second)
+	due := now.Add(time.Hour)
+	todo := &Todo{
+		Title: "Runbook", List: []TodoItem{{Item: "deploy", Checked: true}}, IsActive: true,
+		CreatedAt: now, UpdatedAt: now, UserID: user.ID, DueAt: &due, Recurrence: "FREQ=WEEKLY",
+	}
+	assert.NoError(t, store.CreateTodo(todo))
+	ops := &Tag{UserID: user.ID, Name: "ops"}
+	assert.NoError(t, store.CreateTag(ops))
+	assert.NoError(t, store.AttachTags(todo.ID, []int64{ops.ID}, user.ID))
+	assert.NoError(t, store.CreateTodo(&Todo{Title: "One-off", IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: user.ID}))
+	trashed := &Todo{Title: "Trashed", IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: user.ID, Recurrence: "FREQ=DAILY"}
+	assert.NoError(t, store.CreateTodo(trashed))
+	assert.NoError(t, store.TrashTodo(Todo{ID: trashed.ID}, user.ID))
+
+	pending, err := store.PendingRecurrences()
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, "Runbook", pending[0].Title)
+		assert.Equal(t, 1, pending[0].Occurrence)
+		assert.Len(t, pending[0].List, 1)
+	}
+
+	next := pending[0].NextOccurrence(now)
+	if !assert.NotNil(t, next) {
+		return
+	}
+	assert.NoError(t, store.CreateNextOccurrence(pending[0], next))
+	assert.ErrorIs(t, store.CreateNextOccurrence(pending[0], &Todo{Title: "Again", UserID: user.ID, CreatedAt: now, UpdatedAt: now}), ErrVersionConflict)
+
+	fetched, err := store.GetTodoById(todo.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, next.ID, fetched.NextID)
+	fetched, err = store.GetTodoById(next.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, todo.ID, fetched.SeriesID)
+	assert.Equal(t, 2, fetched.Occurrence)
+	assert.Equal(t, "FREQ=WEEKLY", fetched.Recurrence)
+	assert.Equal(t, []Tag{*ops}, fetched.Tags)
+	assert.False(t, fetched.List[0].Checked)
+	assert.True(t, due.AddDate(0, 0, 7).Equal(*fetched.DueAt))
+
+	pending, err = store.PendingRecurrences()
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, next.ID, pending[0].ID)
+	}
+
+	// Clearing the rule ends the series.
+	fetched.Recurrence = ""
+	assert.NoError(t, store.UpdateTodo(*fetched, user.ID))
+	pending, err = store.PendingRecurrences()
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func testTags(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+	other := &User{Email: "jane@example.com", FirstName: "Jane", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(other))
+
+	work := &Tag{UserID: user.ID, Name: "Work", Color: "#ff0000"}
+	assert.NoError(t, store.CreateTag(work))
+	home := &Tag{UserID: user.ID, Name: "home"}
+	assert.NoError(t, store.CreateTag(home))
+	urgent := &Tag{UserID: user.ID, Name: "urgent"}
+	assert.NoError(t, store.CreateTag(urgent))
+	assert.ErrorIs(t, store.CreateTag(&Tag{UserID: user.ID, Name: "WORK"}), ErrDuplicate)
+	othersWork := &Tag{UserID: other.ID, Name: "work"}
+	assert.NoError(t, store.CreateTag(othersWork))
+
+	tags, err := store.GetTags(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{*home, *urgent, *work}, tags)
+
+	now := time.Now()
+	create := func(title string, tags ...*Tag) *Todo {
+		todo := &Todo{Title: title, IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: user.ID}
+		assert.NoError(t, store.CreateTodo(todo))
+		var ids []int64
+		for _, tag := range tags {
+			ids = append(ids, tag.ID)
+		}
+		if len(ids) > 0 {
+			assert.NoError(t, store.AttachTags(todo.ID, ids, user.ID))
+		}
+		return todo
+	}
+	both := create("Both", work, urgent)
+	create("Work only", work)
+	homeOnly := create("Home only", home)
+	create("Untagged")
+
+	fetched, err := store.GetTodoById(both.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{*urgent, *work}, fetched.Tags)
+	assert.Equal(t, int64(2), fetched.Version)
+
+	// Attaching a tag the todo already has changes nothing.
+	assert.NoError(t, store.AttachTags(both.ID, []int64{work.ID}, user.ID))
+	fetched, err = store.GetTodoById(both.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), fetched.Version)
+
+	titles := func(q TodoQuery) []string {
+		q.UserID = user.ID
+		page, err := store.GetAllTodos(q)
+		assert.NoError(t, err)
+		var titles []string
+		for _, todo := range page.Todos {
+			titles = append(titles, todo.Title)
+		}
+		return titles
+	}
+	assert.Equal(t, []string{"Both", "Work only"}, titles(TodoQuery{Tags: []string{"work"}}))
+	assert.Equal(t, []string{"Both"}, titles(TodoQuery{Tags: []string{"WORK", "urgent"}}))
+	assert.Equal(t, []string{"Both", "Home only"}, titles(TodoQuery{Tags: []string{"urgent", "home"}, AnyTag: true}))
+	assert.Empty(t, titles(TodoQuery{Tags: []string{"home", "work"}}))
+
+	// Someone else's tag of the same name is listed on the todo, as in a
+	// workspace, but only the user's own tags match the filter.
+	assert.NoError(t, store.AttachTags(homeOnly.ID, []int64{othersWork.ID}, other.ID))
+	fetched, err = store.GetTodoById(homeOnly.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{*home, *othersWork}, fetched.Tags)
+	assert.Equal(t, []string{"Both", "Work only"}, titles(TodoQuery{Tags: []string{"work"}}))
+	assert.Equal(t, []string{"Both", "Work only"}, titles(TodoQuery{Tags: []string{"work", "office"}, AnyTag: true}))
+
+	// Renaming shows up on every todo carrying the tag.
+	work.Name = "Office"
+	assert.NoError(t, store.UpdateTag(*work))
+	fetched, err = store.GetTodoById(both.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{*work, *urgent}, fetched.Tags)
+	assert.Equal(t, int64(3), fetched.Version)
+	home.Name = "office"
+	assert.ErrorIs(t, store.UpdateTag(*home), ErrDuplicate)
+	home.Name = "home"
+
+	// Merging moves todos over without duplicating the tag.
+	assert.NoError(t, store.MergeTags(*work, *urgent))
+	_, err = store.GetTag(work.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	fetched, err = store.GetTodoById(both.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{*urgent}, fetched.Tags)
+	assert.Equal(t, []string{"Both", "Work only"}, titles(TodoQuery{Tags: []string{"urgent"}}))
+
+	assert.ErrorIs(t, store.DetachTag(both.ID, home.ID, user.ID), ErrNotFound)
+	assert.NoError(t, store.DetachTag(both.ID, urgent.ID, user.ID))
+	fetched, err = store.GetTodoById(both.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, fetched.Tags)
+
+	assert.NoError(t, store.DeleteTag(*urgent))
+	assert.Empty(t, titles(TodoQuery{Tags: []string{"urgent"}}))
+	tags, err = store.GetTags(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{*home}, tags)
+}
+
+func testRanking(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+	other := &User{Email: "jane@example.com", FirstName: "Jane", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(other))
+
+	now := time.Now()
+	create := func(title, priority string) *Todo {
+		todo := &Todo{Title: title, Priority: priority, IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: user.ID}
+		assert.NoError(t, store.CreateTodo(todo))
+		return todo
+	}
+	a := create("A", "")
+	b := create("B", P0)
+	c := create("C", P3)
+	d := create("D", P0)
+	assert.Equal(t, DefaultPriority, a.Priority)
+	assert.True(t, a.Rank < b.Rank && b.Rank < c.Rank && c.Rank < d.Rank)
+	foreign := &Todo{Title: "Foreign", IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: other.ID}
+	assert.NoError(t, store.CreateTodo(foreign))
+
+	titles := func(q TodoQuery) []string {
+		q.UserID = user.ID
+		page, err := store.GetAllTodos(q)
+		assert.NoError(t, err)
+		var titles []string
+		for _, todo := range page.Todos {
+			titles = append(titles, todo.Title)
+		}
+		return titles
+	}
+	assert.Equal(t, []string{"A", "B", "C", "D"}, titles(TodoQuery{}))
+	assert.Equal(t, []string{"B", "D", "A", "C"}, titles(TodoQuery{SortBy: SortByPriority}))
+	assert.Equal(t, []string{"B", "D"}, titles(TodoQuery{Priorities: []string{P0}}))
+
+	assert.NoError(t, store.MoveTodo(user.ID, Move{ID: d.ID, BeforeID: a.ID}))
+	assert.NoError(t, store.MoveTodo(user.ID, Move{ID: a.ID, AfterID: c.ID}))
+	assert.ErrorIs(t, store.MoveTodo(user.ID, Move{ID: b.ID, AfterID: a.ID, BeforeID: d.ID}), ErrInvalidMove)
+	assert.NoError(t, store.MoveTodo(user.ID, Move{ID: b.ID, AfterID: c.ID, BeforeID: a.ID}))
+	assert.Equal(t, []string{"D", "C", "B", "A"}, titles(TodoQuery{}))
+	assert.Equal(t, []string{"D", "C"}, titles(TodoQuery{Limit: 2}))
+
+	// Only the moved todo changes.
+	fetched, err := store.GetTodoById(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, c.Rank, fetched.Rank)
+	assert.Equal(t, int64(1), fetched.Version)
+	fetched, err = store.GetTodoById(b.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), fetched.Version)
+
+	assert.ErrorIs(t, store.MoveTodo(user.ID, Move{ID: a.ID}), ErrInvalidMove)
+	assert.ErrorIs(t, store.MoveTodo(user.ID, Move{ID: a.ID, AfterID: a.ID}), ErrInvalidMove)
+	assert.ErrorIs(t, store.MoveTodo(user.ID, Move{ID: a.ID, AfterID: foreign.ID}), ErrNotFound)
+	assert.ErrorIs(t, store.MoveTodo(other.ID, Move{ID: a.ID, AfterID: foreign.ID}), ErrNotFound)
+	assert.ErrorIs(t, store.MoveTodo(user.ID, Move{ID: b.ID, AfterID: d.ID, Version: 1}), ErrVersionConflict)
+	assert.Equal(t, []string{"D", "C", "B", "A"}, titles(TodoQuery{}))
+
+	// A full update keeps the rank and sets the priority.
+	fetched.Priority = P1
+	assert.NoError(t, store.UpdateTodo(*fetched, user.ID))
+	fetched, err = store.GetTodoById(b.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, P1, fetched.Priority)
+	assert.Equal(t, []string{"D", "C", "B", "A"}, titles(TodoQuery{}))
+
+	// Items move the same way, and a full update only re-ranks the items
+	// that changed place.
+	list := &Todo{Title: "Packing", List: []TodoItem{{Item: "tent"}, {Item: "stove"}, {Item: "lamp"}}, UserID: user.ID}
+	assert.NoError(t, store.CreateTodo(list))
+	tent, stove, lamp := list.List[0], list.List[1], list.List[2]
+	assert.NoError(t, store.MoveItem(list.ID, Move{ID: lamp.ID, BeforeID: tent.ID}, 0, user.ID))
+	assert.ErrorIs(t, store.MoveItem(list.ID, Move{ID: lamp.ID, AfterID: foreign.ID}, 0, user.ID), ErrNotFound)
+	assert.ErrorIs(t, store.MoveItem(list.ID+1, Move{ID: lamp.ID, AfterID: tent.ID}, 0, user.ID), ErrNotFound)
+	fetched, err = store.GetTodoById(list.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lamp", "tent", "stove"}, []string{fetched.List[0].Item, fetched.List[1].Item, fetched.List[2].Item})
+	assert.Equal(t, 2, fetched.List[2].Position)
+	assert.Equal(t, int64(2), fetched.Version)
+
+	item, err := store.GetItem(list.ID, stove.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, item.Position)
+
+	fetched.List = []TodoItem{fetched.List[1], fetched.List[0], fetched.List[2], {Item: "rope"}}
+	assert.NoError(t, store.UpdateTodo(*fetched, user.ID))
+	updated, err := store.GetTodoById(list.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, tent.Rank, updated.List[0].Rank)
+	assert.Equal(t, stove.Rank, updated.List[2].Rank)
+	assert.Equal(t, []string{"tent", "lamp", "stove", "rope"},
+		[]string{updated.List[0].Item, updated.List[1].Item, updated.List[2].Item, updated.List[3].Item})
+}
+
+func testProjects(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+
+	now := time.Now()
+	project := func(name string, parent *Project) *Project {
+		p := &Project{UserID: user.ID, Name: name, CreatedAt: now, UpdatedAt: now}
+		if parent != nil {
+			p.ParentID = parent.ID
+		}
+		assert.NoError(t, store.CreateProject(p))
+		return p
+	}
+	work := project("work", nil)
+	reports := project("Reports", work)
+	weekly := project("weekly", reports)
+	home := project("Home", nil)
+
+	projects, err := store.GetProjects(user.ID)
+	assert.NoError(t, err)
+	var names []string
+	for _, p := range projects {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"Home", "Reports", "weekly", "work"}, names)
+	assert.Equal(t, []int64{work.ID, reports.ID, weekly.ID}, Subtree(projects, work.ID))
+
+	_, err = store.GetProject(home.ID + 100)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Moving a project keeps its subprojects under it.
+	reports.ParentID, reports.Name = home.ID, "reports"
+	assert.NoError(t, store.UpdateProject(*reports))
+	fetched, err := store.GetProject(reports.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "reports", fetched.Name)
+	assert.Equal(t, home.ID, fetched.ParentID)
+	projects, err = store.GetProjects(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{home.ID, reports.ID, weekly.ID}, Subtree(projects, home.ID))
+
+	create := func(title string, p *Project) *Todo {
+		todo := &Todo{Title: title, IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: user.ID}
+		if p != nil {
+			todo.ProjectID = p.ID
+		}
+		assert.NoError(t, store.CreateTodo(todo))
+		return todo
+	}
+	inbox := create("Inbox", nil)
+	chores := create("Chores", home)
+	summary := create("Summary", weekly)
+	create("Plan", work)
+
+	titles := func(q TodoQuery) []string {
+		q.UserID = user.ID
+		page, err := store.GetAllTodos(q)
+		assert.NoError(t, err)
+		var titles []string
+		for _, todo := range page.Todos {
+			titles = append(titles, todo.Title)
+		}
+		return titles
+	}
+	assert.Equal(t, []string{"Chores", "Summary"}, titles(TodoQuery{ProjectIDs: []int64{home.ID, weekly.ID}}))
+
+	fetchedTodo, err := store.GetTodoById(summary.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, weekly.ID, fetchedTodo.ProjectID)
+	fetchedTodo.ProjectID = 0
+	assert.NoError(t, store.UpdateTodo(*fetchedTodo, user.ID))
+	fetchedTodo, err = store.GetTodoById(summary.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), fetchedTodo.ProjectID)
+	fetchedTodo.ProjectID = weekly.ID
+	assert.NoError(t, store.UpdateTodo(*fetchedTodo, user.ID))
+
+	// Archiving covers the subtree and hides its todos from listings.
+	assert.NoError(t, store.ArchiveProject(*home, true))
+	for _, id := range []int64{home.ID, reports.ID, weekly.ID} {
+		fetched, err = store.GetProject(id)
+		assert.NoError(t, err)
+		assert.NotNil(t, fetched.ArchivedAt)
+	}
+	fetched, err = store.GetProject(work.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, fetched.ArchivedAt)
+	assert.Equal(t, []string{"Inbox", "Plan"}, titles(TodoQuery{}))
+	assert.Equal(t, []string{"Inbox", "Chores", "Summary", "Plan"}, titles(TodoQuery{IncludeArchived: true}))
+
+	assert.NoError(t, store.ArchiveProject(*reports, false))
+	fetched, err = store.GetProject(weekly.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, fetched.ArchivedAt)
+	assert.Equal(t, []string{"Inbox", "Summary", "Plan"}, titles(TodoQuery{}))
+
+	// Deleting with keepTodos moves the todos out of the subtree.
+	assert.NoError(t, store.DeleteProject(*reports, true))
+	_, err = store.GetProject(weekly.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	fetchedTodo, err = store.GetTodoById(summary.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), fetchedTodo.ProjectID)
+	assert.Nil(t, fetchedTodo.DeletedAt)
+	assert.Equal(t, int64(4), fetchedTodo.Version)
+
+	// Otherwise they go to the trash.
+	assert.NoError(t, store.DeleteProject(*home, false))
+	_, err = store.GetProject(home.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	trash, err := store.GetTrash(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, trash, 1)
+	assert.Equal(t, chores.ID, trash[0].ID)
+	assert.Equal(t, int64(0), trash[0].ProjectID)
+	assert.Equal(t, []string{"Inbox", "Summary", "Plan"}, titles(TodoQuery{}))
+	fetchedTodo, err = store.GetTodoById(inbox.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), fetchedTodo.Version)
+
+	projects, err = store.GetProjects(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, projects, 1)
+	assert.Equal(t, work.ID, projects[0].ID)
+}
+
+func testShares(t *testing.T, store Store) {
+	owner := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(owner))
+	viewer := &User{Email: "jane@example.com", FirstName: "Jane", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(viewer))
+	editor := &User{Email: "joe@example.com", FirstName: "Joe", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(editor))
+
+	now := time.Now().Truncate(time.Second)
+	create := func(title string, user *User) *Todo {
+		todo := &Todo{Title: title, IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: user.ID}
+		assert.NoError(t, store.CreateTodo(todo))
+		return todo
+	}
+	groceries := create("Groceries", owner)
+	trip := create("Trip", owner)
+	create("Own", viewer)
+
+	assert.NoError(t, store.ShareTodo(&Share{TodoID: groceries.ID, UserID: viewer.ID, Role: RoleEditor, CreatedAt: now}))
+	assert.NoError(t, store.ShareTodo(&Share{TodoID: groceries.ID, UserID: editor.ID, Role: RoleEditor, CreatedAt: now.Add(time.Second)}))
+	// Sharing again changes the role but keeps the first grant's time.
+	share := &Share{TodoID: groceries.ID, UserID: viewer.ID, Role: RoleViewer, CreatedAt: now.Add(time.Hour)}
+	assert.NoError(t, store.ShareTodo(share))
+	assert.True(t, now.Equal(share.CreatedAt))
+
+	shares, err := store.GetShares(groceries.ID)
+	assert.NoError(t, err)
+	assert.Len(t, shares, 2)
+	assert.Equal(t, []string{"jane@example.com", "joe@example.com"}, []string{shares[0].Email, shares[1].Email})
+	assert.Equal(t, []string{RoleViewer, RoleEditor}, []string{shares[0].Role, shares[1].Role})
+
+	role, err := store.GetShareRole(groceries.ID, viewer.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, RoleViewer, role)
+	role, err = store.GetShareRole(trip.ID, viewer.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "", role)
+
+	page, err := store.GetAllTodos(TodoQuery{UserID: viewer.ID, IncludeShared: true, SortBy: SortById})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Todos, 2) {
+		assert.Equal(t, "Groceries", page.Todos[0].Title)
+		assert.True(t, page.Todos[0].Shared)
+		assert.Equal(t, RoleViewer, page.Todos[0].Role)
+		assert.False(t, page.Todos[1].Shared)
+		assert.Equal(t, "", page.Todos[1].Role)
+	}
+	page, err = store.GetAllTodos(TodoQuery{UserID: viewer.ID})
+	assert.NoError(t, err)
+	assert.Len(t, page.Todos, 1)
+	page, err = store.GetAllTodos(TodoQuery{UserID: owner.ID, IncludeShared: true})
+	assert.NoError(t, err)
+	assert.Len(t, page.Todos, 2)
+	assert.False(t, page.Todos[0].Shared)
+
+	assert.NoError(t, store.UnshareTodo(groceries.ID, viewer.ID))
+	assert.ErrorIs(t, store.UnshareTodo(groceries.ID, viewer.ID), ErrNotFound)
+	page, err = store.GetAllTodos(TodoQuery{UserID: viewer.ID, IncludeShared: true})
+	assert.NoError(t, err)
+	assert.Len(t, page.Todos, 1)
+
+	// Purging the todo drops its shares.
+	assert.NoError(t, store.TrashTodo(*groceries, owner.ID))
+	_, err = store.PurgeTrash(time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	shares, err = store.GetShares(groceries.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, shares)
+}
+
+func testWorkspaces(t *testing.T, store Store) {
+	owner := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(owner))
+	member := &User{Email: "jane@example.com", FirstName: "Jane", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(member))
+
+	now := time.Now().Truncate(time.Second)
+	team := &Workspace{Name: "Team", CreatedAt: now, UpdatedAt: now}
+	assert.NoError(t, store.CreateWorkspace(team, owner.ID))
+	assert.NoError(t, store.CreateWorkspace(&Workspace{Name: "another", CreatedAt: now, UpdatedAt: now}, owner.ID))
+
+	workspaces, err := store.GetWorkspaces(owner.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"another", "Team"}, []string{workspaces[0].Name, workspaces[1].Name})
+	assert.Equal(t, WorkspaceOwner, workspaces[1].Role)
+	workspaces, err = store.GetWorkspaces(member.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, workspaces)
+
+	invite := &Invite{WorkspaceID: team.ID, Email: "Jane@Example.com", Role: WorkspaceMember, Token: "secret", InvitedBy: owner.ID, CreatedAt: now}
+	assert.NoError(t, store.CreateInvite(invite))
+	invites, err := store.GetInvitesForEmail(member.Email)
+	assert.NoError(t, err)
+	if assert.Len(t, invites, 1) {
+		assert.Equal(t, "Team", invites[0].WorkspaceName)
+	}
+	fetched, err := store.GetInvite("secret")
+	assert.NoError(t, err)
+	assert.Equal(t, invite.ID, fetched.ID)
+	_, err = store.GetInvite("guess")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, store.AcceptInvite(*fetched, member.ID))
+	role, err := store.GetMemberRole(team.ID, member.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, WorkspaceMember, role)
+	invites, err = store.GetInvites(team.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, invites)
+	fetched, err = store.GetInvite("secret")
+	assert.NoError(t, err)
+	assert.NotNil(t, fetched.AcceptedAt)
+
+	members, err := store.GetMembers(team.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, members, 2) {
+		assert.Equal(t, "john@example.com", members[0].Email)
+		assert.Equal(t, WorkspaceOwner, members[0].Role)
+		assert.Equal(t, "Jane", members[1].FirstName)
+	}
+	assert.NoError(t, store.SetMemberRole(team.ID, member.ID, WorkspaceGuest))
+	role, err = store.GetMemberRole(team.ID, member.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, WorkspaceGuest, role)
+
+	// Workspace todos are listed by workspace and kept out of personal
+	// listings, search and trash.
+	create := func(title string, workspaceId int64) *Todo {
+		todo := &Todo{Title: title, IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: owner.ID, WorkspaceID: workspaceId}
+		assert.NoError(t, store.CreateTodo(todo))
+		return todo
+	}
+	create("Personal plan", 0)
+	roadmap := create("Team plan", team.ID)
+	page, err := store.GetAllTodos(TodoQuery{UserID: owner.ID, IncludeShared: true})
+	assert.NoError(t, err)
+	assert.Len(t, page.Todos, 1)
+	assert.Equal(t, "Personal plan", page.Todos[0].Title)
+	page, err = store.GetAllTodos(TodoQuery{UserID: member.ID, WorkspaceID: team.ID})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Todos, 1) {
+		assert.Equal(t, team.ID, page.Todos[0].WorkspaceID)
+	}
+	results, err := store.SearchTodos(SearchQuery{UserID: owner.ID, Text: "plan"})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	assert.NoError(t, store.TrashTodo(*roadmap, owner.ID))
+	trash, err := store.GetTrash(owner.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, trash)
+	trash, err = store.GetWorkspaceTrash(team.ID)
+	assert.NoError(t, err)
+	assert.Len(t, trash, 1)
+	count, err := store.EmptyTrash(owner.ID)
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+
+	assert.NoError(t, store.RemoveMember(team.ID, member.ID))
+	assert.ErrorIs(t, store.RemoveMember(team.ID, member.ID), ErrNotFound)
+	assert.ErrorIs(t, store.SetMemberRole(team.ID, member.ID, WorkspaceAdmin), ErrNotFound)
+	assert.ErrorIs(t, store.DeleteInvite(team.ID+1, invite.ID), ErrNotFound)
+	assert.NoError(t, store.DeleteInvite(team.ID, invite.ID))
+
+	assert.NoError(t, store.DeleteWorkspace(team.ID, owner.ID))
+	_, err = store.GetWorkspace(team.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = store.GetTodoById(roadmap.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	workspaces, err = store.GetWorkspaces(owner.ID)
+	assert.NoError(t, err)
+	assert.Len(t, workspaces, 1)
+}
+
+func testShareLinks(t *testing.T, store Store) {
+	owner := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(owner))
+	now := time.Now().Truncate(time.Second)
+	todo := &Todo{Title: "Groceries", IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: owner.ID}
+	assert.NoError(t, store.CreateTodo(todo))
+
+	expires := now.Add(time.Hour)
+	first := &ShareLink{TodoID: todo.ID, Token: "first", CreatedBy: owner.ID, ExpiresAt: &expires, CreatedAt: now}
+	assert.NoError(t, store.CreateShareLink(first))
+	second := &ShareLink{TodoID: todo.ID, Token: "second", CreatedBy: owner.ID, CreatedAt: now.Add(time.Second)}
+	assert.NoError(t, store.CreateShareLink(second))
+	assert.Error(t, store.CreateShareLink(&ShareLink{TodoID: todo.ID, Token: "first", CreatedBy: owner.ID, CreatedAt: now}))
+
+	link, err := store.GetShareLink("first")
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, link.ID)
+	assert.True(t, expires.Equal(*link.ExpiresAt))
+	assert.False(t, link.Expired(now))
+	assert.True(t, link.Expired(expires))
+	_, err = store.GetShareLink("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, store.LogLinkAccess(LinkAccess{LinkID: first.ID, IP: "10.0.0.1", UserAgent: "curl", AccessedAt: now}))
+	assert.NoError(t, store.LogLinkAccess(LinkAccess{LinkID: first.ID, IP: "10.0.0.2", UserAgent: "curl", AccessedAt: now.Add(time.Minute)}))
+	accesses, err := store.GetLinkAccesses(first.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, accesses, 2) {
+		assert.Equal(t, "10.0.0.2", accesses[0].IP)
+	}
+
+	links, err := store.GetShareLinks(todo.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, links, 2) {
+		assert.Equal(t, []string{"second", "first"}, []string{links[0].Token, links[1].Token})
+		assert.Equal(t, []int{0, 2}, []int{links[0].Accesses, links[1].Accesses})
+	}
+
+	// Revoking twice keeps the first time.
+	assert.NoError(t, store.RevokeShareLink(todo.ID, first.ID, now))
+	assert.NoError(t, store.RevokeShareLink(todo.ID, first.ID, now.Add(time.Hour)))
+	link, err = store.GetShareLink("first")
+	assert.NoError(t, err)
+	assert.True(t, now.Equal(*link.RevokedAt))
+	assert.ErrorIs(t, store.RevokeShareLink(todo.ID+1, second.ID, now), ErrNotFound)
+
+	// Purging the todo drops its links.
+	assert.NoError(t, store.TrashTodo(*todo, owner.ID))
+	_, err = store.PurgeTrash(time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	_, err = store.GetShareLink("second")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func testHistory(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+	other := &User{Email: "jane@example.com", FirstName: "Jane", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(other))
+	now := time.Now().Truncate(time.Second)
+
+	actions := func(todoId int64) ([]string, []int64) {
+		history, err := store.GetHistory(todoId)
+		assert.NoError(t, err)
+		var actions []string
+		var actors []int64
+		for _, entry := range history {
+			actions = append(actions, entry.Action)
+			actors = append(actors, entry.ActorID)
+		}
+		return actions, actors
+	}
+
+	// Every change is recorded by the store with the user who made it.
+	todo := &Todo{Title: "Groceries", IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: user.ID, List: []TodoItem{{Item: "milk"}}}
+	assert.NoError(t, store.CreateTodo(todo))
+	updated := *todo
+	updated.Title = "Weekly groceries"
+	updated.List = append([]TodoItem(nil), todo.List...)
+	assert.NoError(t, store.UpdateTodo(updated, other.ID))
+	eggs := &TodoItem{TodoID: todo.ID, Item: "eggs", Position: -1}
+	assert.NoError(t, store.CreateItem(eggs, 0, other.ID))
+	assert.NoError(t, store.TrashTodo(Todo{ID: todo.ID}, user.ID))
+	assert.NoError(t, store.RestoreTodo(Todo{ID: todo.ID}, user.ID))
+
+	history, err := store.GetHistory(todo.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, history, 5) {
+		actions, actors := actions(todo.ID)
+		assert.Equal(t, []string{HistoryRestored, HistoryTrashed, HistoryUpdated, HistoryUpdated, HistoryCreated}, actions)
+		assert.Equal(t, []int64{user.ID, user.ID, other.ID, other.ID, user.ID}, actors)
+		assert.Equal(t, "jane@example.com", history[2].ActorEmail)
+		assert.Equal(t, []ItemState{{ID: eggs.ID, Item: "eggs"}}, history[2].Diff.Added)
+		assert.Equal(t, &TitleChange{From: "Groceries", To: "Weekly groceries"}, history[3].Diff.Title)
+		assert.Equal(t, TodoDiff{Title: &TitleChange{To: "Groceries"}, Added: []ItemState{{ID: todo.List[0].ID, Item: "milk"}}}, history[4].Diff)
+		assert.Equal(t, TodoDiff{}, history[1].Diff)
+	}
+
+	// Reordering and tagging are recorded too, with nothing to diff.
+	errands := &Todo{Title: "Errands", IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: user.ID}
+	assert.NoError(t, store.CreateTodo(errands))
+	assert.NoError(t, store.MoveTodo(user.ID, Move{ID: errands.ID, BeforeID: todo.ID}))
+	shops := &Tag{UserID: user.ID, Name: "shops"}
+	assert.NoError(t, store.CreateTag(shops))
+	assert.NoError(t, store.AttachTags(errands.ID, []int64{shops.ID}, other.ID))
+	shops.Name = "stores"
+	assert.NoError(t, store.UpdateTag(*shops))
+	assert.NoError(t, store.DetachTag(errands.ID, shops.ID, other.ID))
+	assert.NoError(t, store.AttachTags(errands.ID, []int64{shops.ID}, other.ID))
+	assert.NoError(t, store.DeleteTag(*shops))
+	actionList, actors := actions(errands.ID)
+	assert.Equal(t, []string{HistoryUpdated, HistoryUpdated, HistoryUpdated, HistoryUpdated, HistoryUpdated, HistoryUpdated, HistoryCreated},
+		actionList)
+	assert.Equal(t, []int64{user.ID, other.ID, other.ID, user.ID, other.ID, user.ID, user.ID}, actors)
+	history, err = store.GetHistory(errands.ID)
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, history) {
+		assert.Equal(t, TodoDiff{}, history[0].Diff)
+	}
+
+	// So are the changes the server makes, with actor 0, and todos going
+	// with their project or workspace. Entries outlive their todo.
+	next := &Todo{Title: "Weekly groceries", IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: user.ID, SeriesID: todo.ID, Occurrence: 2}
+	assert.NoError(t, store.CreateNextOccurrence(*todo, next))
+	home := &Project{Name: "Home", UserID: user.ID, CreatedAt: now, UpdatedAt: now}
+	assert.NoError(t, store.CreateProject(home))
+	chores := &Todo{Title: "Chores", IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: user.ID, ProjectID: home.ID}
+	assert.NoError(t, store.CreateTodo(chores))
+	assert.NoError(t, store.DeleteProject(*home, false))
+	_, err = store.PurgeTrash(time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	team := &Workspace{Name: "Team", CreatedAt: now, UpdatedAt: now}
+	assert.NoError(t, store.CreateWorkspace(team, user.ID))
+	roadmap := &Todo{Title: "Roadmap", IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: user.ID, WorkspaceID: team.ID}
+	assert.NoError(t, store.CreateTodo(roadmap))
+	assert.NoError(t, store.DeleteWorkspace(team.ID, other.ID))
+
+	actionList, actors = actions(next.ID)
+	assert.Equal(t, []string{HistoryCreated}, actionList)
+	assert.Equal(t, []int64{0}, actors)
+	actionList, actors = actions(chores.ID)
+	assert.Equal(t, []string{HistoryDeleted, HistoryTrashed, HistoryCreated}, actionList)
+	assert.Equal(t, []int64{0, user.ID, user.ID}, actors)
+	actionList, actors = actions(roadmap.ID)
+	assert.Equal(t, []string{HistoryDeleted, HistoryCreated}, actionList)
+	assert.Equal(t, []int64{other.ID, user.ID}, actors)
+	history, err = store.GetHistory(chores.ID)
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, history) {
+		assert.Equal(t, "", history[0].ActorEmail)
+		assert.Equal(t, &TitleChange{From: "Chores"}, history[0].Diff.Title)
+	}
+
+	history, err = store.GetHistory(roadmap.ID + 1)
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func testVersions(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+	other := &User{Email: "jane@example.com", FirstName: "Jane", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(other))
+	now := time.Now().Truncate(time.Second)
+	todo := &Todo{Title: "Groceries", IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: user.ID,
+		List: []TodoItem{{Item: "milk"}, {Item: "eggs"}}}
+	assert.NoError(t, store.CreateTodo(todo))
+
+	// Every update saves the version it replaces, failed ones none.
+	updated := *todo
+	updated.Title = "Weekly groceries"
+	updated.List = []TodoItem{{ID: todo.List[1].ID, Item: "eggs", Checked: true}}
+	updated.UpdatedAt = now.Add(time.Minute)
+	assert.NoError(t, store.UpdateTodo(updated, user.ID))
+	updated.Version = 1
+	assert.ErrorIs(t, store.UpdateTodo(updated, user.ID), ErrVersionConflict)
+	updated.Version = 0
+	updated.Title = "Monthly groceries"
+	updated.UpdatedAt = now.Add(time.Hour)
+	assert.NoError(t, store.UpdateTodo(updated, user.ID))
+
+	versions, err := store.GetVersions(todo.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, versions, 2) {
+		assert.Equal(t, []int64{2, 1}, []int64{versions[0].Version, versions[1].Version})
+		assert.Equal(t, "Weekly groceries", versions[0].Title)
+		assert.True(t, now.Add(time.Hour).Equal(versions[0].CreatedAt))
+	}
+	version, err := store.GetVersion(todo.ID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Groceries", version.Title)
+	if assert.Len(t, version.List, 2) {
+		assert.Equal(t, todo.List[0].ID, version.List[0].ID)
+		assert.Equal(t, "milk", version.List[0].Item)
+	}
+	_, err = store.GetVersion(todo.ID, 3)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Undo tokens are single use and only for their user; expired ones are
+	// dropped once a newer token is created.
+	undo := Undo{Token: "undo", TodoID: todo.ID, UserID: user.ID, Action: HistoryUpdated, Version: 3, CreatedAt: now,
+		ExpiresAt: now.Add(UndoTTL)}
+	assert.NoError(t, store.CreateUndo(undo))
+	_, err = store.TakeUndo("undo", other.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	taken, err := store.TakeUndo("undo", user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), taken.Version)
+	assert.True(t, undo.ExpiresAt.Equal(taken.ExpiresAt))
+	_, err = store.TakeUndo("undo", user.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, store.CreateUndo(undo))
+	later := undo
+	later.Token, later.CreatedAt, later.ExpiresAt = "later", undo.ExpiresAt, undo.ExpiresAt.Add(UndoTTL)
+	assert.NoError(t, store.CreateUndo(later))
+	_, err = store.TakeUndo("undo", user.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// So does every other change that bumps the version, leaving no gaps.
+	home := &Project{Name: "Home", UserID: user.ID, CreatedAt: now, UpdatedAt: now}
+	assert.NoError(t, store.CreateProject(home))
+	chores := &Todo{Title: "Chores", IsActive: true, CreatedAt: now, UpdatedAt: now, UserID: user.ID, ProjectID: home.ID,
+		List: []TodoItem{{Item: "dishes"}}}
+	assert.NoError(t, store.CreateTodo(chores))
+	laundry := &TodoItem{TodoID: chores.ID, Item: "laundry", Position: -1}
+	assert.NoError(t, store.CreateItem(laundry, 0, user.ID))
+	laundry.Checked = true
+	assert.NoError(t, store.UpdateItem(*laundry, 0, user.ID))
+	assert.NoError(t, store.MoveItem(chores.ID, Move{ID: laundry.ID, BeforeID: chores.List[0].ID}, 0, user.ID))
+	assert.NoError(t, store.DeleteItem(*laundry, 0, user.ID))
+	assert.NoError(t, store.MoveTodo(user.ID, Move{ID: chores.ID, BeforeID: todo.ID}))
+	tag := &Tag{UserID: user.ID, Name: "home"}
+	assert.NoError(t, store.CreateTag(tag))
+	assert.NoError(t, store.AttachTags(chores.ID, []int64{tag.ID}, user.ID))
+	tag.Name = "house"
+	assert.NoError(t, store.UpdateTag(*tag))
+	assert.NoError(t, store.DetachTag(chores.ID, tag.ID, user.ID))
+	assert.NoError(t, store.DeleteProject(*home, false))
+	assert.NoError(t, store.RestoreTodo(Todo{ID: chores.ID}, user.ID))
+	// Trashing a todo already in the trash changes nothing.
+	assert.NoError(t, store.TrashTodo(Todo{ID: chores.ID}, user.ID))
+	assert.NoError(t, store.TrashTodo(Todo{ID: chores.ID}, user.ID))
+
+	fetched, err := store.GetTodoById(chores.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), fetched.Version)
+	versions, err = store.GetVersions(chores.ID)
+	assert.NoError(t, err)
+	var numbers []int64
+	for _, version := range versions {
+		numbers = append(numbers, version.Version)
+	}
+	assert.Equal(t, []int64{11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, numbers)
+	if assert.Len(t, versions, 11) {
+		assert.Len(t, versions[10].List, 1)
+		assert.Equal(t, []string{"dishes", "laundry"}, []string{versions[9].List[0].Item, versions[9].List[1].Item})
+		assert.True(t, versions[8].List[1].Checked)
+		assert.Equal(t, []string{"laundry", "dishes"}, []string{versions[7].List[0].Item, versions[7].List[1].Item})
+	}
+}
+
+func testRefreshTokens(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+	now := time.Now().Truncate(time.Second)
+	token := &RefreshToken{UserID: user.ID, FamilyID: "family", TokenHash: "first", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	assert.NoError(t, store.CreateRefreshToken(token))
+	assert.NotZero(t, token.ID)
+	assert.ErrorIs(t, store.CreateRefreshToken(&RefreshToken{UserID: user.ID, FamilyID: "other", TokenHash: "first",
+		CreatedAt: now, ExpiresAt: now.Add(time.Hour)}), ErrDuplicate)
+
+	// A token is used once; the family stays readable after revoking.
+	assert.NoError(t, store.UseRefreshToken(token.ID, now))
+	assert.ErrorIs(t, store.UseRefreshToken(token.ID, now), ErrNotFound)
+	next := &RefreshToken{UserID: user.ID, FamilyID: "family", TokenHash: "second", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	assert.NoError(t, store.CreateRefreshToken(next))
+	assert.NoError(t, store.RevokeRefreshFamily("family", now.Add(time.Minute)))
+	assert.ErrorIs(t, store.UseRefreshToken(next.ID, now), ErrNotFound)
+	found, err := store.GetRefreshToken("second")
+	assert.NoError(t, err)
+	assert.Equal(t, "family", found.FamilyID)
+	if assert.NotNil(t, found.RevokedAt) {
+		assert.True(t, now.Add(time.Minute).Equal(*found.RevokedAt))
+	}
+	assert.Nil(t, found.UsedAt)
+	found, err = store.GetRefreshToken("first")
+	assert.NoError(t, err)
+	assert.NotNil(t, found.UsedAt)
+	_, err = store.GetRefreshToken("unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Expired tokens are dropped once a newer token is created.
+	later := &RefreshToken{UserID: user.ID, FamilyID: "later", TokenHash: "third", CreatedAt: now.Add(time.Hour),
+		ExpiresAt: now.Add(2 * time.Hour)}
+	assert.NoError(t, store.CreateRefreshToken(later))
+	_, err = store.GetRefreshToken("first")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func testSessions(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+	other := &User{Email: "jane@example.com", FirstName: "Jane", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(other))
+	now := time.Now().Truncate(time.Second)
+	laptop := &Session{UserID: user.ID, FamilyID: "laptop", UserAgent: "Firefox", IP: "10.0.0.1", CreatedAt: now, LastUsedAt: now}
+	assert.NoError(t, store.CreateSession(laptop))
+	phone := &Session{UserID: user.ID, FamilyID: "phone", UserAgent: "Safari", IP: "10.0.0.2", CreatedAt: now, LastUsedAt: now}
+	assert.NoError(t, store.CreateSession(phone))
+	assert.ErrorIs(t, store.CreateSession(&Session{UserID: user.ID, FamilyID: "phone", CreatedAt: now, LastUsedAt: now}), ErrDuplicate)
+	found, err := store.GetSessionByFamily("phone")
+	assert.NoError(t, err)
+	assert.Equal(t, phone.ID, found.ID)
+	assert.Equal(t, "Safari", found.UserAgent)
+	_, err = store.GetSessionByFamily("tablet")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Handing out a token marks the session used from where it was asked.
+	token := IssuedToken{JTI: "first", SessionID: laptop.ID, UserAgent: "Firefox 2", IP: "10.0.0.3", IssuedAt: now.Add(time.Minute),
+		ExpiresAt: now.Add(time.Hour)}
+	assert.NoError(t, store.RecordToken(token))
+	sessions, err := store.GetSessions(user.ID, now.Add(-time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, sessions, 2) {
+		assert.Equal(t, laptop.ID, sessions[0].ID)
+		assert.Equal(t, "Firefox 2", sessions[0].UserAgent)
+		assert.Equal(t, "10.0.0.3", sessions[0].IP)
+		assert.True(t, now.Add(time.Minute).Equal(sessions[0].LastUsedAt))
+	}
+	sessions, err = store.GetSessions(user.ID, now)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	session, err := store.GetTokenSession("first")
+	assert.NoError(t, err)
+	assert.Equal(t, laptop.ID, session.ID)
+	assert.Nil(t, session.RevokedAt)
+	_, err = store.GetTokenSession("unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Revoking a session revokes its refresh tokens and access tokens.
+	refresh := &RefreshToken{UserID: user.ID, FamilyID: "laptop", TokenHash: "hash", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	assert.NoError(t, store.CreateRefreshToken(refresh))
+	assert.ErrorIs(t, store.RevokeSession(other.ID, laptop.ID, now), ErrNotFound)
+	assert.NoError(t, store.RevokeSession(user.ID, laptop.ID, now.Add(2*time.Minute)))
+	session, err = store.GetTokenSession("first")
+	assert.NoError(t, err)
+	assert.NotNil(t, session.RevokedAt)
+	refreshed, err := store.GetRefreshToken("hash")
+	assert.NoError(t, err)
+	assert.NotNil(t, refreshed.RevokedAt)
+	sessions, err = store.GetSessions(user.ID, now.Add(-time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, sessions, 1) {
+		assert.Equal(t, phone.ID, sessions[0].ID)
+	}
+
+	// So does revoking its refresh token family, and expired tokens go.
+	assert.NoError(t, store.RevokeRefreshFamily("phone", now))
+	sessions, err = store.GetSessions(user.ID, now.Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+	assert.NoError(t, store.RecordToken(IssuedToken{JTI: "second", SessionID: phone.ID, IssuedAt: now.Add(time.Hour),
+		ExpiresAt: now.Add(2 * time.Hour)}))
+	_, err = store.GetTokenSession("first")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Revoking all of a user's sessions leaves other users logged in.
+	tablet := &Session{UserID: user.ID, FamilyID: "tablet", CreatedAt: now, LastUsedAt: now}
+	assert.NoError(t, store.CreateSession(tablet))
+	desktop := &Session{UserID: other.ID, FamilyID: "desktop", CreatedAt: now, LastUsedAt: now}
+	assert.NoError(t, store.CreateSession(desktop))
+	assert.NoError(t, store.CreateRefreshToken(&RefreshToken{UserID: user.ID, FamilyID: "tablet", TokenHash: "tablet",
+		CreatedAt: now, ExpiresAt: now.Add(time.Hour)}))
+	assert.NoError(t, store.RevokeSessions(user.ID, now))
+	sessions, err = store.GetSessions(user.ID, now.Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+	refreshed, err = store.GetRefreshToken("tablet")
+	assert.NoError(t, err)
+	assert.NotNil(t, refreshed.RevokedAt)
+	sessions, err = store.GetSessions(other.ID, now.Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+}
+
+func testPasswordResets(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+	now := time.Now().Truncate(time.Second)
+	first := &PasswordReset{UserID: user.ID, TokenHash: "first", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	assert.NoError(t, store.CreatePasswordReset(first))
+	assert.NotZero(t, first.ID)
+
+	// A new reset replaces the earlier one.
+	second := &PasswordReset{UserID: user.ID, TokenHash: "second", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	assert.NoError(t, store.CreatePasswordReset(second))
+	_, err := store.UsePasswordReset("first", now)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Each reset works once.
+	reset, err := store.UsePasswordReset("second", now.Add(time.Minute))
+	assert.NoError(t, err)
+	if assert.NotNil(t, reset) {
+		assert.Equal(t, user.ID, reset.UserID)
+		assert.True(t, now.Add(time.Hour).Equal(reset.ExpiresAt))
+		if assert.NotNil(t, reset.UsedAt) {
+			assert.True(t, now.Add(time.Minute).Equal(*reset.UsedAt))
+		}
+	}
+	_, err = store.UsePasswordReset("second", now.Add(time.Minute))
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = store.UsePasswordReset("unknown", now)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Password updates only apply to known users.
+	assert.NoError(t, store.UpdatePassword(user.ID, "rehashed"))
+	found, err := store.GetUserById(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "rehashed", found.Password)
+	assert.ErrorIs(t, store.UpdatePassword(user.ID+100, "rehashed"), ErrNotFound)
+}
+
+func testTOTP(t *testing.T, store Store) {
+	user := &User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(user))
+	other := &User{Email: "jane@example.com", FirstName: "Jane", LastName: "Doe", Password: "hashed"}
+	assert.NoError(t, store.CreateUser(other))
+	now := time.Now().Truncate(time.Second)
+	_, err := store.GetTOTP(user.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Enrolling again replaces the unconfirmed secret.
+	assert.NoError(t, store.CreateTOTP(&TOTP{UserID: user.ID, Secret: "first", CreatedAt: now}))
+	assert.NoError(t, store.CreateTOTP(&TOTP{UserID: user.ID, Secret: "second", CreatedAt: now}))
+	factor, err := store.GetTOTP(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", factor.Secret)
+	assert.False(t, factor.Confirmed())
+	assert.ErrorIs(t, store.UseTOTPStep(user.ID, 10, TOTPLockout{}, now), ErrNotFound)
+
+	assert.NoError(t, store.ConfirmTOTP(user.ID, 10, now, []string{"a", "b"}))
+	assert.ErrorIs(t, store.ConfirmTOTP(user.ID, 11, now, []string{"c"}), ErrNotFound)
+	assert.ErrorIs(t, store.CreateTOTP(&TOTP{UserID: user.ID, Secret: "third", CreatedAt: now}), ErrDuplicate)
+	factor, err = store.GetTOTP(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", factor.Secret)
+	assert.True(t, factor.Confirmed())
+	assert.Equal(t, int64(10), factor.LastUsedStep)
+
+	// Codes can't be replayed, and failed attempts are counted until a
+	// code works.
+	lockout := TOTPLockout{MaxAttempts: 2, Duration: time.Minute}
+	assert.ErrorIs(t, store.UseTOTPStep(user.ID, 10, lockout, now), ErrNotFound)
+	assert.NoError(t, store.FailTOTP(user.ID, lockout, now))
+	assert.NoError(t, store.FailTOTP(user.ID, lockout, now.Add(time.Second)))
+	factor, err = store.GetTOTP(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, factor.FailedAttempts)
+	if assert.NotNil(t, factor.FailedAt) {
+		assert.True(t, now.Add(time.Second).Equal(*factor.FailedAt))
+	}
+	assert.True(t, factor.Locked(lockout, now.Add(time.Second)))
+	assert.False(t, factor.Locked(lockout, now.Add(time.Hour)))
+
+	// While locked out, neither wrong nor right codes are taken.
+	assert.ErrorIs(t, store.FailTOTP(user.ID, lockout, now.Add(2*time.Second)), ErrLocked)
+	assert.ErrorIs(t, store.UseTOTPStep(user.ID, 12, lockout, now.Add(2*time.Second)), ErrLocked)
+	factor, err = store.GetTOTP(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, factor.FailedAttempts)
+	assert.Equal(t, int64(10), factor.LastUsedStep)
+
+	assert.NoError(t, store.UseTOTPStep(user.ID, 12, lockout, now.Add(time.Hour)))
+	factor, err = store.GetTOTP(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, factor.FailedAttempts)
+	assert.Equal(t, int64(12), factor.LastUsedStep)
+	assert.ErrorIs(t, store.FailTOTP(other.ID, lockout, now), ErrNotFound)
+
+	// Recovery codes work once, for their own user.
+	count, err := store.CountRecoveryCodes(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.ErrorIs(t, store.UseRecoveryCode(other.ID, "a", now), ErrNotFound)
+	assert.NoError(t, store.FailTOTP(user.ID, lockout, now))
+	assert.NoError(t, store.UseRecoveryCode(user.ID, "a", now))
+	assert.ErrorIs(t, store.UseRecoveryCode(user.ID, "a", now), ErrNotFound)
+	assert.ErrorIs(t, store.UseRecoveryCode(user.ID, "unknown", now), ErrNotFound)
+	count, err = store.CountRecoveryCodes(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	factor, err = store.GetTOTP(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, factor.FailedAttempts)
+
+	// Disabling removes the secret and the recovery codes.
+	assert.NoError(t, store.DeleteTOTP(user.ID))
+	assert.ErrorIs(t, store.DeleteTOTP(user.ID), ErrNotFound)
+	_, err = store.GetTOTP(user.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	count, err = store.CountRecoveryCodes(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+	assert.ErrorIs(t, store.UseRecoveryCode(user.ID, "b", now), ErrNotFound)
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// Tag is a user's label for todos. Names are unique per user, ignoring
+// case; Color is a "#rrggbb" value or empty.
+type Tag struct {
+	ID     int64  `db:"id" json:"id"`
+	UserID int64  `db:"user_id" json:"-"`
+	Name   string `db:"name" json:"name"`
+	Color  string `db:"color" json:"color"`
+}
+
+// sortTags orders tags the way the SQL backends return them.
+func sortTags(tags []Tag) {
+	sort.Slice(tags, func(i, j int) bool {
+		a, b := strings.ToLower(tags[i].Name), strings.ToLower(tags[j].Name)
+		if a != b {
+			return a < b
+		}
+		return tags[i].ID < tags[j].ID
+	})
+}
+
+// tagNames lower-cases names for matching against tags.
+func tagNames(names []string) []string {
+	lowered := make([]string, len(names))
+	for i, name := range names {
+		lowered[i] = strings.ToLower(name)
+	}
+	return lowered
+}
@@ -1,17 +1,24 @@
 package models
 
 import (
-	"encoding/json"
-	"fmt"
-	"project_todo/db"
 	"time"
 )
 
+// TodoItem is one checklist entry. Position is its zero-based index within
+// the todo's List, which is kept in Rank order. DueAt is read in the todo's
+// TimeZone.
 type TodoItem struct {
-	Item    string `json:"item"`
-	Checked bool   `json:"checked"`
+	ID       int64      `db:"id" json:"id"`
+	TodoID   int64      `db:"todo_id" json:"-"`
+	Item     string     `db:"item" json:"item"`
+	Checked  bool       `db:"checked" json:"checked"`
+	Position int        `db:"-" json:"position"`
+	Rank     string     `db:"rank" json:"-"`
+	DueAt    *time.Time `db:"due_at" json:"dueAt,omitempty"`
 }
 
+// Todo.Version starts at 1 and goes up by one with every change to the todo
+// or its items. It backs the ETag handed out by the API.
 type Todo struct {
 	ID        int64      `db:"id" json:"id"`
 	Title     string     `db:"title" json:"title"`
@@ -20,114 +27,67 @@ type Todo struct {
 	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
 	UpdatedAt time.Time  `db:"updated_at" json:"updatedAt"`
 	UserID    int64      `db:"user_id" json:"userId"`
+	Version   int64      `db:"version" json:"version"`
+	// DeletedAt is set while the todo sits in the trash.
+	DeletedAt *time.Time `db:"deleted_at" json:"deletedAt,omitempty"`
+	DueAt     *time.Time `db:"due_at" json:"dueAt,omitempty"`
+	// TimeZone is the IANA zone the due dates were set in, e.g.
+	// "Europe/Berlin"; empty means UTC.
+	TimeZone string `db:"time_zone" json:"timeZone,omitempty"`
+	// Recurrence is an RRULE (see ParseRule). Each todo of a series shares
+	// the SeriesID of the first one and links to the todo generated after
+	// it through NextID.
+	Recurrence string `db:"recurrence" json:"recurrence,omitempty"`
+	SeriesID   int64  `db:"series_id" json:"seriesId,omitempty"`
+	Occurrence int    `db:"occurrence" json:"occurrence,omitempty"`
+	NextID     int64  `db:"next_id" json:"nextId,omitempty"`
+	// Tags are managed through TagStore; UpdateTodo leaves them alone.
+	Tags     []Tag  `db:"tags" json:"tags,omitempty"`
+	Priority string `db:"priority" json:"priority,omitempty"`
+	// Rank is the todo's place in the user's manual order (see RankBetween).
+	// It is set by CreateTodo and MoveTodo only.
+	Rank string `db:"rank" json:"rank,omitempty"`
+	// ProjectID is zero for todos outside any project.
+	ProjectID int64 `db:"project_id" json:"projectId,omitempty"`
+	// WorkspaceID is set for todos that belong to a workspace rather than
+	// to UserID, who only created them.
+	WorkspaceID int64 `db:"workspace_id" json:"workspaceId,omitempty"`
+	// Shared and Role mark a todo listed for a user it was shared with; Role
+	// is that user's role on it.
+	Shared bool   `db:"-" json:"shared,omitempty"`
+	Role   string `db:"-" json:"role,omitempty"`
 }
 
-func (t *Todo) Save() error {
-
-	listJSON, err := json.Marshal(t.List)
-	fmt.Println("listJSON", listJSON)
-	if err != nil {
-		return err
-	}
-	// Convert listJSON to string
-	listJSONString := string(listJSON)
-
-	query := `
-	INSERT INTO todos(title, list, is_active, created_at, updated_at, user_id)
-	VALUES ($1, $2, $3, $4, $5, $6) RETURNING id
-	`
-	stmt, err := db.DB.Prepare(query)
-	if err != nil {
-		fmt.Println("Error preparing query:", err)
-		return err
-	}
-	defer stmt.Close()
-
-	err = stmt.QueryRow(t.Title, listJSONString, t.IsActive, t.CreatedAt, t.UpdatedAt, t.UserID).Scan(&t.ID)
-	return err
-}
-
-func GetAllTodos(userId int64) ([]Todo, error) {
-	query := "SELECT * FROM todos WHERE user_id = $1"
-	rows, err := db.DB.Query(query, userId)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var todos []Todo
-
-	for rows.Next() {
-		var todo Todo
-		var listJson []byte
-		err := rows.Scan(&todo.ID, &todo.Title, &listJson, &todo.IsActive, &todo.CreatedAt, &todo.UpdatedAt, &todo.UserID)
-		if err != nil {
-			return nil, err
-		}
-		// Unmarshal the JSONB field into the List slice
-		err = json.Unmarshal(listJson, &todo.List)
-		if err != nil {
-			return nil, err
-		}
-		todos = append(todos, todo)
-	}
-	return todos, nil
-}
-
-func GetTodoById(id int64) (*Todo, error) {
-	query := "SELECT * FROM todos where id = $1"
-	row := db.DB.QueryRow(query, id)
-	var todo Todo
-	var listJson []byte
-	err := row.Scan(&todo.ID, &todo.Title, &listJson, &todo.IsActive, &todo.CreatedAt, &todo.UpdatedAt, &todo.UserID)
-	if err != nil {
-		fmt.Println("Error in fetching todo", err)
-		return nil, err
-	}
-	// Unmarshal the JSONB field into the List slice
-	err = json.Unmarshal(listJson, &todo.List)
+// Location is the time zone the due dates were set in, falling back to UTC.
+func (t Todo) Location() *time.Location {
+	location, err := time.LoadLocation(t.TimeZone)
 	if err != nil {
-		fmt.Println("Error while unmarshaling", err)
-		return nil, err
+		return time.UTC
 	}
-	return &todo, nil
+	return location
 }
 
-func (t Todo) Update() error {
-	query := `
-	UPDATE todos
-	SET title =$1, list=$2, is_active=$3, updated_at=$4
-	WHERE id = $5
-	`
-	stmt, err := db.DB.Prepare(query)
-	if err != nil {
-		fmt.Println("error in preparing query")
-		return err
-	}
-	defer stmt.Close()
-	var listJSON []byte
-	listJSON, err = json.Marshal(t.List)
+// Priorities from most to least urgent. Todos created without one get
+// DefaultPriority.
+const (
+	P0              = "P0"
+	P1              = "P1"
+	P2              = "P2"
+	P3              = "P3"
+	DefaultPriority = P2
+)
 
-	if err != nil {
-		fmt.Println("error in marshaling json")
-		return err
+func IsValidPriority(priority string) bool {
+	switch priority {
+	case P0, P1, P2, P3:
+		return true
 	}
-
-	// Convert listJSON to string
-	listJSONString := string(listJSON)
-
-	_, err = stmt.Exec(t.Title, listJSONString, t.IsActive, t.UpdatedAt, t.ID)
-	return err
+	return false
 }
 
-func (t Todo) Delete() error {
-	query := "DELETE FROM todos WHERE id = $1"
-	stmt, err := db.DB.Prepare(query)
-
-	if err != nil {
-		return err
+func priorityOrDefault(priority string) string {
+	if priority == "" {
+		return DefaultPriority
 	}
-	defer stmt.Close()
-
-	_, err = stmt.Exec(t.ID)
-	return err
+	return priority
 }
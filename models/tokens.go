@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// RefreshToken is a refresh token handed out at login, stored by the hash
+// of its value. Each refresh swaps it for a new one in the same family, so
+// a family is one login; a token that is used twice means it leaked, and
+// the family is revoked.
+type RefreshToken struct {
+	ID        int64      `db:"id" json:"id"`
+	UserID    int64      `db:"user_id" json:"userId"`
+	FamilyID  string     `db:"family_id" json:"familyId"`
+	TokenHash string     `db:"token_hash" json:"-"`
+	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expiresAt"`
+	UsedAt    *time.Time `db:"used_at" json:"usedAt,omitempty"`
+	RevokedAt *time.Time `db:"revoked_at" json:"revokedAt,omitempty"`
+}
+
+func (t RefreshToken) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrLocked is returned for codes entered while a TOTP is locked out.
+var ErrLocked = errors.New("too many invalid codes")
+
+// TOTPLockout refuses codes for Duration after MaxAttempts wrong ones in
+// a row.
+type TOTPLockout struct {
+	MaxAttempts int
+	Duration    time.Duration
+}
+
+// TOTP is a user's authenticator app. It only guards logins once
+// ConfirmedAt is set, after the user entered a first code from the app.
+type TOTP struct {
+	UserID int64 `db:"user_id" json:"userId"`
+	// Secret has to be kept as is to compute the codes, unlike tokens.
+	Secret      string     `db:"secret" json:"-"`
+	CreatedAt   time.Time  `db:"created_at" json:"createdAt"`
+	ConfirmedAt *time.Time `db:"confirmed_at" json:"confirmedAt"`
+	// LastUsedStep is the period of the last code accepted; codes from it
+	// or before are refused so a code can't be replayed.
+	LastUsedStep   int64      `db:"last_used_step" json:"-"`
+	FailedAttempts int        `db:"failed_attempts" json:"-"`
+	FailedAt       *time.Time `db:"failed_at" json:"-"`
+}
+
+func (f TOTP) Confirmed() bool {
+	return f.ConfirmedAt != nil
+}
+
+// Locked reports whether too many wrong codes were entered lately to try
+// another one.
+func (f TOTP) Locked(lockout TOTPLockout, now time.Time) bool {
+	return f.FailedAttempts >= lockout.MaxAttempts && f.FailedAt != nil && now.Sub(*f.FailedAt) < lockout.Duration
+}
+
+// RecoveryCode signs a user in once in place of a TOTP code.
+type RecoveryCode struct {
+	ID       int64      `db:"id" json:"id"`
+	UserID   int64      `db:"user_id" json:"userId"`
+	CodeHash string     `db:"code_hash" json:"-"`
+	UsedAt   *time.Time `db:"used_at" json:"usedAt,omitempty"`
+}
@@ -2,8 +2,6 @@ package models
 
 import (
 	"errors"
-	"fmt"
-	"project_todo/db"
 	"project_todo/utils"
 	"time"
 )
@@ -17,41 +15,49 @@ type User struct {
 	IsActive  bool      `db:"is_active" json:"isActive"`
 	CreatedAt time.Time `db:"created_at" json:"createdAt"`
 	UpdatedAt time.Time `db:"updated_at" json:"updatedAt"`
+	// EmailVerifiedAt is nil until the user follows the link mailed to them
+	// on signup.
+	EmailVerifiedAt    *time.Time `db:"email_verified_at" json:"emailVerifiedAt"`
+	VerificationSentAt *time.Time `db:"verification_sent_at" json:"-"`
 }
 
-func (u *User) Save() error {
-	query := `INSERT INTO users(email, first_name, last_name, password, is_active, created_at, updated_at)
-	VALUES ($1,$2, $3, $4, $5, $6, $7) RETURNING id`
-	stmt, err := db.DB.Prepare(query)
+func (u *User) Save(store UserStore) error {
+	hashedPassword, err := utils.HashPassword(u.Password)
 	if err != nil {
-		fmt.Println(err)
 		return err
 	}
-	defer stmt.Close()
 
-	hashedPassword, err := utils.HashPassword(u.Password)
+	user := *u
+	user.Password = hashedPassword
+	err = store.CreateUser(&user)
 	if err != nil {
 		return err
 	}
-
-	err = db.DB.QueryRow(query, u.Email, u.FirstName, u.LastName, hashedPassword, u.IsActive, u.CreatedAt, u.UpdatedAt).Scan(&u.ID)
-	// Scan should has a destination pointer
-	fmt.Println(err)
-	return err
+	u.ID = user.ID
+	return nil
 }
 
-func (u *User) ValidateCredentials() error {
-	query := "SELECT id, password from users where email = $1"
-	row := db.DB.QueryRow(query, u.Email)
-	var existingPassword string
-	err := row.Scan(&u.ID, &existingPassword)
+func (u *User) ValidateCredentials(store UserStore) error {
+	existing, err := store.GetUserByEmail(u.Email)
 	if err != nil {
 		return errors.New("Invalid Credentials")
 	}
 
-	isValid := utils.ComparePassword(u.Password, existingPassword)
+	isValid := utils.ComparePassword(u.Password, existing.Password)
 	if !isValid {
 		return errors.New("Invalid Credentials")
 	}
+	u.ID = existing.ID
+	u.CreatedAt = existing.CreatedAt
+	u.EmailVerifiedAt = existing.EmailVerifiedAt
 	return nil
 }
+
+// SetPassword hashes password and stores it as the user's new password.
+func (u *User) SetPassword(store UserStore, password string) error {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return store.UpdatePassword(u.ID, hashedPassword)
+}
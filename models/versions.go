@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// UndoTTL is how long the undo token handed out for a change can be used.
+const UndoTTL = 5 * time.Minute
+
+// TodoVersion is a todo's title and checklist as they were at Version.
+// UpdateTodo saves one before every change; CreatedAt is when that version
+// was replaced.
+type TodoVersion struct {
+	TodoID    int64      `db:"todo_id" json:"todoId"`
+	Version   int64      `db:"version" json:"version"`
+	Title     string     `db:"title" json:"title"`
+	List      []TodoItem `db:"list" json:"list"`
+	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
+}
+
+// Undo lets UserID take back a change to a todo by presenting Token, as
+// long as the todo is still at Version, the version the change left it at.
+// Action is HistoryTrashed or HistoryUpdated.
+type Undo struct {
+	Token     string    `db:"token" json:"token"`
+	TodoID    int64     `db:"todo_id" json:"todoId"`
+	UserID    int64     `db:"user_id" json:"userId"`
+	Action    string    `db:"action" json:"action"`
+	Version   int64     `db:"version" json:"version"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+	ExpiresAt time.Time `db:"expires_at" json:"expiresAt"`
+}
+
+func (u Undo) Expired(now time.Time) bool {
+	return !now.Before(u.ExpiresAt)
+}
@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+// Workspace roles, from most to least powerful. Every workspace has one
+// owner, the user who created it.
+const (
+	WorkspaceOwner  = "owner"
+	WorkspaceAdmin  = "admin"
+	WorkspaceMember = "member"
+	WorkspaceGuest  = "guest"
+)
+
+// InviteTTL is how long an invite can be accepted.
+const InviteTTL = 7 * 24 * time.Hour
+
+// Workspace is a team that owns todos together. Role is the requesting
+// user's role, filled in by GetWorkspaces.
+type Workspace struct {
+	ID        int64     `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	Role      string    `db:"-" json:"role,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt time.Time `db:"updated_at" json:"updatedAt"`
+}
+
+type Member struct {
+	WorkspaceID int64     `db:"workspace_id" json:"workspaceId"`
+	UserID      int64     `db:"user_id" json:"userId"`
+	Email       string    `db:"email" json:"email"`
+	FirstName   string    `db:"first_name" json:"firstName"`
+	LastName    string    `db:"last_name" json:"lastName"`
+	Role        string    `db:"role" json:"role"`
+	CreatedAt   time.Time `db:"created_at" json:"createdAt"`
+}
+
+// Invite asks whoever signs up or logs in with Email to join a workspace.
+// It is accepted by presenting Token. WorkspaceName is filled in by
+// GetInvitesForEmail.
+type Invite struct {
+	ID            int64      `db:"id" json:"id"`
+	WorkspaceID   int64      `db:"workspace_id" json:"workspaceId"`
+	WorkspaceName string     `db:"-" json:"workspaceName,omitempty"`
+	Email         string     `db:"email" json:"email"`
+	Role          string     `db:"role" json:"role"`
+	Token         string     `db:"token" json:"token"`
+	InvitedBy     int64      `db:"invited_by" json:"invitedBy"`
+	CreatedAt     time.Time  `db:"created_at" json:"createdAt"`
+	AcceptedAt    *time.Time `db:"accepted_at" json:"acceptedAt,omitempty"`
+}
+
+func (i Invite) Expired(now time.Time) bool {
+	return now.Sub(i.CreatedAt) > InviteTTL
+}
+
+// WorkspaceAllows is the workspace counterpart of Allows. Owners may do
+// anything, admins anything but deleting the workspace, members may work
+// on its todos and guests only look at them. Workspace todos are shared
+// through membership, so ActionShare is never allowed.
+func WorkspaceAllows(role string, action Action) bool {
+	switch {
+	case action == ActionShare:
+		return false
+	case role == WorkspaceOwner:
+		return true
+	case role == WorkspaceAdmin:
+		return action != ActionDeleteWorkspace
+	case role == WorkspaceMember:
+		return action == ActionView || action == ActionEdit || action == ActionOrganize || action == ActionDelete
+	case role == WorkspaceGuest:
+		return action == ActionView
+	}
+	return false
+}
+
+// IsValidMemberRole reports whether role can be given through an invite or
+// a role change; ownership can't be handed out.
+func IsValidMemberRole(role string) bool {
+	return role == WorkspaceAdmin || role == WorkspaceMember || role == WorkspaceGuest
+}
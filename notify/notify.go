@@ -0,0 +1,48 @@
+// Package notify delivers messages to users. The server only depends on
+// the Notifier interface; the implementations here are meant for local
+// development and can be swapped for email, push or chat integrations.
+package notify
+
+import (
+	"fmt"
+	"os"
+)
+
+type Notification struct {
+	UserID  int64  `json:"userId"`
+	Email   string `json:"email"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+type Notifier interface {
+	Notify(notification Notification) error
+}
+
+const DefaultFile = "notifications.log"
+
+// FromEnv picks the notifier named by NOTIFIER: "log" (the default) prints
+// to stdout, "file" appends JSON lines to NOTIFIER_FILE.
+func FromEnv() Notifier {
+	switch kind := os.Getenv("NOTIFIER"); kind {
+	case "", "log":
+		return LogNotifier{}
+	case "file":
+		path := os.Getenv("NOTIFIER_FILE")
+		if path == "" {
+			path = DefaultFile
+		}
+		return NewFileNotifier(path)
+	default:
+		fmt.Println("Unknown NOTIFIER", kind, "- logging notifications instead")
+		return LogNotifier{}
+	}
+}
+
+// LogNotifier prints notifications instead of sending them.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(n Notification) error {
+	fmt.Printf("Notification for %s: %s\n%s\n", n.Email, n.Subject, n.Body)
+	return nil
+}
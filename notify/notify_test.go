@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileNotifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.log")
+	notifier := NewFileNotifier(path)
+	assert.NoError(t, notifier.Notify(Notification{UserID: 1, Email: "john@example.com", Subject: "First"}))
+	assert.NoError(t, notifier.Notify(Notification{UserID: 1, Email: "john@example.com", Subject: "Second"}))
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	var subjects []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var n Notification
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &n))
+		subjects = append(subjects, n.Subject)
+	}
+	assert.Equal(t, []string{"First", "Second"}, subjects)
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("NOTIFIER", "")
+	assert.IsType(t, LogNotifier{}, FromEnv())
+
+	t.Setenv("NOTIFIER", "file")
+	t.Setenv("NOTIFIER_FILE", "reminders.log")
+	notifier, ok := FromEnv().(*FileNotifier)
+	assert.True(t, ok)
+	assert.Equal(t, "reminders.log", notifier.Path)
+
+	t.Setenv("NOTIFIER", "pager")
+	assert.IsType(t, LogNotifier{}, FromEnv())
+}
@@ -0,0 +1,143 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"project_todo/mail"
+	"project_todo/models"
+	"project_todo/utils"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// These tests drive the real router against models.MemoryStore, so the
+// middleware, handlers and store semantics are exercised together.
+
+type discardMailer struct{}
+
+func (discardMailer) Send(mail.Message) error {
+	return nil
+}
+
+func newTestServer() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	RegisterRoutes(server, models.NewMemoryStore(), discardMailer{})
+	return server
+}
+
+func doRequest(server *gin.Engine, method, path, token, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	server.ServeHTTP(w, req)
+	return w
+}
+
+// doRequestIfMatch is doRequest for writes that need an If-Match header.
+func doRequestIfMatch(server *gin.Engine, method, path, token, ifMatch, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	req.Header.Set("If-Match", ifMatch)
+	server.ServeHTTP(w, req)
+	return w
+}
+
+// signup creates a user with a verified email.
+func signup(t *testing.T, server *gin.Engine, email string) {
+	w := doRequest(server, "POST", "/signup", "", `{"email":"`+email+`","firstName":"John","lastName":"Doe","password":"password123"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	token, err := utils.GenerateEmailToken(email)
+	assert.NoError(t, err)
+	w = doRequest(server, "GET", "/verify-email?token="+token, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func signupAndLogin(t *testing.T, server *gin.Engine, email string) string {
+	signup(t, server, email)
+
+	w := doRequest(server, "POST", "/login", "", `{"email":"`+email+`","password":"password123"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Token string `json:"token"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response.Token
+}
+
+func TestAPI_TodoLifecycle(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+
+	w := doRequest(server, "POST", "/todos", token, `{"title":"Groceries","list":[{"item":"milk","checked":false}]}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = doRequest(server, "GET", "/todos", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var todos []models.Todo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &todos))
+	assert.Len(t, todos, 1)
+	assert.Equal(t, int64(1), todos[0].ID)
+	assert.Equal(t, "Groceries", todos[0].Title)
+
+	w = doRequestIfMatch(server, "PUT", "/todos/1", token, `"1"`, `{"title":"Weekly groceries","list":[{"item":"milk","checked":true}],"isActive":true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	w = doRequest(server, "GET", "/todos/1", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	var todo models.Todo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &todo))
+	assert.Equal(t, "Weekly groceries", todo.Title)
+	assert.True(t, todo.List[0].Checked)
+
+	w = doRequestIfMatch(server, "DELETE", "/todos/1", token, `"2"`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doRequest(server, "GET", "/todos/1", token, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAPI_TodosAreScopedToTheUser(t *testing.T) {
+	server := newTestServer()
+	owner := signupAndLogin(t, server, "owner@example.com")
+	other := signupAndLogin(t, server, "other@example.com")
+
+	w := doRequest(server, "POST", "/todos", owner, `{"title":"Private"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = doRequest(server, "GET", "/todos", other, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `null`, w.Body.String())
+
+	w = doRequest(server, "GET", "/todos/1", other, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doRequest(server, "DELETE", "/todos/1", other, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAPI_DuplicateSignup(t *testing.T) {
+	server := newTestServer()
+	signupAndLogin(t, server, "john@example.com")
+
+	w := doRequest(server, "POST", "/signup", "", `{"email":"john@example.com","firstName":"John","lastName":"Doe","password":"password123"}`)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestAPI_RequiresToken(t *testing.T) {
+	server := newTestServer()
+
+	w := doRequest(server, "GET", "/todos", "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
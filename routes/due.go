@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"project_todo/models"
+	"time"
+)
+
+// Views accepted by GET /todos?due=.
+const (
+	dueToday   = "today"
+	dueOverdue = "overdue"
+	dueWeek    = "week"
+)
+
+// dueRange turns a due view into a range of due dates. Days start at
+// midnight in location: today is the rest of the current day, week the
+// next seven days including today, and overdue everything due before now.
+func dueRange(view string, location *time.Location, now time.Time) (from, to *time.Time, err error) {
+	now = now.In(location)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	switch view {
+	case dueToday:
+		end := midnight.AddDate(0, 0, 1)
+		return &midnight, &end, nil
+	case dueWeek:
+		end := midnight.AddDate(0, 0, 7)
+		return &midnight, &end, nil
+	case dueOverdue:
+		return nil, &now, nil
+	}
+	return nil, nil, errors.New("Invalid due")
+}
+
+// checkTimeZone makes sure the todo's time zone is one the server knows.
+func checkTimeZone(todo models.Todo) error {
+	if todo.TimeZone == "" {
+		return nil
+	}
+	_, err := time.LoadLocation(todo.TimeZone)
+	if err != nil {
+		return errors.New("Invalid time zone")
+	}
+	return nil
+}
+
+// optionalTime tells an explicit null, which clears a due date, apart from
+// a field that was left out.
+type optionalTime struct {
+	set   bool
+	value *time.Time
+}
+
+func (o *optionalTime) UnmarshalJSON(data []byte) error {
+	o.set = true
+	return json.Unmarshal(data, &o.value)
+}
@@ -0,0 +1,97 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDueRange(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	now := time.Date(2024, 3, 30, 23, 30, 0, 0, time.UTC) // 00:30 on the 31st in Berlin
+
+	from, to, err := dueRange("today", berlin, now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 31, 0, 0, 0, 0, berlin), *from)
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, berlin), *to)
+
+	from, to, err = dueRange("week", time.UTC, now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC), *from)
+	assert.Equal(t, time.Date(2024, 4, 6, 0, 0, 0, 0, time.UTC), *to)
+
+	from, to, err = dueRange("overdue", berlin, now)
+	assert.NoError(t, err)
+	assert.Nil(t, from)
+	assert.True(t, now.Equal(*to))
+
+	_, _, err = dueRange("someday", time.UTC, now)
+	assert.Error(t, err)
+}
+
+func TestGetAllTodos_DueViews(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	now := time.Now().UTC()
+	due := func(d time.Duration) string {
+		return now.Add(d).Format(time.RFC3339)
+	}
+	doRequest(server, "POST", "/todos", token, fmt.Sprintf(`{"title":"Late","dueAt":%q}`, due(-48*time.Hour)))
+	doRequest(server, "POST", "/todos", token, fmt.Sprintf(`{"title":"Soon","dueAt":%q}`, due(72*time.Hour)))
+	doRequest(server, "POST", "/todos", token, fmt.Sprintf(`{"title":"Late item","list":[{"item":"call","dueAt":%q}]}`, due(-time.Hour)))
+	doRequest(server, "POST", "/todos", token, `{"title":"Whenever"}`)
+
+	w := doRequest(server, "GET", "/todos?due=overdue", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"Late", "Late item"}, todoTitles(t, w.Body.Bytes()))
+
+	w = doRequest(server, "GET", "/todos?due=week&tz=America/New_York", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, todoTitles(t, w.Body.Bytes()), "Soon")
+	assert.NotContains(t, todoTitles(t, w.Body.Bytes()), "Whenever")
+
+	w = doRequest(server, "GET", "/todos?due=someday", token, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid due")
+
+	w = doRequest(server, "GET", "/todos?due=today&tz=Mars/Olympus", token, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid tz")
+}
+
+func TestDueDates_TimeZoneAndItems(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+
+	w := doRequest(server, "POST", "/todos", token, `{"title":"Trip","timeZone":"Mars/Olympus"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid time zone")
+
+	w = doRequest(server, "POST", "/todos", token, `{"title":"Trip","dueAt":"2030-05-01T09:00:00+02:00","timeZone":"Europe/Berlin","list":[{"item":"pack"}]}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	todo := getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, "Europe/Berlin", todo.TimeZone)
+	assert.True(t, time.Date(2030, 5, 1, 7, 0, 0, 0, time.UTC).Equal(*todo.DueAt))
+
+	w = doRequestIfMatch(server, "PUT", "/todos/1", token, "*", `{"title":"Trip","timeZone":"Nowhere"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	itemId := todo.List[0].ID
+	path := fmt.Sprintf("/todos/1/items/%d", itemId)
+	w = doRequestIfMatch(server, "PATCH", path, token, "*", `{"dueAt":"2030-04-30T18:00:00Z"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	todo = getTodo(t, server, token, "/todos/1")
+	assert.NotNil(t, todo.List[0].DueAt)
+
+	// Leaving dueAt out keeps it; null clears it.
+	doRequestIfMatch(server, "PATCH", path, token, "*", `{"checked":true}`)
+	todo = getTodo(t, server, token, "/todos/1")
+	assert.NotNil(t, todo.List[0].DueAt)
+	doRequestIfMatch(server, "PATCH", path, token, "*", `{"dueAt":null}`)
+	todo = getTodo(t, server, token, "/todos/1")
+	assert.Nil(t, todo.List[0].DueAt)
+}
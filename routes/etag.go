@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"net/http"
+	"project_todo/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etag is the strong entity tag of a todo at the given version.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatch checks the If-Match header of a write against the todo's current
+// version. It answers 428 when the header is missing and 412 when no tag
+// matches. The returned version is what the store should check the write
+// against; it is zero for "*", which matches any current version.
+func ifMatch(context *gin.Context, todo *models.Todo) (int64, bool) {
+	header := strings.TrimSpace(context.GetHeader("If-Match"))
+	if header == "" {
+		context.JSON(http.StatusPreconditionRequired, gin.H{"message": "If-Match header required"})
+		return 0, false
+	}
+	if header == "*" {
+		return 0, true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == etag(todo.Version) {
+			return todo.Version, true
+		}
+	}
+	context.Header("ETag", etag(todo.Version))
+	preconditionFailed(context)
+	return 0, false
+}
+
+func preconditionFailed(context *gin.Context) {
+	context.JSON(http.StatusPreconditionFailed, gin.H{"message": "Todo has been modified"})
+}
@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"project_todo/models"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETag_RequiresIfMatch(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	w := doRequest(server, "POST", "/todos", token, `{"title":"Groceries"}`)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+
+	w = doRequest(server, "PUT", "/todos/1", token, `{"title":"Weekly groceries"}`)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	assert.JSONEq(t, `{"message":"If-Match header required"}`, w.Body.String())
+
+	w = doRequest(server, "DELETE", "/todos/1", token, "")
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	todo := getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, "Groceries", todo.Title)
+}
+
+func TestETag_StaleWritesFail(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	doRequest(server, "POST", "/todos", token, `{"title":"Groceries","list":[{"item":"milk"}]}`)
+
+	// Another tab ticks an item, which moves the todo to version 2.
+	w := doRequestIfMatch(server, "PATCH", "/todos/1/items/1", token, `"1"`, `{"checked":true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	w = doRequestIfMatch(server, "PUT", "/todos/1", token, `"1"`, `{"title":"Weekly groceries","list":[{"id":1,"item":"milk"}]}`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	assert.JSONEq(t, `{"message":"Todo has been modified"}`, w.Body.String())
+
+	w = doRequestIfMatch(server, "DELETE", "/todos/1", token, `"1"`, "")
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	// Weak tags never match for If-Match.
+	w = doRequestIfMatch(server, "DELETE", "/todos/1", token, `W/"2"`, "")
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	todo := getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, "Groceries", todo.Title)
+	assert.True(t, todo.List[0].Checked)
+
+	w = doRequestIfMatch(server, "PUT", "/todos/1", token, `"1", "2"`, `{"title":"Weekly groceries","list":[{"id":1,"item":"milk"}]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+
+	// Skipping the check still tells the client the new version.
+	w = doRequestIfMatch(server, "PUT", "/todos/1", token, "*", `{"title":"Groceries","list":[{"id":1,"item":"milk"}]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+
+	w = doRequestIfMatch(server, "DELETE", "/todos/1", token, "*", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestETag_ConcurrentWriteFails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
+
+	store.getTodoById = func(id int64) (*models.Todo, error) {
+		return &models.Todo{ID: 1, Title: "Groceries", UserID: 10, Version: 5}, nil
+	}
+	// The todo changes between the handler's read and its write.
+	store.updateTodo = func(todo models.Todo) error {
+		return models.ErrVersionConflict
+	}
+
+	c.Set("userId", int64(10))
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+	c.Request = httptest.NewRequest("PUT", "/todos/1", strings.NewReader(`{"title":"Weekly groceries"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("If-Match", `"5"`)
+	h.updateTodoById(c)
+
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.JSONEq(t, `{"message":"Todo has been modified"}`, w.Body.String())
+}
@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"project_todo/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getHistory lists the changes made to a todo, newest first, to anyone who
+// may view it, also while it is in the trash.
+func (h *handler) getHistory(context *gin.Context) {
+	todoId, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse todo id"})
+		return
+	}
+	todo, err := h.store.GetTodoById(todoId)
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Todo not found"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch todo"})
+		return
+	}
+	if !h.authorize(context, todo, models.ActionView, "Unauthorized access") {
+		return
+	}
+	history, err := h.store.GetHistory(todo.ID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch history"})
+		return
+	}
+	if history == nil {
+		history = []models.HistoryEntry{}
+	}
+	context.JSON(http.StatusOK, history)
+}
@@ -0,0 +1,75 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"project_todo/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	server := newTestServer()
+	owner := signupAndLogin(t, server, "john@example.com")
+	editor := signupAndLogin(t, server, "jane@example.com")
+	stranger := signupAndLogin(t, server, "jim@example.com")
+	doRequest(server, "POST", "/todos", owner, `{"title":"Groceries","list":[{"item":"milk"},{"item":"eggs"}]}`)
+	doRequest(server, "POST", "/todos/1/shares", owner, `{"email":"jane@example.com","role":"editor"}`)
+
+	// The editor removes an item; the history says who and what.
+	w := doRequestIfMatch(server, "DELETE", "/todos/1/items/2", editor, "*", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doPatch(server, "/todos/1", owner, mergePatchType, "*", `{"title":"Weekly groceries"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	doRequestIfMatch(server, "DELETE", "/todos/1", owner, "*", "")
+
+	w = doRequest(server, "GET", "/todos/1/history", stranger, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(server, "GET", "/todos/1/history", editor, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var history []models.HistoryEntry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	if assert.Len(t, history, 4) {
+		assert.Equal(t, models.HistoryTrashed, history[0].Action)
+		assert.Equal(t, models.TodoDiff{}, history[0].Diff)
+
+		assert.Equal(t, models.HistoryUpdated, history[1].Action)
+		assert.Equal(t, &models.TitleChange{From: "Groceries", To: "Weekly groceries"}, history[1].Diff.Title)
+
+		assert.Equal(t, "jane@example.com", history[2].ActorEmail)
+		assert.Equal(t, []models.ItemState{{ID: 2, Item: "eggs"}}, history[2].Diff.Removed)
+
+		assert.Equal(t, models.HistoryCreated, history[3].Action)
+		assert.Equal(t, "john@example.com", history[3].ActorEmail)
+		assert.Len(t, history[3].Diff.Added, 2)
+	}
+
+	w = doRequest(server, "DELETE", "/todos/trash", owner, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "GET", "/todos/1/history", owner, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHistory_ProjectDelete(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	doRequest(server, "POST", "/projects", token, `{"name":"Home"}`)
+	doRequest(server, "POST", "/todos", token, `{"title":"Chores","projectId":1}`)
+
+	// Todos going to the trash with their project are in the history too.
+	w := doRequest(server, "DELETE", "/projects/1", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "POST", "/todos/1/restore", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doRequest(server, "GET", "/todos/1/history", token, "")
+	var history []models.HistoryEntry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	var actions []string
+	for _, entry := range history {
+		actions = append(actions, entry.Action)
+		assert.Equal(t, "john@example.com", entry.ActorEmail)
+	}
+	assert.Equal(t, []string{models.HistoryRestored, models.HistoryTrashed, models.HistoryCreated}, actions)
+}
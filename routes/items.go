@@ -0,0 +1,193 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"project_todo/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type itemRequest struct {
+	Item     *string      `json:"item"`
+	Checked  *bool        `json:"checked"`
+	Position *int         `json:"position"`
+	DueAt    optionalTime `json:"dueAt"`
+}
+
+// accessibleTodo loads the todo named by the :id param and checks the
+// authenticated user may perform action on it. On failure it writes the
+// response and returns nil.
+func (h *handler) accessibleTodo(context *gin.Context, action models.Action) *models.Todo {
+	todoId, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse todo id"})
+		return nil
+	}
+	todo, err := h.store.GetTodoById(todoId)
+	if errors.Is(err, models.ErrNotFound) || (err == nil && todo.DeletedAt != nil) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Todo not found"})
+		return nil
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch todo"})
+		return nil
+	}
+	if !h.authorize(context, todo, action, "Unauthorized access") {
+		return nil
+	}
+	return todo
+}
+
+// todoItem loads the item named by :itemId within todo.
+func (h *handler) todoItem(context *gin.Context, todo *models.Todo) *models.TodoItem {
+	itemId, err := strconv.ParseInt(context.Param("itemId"), 10, 64)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse item id"})
+		return nil
+	}
+	item, err := h.store.GetItem(todo.ID, itemId)
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Item not found"})
+		return nil
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch item"})
+		return nil
+	}
+	return item
+}
+
+// itemsETag sets the ETag of the todo whose items just changed. On failure
+// it writes the response and returns false.
+func (h *handler) itemsETag(context *gin.Context, todoId int64) bool {
+	updated, err := h.store.GetTodoById(todoId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch todo"})
+		return false
+	}
+	context.Header("ETag", etag(updated.Version))
+	return true
+}
+
+func (h *handler) createItem(context *gin.Context) {
+	todo := h.accessibleTodo(context, models.ActionEdit)
+	if todo == nil {
+		return
+	}
+	version, ok := ifMatch(context, todo)
+	if !ok {
+		return
+	}
+
+	var request itemRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil || request.Item == nil || (request.Position != nil && *request.Position < 0) {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+		return
+	}
+	item := models.TodoItem{TodoID: todo.ID, Item: *request.Item, Position: -1, DueAt: request.DueAt.value}
+	if request.Checked != nil {
+		item.Checked = *request.Checked
+	}
+	if request.Position != nil {
+		item.Position = *request.Position
+	}
+
+	err = h.store.CreateItem(&item, version, context.GetInt64("userId"))
+	if errors.Is(err, models.ErrVersionConflict) {
+		preconditionFailed(context)
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to create item"})
+		return
+	}
+	if !h.itemsETag(context, todo.ID) {
+		return
+	}
+	context.JSON(http.StatusCreated, gin.H{"message": "Item created", "item": item})
+}
+
+func (h *handler) updateItem(context *gin.Context) {
+	todo := h.accessibleTodo(context, models.ActionEdit)
+	if todo == nil {
+		return
+	}
+	item := h.todoItem(context, todo)
+	if item == nil {
+		return
+	}
+	version, ok := ifMatch(context, todo)
+	if !ok {
+		return
+	}
+
+	var request itemRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil || (request.Position != nil && *request.Position < 0) {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+		return
+	}
+	if request.Item != nil {
+		item.Item = *request.Item
+	}
+	if request.Checked != nil {
+		item.Checked = *request.Checked
+	}
+	if request.Position != nil {
+		item.Position = *request.Position
+	}
+	if request.DueAt.set {
+		item.DueAt = request.DueAt.value
+	}
+
+	err = h.store.UpdateItem(*item, version, context.GetInt64("userId"))
+	if errors.Is(err, models.ErrVersionConflict) {
+		preconditionFailed(context)
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to update item"})
+		return
+	}
+	if !h.itemsETag(context, todo.ID) {
+		return
+	}
+	item, err = h.store.GetItem(todo.ID, item.ID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch item"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Item updated", "item": item})
+}
+
+func (h *handler) deleteItem(context *gin.Context) {
+	todo := h.accessibleTodo(context, models.ActionEdit)
+	if todo == nil {
+		return
+	}
+	item := h.todoItem(context, todo)
+	if item == nil {
+		return
+	}
+	version, ok := ifMatch(context, todo)
+	if !ok {
+		return
+	}
+
+	err := h.store.DeleteItem(*item, version, context.GetInt64("userId"))
+	if errors.Is(err, models.ErrVersionConflict) {
+		preconditionFailed(context)
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to delete item"})
+		return
+	}
+	if !h.itemsETag(context, todo.ID) {
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Item deleted successfully"})
+}
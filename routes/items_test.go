@@ -0,0 +1,126 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"project_todo/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTodo(t *testing.T, server *gin.Engine, token, path string) models.Todo {
+	w := doRequest(server, "GET", path, token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var todo models.Todo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &todo))
+	return todo
+}
+
+func TestItems_CreateUpdateDelete(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	w := doRequest(server, "POST", "/todos", token, `{"title":"Packing","list":[{"item":"tent"}]}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = doRequestIfMatch(server, "POST", "/todos/1/items", token, `"1"`, `{"item":"stove"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	assert.JSONEq(t, `{"message":"Item created","item":{"id":2,"item":"stove","checked":false,"position":1}}`, w.Body.String())
+
+	w = doRequestIfMatch(server, "POST", "/todos/1/items", token, `"2"`, `{"item":"map","checked":true,"position":0}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	todo := getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, []string{"map", "tent", "stove"}, itemNames(todo))
+
+	w = doRequestIfMatch(server, "PATCH", "/todos/1/items/1", token, `"3"`, `{"checked":true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+	assert.JSONEq(t, `{"message":"Item updated","item":{"id":1,"item":"tent","checked":true,"position":1}}`, w.Body.String())
+
+	w = doRequestIfMatch(server, "PATCH", "/todos/1/items/2", token, `"4"`, `{"position":0}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	todo = getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, []string{"stove", "map", "tent"}, itemNames(todo))
+	assert.True(t, todo.List[2].Checked)
+
+	w = doRequestIfMatch(server, "DELETE", "/todos/1/items/3", token, `"5"`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"6"`, w.Header().Get("ETag"))
+	todo = getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, []string{"stove", "tent"}, itemNames(todo))
+	assert.Equal(t, 1, todo.List[1].Position)
+}
+
+func TestItems_Errors(t *testing.T) {
+	server := newTestServer()
+	owner := signupAndLogin(t, server, "owner@example.com")
+	other := signupAndLogin(t, server, "other@example.com")
+	doRequest(server, "POST", "/todos", owner, `{"title":"Packing","list":[{"item":"tent"}]}`)
+
+	w := doRequest(server, "POST", "/todos/1/items", other, `{"item":"stove"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doRequest(server, "PATCH", "/todos/1/items/1", other, `{"checked":true}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doRequestIfMatch(server, "POST", "/todos/1/items", owner, "*", `{"checked":true}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequestIfMatch(server, "PATCH", "/todos/1/items/1", owner, "*", `{"position":-1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequest(server, "PATCH", "/todos/1/items/99", owner, `{"checked":true}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(server, "DELETE", "/todos/2/items/1", owner, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestItems_IfMatch(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	doRequest(server, "POST", "/todos", token, `{"title":"Packing","list":[{"item":"tent"},{"item":"stove"}]}`)
+
+	w := doRequest(server, "POST", "/todos/1/items", token, `{"item":"lamp"}`)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	w = doRequest(server, "PATCH", "/todos/1/items/1", token, `{"checked":true}`)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	w = doRequest(server, "DELETE", "/todos/1/items/1", token, "")
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	w = doRequest(server, "POST", "/todos/1/items/reorder", token, `{"id":2,"before":1}`)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	// Another tab ticks an item, which moves the todo to version 2.
+	w = doRequestIfMatch(server, "PATCH", "/todos/1/items/1", token, `"1"`, `{"checked":true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doRequestIfMatch(server, "POST", "/todos/1/items", token, `"1"`, `{"item":"lamp"}`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	w = doRequestIfMatch(server, "PATCH", "/todos/1/items/2", token, `"1"`, `{"checked":true}`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = doRequestIfMatch(server, "DELETE", "/todos/1/items/1", token, `"1"`, "")
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = doRequestIfMatch(server, "POST", "/todos/1/items/reorder", token, `"1"`, `{"id":2,"before":1}`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	todo := getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, []string{"tent", "stove"}, itemNames(todo))
+	assert.False(t, todo.List[1].Checked)
+	assert.Equal(t, int64(2), todo.Version)
+
+	w = doRequestIfMatch(server, "DELETE", "/todos/1/items/1", token, `"2"`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+}
+
+func itemNames(todo models.Todo) []string {
+	var names []string
+	for _, item := range todo.List {
+		names = append(names, item.Item)
+	}
+	return names
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"os"
+	"project_todo/utils"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMain(m *testing.M) {
+	utils.HashCost = bcrypt.MinCost
+	// Mailed links need APP_URL; tests that read the mail set their own.
+	os.Setenv("APP_URL", "http://localhost:8080")
+	os.Exit(m.Run())
+}
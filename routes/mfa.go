@@ -0,0 +1,267 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"project_todo/models"
+	"project_todo/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTOTPIssuer = "Todo Checklist"
+	recoveryCodeCount = 10
+)
+
+// After five wrong codes in a row, codes are refused until 15 minutes have
+// passed since the last one; recovery codes still work.
+var totpLockout = models.TOTPLockout{MaxAttempts: 5, Duration: 15 * time.Minute}
+
+type totpCodeRequest struct {
+	Code string `binding:"required" json:"code"`
+}
+
+type secondFactorRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+type mfaLoginRequest struct {
+	MFAToken string `binding:"required" json:"mfaToken"`
+	secondFactorRequest
+}
+
+// totpIssuer names the app in authenticator apps, from TOTP_ISSUER.
+func totpIssuer() string {
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		return defaultTOTPIssuer
+	}
+	return issuer
+}
+
+// codesLocked answers a code entered while the factor is locked out.
+func codesLocked(context *gin.Context) {
+	context.JSON(http.StatusTooManyRequests, gin.H{"message": "Too many invalid codes, try again later or use a recovery code"})
+}
+
+// checkSecondFactor checks the TOTP code or recovery code in request
+// against the user's confirmed factor, answering the request itself when
+// it doesn't pass.
+func (h *handler) checkSecondFactor(context *gin.Context, factor *models.TOTP, request secondFactorRequest) bool {
+	now := time.Now()
+	if request.RecoveryCode != "" {
+		hash := utils.HashToken(utils.NormalizeRecoveryCode(request.RecoveryCode))
+		err := h.store.UseRecoveryCode(factor.UserID, hash, now)
+		if errors.Is(err, models.ErrNotFound) {
+			context.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid code"})
+			return false
+		}
+		if err != nil {
+			context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to check the code"})
+			return false
+		}
+		return true
+	}
+	if request.Code == "" {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Enter a code or a recovery code"})
+		return false
+	}
+
+	// The store checks the lockout along with recording the attempt, so
+	// concurrent guesses can't get past it.
+	step, ok := utils.MatchTOTP(factor.Secret, request.Code, now)
+	if !ok {
+		err := h.store.FailTOTP(factor.UserID, totpLockout, now)
+		if errors.Is(err, models.ErrLocked) {
+			codesLocked(context)
+			return false
+		}
+		if err != nil {
+			context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to check the code"})
+			return false
+		}
+		context.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid code"})
+		return false
+	}
+	err := h.store.UseTOTPStep(factor.UserID, step, totpLockout, now)
+	if errors.Is(err, models.ErrLocked) {
+		codesLocked(context)
+		return false
+	}
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid code"})
+		return false
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to check the code"})
+		return false
+	}
+	return true
+}
+
+// verifyMFA is the second step of logging in for users with two-factor
+// authentication: the challenge from login and a code get the tokens.
+func (h *handler) verifyMFA(context *gin.Context) {
+	var request mfaLoginRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Could not parse the request body"})
+		return
+	}
+	userId, err := utils.VerifyMFAToken(request.MFAToken)
+	if err != nil {
+		context.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired MFA token"})
+		return
+	}
+	user, err := h.store.GetUserById(userId)
+	if err != nil {
+		context.JSON(http.StatusUnauthorized, gin.H{"message": "Unable to authenticate the user"})
+		return
+	}
+	factor, err := h.store.GetTOTP(userId)
+	if errors.Is(err, models.ErrNotFound) || (err == nil && !factor.Confirmed()) {
+		context.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired MFA token"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to authenticate the user"})
+		return
+	}
+	if !h.checkSecondFactor(context, factor, request.secondFactorRequest) {
+		return
+	}
+	h.completeLogin(context, user)
+}
+
+// getTOTP tells whether the user has two-factor authentication on, and
+// how many recovery codes they have left.
+func (h *handler) getTOTP(context *gin.Context) {
+	userId := context.GetInt64("userId")
+	factor, err := h.store.GetTOTP(userId)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch two-factor authentication"})
+		return
+	}
+	count, err := h.store.CountRecoveryCodes(userId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch two-factor authentication"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"enabled": factor != nil && factor.Confirmed(), "recoveryCodesLeft": count})
+}
+
+// enrolTOTP starts setting up two-factor authentication with a new secret
+// for the user's authenticator app. It's only used for logging in once
+// confirmed with confirmTOTP.
+func (h *handler) enrolTOTP(context *gin.Context) {
+	user, err := h.store.GetUserById(context.GetInt64("userId"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to set up two-factor authentication"})
+		return
+	}
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to set up two-factor authentication"})
+		return
+	}
+	err = h.store.CreateTOTP(&models.TOTP{UserID: user.ID, Secret: secret, CreatedAt: time.Now()})
+	if errors.Is(err, models.ErrDuplicate) {
+		context.JSON(http.StatusConflict, gin.H{"message": "Two-factor authentication is already enabled"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to set up two-factor authentication"})
+		return
+	}
+	context.JSON(http.StatusCreated, gin.H{
+		"message": "Add the account to your authenticator app, then confirm with a code from it",
+		"secret":  secret,
+		"uri":     utils.TOTPURI(totpIssuer(), user.Email, secret),
+	})
+}
+
+// confirmTOTP turns two-factor authentication on once the user shows a
+// code from their app, and hands out recovery codes. They are only shown
+// here; the server keeps their hashes.
+func (h *handler) confirmTOTP(context *gin.Context) {
+	var request totpCodeRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Could not parse the request body"})
+		return
+	}
+	userId := context.GetInt64("userId")
+	factor, err := h.store.GetTOTP(userId)
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Two-factor authentication has not been set up"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to enable two-factor authentication"})
+		return
+	}
+	if factor.Confirmed() {
+		context.JSON(http.StatusConflict, gin.H{"message": "Two-factor authentication is already enabled"})
+		return
+	}
+	step, ok := utils.MatchTOTP(factor.Secret, request.Code, time.Now())
+	if !ok {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Invalid code"})
+		return
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		codes[i], err = utils.NewRecoveryCode()
+		if err != nil {
+			context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to enable two-factor authentication"})
+			return
+		}
+		hashes[i] = utils.HashToken(utils.NormalizeRecoveryCode(codes[i]))
+	}
+	err = h.store.ConfirmTOTP(userId, step, time.Now(), hashes)
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusConflict, gin.H{"message": "Two-factor authentication is already enabled"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to enable two-factor authentication"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication enabled", "recoveryCodes": codes})
+}
+
+// disableTOTP turns two-factor authentication off, which takes a code or
+// recovery code once it is on. An unconfirmed setup is just dropped.
+func (h *handler) disableTOTP(context *gin.Context) {
+	var request secondFactorRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Could not parse the request body"})
+		return
+	}
+	userId := context.GetInt64("userId")
+	factor, err := h.store.GetTOTP(userId)
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Two-factor authentication has not been set up"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to disable two-factor authentication"})
+		return
+	}
+	if factor.Confirmed() && !h.checkSecondFactor(context, factor, request) {
+		return
+	}
+	err = h.store.DeleteTOTP(userId)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to disable two-factor authentication"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
@@ -0,0 +1,173 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"project_todo/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type mfaChallenge struct {
+	MFARequired bool   `json:"mfaRequired"`
+	MFAToken    string `json:"mfaToken"`
+	Token       string `json:"token"`
+}
+
+// totpCode returns the code for secret offset periods from now.
+func totpCode(t *testing.T, secret string, offset int64) string {
+	code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Now())+offset)
+	assert.NoError(t, err)
+	return code
+}
+
+// enableTOTP turns two-factor authentication on for the user of token and
+// returns the secret and recovery codes.
+func enableTOTP(t *testing.T, server *gin.Engine, token string) (string, []string) {
+	w := doRequest(server, "POST", "/mfa/totp", token, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var enrolment struct {
+		Secret string `json:"secret"`
+		URI    string `json:"uri"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &enrolment))
+	assert.True(t, strings.HasPrefix(enrolment.URI, "otpauth://totp/"))
+	assert.Contains(t, enrolment.URI, "secret="+enrolment.Secret)
+
+	w = doRequest(server, "POST", "/mfa/totp/confirm", token, `{"code":"`+totpCode(t, enrolment.Secret, 0)+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var confirmed struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &confirmed))
+	assert.Len(t, confirmed.RecoveryCodes, recoveryCodeCount)
+	return enrolment.Secret, confirmed.RecoveryCodes
+}
+
+func loginChallenge(t *testing.T, server *gin.Engine, email string) mfaChallenge {
+	w := doRequest(server, "POST", "/login", "", `{"email":"`+email+`","password":"password123"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var challenge mfaChallenge
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &challenge))
+	return challenge
+}
+
+func TestMFA_Enrolment(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+
+	w := doRequest(server, "POST", "/mfa/totp/confirm", token, `{"code":"123456"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(server, "POST", "/mfa/totp", token, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	// Until confirmed, logging in takes only the password.
+	challenge := loginChallenge(t, server, "john@example.com")
+	assert.False(t, challenge.MFARequired)
+	assert.NotEmpty(t, challenge.Token)
+
+	secret, _ := enableTOTP(t, server, token)
+	w = doRequest(server, "POST", "/mfa/totp", token, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doRequest(server, "POST", "/mfa/totp/confirm", token, `{"code":"`+totpCode(t, secret, 0)+`"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doRequest(server, "GET", "/mfa/totp", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"enabled":true,"recoveryCodesLeft":10}`, w.Body.String())
+}
+
+func TestMFA_Login(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	secret, recoveryCodes := enableTOTP(t, server, token)
+
+	challenge := loginChallenge(t, server, "john@example.com")
+	assert.True(t, challenge.MFARequired)
+	assert.Empty(t, challenge.Token)
+	w := doRequest(server, "GET", "/todos", challenge.MFAToken, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// The code used to confirm can't be replayed; the next one works.
+	w = doRequest(server, "POST", "/login/mfa", "", `{"mfaToken":"`+challenge.MFAToken+`","code":"`+totpCode(t, secret, 0)+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(server, "POST", "/login/mfa", "", `{"mfaToken":"`+challenge.MFAToken+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(server, "POST", "/login/mfa", "", `{"mfaToken":"`+token+`","code":"`+totpCode(t, secret, 1)+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(server, "POST", "/login/mfa", "", `{"mfaToken":"`+challenge.MFAToken+`","code":"`+totpCode(t, secret, 1)+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tokens tokenResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+	w = doRequest(server, "GET", "/todos", tokens.Token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Recovery codes work once, however they are typed.
+	recoveryCode := strings.ToLower(strings.ReplaceAll(recoveryCodes[0], "-", " "))
+	challenge = loginChallenge(t, server, "john@example.com")
+	w = doRequest(server, "POST", "/login/mfa", "", `{"mfaToken":"`+challenge.MFAToken+`","recoveryCode":"`+recoveryCode+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "POST", "/login/mfa", "", `{"mfaToken":"`+challenge.MFAToken+`","recoveryCode":"`+recoveryCodes[0]+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(server, "GET", "/mfa/totp", token, "")
+	assert.JSONEq(t, `{"enabled":true,"recoveryCodesLeft":9}`, w.Body.String())
+}
+
+func TestMFA_Lockout(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	secret, recoveryCodes := enableTOTP(t, server, token)
+	challenge := loginChallenge(t, server, "john@example.com")
+
+	for i := 0; i < totpLockout.MaxAttempts; i++ {
+		w := doRequest(server, "POST", "/login/mfa", "", `{"mfaToken":"`+challenge.MFAToken+`","code":"abcdef"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+	w := doRequest(server, "POST", "/login/mfa", "", `{"mfaToken":"`+challenge.MFAToken+`","code":"abcdef"}`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	w = doRequest(server, "POST", "/login/mfa", "", `{"mfaToken":"`+challenge.MFAToken+`","code":"`+totpCode(t, secret, 1)+`"}`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	w = doRequest(server, "POST", "/login/mfa", "", `{"mfaToken":"`+challenge.MFAToken+`","recoveryCode":"`+recoveryCodes[0]+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "POST", "/login/mfa", "", `{"mfaToken":"`+challenge.MFAToken+`","code":"`+totpCode(t, secret, 1)+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestMFA_FailTOTPError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	store := newFakeStore()
+	RegisterRoutes(server, store, discardMailer{})
+	token := signupAndLogin(t, server, "john@example.com")
+	enableTOTP(t, server, token)
+	challenge := loginChallenge(t, server, "john@example.com")
+
+	// Stub store.FailTOTP to simulate failing to count a wrong code
+	store.failTOTP = func(userId int64) error {
+		return errors.New("update error")
+	}
+
+	w := doRequest(server, "POST", "/login/mfa", "", `{"mfaToken":"`+challenge.MFAToken+`","code":"abcdef"}`)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestMFA_Disable(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	w := doRequest(server, "DELETE", "/mfa/totp", token, `{}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	secret, _ := enableTOTP(t, server, token)
+
+	w = doRequest(server, "DELETE", "/mfa/totp", token, `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(server, "DELETE", "/mfa/totp", token, `{"code":"`+totpCode(t, secret, 1)+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	challenge := loginChallenge(t, server, "john@example.com")
+	assert.False(t, challenge.MFARequired)
+	assert.NotEmpty(t, challenge.Token)
+	w = doRequest(server, "GET", "/mfa/totp", token, "")
+	assert.JSONEq(t, `{"enabled":false,"recoveryCodesLeft":0}`, w.Body.String())
+}
@@ -0,0 +1,134 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"project_todo/mail"
+	"project_todo/models"
+	"project_todo/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type forgotPasswordRequest struct {
+	Email string `binding:"required" json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `binding:"required" json:"token"`
+	Password string `binding:"required" json:"password"`
+}
+
+var errNoAppURL = errors.New("APP_URL is not set")
+
+// appURL is where users reach the app from links in mail, from APP_URL.
+// Links are never built from the request: its Host header is whatever the
+// sender chose, and a reset link pointing elsewhere would hand them the
+// token.
+func appURL() (string, error) {
+	base := strings.TrimRight(os.Getenv("APP_URL"), "/")
+	if base == "" {
+		return "", errNoAppURL
+	}
+	return base, nil
+}
+
+// forgotPassword mails the user a link to reset their password. It answers
+// the same whether or not the email belongs to a user, so it can't be used
+// to find out who has an account.
+func (h *handler) forgotPassword(context *gin.Context) {
+	var request forgotPasswordRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Could not parse the request body"})
+		return
+	}
+	base, err := appURL()
+	if err != nil {
+		fmt.Println("Unable to send password reset mail:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to reset the password"})
+		return
+	}
+	sent := gin.H{"message": "If the email belongs to an account, a reset link has been sent to it"}
+	user, err := h.store.GetUserByEmail(strings.TrimSpace(request.Email))
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusOK, sent)
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to reset the password"})
+		return
+	}
+
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to reset the password"})
+		return
+	}
+	now := time.Now()
+	ttl := utils.PasswordResetTTL()
+	err = h.store.CreatePasswordReset(&models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to reset the password"})
+		return
+	}
+	link := base + "/app-reset-password?token=" + url.QueryEscape(token)
+	err = h.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nFollow this link within %s to choose a new password:\n\n%s\n\n"+
+			"If you didn't ask to reset your password, you can ignore this email.\n", user.FirstName, ttl, link),
+	})
+	if err != nil {
+		fmt.Println("Unable to send the password reset mail", err)
+	}
+	context.JSON(http.StatusOK, sent)
+}
+
+// resetPassword sets a new password with the token from a reset link. The
+// token works once, and every session of the user is revoked so that
+// whoever knew the old password is logged out.
+func (h *handler) resetPassword(context *gin.Context) {
+	var request resetPasswordRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Could not parse the request body"})
+		return
+	}
+	now := time.Now()
+	reset, err := h.store.UsePasswordReset(utils.HashToken(request.Token), now)
+	if errors.Is(err, models.ErrNotFound) || (err == nil && reset.Expired(now)) {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Invalid or expired reset token"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to reset the password"})
+		return
+	}
+	user, err := h.store.GetUserById(reset.UserID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to reset the password"})
+		return
+	}
+	err = user.SetPassword(h.store, request.Password)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to reset the password"})
+		return
+	}
+	err = h.store.RevokeSessions(user.ID, now)
+	if err != nil {
+		fmt.Println("Unable to revoke the sessions of user", user.ID, err)
+	}
+	h.revocations.Reset()
+	context.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
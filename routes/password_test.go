@@ -0,0 +1,152 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"project_todo/mail"
+	"project_todo/models"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const resetSubject = "Reset your password"
+
+const testAppURL = "https://todo.example.com"
+
+func newMailServer(t *testing.T) (*gin.Engine, *mail.FileMailer) {
+	t.Setenv("APP_URL", testAppURL)
+	gin.SetMode(gin.TestMode)
+	mailer := mail.NewFileMailer(filepath.Join(t.TempDir(), "mail.log"))
+	server := gin.New()
+	RegisterRoutes(server, models.NewMemoryStore(), mailer)
+	return server, mailer
+}
+
+// sentWith returns the mail sent with subject, oldest first.
+func sentWith(t *testing.T, mailer *mail.FileMailer, subject string) []mail.Message {
+	sent, err := mailer.Sent()
+	assert.NoError(t, err)
+	var found []mail.Message
+	for _, m := range sent {
+		if m.Subject == subject {
+			found = append(found, m)
+		}
+	}
+	return found
+}
+
+// linkToken returns the token from the link in the latest mail with
+// subject.
+func linkToken(t *testing.T, mailer *mail.FileMailer, subject string) string {
+	sent := sentWith(t, mailer, subject)
+	if !assert.NotEmpty(t, sent) {
+		return ""
+	}
+	body := sent[len(sent)-1].Body
+	start := strings.Index(body, "http")
+	if !assert.GreaterOrEqual(t, start, 0) {
+		return ""
+	}
+	link, err := url.Parse(strings.Fields(body[start:])[0])
+	assert.NoError(t, err)
+	return link.Query().Get("token")
+}
+
+func TestPassword_Reset(t *testing.T) {
+	server, mailer := newMailServer(t)
+	tokens := loginTokens(t, server, "john@example.com")
+
+	// Unknown emails get the same answer, and no mail.
+	w := doRequest(server, "POST", "/password/forgot", "", `{"email":"nobody@example.com"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, sentWith(t, mailer, resetSubject))
+
+	w = doRequest(server, "POST", "/password/forgot", "", `{"email":"john@example.com"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	sent := sentWith(t, mailer, resetSubject)
+	if assert.Len(t, sent, 1) {
+		assert.Equal(t, "john@example.com", sent[0].To)
+		assert.Contains(t, sent[0].Body, "/app-reset-password?token=")
+	}
+	token := linkToken(t, mailer, resetSubject)
+	assert.NotEmpty(t, token)
+
+	w = doRequest(server, "POST", "/password/reset", "", `{"token":"`+token+`","password":"newpassword"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The token works once, the old password and the old login stop
+	// working, and the new password logs in.
+	w = doRequest(server, "POST", "/password/reset", "", `{"token":"`+token+`","password":"another"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(server, "GET", "/todos", tokens.Token, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	code, _ := refresh(t, server, tokens.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	w = doRequest(server, "POST", "/login", "", `{"email":"john@example.com","password":"password123"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(server, "POST", "/login", "", `{"email":"john@example.com","password":"newpassword"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestPassword_ResetTokens(t *testing.T) {
+	server, mailer := newMailServer(t)
+	loginTokens(t, server, "john@example.com")
+
+	w := doRequest(server, "POST", "/password/reset", "", `{"token":"unknown","password":"newpassword"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(server, "POST", "/password/reset", "", `{"token":"unknown"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Asking again replaces the earlier link.
+	doRequest(server, "POST", "/password/forgot", "", `{"email":"john@example.com"}`)
+	first := linkToken(t, mailer, resetSubject)
+	doRequest(server, "POST", "/password/forgot", "", `{"email":"john@example.com"}`)
+	second := linkToken(t, mailer, resetSubject)
+	assert.NotEqual(t, first, second)
+	w = doRequest(server, "POST", "/password/reset", "", `{"token":"`+first+`","password":"newpassword"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	t.Setenv("PASSWORD_RESET_TTL", "1ns")
+	doRequest(server, "POST", "/password/forgot", "", `{"email":"john@example.com"}`)
+	w = doRequest(server, "POST", "/password/reset", "", `{"token":"`+linkToken(t, mailer, resetSubject)+`","password":"newpassword"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid or expired reset token")
+}
+
+func TestPassword_LinksIgnoreHost(t *testing.T) {
+	server, mailer := newMailServer(t)
+	signup(t, server, "john@example.com")
+
+	// The Host header is the sender's to choose; mailed links still point
+	// at APP_URL.
+	forged := func(path, body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Host = "attacker.example"
+		server.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, forged("/password/forgot", `{"email":"john@example.com"}`))
+	assert.Equal(t, http.StatusOK, forged("/signup", `{"email":"jane@example.com","firstName":"Jane","lastName":"Doe","password":"password123"}`))
+	sent, err := mailer.Sent()
+	assert.NoError(t, err)
+	assert.Len(t, sent, 3)
+	for _, m := range sent {
+		assert.NotContains(t, m.Body, "attacker.example")
+		assert.Contains(t, m.Body, testAppURL+"/app-")
+	}
+
+	// Without APP_URL there is nowhere safe to link to, so nothing is sent.
+	t.Setenv("APP_URL", "")
+	assert.Equal(t, http.StatusInternalServerError, forged("/password/forgot", `{"email":"john@example.com"}`))
+	assert.Equal(t, http.StatusInternalServerError, forged("/verify-email/resend", `{"email":"jane@example.com"}`))
+	sent, err = mailer.Sent()
+	assert.NoError(t, err)
+	assert.Len(t, sent, 3)
+}
@@ -0,0 +1,188 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"project_todo/models"
+	"strings"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// patchTodoById applies a JSON Merge Patch (RFC 7396) or JSON Patch
+// (RFC 6902) to the todo as GET /todos/:id returns it. Only title, isActive,
+// dueAt, timeZone, recurrence, priority, projectId and list may change;
+// item positions follow the order of list.
+func (h *handler) patchTodoById(context *gin.Context) {
+	todo := h.accessibleTodo(context, models.ActionEdit)
+	if todo == nil {
+		return
+	}
+	version, ok := ifMatch(context, todo)
+	if !ok {
+		return
+	}
+
+	patch, err := io.ReadAll(context.Request.Body)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to read patch"})
+		return
+	}
+	original, err := json.Marshal(todo)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to patch todo"})
+		return
+	}
+
+	var patched []byte
+	switch context.ContentType() {
+	case mergePatchType:
+		patched, err = jsonpatch.MergePatch(original, patch)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"message": "Invalid merge patch"})
+			return
+		}
+	case jsonPatchType:
+		operations, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"message": "Invalid JSON patch"})
+			return
+		}
+		patched, err = operations.Apply(original)
+		if err != nil {
+			context.JSON(http.StatusUnprocessableEntity, gin.H{"message": "Unable to apply patch: " + err.Error()})
+			return
+		}
+	default:
+		context.Header("Accept-Patch", mergePatchType+", "+jsonPatchType)
+		context.JSON(http.StatusUnsupportedMediaType, gin.H{"message": "Unsupported patch format"})
+		return
+	}
+
+	modifiedTodo, err := decodePatchedTodo(*todo, patched)
+	if err != nil {
+		context.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
+		return
+	}
+	if !h.projectAllowed(context, http.StatusUnprocessableEntity, todo.UserID, modifiedTodo.ProjectID, todo.ProjectID) {
+		return
+	}
+	modifiedTodo.UpdatedAt = time.Now()
+	modifiedTodo.Version = version
+	err = h.store.UpdateTodo(modifiedTodo, context.GetInt64("userId"))
+	if errors.Is(err, models.ErrVersionConflict) {
+		preconditionFailed(context)
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to update todo"})
+		return
+	}
+	updated, err := h.store.GetTodoById(todo.ID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch todo"})
+		return
+	}
+	context.Header("ETag", etag(updated.Version))
+	context.JSON(http.StatusOK, gin.H{"message": "Todo updated successfully", "todo": updated})
+}
+
+// decodePatchedTodo reads the patched document back into a Todo and checks
+// it is still a valid edit of original.
+func decodePatchedTodo(original models.Todo, patched []byte) (models.Todo, error) {
+	var todo models.Todo
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&todo)
+	if err != nil {
+		return todo, errors.New("Patched todo is invalid: " + err.Error())
+	}
+
+	switch {
+	case todo.ID != original.ID:
+		return todo, readOnlyField("id")
+	case todo.UserID != original.UserID:
+		return todo, readOnlyField("userId")
+	case todo.Version != original.Version:
+		return todo, readOnlyField("version")
+	case !todo.CreatedAt.Equal(original.CreatedAt):
+		return todo, readOnlyField("createdAt")
+	case !todo.UpdatedAt.Equal(original.UpdatedAt):
+		return todo, readOnlyField("updatedAt")
+	case todo.SeriesID != original.SeriesID:
+		return todo, readOnlyField("seriesId")
+	case todo.Occurrence != original.Occurrence:
+		return todo, readOnlyField("occurrence")
+	case todo.NextID != original.NextID:
+		return todo, readOnlyField("nextId")
+	case !sameTags(todo.Tags, original.Tags):
+		return todo, readOnlyField("tags")
+	case todo.Rank != original.Rank:
+		return todo, readOnlyField("rank")
+	case todo.WorkspaceID != original.WorkspaceID:
+		return todo, readOnlyField("workspaceId")
+	case todo.Shared != original.Shared:
+		return todo, readOnlyField("shared")
+	case todo.Role != original.Role:
+		return todo, readOnlyField("role")
+	case strings.TrimSpace(todo.Title) == "":
+		return todo, errors.New("Title is required")
+	}
+	err = checkTimeZone(todo)
+	if err == nil {
+		err = normalizeRecurrence(&todo)
+	}
+	if err == nil {
+		err = normalizePriority(&todo)
+	}
+	if err != nil {
+		return todo, err
+	}
+
+	known := make(map[int64]bool, len(original.List))
+	for _, item := range original.List {
+		known[item.ID] = true
+	}
+	seen := make(map[int64]bool, len(todo.List))
+	for _, item := range todo.List {
+		if item.ID == 0 {
+			continue
+		}
+		if !known[item.ID] {
+			return todo, fmt.Errorf("Unknown item id %d", item.ID)
+		}
+		if seen[item.ID] {
+			return todo, fmt.Errorf("Item id %d appears more than once", item.ID)
+		}
+		seen[item.ID] = true
+	}
+	return todo, nil
+}
+
+// sameTags compares tags by ID; they are changed through /todos/:id/tags.
+func sameTags(a, b []models.Tag) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].ID != b[i].ID {
+			return false
+		}
+	}
+	return true
+}
+
+func readOnlyField(name string) error {
+	return fmt.Errorf("Field %s cannot be changed", name)
+}
@@ -0,0 +1,135 @@
+package routes
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func doPatch(server *gin.Engine, path, token, contentType, ifMatch, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("PATCH", path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", token)
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	server.ServeHTTP(w, req)
+	return w
+}
+
+func newPatchServer(t *testing.T) (*gin.Engine, string) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	w := doRequest(server, "POST", "/todos", token, `{"title":"Packing","list":[{"item":"tent"},{"item":"stove"}]}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	return server, token
+}
+
+func TestPatchTodo_MergePatch(t *testing.T) {
+	server, token := newPatchServer(t)
+
+	// Fields left out of a merge patch keep their stored values.
+	w := doPatch(server, "/todos/1", token, mergePatchType, `"1"`, `{"title":"Camping"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	todo := getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, "Camping", todo.Title)
+	assert.True(t, todo.IsActive)
+	assert.Equal(t, []string{"tent", "stove"}, itemNames(todo))
+
+	w = doPatch(server, "/todos/1", token, mergePatchType, `"2"`, `{"isActive":false,"list":[{"id":2,"item":"stove","checked":true},{"item":"map"}]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	todo = getTodo(t, server, token, "/todos/1")
+	assert.False(t, todo.IsActive)
+	assert.Equal(t, []string{"stove", "map"}, itemNames(todo))
+	assert.Equal(t, int64(2), todo.List[0].ID)
+	assert.True(t, todo.List[0].Checked)
+}
+
+func TestPatchTodo_JSONPatch(t *testing.T) {
+	server, token := newPatchServer(t)
+
+	w := doPatch(server, "/todos/1", token, jsonPatchType, `"1"`, `[
+		{"op":"test","path":"/title","value":"Packing"},
+		{"op":"replace","path":"/list/0/checked","value":true},
+		{"op":"add","path":"/list/-","value":{"item":"map"}},
+		{"op":"move","from":"/list/1","path":"/list/0"},
+		{"op":"replace","path":"/isActive","value":false}
+	]`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	todo := getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, []string{"stove", "tent", "map"}, itemNames(todo))
+	assert.Equal(t, []int{0, 1, 2}, []int{todo.List[0].Position, todo.List[1].Position, todo.List[2].Position})
+	assert.True(t, todo.List[1].Checked)
+	assert.False(t, todo.IsActive)
+
+	w = doPatch(server, "/todos/1", token, jsonPatchType, `"2"`, `[{"op":"remove","path":"/list/2"}]`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	todo = getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, []string{"stove", "tent"}, itemNames(todo))
+
+	// A failed test op leaves the todo untouched.
+	w = doPatch(server, "/todos/1", token, jsonPatchType, `"3"`, `[
+		{"op":"replace","path":"/title","value":"Renamed"},
+		{"op":"test","path":"/title","value":"Packing"}
+	]`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "Packing", getTodo(t, server, token, "/todos/1").Title)
+}
+
+func TestPatchTodo_Validation(t *testing.T) {
+	server, token := newPatchServer(t)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		code        int
+		message     string
+	}{
+		{"read-only field", mergePatchType, `{"userId":2}`, http.StatusUnprocessableEntity, "Field userId cannot be changed"},
+		{"read-only id", jsonPatchType, `[{"op":"replace","path":"/id","value":7}]`, http.StatusUnprocessableEntity, "Field id cannot be changed"},
+		{"empty title", mergePatchType, `{"title":" "}`, http.StatusUnprocessableEntity, "Title is required"},
+		{"unknown field", mergePatchType, `{"color":"red"}`, http.StatusUnprocessableEntity, ""},
+		{"wrong type", mergePatchType, `{"isActive":"yes"}`, http.StatusUnprocessableEntity, ""},
+		{"unknown item", mergePatchType, `{"list":[{"id":99,"item":"x"}]}`, http.StatusUnprocessableEntity, "Unknown item id 99"},
+		{"copied item", jsonPatchType, `[{"op":"copy","from":"/list/0","path":"/list/-"}]`, http.StatusUnprocessableEntity, "Item id 1 appears more than once"},
+		{"missing path", jsonPatchType, `[{"op":"remove","path":"/list/9"}]`, http.StatusUnprocessableEntity, ""},
+		{"malformed merge patch", mergePatchType, `{"title":`, http.StatusBadRequest, "Invalid merge patch"},
+		{"malformed json patch", jsonPatchType, `{"op":"remove"}`, http.StatusBadRequest, "Invalid JSON patch"},
+		{"plain json", "application/json", `{"title":"Camping"}`, http.StatusUnsupportedMediaType, "Unsupported patch format"},
+	}
+	for _, test := range tests {
+		w := doPatch(server, "/todos/1", token, test.contentType, `"1"`, test.body)
+		assert.Equal(t, test.code, w.Code, test.name)
+		if test.message != "" {
+			assert.JSONEq(t, `{"message":"`+test.message+`"}`, w.Body.String(), test.name)
+		}
+	}
+
+	todo := getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, "Packing", todo.Title)
+	assert.Equal(t, int64(1), todo.Version)
+}
+
+func TestPatchTodo_Preconditions(t *testing.T) {
+	server, token := newPatchServer(t)
+	other := signupAndLogin(t, server, "jane@example.com")
+
+	w := doPatch(server, "/todos/1", token, mergePatchType, "", `{"title":"Camping"}`)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	w = doPatch(server, "/todos/1", token, mergePatchType, `"7"`, `{"title":"Camping"}`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = doPatch(server, "/todos/1", other, mergePatchType, `"1"`, `{"title":"Camping"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doPatch(server, "/todos/9", token, mergePatchType, `"1"`, `{"title":"Camping"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
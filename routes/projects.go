@@ -0,0 +1,320 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"project_todo/models"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxProjectName = 100
+
+type projectRequest struct {
+	Name     *string    `json:"name"`
+	ParentID optionalId `json:"parentId"`
+	Archived *bool      `json:"archived"`
+}
+
+// optionalId tells an explicit null, which moves a project to the top
+// level, apart from a field that was left out.
+type optionalId struct {
+	set   bool
+	value int64
+}
+
+func (o *optionalId) UnmarshalJSON(data []byte) error {
+	o.set = true
+	var value *int64
+	err := json.Unmarshal(data, &value)
+	if value != nil {
+		o.value = *value
+	}
+	return err
+}
+
+func checkProjectName(project *models.Project) error {
+	project.Name = strings.TrimSpace(project.Name)
+	switch {
+	case project.Name == "":
+		return errors.New("Project name is required")
+	case len(project.Name) > maxProjectName:
+		return errors.New("Project name is too long")
+	}
+	return nil
+}
+
+// ownedProject loads the project named by the :id param and checks it
+// belongs to the authenticated user. On failure it writes the response and
+// returns nil.
+func (h *handler) ownedProject(context *gin.Context) *models.Project {
+	projectId, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse project id"})
+		return nil
+	}
+	project, err := h.store.GetProject(projectId)
+	if errors.Is(err, models.ErrNotFound) || (err == nil && project.UserID != context.GetInt64("userId")) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Project not found"})
+		return nil
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch project"})
+		return nil
+	}
+	return project
+}
+
+// userProject looks up one of the authenticated user's projects by ID. It
+// returns models.ErrNotFound for another user's project.
+func (h *handler) userProject(context *gin.Context, projectId int64) (*models.Project, error) {
+	project, err := h.store.GetProject(projectId)
+	if err == nil && project.UserID != context.GetInt64("userId") {
+		return nil, models.ErrNotFound
+	}
+	return project, err
+}
+
+// projectAllowed checks a todo may be put in projectId: it must be one of
+// the todo owner's projects and not archived, unless the todo is already in
+// it (current). On failure it writes the response with status and returns
+// false.
+func (h *handler) projectAllowed(context *gin.Context, status int, ownerId, projectId, current int64) bool {
+	if projectId == 0 || projectId == current {
+		return true
+	}
+	// Projects are personal, so they can't hold workspace todos.
+	if context.GetInt64("workspaceId") != 0 {
+		context.JSON(status, gin.H{"message": "Workspace todos cannot be put in a project"})
+		return false
+	}
+	project, err := h.store.GetProject(projectId)
+	if errors.Is(err, models.ErrNotFound) || (err == nil && project.UserID != ownerId) {
+		context.JSON(status, gin.H{"message": "Project not found"})
+		return false
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch project"})
+		return false
+	}
+	if project.ArchivedAt != nil {
+		context.JSON(status, gin.H{"message": "Project is archived"})
+		return false
+	}
+	return true
+}
+
+// parentAllowed checks parentId can hold project, which is nil for a new
+// project. On failure it writes the response and returns false.
+func (h *handler) parentAllowed(context *gin.Context, project *models.Project, parentId int64, archived bool) bool {
+	if parentId == 0 {
+		return true
+	}
+	parent, err := h.userProject(context, parentId)
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Parent project not found"})
+		return false
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch project"})
+		return false
+	}
+	if parent.ArchivedAt != nil && !archived {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Parent project is archived"})
+		return false
+	}
+	if project == nil {
+		return true
+	}
+	projects, err := h.store.GetProjects(project.UserID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch projects"})
+		return false
+	}
+	if slices.Contains(models.Subtree(projects, project.ID), parentId) {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "A project cannot be moved under itself or its subprojects"})
+		return false
+	}
+	return true
+}
+
+// getProjects lists the user's projects by name; archived ones only with
+// includeArchived=true.
+func (h *handler) getProjects(context *gin.Context) {
+	includeArchived, err := boolParam(context, "includeArchived")
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	projects, err := h.store.GetProjects(context.GetInt64("userId"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch projects"})
+		return
+	}
+	if includeArchived == nil || !*includeArchived {
+		projects = slices.DeleteFunc(projects, func(project models.Project) bool {
+			return project.ArchivedAt != nil
+		})
+	}
+	if projects == nil {
+		projects = []models.Project{}
+	}
+	context.JSON(http.StatusOK, projects)
+}
+
+func (h *handler) createProject(context *gin.Context) {
+	var project models.Project
+	err := context.ShouldBindJSON(&project)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+		return
+	}
+	err = checkProjectName(&project)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if !h.parentAllowed(context, nil, project.ParentID, false) {
+		return
+	}
+	project.UserID = context.GetInt64("userId")
+	project.ArchivedAt = nil
+	project.CreatedAt = time.Now()
+	project.UpdatedAt = time.Now()
+
+	err = h.store.CreateProject(&project)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to create project"})
+		return
+	}
+	context.JSON(http.StatusCreated, gin.H{"message": "Project created", "project": project})
+}
+
+func (h *handler) getProject(context *gin.Context) {
+	project := h.ownedProject(context)
+	if project == nil {
+		return
+	}
+	context.JSON(http.StatusOK, project)
+}
+
+// updateProject renames, moves, archives or unarchives a project. Archiving
+// changes the whole subtree; a project can't be unarchived, or moved
+// without being archived, under an archived parent.
+func (h *handler) updateProject(context *gin.Context) {
+	project := h.ownedProject(context)
+	if project == nil {
+		return
+	}
+	var request projectRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+		return
+	}
+	if request.Name != nil {
+		project.Name = *request.Name
+	}
+	err = checkProjectName(project)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	archived := project.ArchivedAt != nil
+	if request.Archived != nil {
+		archived = *request.Archived
+	}
+	if request.ParentID.set && request.ParentID.value != project.ParentID {
+		if !h.parentAllowed(context, project, request.ParentID.value, archived) {
+			return
+		}
+		project.ParentID = request.ParentID.value
+	} else if !archived && project.ArchivedAt != nil && project.ParentID != 0 {
+		parent, err := h.store.GetProject(project.ParentID)
+		if err != nil {
+			context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch project"})
+			return
+		}
+		if parent.ArchivedAt != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"message": "Unarchive the parent project first"})
+			return
+		}
+	}
+
+	project.UpdatedAt = time.Now()
+	err = h.store.UpdateProject(*project)
+	if err == nil && archived != (project.ArchivedAt != nil) {
+		err = h.store.ArchiveProject(*project, archived)
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to update project"})
+		return
+	}
+	updated, err := h.store.GetProject(project.ID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch project"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Project updated", "project": updated})
+}
+
+// deleteProject deletes a project and its subprojects. Their todos go to
+// the trash, or with todos=inbox stay out of any project.
+func (h *handler) deleteProject(context *gin.Context) {
+	project := h.ownedProject(context)
+	if project == nil {
+		return
+	}
+	var keepTodos bool
+	switch context.DefaultQuery("todos", "trash") {
+	case "trash":
+	case "inbox":
+		keepTodos = true
+	default:
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Invalid todos, use trash or inbox"})
+		return
+	}
+	err := h.store.DeleteProject(*project, keepTodos)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to delete project"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Project deleted"})
+}
+
+// getProjectTodos lists the project's todos, or with recursive=true those
+// of its subprojects too, taking the same filters, sorts and paging as
+// GET /todos. Todos show up even when the project is archived.
+func (h *handler) getProjectTodos(context *gin.Context) {
+	project := h.ownedProject(context)
+	if project == nil {
+		return
+	}
+	recursive, err := boolParam(context, "recursive")
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	query, err := parseTodoQuery(context)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	query.UserID = project.UserID
+	query.ProjectIDs = []int64{project.ID}
+	query.IncludeArchived = true
+	if recursive != nil && *recursive {
+		projects, err := h.store.GetProjects(project.UserID)
+		if err != nil {
+			context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch projects"})
+			return
+		}
+		query.ProjectIDs = models.Subtree(projects, project.ID)
+	}
+	h.writeTodoPage(context, query)
+}
@@ -0,0 +1,134 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func projectNames(t *testing.T, body []byte) []string {
+	var projects []struct {
+		Name string `json:"name"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &projects))
+	names := []string{}
+	for _, project := range projects {
+		names = append(names, project.Name)
+	}
+	return names
+}
+
+func TestProjects_CRUD(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	other := signupAndLogin(t, server, "jane@example.com")
+
+	w := doRequest(server, "POST", "/projects", token, `{"name":" Work "}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"Work"`)
+	doRequest(server, "POST", "/projects", token, `{"name":"Reports","parentId":1}`)
+	doRequest(server, "POST", "/projects", token, `{"name":"Weekly","parentId":2}`)
+	doRequest(server, "POST", "/projects", other, `{"name":"Foreign"}`)
+
+	for body, message := range map[string]string{
+		`{"name":""}`:                   "Project name is required",
+		`{"name":"Home","parentId":4}`:  "Parent project not found",
+		`{"name":"Home","parentId":99}`: "Parent project not found",
+	} {
+		w = doRequest(server, "POST", "/projects", token, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		assert.JSONEq(t, `{"message":"`+message+`"}`, w.Body.String())
+	}
+
+	w = doRequest(server, "GET", "/projects", token, "")
+	assert.Equal(t, []string{"Reports", "Weekly", "Work"}, projectNames(t, w.Body.Bytes()))
+	w = doRequest(server, "GET", "/projects/4", token, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// A project can't move under its own subtree.
+	w = doRequest(server, "PATCH", "/projects/1", token, `{"parentId":3}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"message":"A project cannot be moved under itself or its subprojects"}`, w.Body.String())
+	w = doRequest(server, "PATCH", "/projects/2", token, `{"name":"Reporting","parentId":null}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "GET", "/projects/2", token, "")
+	assert.NotContains(t, w.Body.String(), "parentId")
+	assert.Contains(t, w.Body.String(), `"name":"Reporting"`)
+
+	// Archiving covers the subtree and hides it unless asked for.
+	w = doRequest(server, "PATCH", "/projects/2", token, `{"archived":true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "archivedAt")
+	w = doRequest(server, "GET", "/projects", token, "")
+	assert.Equal(t, []string{"Work"}, projectNames(t, w.Body.Bytes()))
+	w = doRequest(server, "GET", "/projects?includeArchived=true", token, "")
+	assert.Equal(t, []string{"Reporting", "Weekly", "Work"}, projectNames(t, w.Body.Bytes()))
+
+	w = doRequest(server, "POST", "/projects", token, `{"name":"Monthly","parentId":2}`)
+	assert.JSONEq(t, `{"message":"Parent project is archived"}`, w.Body.String())
+	w = doRequest(server, "PATCH", "/projects/3", token, `{"archived":false}`)
+	assert.JSONEq(t, `{"message":"Unarchive the parent project first"}`, w.Body.String())
+	w = doRequest(server, "PATCH", "/projects/3", token, `{"archived":false,"parentId":1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "archivedAt")
+
+	w = doRequest(server, "DELETE", "/projects/1?todos=later", token, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(server, "DELETE", "/projects/1", other, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(server, "DELETE", "/projects/1", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "GET", "/projects?includeArchived=true", token, "")
+	assert.Equal(t, []string{"Reporting"}, projectNames(t, w.Body.Bytes()))
+}
+
+func TestProjects_Todos(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	other := signupAndLogin(t, server, "jane@example.com")
+	doRequest(server, "POST", "/projects", token, `{"name":"Work"}`)
+	doRequest(server, "POST", "/projects", token, `{"name":"Reports","parentId":1}`)
+	doRequest(server, "POST", "/projects", other, `{"name":"Foreign"}`)
+
+	doRequest(server, "POST", "/todos", token, `{"title":"Inbox"}`)
+	doRequest(server, "POST", "/todos", token, `{"title":"Plan","projectId":1}`)
+	doRequest(server, "POST", "/todos", token, `{"title":"Summary","projectId":2}`)
+	w := doRequest(server, "POST", "/todos", token, `{"title":"Sneaky","projectId":3}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"message":"Project not found"}`, w.Body.String())
+
+	w = doRequest(server, "GET", "/projects/1/todos", token, "")
+	assert.Equal(t, []string{"Plan"}, todoTitles(t, w.Body.Bytes()))
+	w = doRequest(server, "GET", "/projects/1/todos?recursive=true", token, "")
+	assert.Equal(t, []string{"Plan", "Summary"}, todoTitles(t, w.Body.Bytes()))
+	w = doRequest(server, "GET", "/projects/1/todos?recursive=true&title=sum", token, "")
+	assert.Equal(t, []string{"Summary"}, todoTitles(t, w.Body.Bytes()))
+	w = doRequest(server, "GET", "/projects/3/todos", token, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Todos of archived projects leave GET /todos but not the project view,
+	// and no todo can be moved into them.
+	doRequest(server, "PATCH", "/projects/2", token, `{"archived":true}`)
+	w = doRequest(server, "GET", "/todos", token, "")
+	assert.Equal(t, []string{"Inbox", "Plan"}, todoTitles(t, w.Body.Bytes()))
+	w = doRequest(server, "GET", "/projects/2/todos", token, "")
+	assert.Equal(t, []string{"Summary"}, todoTitles(t, w.Body.Bytes()))
+	w = doPatch(server, "/todos/1", token, mergePatchType, "*", `{"projectId":2}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "Project is archived")
+	w = doRequestIfMatch(server, "PUT", "/todos/3", token, "*", `{"title":"Weekly summary","projectId":2}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Deleting to the inbox keeps the todos, deleting otherwise trashes them.
+	w = doRequest(server, "DELETE", "/projects/2?todos=inbox", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Zero(t, getTodo(t, server, token, "/todos/3").ProjectID)
+	w = doRequest(server, "DELETE", "/projects/1", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "GET", "/todos", token, "")
+	assert.Equal(t, []string{"Inbox", "Weekly summary"}, todoTitles(t, w.Body.Bytes()))
+	w = doRequest(server, "GET", "/todos/trash", token, "")
+	assert.Contains(t, w.Body.String(), "Plan")
+}
@@ -0,0 +1,132 @@
+package routes
+
+import (
+	"errors"
+	"project_todo/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseTodoQuery reads the GET /todos query string:
+//
+//	limit, cursor                      paging
+//	isActive, hasUnchecked             true/false filters
+//	createdFrom, createdTo             RFC 3339 range, from inclusive
+//	updatedFrom, updatedTo             RFC 3339 range, from inclusive
+//	title                              case-insensitive substring
+//	due                                today, week or overdue; overdue
+//	                                   implies isActive=true unless given
+//	tz                                 IANA time zone the due views use
+//	                                   for midnight, UTC by default
+//	tag, tagMode                       tag names, repeatable; todos must
+//	                                   carry all of them, or any with
+//	                                   tagMode=any
+//	priority                           P0 to P3, repeatable
+//	sort                               rank (the default, the manual
+//	                                   order), priority, id, createdAt,
+//	                                   updatedAt or title; prefix with -
+//	                                   for descending
+func parseTodoQuery(context *gin.Context) (models.TodoQuery, error) {
+	query := models.TodoQuery{Cursor: context.Query("cursor"), TitleContains: context.Query("title")}
+
+	if limit := context.Query("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > models.MaxTodoLimit {
+			return query, errors.New("Invalid limit")
+		}
+		query.Limit = value
+	}
+
+	var err error
+	if query.IsActive, err = boolParam(context, "isActive"); err != nil {
+		return query, err
+	}
+	if query.HasUnchecked, err = boolParam(context, "hasUnchecked"); err != nil {
+		return query, err
+	}
+	if query.CreatedFrom, err = timeParam(context, "createdFrom"); err != nil {
+		return query, err
+	}
+	if query.CreatedTo, err = timeParam(context, "createdTo"); err != nil {
+		return query, err
+	}
+	if query.UpdatedFrom, err = timeParam(context, "updatedFrom"); err != nil {
+		return query, err
+	}
+	if query.UpdatedTo, err = timeParam(context, "updatedTo"); err != nil {
+		return query, err
+	}
+
+	if due := context.Query("due"); due != "" {
+		location := time.UTC
+		if tz := context.Query("tz"); tz != "" {
+			location, err = time.LoadLocation(tz)
+			if err != nil {
+				return query, errors.New("Invalid tz")
+			}
+		}
+		query.DueFrom, query.DueTo, err = dueRange(due, location, time.Now())
+		if err != nil {
+			return query, err
+		}
+		if due == dueOverdue && query.IsActive == nil {
+			active := true
+			query.IsActive = &active
+		}
+	}
+
+	query.Tags = context.QueryArray("tag")
+	switch context.Query("tagMode") {
+	case "", "all":
+	case "any":
+		query.AnyTag = true
+	default:
+		return query, errors.New("Invalid tagMode")
+	}
+
+	for _, priority := range context.QueryArray("priority") {
+		priority = strings.ToUpper(priority)
+		if !models.IsValidPriority(priority) {
+			return query, errors.New("Invalid priority")
+		}
+		query.Priorities = append(query.Priorities, priority)
+	}
+
+	if sort := context.Query("sort"); sort != "" {
+		query.Descending = strings.HasPrefix(sort, "-")
+		query.SortBy = strings.TrimPrefix(sort, "-")
+		switch query.SortBy {
+		case models.SortById, models.SortByCreatedAt, models.SortByUpdatedAt, models.SortByTitle, models.SortByRank, models.SortByPriority:
+		default:
+			return query, errors.New("Invalid sort")
+		}
+	}
+	return query, nil
+}
+
+func boolParam(context *gin.Context, name string) (*bool, error) {
+	raw := context.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, errors.New("Invalid " + name)
+	}
+	return &value, nil
+}
+
+func timeParam(context *gin.Context, name string) (*time.Time, error) {
+	raw := context.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, errors.New("Invalid " + name)
+	}
+	return &value, nil
+}
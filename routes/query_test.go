@@ -0,0 +1,77 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"project_todo/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func todoTitles(t *testing.T, body []byte) []string {
+	var todos []models.Todo
+	assert.NoError(t, json.Unmarshal(body, &todos))
+	var titles []string
+	for _, todo := range todos {
+		titles = append(titles, todo.Title)
+	}
+	return titles
+}
+
+func TestGetAllTodos_Paging(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	for i := 1; i <= 5; i++ {
+		doRequest(server, "POST", "/todos", token, fmt.Sprintf(`{"title":"Todo %d","list":[{"item":"one"}]}`, i))
+	}
+
+	w := doRequest(server, "GET", "/todos?limit=2&sort=-id", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"Todo 5", "Todo 4"}, todoTitles(t, w.Body.Bytes()))
+	cursor := w.Header().Get("X-Next-Cursor")
+	assert.NotEmpty(t, cursor)
+	assert.Contains(t, w.Header().Get("Link"), `rel="next"`)
+
+	w = doRequest(server, "GET", "/todos?limit=2&sort=-id&cursor="+cursor, token, "")
+	assert.Equal(t, []string{"Todo 3", "Todo 2"}, todoTitles(t, w.Body.Bytes()))
+	cursor = w.Header().Get("X-Next-Cursor")
+
+	w = doRequest(server, "GET", "/todos?limit=2&sort=-id&cursor="+cursor, token, "")
+	assert.Equal(t, []string{"Todo 1"}, todoTitles(t, w.Body.Bytes()))
+	assert.Empty(t, w.Header().Get("X-Next-Cursor"))
+	assert.Empty(t, w.Header().Get("Link"))
+}
+
+func TestGetAllTodos_Filters(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	doRequest(server, "POST", "/todos", token, `{"title":"Groceries","list":[{"item":"milk"}]}`)
+	doRequest(server, "POST", "/todos", token, `{"title":"Taxes","list":[{"item":"file","checked":true}]}`)
+
+	w := doRequest(server, "GET", "/todos?title=groc", token, "")
+	assert.Equal(t, []string{"Groceries"}, todoTitles(t, w.Body.Bytes()))
+
+	w = doRequest(server, "GET", "/todos?hasUnchecked=false", token, "")
+	assert.Equal(t, []string{"Taxes"}, todoTitles(t, w.Body.Bytes()))
+
+	w = doRequest(server, "GET", "/todos?isActive=true&sort=title&createdFrom=2000-01-01T00:00:00Z", token, "")
+	assert.Equal(t, []string{"Groceries", "Taxes"}, todoTitles(t, w.Body.Bytes()))
+
+	w = doRequest(server, "GET", "/todos?createdTo=2000-01-01T00:00:00Z", token, "")
+	assert.Empty(t, todoTitles(t, w.Body.Bytes()))
+}
+
+func TestGetAllTodos_InvalidParams(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+
+	for _, query := range []string{"limit=0", "limit=101", "limit=abc", "sort=colour", "priority=P4", "isActive=maybe", "createdFrom=yesterday", "cursor=garbage"} {
+		w := doRequest(server, "GET", "/todos?"+query, token, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	w := doRequest(server, "GET", "/todos?limit=1&sort=title", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
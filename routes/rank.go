@@ -0,0 +1,118 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"project_todo/models"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// normalizePriority accepts priorities in either case. An empty priority
+// is left for the store to default.
+func normalizePriority(todo *models.Todo) error {
+	if todo.Priority == "" {
+		return nil
+	}
+	todo.Priority = strings.ToUpper(todo.Priority)
+	if !models.IsValidPriority(todo.Priority) {
+		return errors.New("Invalid priority, use P0 to P3")
+	}
+	return nil
+}
+
+// reorderTodo moves a todo in the user's manual order, which GET /todos
+// uses by default. The body names the todo and its new neighbours:
+// {"id": 3, "after": 5} or {"id": 3, "before": 1}, or both to place it
+// between two todos. Only the moved todo's rank changes. If-Match carries
+// the moved todo's version.
+func (h *handler) reorderTodo(context *gin.Context) {
+	var move models.Move
+	err := context.ShouldBindJSON(&move)
+	if err != nil || move.ID == 0 {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+		return
+	}
+	todo, err := h.store.GetTodoById(move.ID)
+	if errors.Is(err, models.ErrNotFound) || (err == nil && todo.UserID != context.GetInt64("userId")) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Todo not found"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch todo"})
+		return
+	}
+	version, ok := ifMatch(context, todo)
+	if !ok {
+		return
+	}
+	move.Version = version
+	err = h.store.MoveTodo(context.GetInt64("userId"), move)
+	if errors.Is(err, models.ErrVersionConflict) {
+		preconditionFailed(context)
+		return
+	}
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Todo not found"})
+		return
+	}
+	if errors.Is(err, models.ErrInvalidMove) {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Invalid move, give after and/or before in list order"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to move todo"})
+		return
+	}
+
+	updated, err := h.store.GetTodoById(move.ID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch todo"})
+		return
+	}
+	context.Header("ETag", etag(updated.Version))
+	context.JSON(http.StatusOK, gin.H{"message": "Todo moved", "todo": updated})
+}
+
+// reorderItems is reorderTodo for the checklist of one todo.
+func (h *handler) reorderItems(context *gin.Context) {
+	todo := h.accessibleTodo(context, models.ActionEdit)
+	if todo == nil {
+		return
+	}
+	version, ok := ifMatch(context, todo)
+	if !ok {
+		return
+	}
+	var move models.Move
+	err := context.ShouldBindJSON(&move)
+	if err != nil || move.ID == 0 {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+		return
+	}
+	err = h.store.MoveItem(todo.ID, move, version, context.GetInt64("userId"))
+	if errors.Is(err, models.ErrVersionConflict) {
+		preconditionFailed(context)
+		return
+	}
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Item not found"})
+		return
+	}
+	if errors.Is(err, models.ErrInvalidMove) {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Invalid move, give after and/or before in list order"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to move item"})
+		return
+	}
+	updated, err := h.store.GetTodoById(todo.ID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch todo"})
+		return
+	}
+	context.Header("ETag", etag(updated.Version))
+	context.JSON(http.StatusOK, gin.H{"message": "Item moved", "list": updated.List})
+}
@@ -0,0 +1,82 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReorder_Todos(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	other := signupAndLogin(t, server, "jane@example.com")
+	doRequest(server, "POST", "/todos", token, `{"title":"A"}`)
+	doRequest(server, "POST", "/todos", token, `{"title":"B","priority":"p0"}`)
+	doRequest(server, "POST", "/todos", token, `{"title":"C","priority":"P3"}`)
+	doRequest(server, "POST", "/todos", other, `{"title":"Foreign"}`)
+
+	w := doRequestIfMatch(server, "POST", "/todos/reorder", token, `"1"`, `{"id":3,"before":1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	w = doRequestIfMatch(server, "POST", "/todos/reorder", token, "*", `{"id":1,"after":3,"before":2}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// If-Match carries the version of the moved todo.
+	w = doRequest(server, "POST", "/todos/reorder", token, `{"id":3,"after":2}`)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	w = doRequestIfMatch(server, "POST", "/todos/reorder", token, `"1"`, `{"id":3,"after":2}`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	w = doRequest(server, "GET", "/todos", token, "")
+	assert.Equal(t, []string{"C", "A", "B"}, todoTitles(t, w.Body.Bytes()))
+	w = doRequest(server, "GET", "/todos?sort=priority", token, "")
+	assert.Equal(t, []string{"B", "A", "C"}, todoTitles(t, w.Body.Bytes()))
+	w = doRequest(server, "GET", "/todos?priority=P0&priority=p3", token, "")
+	assert.Equal(t, []string{"C", "B"}, todoTitles(t, w.Body.Bytes()))
+
+	for body, code := range map[string]int{
+		`{"id":1}`:                      http.StatusBadRequest,
+		`{"id":1,"after":2,"before":3}`: http.StatusBadRequest,
+		`{"after":2}`:                   http.StatusBadRequest,
+		`{"id":1,"after":4}`:            http.StatusNotFound,
+		`{"id":4,"after":1}`:            http.StatusNotFound,
+	} {
+		w = doRequestIfMatch(server, "POST", "/todos/reorder", token, "*", body)
+		assert.Equal(t, code, w.Code, body)
+	}
+
+	// The rank is only changed by reordering.
+	w = doPatch(server, "/todos/1", token, mergePatchType, "*", `{"rank":"0"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = doPatch(server, "/todos/1", token, mergePatchType, "*", `{"priority":"P1"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "P1", getTodo(t, server, token, "/todos/1").Priority)
+	w = doPatch(server, "/todos/1", token, mergePatchType, "*", `{"priority":"urgent"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = doRequest(server, "POST", "/todos", token, `{"title":"D","priority":"P9"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"message":"Invalid priority, use P0 to P3"}`, w.Body.String())
+}
+
+func TestReorder_Items(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	other := signupAndLogin(t, server, "jane@example.com")
+	doRequest(server, "POST", "/todos", token, `{"title":"Packing","list":[{"item":"tent"},{"item":"stove"},{"item":"lamp"}]}`)
+
+	w := doRequestIfMatch(server, "POST", "/todos/1/items/reorder", token, `"1"`, `{"id":3,"after":1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	todo := getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, "lamp", todo.List[1].Item)
+	assert.Equal(t, 1, todo.List[1].Position)
+
+	w = doRequestIfMatch(server, "POST", "/todos/1/items/reorder", token, "*", `{"id":3,"after":9}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequestIfMatch(server, "POST", "/todos/1/items/reorder", token, "*", `{"id":3,"after":2,"before":1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(server, "POST", "/todos/1/items/reorder", other, `{"id":3,"before":1}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"errors"
+	"project_todo/models"
+	"strings"
+)
+
+// normalizeRecurrence checks the todo's recurrence rule and stores it in
+// its canonical RRULE form, so "weekly" is saved as "FREQ=WEEKLY".
+func normalizeRecurrence(todo *models.Todo) error {
+	if todo.Recurrence == "" {
+		return nil
+	}
+	rule, err := models.ParseRule(todo.Recurrence)
+	if err != nil {
+		detail := strings.TrimPrefix(err.Error(), models.ErrInvalidRecurrence.Error()+": ")
+		return errors.New("Invalid recurrence: " + detail)
+	}
+	todo.Recurrence = rule.String()
+	return nil
+}
@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecurrence_Validation(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+
+	w := doRequest(server, "POST", "/todos", token, `{"title":"Runbook","recurrence":"FREQ=HOURLY"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid recurrence: FREQ must be DAILY, WEEKLY or MONTHLY")
+
+	w = doRequest(server, "POST", "/todos", token, `{"title":"Runbook","recurrence":"weekly","seriesId":9,"nextId":4}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	todo := getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, "FREQ=WEEKLY", todo.Recurrence)
+	assert.Equal(t, int64(0), todo.SeriesID)
+	assert.Equal(t, int64(0), todo.NextID)
+	assert.Equal(t, 1, todo.Occurrence)
+
+	w = doRequestIfMatch(server, "PUT", "/todos/1", token, "*", `{"title":"Runbook","isActive":true,"recurrence":"FREQ=WEEKLY;BYDAY=XX"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doPatch(server, "/todos/1", token, mergePatchType, "*", `{"recurrence":"rrule:freq=monthly;bymonthday=-1"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	todo = getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, "FREQ=MONTHLY;BYMONTHDAY=-1", todo.Recurrence)
+
+	w = doPatch(server, "/todos/1", token, mergePatchType, "*", `{"occurrence":5}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "Field occurrence cannot be changed")
+}
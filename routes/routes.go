@@ -1,20 +1,121 @@
 package routes
 
 import (
+	"project_todo/mail"
 	"project_todo/middlewares"
+	"project_todo/models"
+	"project_todo/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutes(server *gin.Engine) {
+type handler struct {
+	store       models.Store
+	revocations *middlewares.Revocations
+	mailer      mail.Mailer
+}
+
+func RegisterRoutes(server *gin.Engine, store models.Store, mailer mail.Mailer) {
+	revocations := middlewares.NewRevocations(store, utils.RevocationCacheTTL())
+	h := &handler{store: store, revocations: revocations, mailer: mailer}
+
 	authenticated := server.Group("/")
-	authenticated.Use(middlewares.Authenticate)
-	authenticated.GET("/todos", getAllTodos)
-	authenticated.POST("/todos", createTodo)
-	authenticated.GET("/todos/:id", getTodoById)
-	authenticated.PUT("/todos/:id", updateTodoById)
-	authenticated.DELETE("/todos/:id", deleteTodoById)
+	authenticated.Use(middlewares.Authenticate(revocations))
+	authenticated.GET("/todos", h.getAllTodos)
+	authenticated.POST("/todos", h.createTodo)
+	authenticated.GET("/todos/search", h.searchTodos)
+	authenticated.POST("/todos/reorder", h.reorderTodo)
+	authenticated.GET("/todos/trash", h.getTrash)
+	authenticated.DELETE("/todos/trash", h.emptyTrash)
+	authenticated.DELETE("/todos/trash/:id", h.purgeTodo)
+	authenticated.GET("/todos/:id", h.getTodoById)
+	authenticated.PUT("/todos/:id", h.updateTodoById)
+	authenticated.PATCH("/todos/:id", h.patchTodoById)
+	authenticated.DELETE("/todos/:id", h.deleteTodoById)
+	authenticated.POST("/todos/:id/restore", h.restoreTodo)
+	authenticated.GET("/todos/:id/history", h.getHistory)
+	authenticated.GET("/todos/:id/versions", h.getVersions)
+	authenticated.POST("/todos/:id/revert", h.revertTodo)
+	authenticated.POST("/todos/:id/undo", h.undoChange)
+	authenticated.POST("/todos/:id/items", h.createItem)
+	authenticated.POST("/todos/:id/items/reorder", h.reorderItems)
+	authenticated.PATCH("/todos/:id/items/:itemId", h.updateItem)
+	authenticated.DELETE("/todos/:id/items/:itemId", h.deleteItem)
+	authenticated.POST("/todos/:id/tags", h.attachTags)
+	authenticated.DELETE("/todos/:id/tags/:tagId", h.detachTag)
+	authenticated.GET("/todos/:id/shares", h.getShares)
+	authenticated.POST("/todos/:id/shares", h.shareTodo)
+	authenticated.DELETE("/todos/:id/shares/:userId", h.unshareTodo)
+	authenticated.GET("/todos/:id/links", h.getShareLinks)
+	authenticated.POST("/todos/:id/links", h.createShareLink)
+	authenticated.DELETE("/todos/:id/links/:linkId", h.revokeShareLink)
+	authenticated.GET("/todos/:id/links/:linkId/accesses", h.getLinkAccesses)
+	authenticated.GET("/tags", h.getTags)
+	authenticated.POST("/tags", h.createTag)
+	authenticated.PATCH("/tags/:tagId", h.updateTag)
+	authenticated.DELETE("/tags/:tagId", h.deleteTag)
+	authenticated.POST("/tags/:tagId/merge", h.mergeTag)
+	authenticated.GET("/projects", h.getProjects)
+	authenticated.POST("/projects", h.createProject)
+	authenticated.GET("/projects/:id", h.getProject)
+	authenticated.PATCH("/projects/:id", h.updateProject)
+	authenticated.DELETE("/projects/:id", h.deleteProject)
+	authenticated.GET("/projects/:id/todos", h.getProjectTodos)
+	authenticated.GET("/sessions", h.getSessions)
+	authenticated.DELETE("/sessions/:id", h.deleteSession)
+	authenticated.GET("/mfa/totp", h.getTOTP)
+	authenticated.POST("/mfa/totp", h.enrolTOTP)
+	authenticated.POST("/mfa/totp/confirm", h.confirmTOTP)
+	authenticated.DELETE("/mfa/totp", h.disableTOTP)
+	authenticated.GET("/workspaces", h.getWorkspaces)
+	authenticated.POST("/workspaces", h.createWorkspace)
+	authenticated.GET("/invites", h.getMyInvites)
+	authenticated.POST("/invites/:token/accept", h.acceptInvite)
+	authenticated.POST("/invites/:token/decline", h.declineInvite)
+
+	// Every workspace route checks the user's role for its action before
+	// the handler runs; the todo handlers are the same as above.
+	workspace := authenticated.Group("/workspaces/:workspaceId")
+	allow := func(action models.Action) gin.HandlerFunc {
+		return middlewares.Workspace(store, action)
+	}
+	workspace.GET("", allow(models.ActionView), h.getWorkspace)
+	workspace.PATCH("", allow(models.ActionManageWorkspace), h.updateWorkspace)
+	workspace.DELETE("", allow(models.ActionDeleteWorkspace), h.deleteWorkspace)
+	workspace.GET("/members", allow(models.ActionView), h.getMembers)
+	workspace.PATCH("/members/:userId", allow(models.ActionManageWorkspace), h.updateMember)
+	workspace.DELETE("/members/:userId", allow(models.ActionView), h.removeMember)
+	workspace.GET("/invites", allow(models.ActionManageWorkspace), h.getInvites)
+	workspace.POST("/invites", allow(models.ActionManageWorkspace), h.createInvite)
+	workspace.DELETE("/invites/:inviteId", allow(models.ActionManageWorkspace), h.deleteInvite)
+	workspace.GET("/todos", allow(models.ActionView), h.getAllTodos)
+	workspace.POST("/todos", allow(models.ActionEdit), h.createTodo)
+	workspace.GET("/todos/trash", allow(models.ActionView), h.getTrash)
+	workspace.DELETE("/todos/trash/:id", allow(models.ActionDelete), h.purgeTodo)
+	workspace.GET("/todos/:id", allow(models.ActionView), h.getTodoById)
+	workspace.PUT("/todos/:id", allow(models.ActionEdit), h.updateTodoById)
+	workspace.PATCH("/todos/:id", allow(models.ActionEdit), h.patchTodoById)
+	workspace.DELETE("/todos/:id", allow(models.ActionDelete), h.deleteTodoById)
+	workspace.POST("/todos/:id/restore", allow(models.ActionDelete), h.restoreTodo)
+	workspace.GET("/todos/:id/history", allow(models.ActionView), h.getHistory)
+	workspace.GET("/todos/:id/versions", allow(models.ActionView), h.getVersions)
+	workspace.POST("/todos/:id/revert", allow(models.ActionEdit), h.revertTodo)
+	workspace.POST("/todos/:id/undo", allow(models.ActionView), h.undoChange)
+	workspace.POST("/todos/:id/items", allow(models.ActionEdit), h.createItem)
+	workspace.POST("/todos/:id/items/reorder", allow(models.ActionEdit), h.reorderItems)
+	workspace.PATCH("/todos/:id/items/:itemId", allow(models.ActionEdit), h.updateItem)
+	workspace.DELETE("/todos/:id/items/:itemId", allow(models.ActionEdit), h.deleteItem)
+	workspace.POST("/todos/:id/tags", allow(models.ActionOrganize), h.attachTags)
+	workspace.DELETE("/todos/:id/tags/:tagId", allow(models.ActionOrganize), h.detachTag)
 
-	server.POST("/signup", signup)
-	server.POST("/login", login)
+	server.POST("/signup", h.signup)
+	server.POST("/login", h.login)
+	server.POST("/login/mfa", h.verifyMFA)
+	server.POST("/token/refresh", h.refreshToken)
+	server.POST("/logout", h.logout)
+	server.POST("/password/forgot", h.forgotPassword)
+	server.POST("/password/reset", h.resetPassword)
+	server.GET("/verify-email", h.verifyEmail)
+	server.POST("/verify-email/resend", h.resendVerification)
+	server.GET("/shared/:token", h.getSharedTodo)
 }
@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"net/http"
+	"project_todo/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// searchTodos handles GET /todos/search?q=...&limit=... and returns the
+// ranked matches with highlighted snippets.
+func (h *handler) searchTodos(context *gin.Context) {
+	query := models.SearchQuery{UserID: context.GetInt64("userId"), Text: context.Query("q")}
+	if strings.TrimSpace(query.Text) == "" {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Missing search query"})
+		return
+	}
+	if limit := context.Query("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > models.MaxTodoLimit {
+			context.JSON(http.StatusBadRequest, gin.H{"message": "Invalid limit"})
+			return
+		}
+		query.Limit = value
+	}
+
+	results, err := h.store.SearchTodos(query)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to search todos"})
+		return
+	}
+	if results == nil {
+		results = []models.SearchResult{}
+	}
+	context.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,61 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"project_todo/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchTodos(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	other := signupAndLogin(t, server, "jane@example.com")
+	doRequest(server, "POST", "/todos", token, `{"title":"Camping trip","list":[{"item":"tent"},{"item":"camping stove"}]}`)
+	doRequest(server, "POST", "/todos", token, `{"title":"Groceries","list":[{"item":"milk"}]}`)
+	doRequest(server, "POST", "/todos", other, `{"title":"Camping gear","list":[{"item":"tent"}]}`)
+
+	w := doRequest(server, "GET", "/todos/search?q=camping", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var results []models.SearchResult
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &results))
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Camping trip", results[0].Todo.Title)
+	assert.Equal(t, "<mark>Camping</mark> trip", results[0].TitleSnippet)
+	assert.Equal(t, []models.ItemSnippet{{ItemID: 2, Snippet: "<mark>camping</mark> stove"}}, results[0].ItemSnippets)
+
+	w = doRequest(server, "GET", "/todos/search?q=bicycle", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+
+	w = doRequest(server, "GET", "/todos/search?q=%20", token, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequest(server, "GET", "/todos/search?q=tent&limit=500", token, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequest(server, "GET", "/todos/search?q=tent", "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestSearchTodos_StoreError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
+	store.searchTodos = func(query models.SearchQuery) ([]models.SearchResult, error) {
+		return nil, errors.New("database down")
+	}
+
+	c.Set("userId", int64(10))
+	c.Request = httptest.NewRequest("GET", "/todos/search?q=tent", nil)
+	h.searchTodos(c)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"message":"Unable to search todos"}`, w.Body.String())
+}
@@ -0,0 +1,51 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"project_todo/models"
+	"project_todo/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getSessions lists where the user is logged in, most recently used
+// first, marking the session the request came from.
+func (h *handler) getSessions(context *gin.Context) {
+	since := time.Now().Add(-utils.RefreshTokenTTL())
+	sessions, err := h.store.GetSessions(context.GetInt64("userId"), since)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch sessions"})
+		return
+	}
+	if sessions == nil {
+		sessions = []models.Session{}
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == context.GetInt64("sessionId")
+	}
+	context.JSON(http.StatusOK, sessions)
+}
+
+// deleteSession logs one of the user's sessions out, e.g. on a lost
+// device. Its tokens stop working at once.
+func (h *handler) deleteSession(context *gin.Context) {
+	sessionId, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse session id"})
+		return
+	}
+	err = h.store.RevokeSession(context.GetInt64("userId"), sessionId, time.Now())
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Session not found"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to revoke session"})
+		return
+	}
+	h.revocations.Reset()
+	context.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
@@ -0,0 +1,76 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"project_todo/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// loginFrom logs in as email with the given user agent.
+func loginFrom(t *testing.T, server *gin.Engine, email, userAgent string) tokenResponse {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/login", bytes.NewBufferString(`{"email":"`+email+`","password":"password123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	server.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tokens tokenResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+	return tokens
+}
+
+func TestSessions_ListAndRevoke(t *testing.T) {
+	server := newTestServer()
+	stranger := signupAndLogin(t, server, "jim@example.com")
+	signup(t, server, "john@example.com")
+	laptop := loginFrom(t, server, "john@example.com", "Firefox")
+	phone := loginFrom(t, server, "john@example.com", "Safari")
+
+	w := doRequest(server, "GET", "/sessions", laptop.Token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var sessions []models.Session
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &sessions))
+	if assert.Len(t, sessions, 2) {
+		assert.Equal(t, "Safari", sessions[0].UserAgent)
+		assert.False(t, sessions[0].Current)
+		assert.Equal(t, "Firefox", sessions[1].UserAgent)
+		assert.True(t, sessions[1].Current)
+	}
+
+	// Revoking the phone's session locks it out at once, refreshing
+	// included; the laptop stays logged in.
+	w = doRequest(server, "DELETE", "/sessions/3", stranger, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(server, "DELETE", "/sessions/3", laptop.Token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "GET", "/todos", phone.Token, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	code, _ := refresh(t, server, phone.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	w = doRequest(server, "GET", "/sessions", laptop.Token, "")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &sessions))
+	assert.Len(t, sessions, 1)
+
+	// Logging out revokes the access token too.
+	w = doRequest(server, "POST", "/logout", "", `{"refreshToken":"`+laptop.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "GET", "/todos", laptop.Token, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestSessions_UntrackedTokens(t *testing.T) {
+	server := newTestServer()
+	signupAndLogin(t, server, "john@example.com")
+	token := signedToken(t, jwt.MapClaims{"email": "john@example.com", "userId": 1, "jti": "untracked",
+		"iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix()})
+	w := doRequest(server, "GET", "/todos", token, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,231 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"project_todo/models"
+	"project_todo/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type shareLinkRequest struct {
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// sharedTodo is what a share link shows: the todo's content and nothing
+// that identifies its owner or leads to other todos. Due dates are given
+// in the todo's time zone.
+type sharedTodo struct {
+	Title     string       `json:"title"`
+	List      []sharedItem `json:"list"`
+	IsActive  bool         `json:"isActive"`
+	DueAt     *time.Time   `json:"dueAt,omitempty"`
+	Priority  string       `json:"priority,omitempty"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+}
+
+type sharedItem struct {
+	Item    string     `json:"item"`
+	Checked bool       `json:"checked"`
+	DueAt   *time.Time `json:"dueAt,omitempty"`
+}
+
+func newSharedTodo(todo models.Todo) sharedTodo {
+	location := todo.Location()
+	in := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		local := t.In(location)
+		return &local
+	}
+	shared := sharedTodo{
+		Title:     todo.Title,
+		List:      []sharedItem{},
+		IsActive:  todo.IsActive,
+		DueAt:     in(todo.DueAt),
+		Priority:  todo.Priority,
+		UpdatedAt: todo.UpdatedAt,
+	}
+	for _, item := range todo.List {
+		shared.List = append(shared.List, sharedItem{Item: item.Item, Checked: item.Checked, DueAt: in(item.DueAt)})
+	}
+	return shared
+}
+
+// todoLink finds the link named by the :linkId param among todo's links. On
+// failure it writes the response and returns nil.
+func (h *handler) todoLink(context *gin.Context, todo *models.Todo) *models.ShareLink {
+	linkId, err := strconv.ParseInt(context.Param("linkId"), 10, 64)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse link id"})
+		return nil
+	}
+	links, err := h.store.GetShareLinks(todo.ID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch share links"})
+		return nil
+	}
+	for _, link := range links {
+		if link.ID == linkId {
+			return &link
+		}
+	}
+	context.JSON(http.StatusNotFound, gin.H{"message": "Share link not found"})
+	return nil
+}
+
+func (h *handler) getShareLinks(context *gin.Context) {
+	todo := h.accessibleTodo(context, models.ActionShare)
+	if todo == nil {
+		return
+	}
+	links, err := h.store.GetShareLinks(todo.ID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch share links"})
+		return
+	}
+	if links == nil {
+		links = []models.ShareLink{}
+	}
+	context.JSON(http.StatusOK, links)
+}
+
+// createShareLink hands out a new link to the todo, valid until expiresAt
+// or, without one, until it is revoked.
+func (h *handler) createShareLink(context *gin.Context) {
+	todo := h.accessibleTodo(context, models.ActionShare)
+	if todo == nil {
+		return
+	}
+	var request shareLinkRequest
+	if context.Request.ContentLength != 0 {
+		err := context.ShouldBindJSON(&request)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+			return
+		}
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Expiry must be in the future"})
+		return
+	}
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to create share link"})
+		return
+	}
+	link := models.ShareLink{
+		TodoID:    todo.ID,
+		Token:     token,
+		CreatedBy: context.GetInt64("userId"),
+		ExpiresAt: request.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	err = h.store.CreateShareLink(&link)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to create share link"})
+		return
+	}
+	context.JSON(http.StatusCreated, gin.H{"message": "Share link created", "link": link, "url": "/shared/" + link.Token})
+}
+
+func (h *handler) revokeShareLink(context *gin.Context) {
+	todo := h.accessibleTodo(context, models.ActionShare)
+	if todo == nil {
+		return
+	}
+	link := h.todoLink(context, todo)
+	if link == nil {
+		return
+	}
+	err := h.store.RevokeShareLink(todo.ID, link.ID, time.Now())
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to revoke share link"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Share link revoked"})
+}
+
+func (h *handler) getLinkAccesses(context *gin.Context) {
+	todo := h.accessibleTodo(context, models.ActionShare)
+	if todo == nil {
+		return
+	}
+	link := h.todoLink(context, todo)
+	if link == nil {
+		return
+	}
+	accesses, err := h.store.GetLinkAccesses(link.ID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch access log"})
+		return
+	}
+	if accesses == nil {
+		accesses = []models.LinkAccess{}
+	}
+	context.JSON(http.StatusOK, accesses)
+}
+
+// getSharedTodo shows the todo behind a share link to anyone, logged in or
+// not, as JSON or, for browsers asking for HTML, as the shared.html page.
+// Unknown and revoked links look the same. Every view is logged.
+func (h *handler) getSharedTodo(context *gin.Context) {
+	// The token is the only secret, so keep it out of caches, referrers and
+	// search engines.
+	context.Header("Cache-Control", "no-store")
+	context.Header("Referrer-Policy", "no-referrer")
+	context.Header("X-Robots-Tag", "noindex")
+
+	link, err := h.store.GetShareLink(context.Param("token"))
+	if errors.Is(err, models.ErrNotFound) || (err == nil && link.RevokedAt != nil) {
+		writeShared(context, http.StatusNotFound, nil, "Share link not found")
+		return
+	}
+	if err != nil {
+		writeShared(context, http.StatusInternalServerError, nil, "Unable to fetch share link")
+		return
+	}
+	if link.Expired(time.Now()) {
+		writeShared(context, http.StatusGone, nil, "Share link has expired")
+		return
+	}
+	todo, err := h.store.GetTodoById(link.TodoID)
+	if errors.Is(err, models.ErrNotFound) || (err == nil && todo.DeletedAt != nil) {
+		writeShared(context, http.StatusNotFound, nil, "Share link not found")
+		return
+	}
+	if err != nil {
+		writeShared(context, http.StatusInternalServerError, nil, "Unable to fetch todo")
+		return
+	}
+
+	access := models.LinkAccess{
+		LinkID:     link.ID,
+		IP:         context.ClientIP(),
+		UserAgent:  context.Request.UserAgent(),
+		AccessedAt: time.Now(),
+	}
+	err = h.store.LogLinkAccess(access)
+	if err != nil {
+		fmt.Println("Unable to log share link access", link.ID, err)
+	}
+	shared := newSharedTodo(*todo)
+	writeShared(context, http.StatusOK, &shared, "")
+}
+
+func writeShared(context *gin.Context, status int, todo *sharedTodo, message string) {
+	if context.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML {
+		context.HTML(status, "shared.html", gin.H{"todo": todo, "message": message})
+		return
+	}
+	if todo == nil {
+		context.JSON(status, gin.H{"message": message})
+		return
+	}
+	context.JSON(status, todo)
+}
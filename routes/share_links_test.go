@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShareLinks(t *testing.T) {
+	server := newTestServer()
+	server.LoadHTMLFiles("../static/shared.html")
+	owner := signupAndLogin(t, server, "john@example.com")
+	editor := signupAndLogin(t, server, "jane@example.com")
+	doRequest(server, "POST", "/todos", owner, `{"title":"<b>Groceries</b>","list":[{"item":"milk"}]}`)
+	doRequest(server, "POST", "/todos", owner, `{"title":"Private"}`)
+	doRequest(server, "POST", "/todos/1/shares", owner, `{"email":"jane@example.com","role":"editor"}`)
+
+	w := doRequest(server, "POST", "/todos/1/links", editor, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(server, "POST", "/todos/1/links", owner, `{"expiresAt":"2000-01-01T00:00:00Z"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(server, "POST", "/todos/1/links", owner, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var response struct {
+		URL string `json:"url"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	// Anyone with the link sees the todo, but not who owns it.
+	w = doRequest(server, "GET", response.URL, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var shared map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &shared))
+	assert.Equal(t, "<b>Groceries</b>", shared["title"])
+	assert.NotContains(t, w.Body.String(), "userId")
+	assert.NotContains(t, w.Body.String(), `"id"`)
+
+	req := httptest.NewRequest("GET", response.URL, nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("User-Agent", "Browser/1.0")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), "&lt;b&gt;Groceries&lt;/b&gt;")
+	assert.Contains(t, w.Body.String(), "milk")
+
+	w = doRequest(server, "GET", "/todos/1/links/1/accesses", owner, "")
+	var accesses []map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &accesses))
+	if assert.Len(t, accesses, 2) {
+		assert.Equal(t, "Browser/1.0", accesses[0]["userAgent"])
+	}
+	w = doRequest(server, "GET", "/todos/1/links", owner, "")
+	assert.Contains(t, w.Body.String(), `"accesses":2`)
+	w = doRequest(server, "GET", "/todos/2/links/1/accesses", owner, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Revoked links look like links that never existed.
+	w = doRequest(server, "DELETE", "/todos/1/links/1", owner, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "GET", response.URL, "", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(server, "GET", "/shared/nope", "", "")
+	assert.JSONEq(t, `{"message":"Share link not found"}`, w.Body.String())
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	w = doRequest(server, "POST", "/todos/2/links", owner, `{"expiresAt":"`+expiresAt+`"}`)
+	assert.Contains(t, w.Body.String(), "expiresAt")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	w = doRequest(server, "GET", response.URL, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	doRequestIfMatch(server, "DELETE", "/todos/2", owner, "*", "")
+	w = doRequest(server, "GET", response.URL, "", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,153 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"project_todo/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type shareRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// authorize applies the sharing policy (models.Allows) to the authenticated
+// user's role on todo, or for workspace todos the workspace policy
+// (models.WorkspaceAllows) to their role in the workspace. Workspace todos
+// are only reachable under their workspace's routes and personal todos
+// only outside of any. Users without access get 401 with message, users
+// whose role does not allow action get 403. On success a todo shared with
+// the user is marked with their role.
+func (h *handler) authorize(context *gin.Context, todo *models.Todo, action models.Action, message string) bool {
+	userId := context.GetInt64("userId")
+	workspaceId := context.GetInt64("workspaceId")
+	if todo.WorkspaceID != workspaceId {
+		context.JSON(http.StatusUnauthorized, gin.H{"message": message})
+		return false
+	}
+	if workspaceId != 0 {
+		if !models.WorkspaceAllows(context.GetString("workspaceRole"), action) {
+			context.JSON(http.StatusForbidden, gin.H{"message": "Your workspace role does not allow that"})
+			return false
+		}
+		return true
+	}
+
+	role := models.RoleOwner
+	if todo.UserID != userId {
+		var err error
+		role, err = h.store.GetShareRole(todo.ID, userId)
+		if err != nil {
+			context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch todo"})
+			return false
+		}
+	}
+	if role == "" {
+		context.JSON(http.StatusUnauthorized, gin.H{"message": message})
+		return false
+	}
+	if !models.Allows(role, action) {
+		context.JSON(http.StatusForbidden, gin.H{"message": "Your role on this todo does not allow that"})
+		return false
+	}
+	if role != models.RoleOwner {
+		todo.Shared, todo.Role = true, role
+	}
+	return true
+}
+
+// getShares lists who the todo is shared with; anyone with access may see
+// it.
+func (h *handler) getShares(context *gin.Context) {
+	todo := h.accessibleTodo(context, models.ActionView)
+	if todo == nil {
+		return
+	}
+	shares, err := h.store.GetShares(todo.ID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch shares"})
+		return
+	}
+	if shares == nil {
+		shares = []models.Share{}
+	}
+	context.JSON(http.StatusOK, shares)
+}
+
+// shareTodo grants the user with the given email viewer or editor access,
+// or changes the role they already have.
+func (h *handler) shareTodo(context *gin.Context) {
+	todo := h.accessibleTodo(context, models.ActionShare)
+	if todo == nil {
+		return
+	}
+	var request shareRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil || strings.TrimSpace(request.Email) == "" {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+		return
+	}
+	request.Role = strings.ToLower(request.Role)
+	if request.Role == "" {
+		request.Role = models.RoleViewer
+	}
+	if !models.IsValidShareRole(request.Role) {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Invalid role, use viewer or editor"})
+		return
+	}
+	user, err := h.store.GetUserByEmail(strings.TrimSpace(request.Email))
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch user"})
+		return
+	}
+	if user.ID == todo.UserID {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Cannot share a todo with its owner"})
+		return
+	}
+
+	share := models.Share{TodoID: todo.ID, UserID: user.ID, Role: request.Role, CreatedAt: time.Now()}
+	err = h.store.ShareTodo(&share)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to share todo"})
+		return
+	}
+	share.Email = user.Email
+	context.JSON(http.StatusOK, gin.H{"message": "Todo shared", "share": share})
+}
+
+// unshareTodo revokes a user's access. The owner can remove anyone; other
+// users can only remove themselves.
+func (h *handler) unshareTodo(context *gin.Context) {
+	shareUserId, err := strconv.ParseInt(context.Param("userId"), 10, 64)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse user id"})
+		return
+	}
+	action := models.ActionShare
+	if shareUserId == context.GetInt64("userId") {
+		action = models.ActionView
+	}
+	todo := h.accessibleTodo(context, action)
+	if todo == nil {
+		return
+	}
+	err = h.store.UnshareTodo(todo.ID, shareUserId)
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Share not found"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to remove share"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Share removed"})
+}
@@ -0,0 +1,96 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShares_Policy(t *testing.T) {
+	server := newTestServer()
+	owner := signupAndLogin(t, server, "john@example.com")
+	viewer := signupAndLogin(t, server, "jane@example.com")
+	editor := signupAndLogin(t, server, "joe@example.com")
+	stranger := signupAndLogin(t, server, "jim@example.com")
+	doRequest(server, "POST", "/todos", owner, `{"title":"Groceries","list":[{"item":"milk"}]}`)
+	doRequest(server, "POST", "/todos", viewer, `{"title":"Own"}`)
+
+	w := doRequest(server, "POST", "/todos/1/shares", owner, `{"email":"jane@example.com"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"role":"viewer"`)
+	w = doRequest(server, "POST", "/todos/1/shares", owner, `{"email":"joe@example.com","role":"Editor"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	for body, code := range map[string]int{
+		`{"email":"nobody@example.com"}`:             http.StatusNotFound,
+		`{"email":"john@example.com"}`:               http.StatusBadRequest,
+		`{"email":"jim@example.com","role":"owner"}`: http.StatusBadRequest,
+		`{"role":"viewer"}`:                          http.StatusBadRequest,
+		`{"email":"jim@example.com","role":"viewer"`: http.StatusBadRequest,
+	} {
+		w = doRequest(server, "POST", "/todos/1/shares", owner, body)
+		assert.Equal(t, code, w.Code, body)
+	}
+
+	// Shared todos show up in the recipient's list, marked as shared.
+	w = doRequest(server, "GET", "/todos", viewer, "")
+	var todos []map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &todos))
+	if assert.Len(t, todos, 2) {
+		assert.Equal(t, "Groceries", todos[0]["title"])
+		assert.Equal(t, true, todos[0]["shared"])
+		assert.Equal(t, "viewer", todos[0]["role"])
+		assert.Nil(t, todos[1]["shared"])
+	}
+	w = doRequest(server, "GET", "/todos", owner, "")
+	assert.NotContains(t, w.Body.String(), "shared")
+
+	// Viewers may read, editors may also edit, only the owner deletes and
+	// shares; strangers get nothing.
+	w = doRequest(server, "GET", "/todos/1", viewer, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"role":"viewer"`)
+	w = doRequest(server, "GET", "/todos/1", stranger, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequestIfMatch(server, "PUT", "/todos/1", viewer, "*", `{"title":"Mine now"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(server, "POST", "/todos/1/items", viewer, `{"item":"eggs"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequestIfMatch(server, "POST", "/todos/1/items", editor, "*", `{"item":"eggs"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = doPatch(server, "/todos/1", editor, mergePatchType, "*", `{"title":"Weekly groceries"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doPatch(server, "/todos/1", editor, mergePatchType, "*", `{"role":"owner"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = doRequest(server, "POST", "/todos/1/tags", editor, `{"tags":["food"]}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequestIfMatch(server, "DELETE", "/todos/1", editor, "*", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(server, "POST", "/todos/1/shares", editor, `{"email":"jim@example.com"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	todo := getTodo(t, server, owner, "/todos/1")
+	assert.Equal(t, "Weekly groceries", todo.Title)
+	assert.Len(t, todo.List, 2)
+
+	w = doRequest(server, "GET", "/todos/1/shares", viewer, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var shares []map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &shares))
+	assert.Len(t, shares, 2)
+	w = doRequest(server, "GET", "/todos/1/shares", stranger, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Recipients can leave; only the owner removes others.
+	w = doRequest(server, "DELETE", "/todos/1/shares/3", viewer, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(server, "DELETE", "/todos/1/shares/2", viewer, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "GET", "/todos/1", viewer, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(server, "DELETE", "/todos/1/shares/3", owner, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "DELETE", "/todos/1/shares/3", owner, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
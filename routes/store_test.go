@@ -0,0 +1,104 @@
+package routes
+
+import (
+	"project_todo/models"
+	"time"
+)
+
+// fakeStore lets each test stub only the store calls it cares about.
+// Anything not stubbed falls through to an in-memory store.
+type fakeStore struct {
+	*models.MemoryStore
+	createTodo     func(todo *models.Todo) error
+	getAllTodos    func(query models.TodoQuery) (models.TodoPage, error)
+	getTodoById    func(id int64) (*models.Todo, error)
+	updateTodo     func(todo models.Todo) error
+	deleteTodo     func(todo models.Todo) error
+	trashTodo      func(todo models.Todo) error
+	searchTodos    func(query models.SearchQuery) ([]models.SearchResult, error)
+	createUser     func(user *models.User) error
+	getUserByEmail func(email string) (*models.User, error)
+	recordToken    func(token models.IssuedToken) error
+	failTOTP       func(userId int64) error
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{MemoryStore: models.NewMemoryStore()}
+}
+
+func (f *fakeStore) CreateTodo(todo *models.Todo) error {
+	if f.createTodo == nil {
+		return f.MemoryStore.CreateTodo(todo)
+	}
+	return f.createTodo(todo)
+}
+
+func (f *fakeStore) GetAllTodos(query models.TodoQuery) (models.TodoPage, error) {
+	if f.getAllTodos == nil {
+		return f.MemoryStore.GetAllTodos(query)
+	}
+	return f.getAllTodos(query)
+}
+
+func (f *fakeStore) GetTodoById(id int64) (*models.Todo, error) {
+	if f.getTodoById == nil {
+		return f.MemoryStore.GetTodoById(id)
+	}
+	return f.getTodoById(id)
+}
+
+func (f *fakeStore) UpdateTodo(todo models.Todo, actorId int64) error {
+	if f.updateTodo == nil {
+		return f.MemoryStore.UpdateTodo(todo, actorId)
+	}
+	return f.updateTodo(todo)
+}
+
+func (f *fakeStore) DeleteTodo(todo models.Todo, actorId int64) error {
+	if f.deleteTodo == nil {
+		return f.MemoryStore.DeleteTodo(todo, actorId)
+	}
+	return f.deleteTodo(todo)
+}
+
+func (f *fakeStore) TrashTodo(todo models.Todo, actorId int64) error {
+	if f.trashTodo == nil {
+		return f.MemoryStore.TrashTodo(todo, actorId)
+	}
+	return f.trashTodo(todo)
+}
+
+func (f *fakeStore) SearchTodos(query models.SearchQuery) ([]models.SearchResult, error) {
+	if f.searchTodos == nil {
+		return f.MemoryStore.SearchTodos(query)
+	}
+	return f.searchTodos(query)
+}
+
+func (f *fakeStore) CreateUser(user *models.User) error {
+	if f.createUser == nil {
+		return f.MemoryStore.CreateUser(user)
+	}
+	return f.createUser(user)
+}
+
+func (f *fakeStore) GetUserByEmail(email string) (*models.User, error) {
+	if f.getUserByEmail == nil {
+		return f.MemoryStore.GetUserByEmail(email)
+	}
+	return f.getUserByEmail(email)
+}
+
+func (f *fakeStore) RecordToken(token models.IssuedToken) error {
+	if f.recordToken == nil {
+		return f.MemoryStore.RecordToken(token)
+	}
+	return f.recordToken(token)
+}
+
+func (f *fakeStore) FailTOTP(userId int64, lockout models.TOTPLockout, failedAt time.Time) error {
+	if f.failTOTP == nil {
+		return f.MemoryStore.FailTOTP(userId, lockout, failedAt)
+	}
+	return f.failTOTP(userId)
+}
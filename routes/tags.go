@@ -0,0 +1,283 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"project_todo/models"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxTagName = 50
+
+var tagColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type tagRequest struct {
+	Name  *string `json:"name"`
+	Color *string `json:"color"`
+}
+
+// attachRequest names tags by name, creating the ones the user does not
+// have yet, or by ID. Either way the tags are the authenticated user's own,
+// also on todos shared with them or in a workspace.
+type attachRequest struct {
+	Tags   []string `json:"tags"`
+	TagIds []int64  `json:"tagIds"`
+}
+
+func checkTag(tag *models.Tag) error {
+	tag.Name = strings.TrimSpace(tag.Name)
+	switch {
+	case tag.Name == "":
+		return errors.New("Tag name is required")
+	case len(tag.Name) > maxTagName:
+		return errors.New("Tag name is too long")
+	case tag.Color != "" && !tagColor.MatchString(tag.Color):
+		return errors.New("Invalid color, use #rrggbb")
+	}
+	return nil
+}
+
+// ownedTag loads the tag named by the :tagId param and checks it belongs to
+// the authenticated user. On failure it writes the response and returns nil.
+func (h *handler) ownedTag(context *gin.Context, param string) *models.Tag {
+	tagId, err := strconv.ParseInt(context.Param(param), 10, 64)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse tag id"})
+		return nil
+	}
+	return h.userTag(context, tagId)
+}
+
+func (h *handler) userTag(context *gin.Context, tagId int64) *models.Tag {
+	tag, err := h.store.GetTag(tagId)
+	if errors.Is(err, models.ErrNotFound) || (err == nil && tag.UserID != context.GetInt64("userId")) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Tag not found"})
+		return nil
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch tag"})
+		return nil
+	}
+	return tag
+}
+
+func (h *handler) getTags(context *gin.Context) {
+	tags, err := h.store.GetTags(context.GetInt64("userId"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch tags"})
+		return
+	}
+	if tags == nil {
+		tags = []models.Tag{}
+	}
+	context.JSON(http.StatusOK, tags)
+}
+
+func (h *handler) createTag(context *gin.Context) {
+	var tag models.Tag
+	err := context.ShouldBindJSON(&tag)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+		return
+	}
+	err = checkTag(&tag)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	tag.UserID = context.GetInt64("userId")
+
+	err = h.store.CreateTag(&tag)
+	if errors.Is(err, models.ErrDuplicate) {
+		context.JSON(http.StatusConflict, gin.H{"message": "Tag already exists"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to create tag"})
+		return
+	}
+	context.JSON(http.StatusCreated, gin.H{"message": "Tag created", "tag": tag})
+}
+
+// updateTag renames or recolours a tag. Renaming onto another of the
+// user's tags is refused; merge the two tags instead.
+func (h *handler) updateTag(context *gin.Context) {
+	tag := h.ownedTag(context, "tagId")
+	if tag == nil {
+		return
+	}
+	var request tagRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+		return
+	}
+	if request.Name != nil {
+		tag.Name = *request.Name
+	}
+	if request.Color != nil {
+		tag.Color = *request.Color
+	}
+	err = checkTag(tag)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = h.store.UpdateTag(*tag)
+	if errors.Is(err, models.ErrDuplicate) {
+		context.JSON(http.StatusConflict, gin.H{"message": "Another tag already has that name, merge them instead"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to update tag"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Tag updated", "tag": tag})
+}
+
+func (h *handler) deleteTag(context *gin.Context) {
+	tag := h.ownedTag(context, "tagId")
+	if tag == nil {
+		return
+	}
+	err := h.store.DeleteTag(*tag)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to delete tag"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Tag deleted"})
+}
+
+// mergeTag moves every todo from the :tagId tag onto the tag given as
+// "into" and deletes the first one.
+func (h *handler) mergeTag(context *gin.Context) {
+	source := h.ownedTag(context, "tagId")
+	if source == nil {
+		return
+	}
+	var request struct {
+		Into int64 `json:"into"`
+	}
+	err := context.ShouldBindJSON(&request)
+	if err != nil || request.Into == 0 {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+		return
+	}
+	if request.Into == source.ID {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Cannot merge a tag into itself"})
+		return
+	}
+	target := h.userTag(context, request.Into)
+	if target == nil {
+		return
+	}
+
+	err = h.store.MergeTags(*source, *target)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to merge tags"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Tags merged", "tag": target})
+}
+
+func (h *handler) attachTags(context *gin.Context) {
+	todo := h.accessibleTodo(context, models.ActionOrganize)
+	if todo == nil {
+		return
+	}
+	var request attachRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil || len(request.Tags)+len(request.TagIds) == 0 {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+		return
+	}
+
+	var ids []int64
+	for _, tagId := range request.TagIds {
+		tag := h.userTag(context, tagId)
+		if tag == nil {
+			return
+		}
+		ids = append(ids, tag.ID)
+	}
+	if len(request.Tags) > 0 {
+		named, err := h.namedTags(context.GetInt64("userId"), request.Tags)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		ids = append(ids, named...)
+	}
+
+	err = h.store.AttachTags(todo.ID, ids, context.GetInt64("userId"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to attach tags"})
+		return
+	}
+	updated, err := h.store.GetTodoById(todo.ID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch todo"})
+		return
+	}
+	context.Header("ETag", etag(updated.Version))
+	context.JSON(http.StatusOK, gin.H{"message": "Tags attached", "tags": updated.Tags})
+}
+
+// namedTags returns the IDs of the user's tags with the given names,
+// creating the missing ones without a colour.
+func (h *handler) namedTags(userId int64, names []string) ([]int64, error) {
+	existing, err := h.store.GetTags(userId)
+	if err != nil {
+		return nil, errors.New("Unable to fetch tags")
+	}
+	byName := make(map[string]int64, len(existing))
+	for _, tag := range existing {
+		byName[strings.ToLower(tag.Name)] = tag.ID
+	}
+
+	ids := make([]int64, 0, len(names))
+	for _, name := range names {
+		tag := models.Tag{UserID: userId, Name: name}
+		err = checkTag(&tag)
+		if err != nil {
+			return nil, err
+		}
+		id, ok := byName[strings.ToLower(tag.Name)]
+		if !ok {
+			err = h.store.CreateTag(&tag)
+			if err != nil {
+				return nil, errors.New("Unable to create tag " + tag.Name)
+			}
+			id = tag.ID
+			byName[strings.ToLower(tag.Name)] = id
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (h *handler) detachTag(context *gin.Context) {
+	todo := h.accessibleTodo(context, models.ActionOrganize)
+	if todo == nil {
+		return
+	}
+	tag := h.ownedTag(context, "tagId")
+	if tag == nil {
+		return
+	}
+	err := h.store.DetachTag(todo.ID, tag.ID, context.GetInt64("userId"))
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Tag not attached to todo"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to detach tag"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Tag detached"})
+}
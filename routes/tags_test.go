@@ -0,0 +1,149 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"project_todo/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tagNames(tags []models.Tag) []string {
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func TestTags_AttachAndFilter(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	doRequest(server, "POST", "/todos", token, `{"title":"Report"}`)
+	doRequest(server, "POST", "/todos", token, `{"title":"Groceries"}`)
+	doRequest(server, "POST", "/todos", token, `{"title":"Untagged"}`)
+
+	w := doRequest(server, "POST", "/tags", token, `{"name":"work","color":"#336699"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Names are matched ignoring case and missing tags are created.
+	w = doRequest(server, "POST", "/todos/1/tags", token, `{"tags":["Work","urgent"]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	todo := getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, []string{"urgent", "work"}, tagNames(todo.Tags))
+	assert.Equal(t, "#336699", todo.Tags[1].Color)
+
+	doRequest(server, "POST", "/todos/2/tags", token, `{"tags":["home"]}`)
+
+	w = doRequest(server, "GET", "/todos?tag=work&tag=urgent", token, "")
+	assert.Equal(t, []string{"Report"}, todoTitles(t, w.Body.Bytes()))
+	w = doRequest(server, "GET", "/todos?tag=urgent&tag=home&tagMode=any", token, "")
+	assert.Equal(t, []string{"Report", "Groceries"}, todoTitles(t, w.Body.Bytes()))
+	w = doRequest(server, "GET", "/todos?tag=work&tagMode=some", token, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequest(server, "GET", "/tags", token, "")
+	var tags []models.Tag
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tags))
+	assert.Equal(t, []string{"home", "urgent", "work"}, tagNames(tags))
+
+	w = doRequest(server, "DELETE", "/todos/1/tags/3", token, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(server, "DELETE", "/todos/1/tags/2", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	todo = getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, []string{"work"}, tagNames(todo.Tags))
+
+	// Tags can only be changed through their own endpoints.
+	w = doPatch(server, "/todos/1", token, mergePatchType, "*", `{"tags":[]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestTags_RenameMergeDelete(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	doRequest(server, "POST", "/todos", token, `{"title":"Report"}`)
+	doRequest(server, "POST", "/todos/1/tags", token, `{"tags":["wrk","work"]}`)
+
+	w := doRequest(server, "POST", "/tags", token, `{"name":"WORK"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doRequest(server, "POST", "/tags", token, `{"name":"x","color":"red"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequest(server, "PATCH", "/tags/1", token, `{"name":"Work"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doRequest(server, "PATCH", "/tags/1", token, `{"name":"office","color":"#ff8800"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	todo := getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, []string{"office", "work"}, tagNames(todo.Tags))
+
+	w = doRequest(server, "POST", "/tags/1/merge", token, `{"into":1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(server, "POST", "/tags/1/merge", token, `{"into":2}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	todo = getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, []string{"work"}, tagNames(todo.Tags))
+	w = doRequest(server, "GET", "/tags/1", token, "")
+	assert.NotEqual(t, http.StatusOK, w.Code)
+
+	w = doRequest(server, "DELETE", "/tags/2", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	todo = getTodo(t, server, token, "/todos/1")
+	assert.Empty(t, todo.Tags)
+}
+
+func TestTags_ScopedToTheUser(t *testing.T) {
+	server := newTestServer()
+	john := signupAndLogin(t, server, "john@example.com")
+	jane := signupAndLogin(t, server, "jane@example.com")
+	doRequest(server, "POST", "/tags", john, `{"name":"private"}`)
+	doRequest(server, "POST", "/todos", jane, `{"title":"Jane's"}`)
+
+	w := doRequest(server, "PATCH", "/tags/1", jane, `{"name":"mine"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(server, "POST", "/todos/1/tags", jane, `{"tagIds":[1]}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The same name is free for another user.
+	w = doRequest(server, "POST", "/tags", jane, `{"name":"private"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestTags_WorkspaceTodosGetTheRequestersTags(t *testing.T) {
+	server := newTestServer()
+	owner := signupAndLogin(t, server, "john@example.com")
+	member := signupAndLogin(t, server, "jane@example.com")
+	doRequest(server, "POST", "/workspaces", owner, `{"name":"Team"}`)
+	joinWorkspace(t, server, owner, member, "jane@example.com", "member")
+	doRequest(server, "POST", "/workspaces/1/todos", owner, `{"title":"Release"}`)
+	doRequest(server, "POST", "/tags", owner, `{"name":"urgent"}`)
+	doRequest(server, "POST", "/tags", member, `{"name":"ops"}`)
+
+	// Names and IDs both resolve to the member's own tags, not the creator's.
+	w := doRequest(server, "POST", "/workspaces/1/todos/1/tags", member, `{"tags":["urgent"],"tagIds":[2]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Tags []models.Tag `json:"tags"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []string{"ops", "urgent"}, tagNames(response.Tags))
+	assert.Equal(t, []int64{2, 3}, []int64{response.Tags[0].ID, response.Tags[1].ID})
+	w = doRequest(server, "POST", "/workspaces/1/todos/1/tags", member, `{"tagIds":[1]}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Only the member can take their tags off again.
+	w = doRequest(server, "DELETE", "/workspaces/1/todos/1/tags/2", owner, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(server, "DELETE", "/workspaces/1/todos/1/tags/2", member, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doRequest(server, "GET", "/tags", owner, "")
+	var tags []models.Tag
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tags))
+	assert.Equal(t, []string{"urgent"}, tagNames(tags))
+	w = doRequest(server, "GET", "/tags", member, "")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tags))
+	assert.Equal(t, []string{"ops", "urgent"}, tagNames(tags))
+}
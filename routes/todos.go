@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"errors"
 	"net/http"
 	"project_todo/models"
 	"strconv"
@@ -9,17 +10,45 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func getAllTodos(context *gin.Context) {
-	userId := context.GetInt64("userId")
-	todos, err := models.GetAllTodos(userId)
+// getAllTodos returns a page of todos as a plain array. When more results
+// exist the cursor for the next page is sent in the X-Next-Cursor header
+// and as a Link header.
+func (h *handler) getAllTodos(context *gin.Context) {
+	query, err := parseTodoQuery(context)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	query.UserID = context.GetInt64("userId")
+	query.WorkspaceID = context.GetInt64("workspaceId")
+	query.IncludeShared = query.WorkspaceID == 0
+	h.writeTodoPage(context, query)
+}
+
+// writeTodoPage runs query and writes the page the way getAllTodos
+// returns it.
+func (h *handler) writeTodoPage(context *gin.Context, query models.TodoQuery) {
+	page, err := h.store.GetAllTodos(query)
+	if errors.Is(err, models.ErrInvalidCursor) {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Invalid cursor"})
+		return
+	}
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch todos"})
 		return
 	}
-	context.JSON(http.StatusOK, todos)
+	if page.NextCursor != "" {
+		next := *context.Request.URL
+		values := next.Query()
+		values.Set("cursor", page.NextCursor)
+		next.RawQuery = values.Encode()
+		context.Header("X-Next-Cursor", page.NextCursor)
+		context.Header("Link", "<"+next.RequestURI()+`>; rel="next"`)
+	}
+	context.JSON(http.StatusOK, page.Todos)
 }
 
-func createTodo(context *gin.Context) {
+func (h *handler) createTodo(context *gin.Context) {
 	var todo models.Todo
 	err := context.ShouldBindJSON(&todo)
 
@@ -27,53 +56,80 @@ func createTodo(context *gin.Context) {
 		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
 		return
 	}
+	err = checkTimeZone(todo)
+	if err == nil {
+		err = normalizeRecurrence(&todo)
+	}
+	if err == nil {
+		err = normalizePriority(&todo)
+	}
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if !h.projectAllowed(context, http.StatusBadRequest, context.GetInt64("userId"), todo.ProjectID, 0) {
+		return
+	}
+	// A new todo always starts its own series.
+	todo.SeriesID, todo.Occurrence, todo.NextID = 0, 0, 0
 	todo.UserID = context.GetInt64("userId")
+	todo.WorkspaceID = context.GetInt64("workspaceId")
 	todo.CreatedAt = time.Now()
 	todo.UpdatedAt = time.Now()
 	todo.IsActive = true
 
-	err = todo.Save()
+	err = h.store.CreateTodo(&todo)
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to create todo"})
 		return
 	}
+	context.Header("ETag", etag(todo.Version))
 	context.JSON(http.StatusCreated, gin.H{"message": "Todo created", "todo": todo})
 }
 
-func getTodoById(context *gin.Context) {
+func (h *handler) getTodoById(context *gin.Context) {
 	todoId, err := strconv.ParseInt(context.Param("id"), 10, 64)
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to parse todo id"})
 		return
 	}
-	userId := context.GetInt64("userId")
-	todo, err := models.GetTodoById(todoId)
+	todo, err := h.store.GetTodoById(todoId)
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch todo"})
 		return
 	}
-	if todo.UserID != userId {
-		context.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized access"})
+	if !h.authorize(context, todo, models.ActionView, "Unauthorized access") {
 		return
 	}
+	if todo.DeletedAt != nil {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Todo not found"})
+		return
+	}
+	context.Header("ETag", etag(todo.Version))
 	context.JSON(http.StatusOK, todo)
 }
 
-func updateTodoById(context *gin.Context) {
+func (h *handler) updateTodoById(context *gin.Context) {
 	todoId, err := strconv.ParseInt(context.Param("id"), 10, 64)
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to parse todo id"})
 		return
 	}
 
-	userId := context.GetInt64("userId")
-	todo, err := models.GetTodoById(todoId)
+	todo, err := h.store.GetTodoById(todoId)
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch todo to update"})
 		return
 	}
-	if todo.UserID != userId {
-		context.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized update"})
+	if !h.authorize(context, todo, models.ActionEdit, "Unauthorized update") {
+		return
+	}
+	if todo.DeletedAt != nil {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Todo not found"})
+		return
+	}
+	version, ok := ifMatch(context, todo)
+	if !ok {
 		return
 	}
 
@@ -83,37 +139,74 @@ func updateTodoById(context *gin.Context) {
 		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
 		return
 	}
+	err = checkTimeZone(modifiedTodo)
+	if err == nil {
+		err = normalizeRecurrence(&modifiedTodo)
+	}
+	if err == nil {
+		err = normalizePriority(&modifiedTodo)
+	}
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if !h.projectAllowed(context, http.StatusBadRequest, todo.UserID, modifiedTodo.ProjectID, todo.ProjectID) {
+		return
+	}
 	modifiedTodo.ID = todoId
 	modifiedTodo.UpdatedAt = time.Now()
-	err = modifiedTodo.Update()
+	modifiedTodo.Version = version
+	err = h.store.UpdateTodo(modifiedTodo, context.GetInt64("userId"))
+	if errors.Is(err, models.ErrVersionConflict) {
+		preconditionFailed(context)
+		return
+	}
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to update todo"})
 		return
 	}
-	context.JSON(http.StatusOK, gin.H{"message": "Todo updated successfully"})
+	// Without the todo as stored now there is simply no undo token or ETag.
+	updated, _ := h.store.GetTodoById(todoId)
+	if updated != nil {
+		context.Header("ETag", etag(updated.Version))
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Todo updated successfully", "undoToken": h.issueUndo(context, models.HistoryUpdated, updated)})
 }
 
-func deleteTodoById(context *gin.Context) {
+// deleteTodoById moves the todo to the trash; see trash.go for restoring
+// and permanent deletes.
+func (h *handler) deleteTodoById(context *gin.Context) {
 	todoId, err := strconv.ParseInt(context.Param("id"), 10, 64)
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to parse todo id"})
 		return
 	}
-	userId := context.GetInt64("userId")
-	todo, err := models.GetTodoById(todoId)
+	todo, err := h.store.GetTodoById(todoId)
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch the todo"})
 		return
 	}
-	if todo.UserID != userId {
-		context.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized delete"})
+	if !h.authorize(context, todo, models.ActionDelete, "Unauthorized delete") {
+		return
+	}
+	if todo.DeletedAt != nil {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Todo not found"})
+		return
+	}
+	version, ok := ifMatch(context, todo)
+	if !ok {
+		return
+	}
+	todo.Version = version
+	err = h.store.TrashTodo(*todo, context.GetInt64("userId"))
+	if errors.Is(err, models.ErrVersionConflict) {
+		preconditionFailed(context)
 		return
 	}
-	err = todo.Delete()
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to delete the todo"})
 		return
 	}
-
-	context.JSON(http.StatusOK, gin.H{"message": "Todo deleted successfully"})
+	trashed, _ := h.store.GetTodoById(todo.ID)
+	context.JSON(http.StatusOK, gin.H{"message": "Todo moved to trash", "undoToken": h.issueUndo(context, models.HistoryTrashed, trashed)})
 }
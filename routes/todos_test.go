@@ -2,17 +2,16 @@ package routes
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"project_todo/models"
-	"reflect"
 	"strconv"
 	"testing"
 	"time"
 
-	"bou.ke/monkey"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -21,6 +20,8 @@ func TestGetAllTodosSuccess(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	mockDate := time.Now().Truncate(time.Second)
 	mockUserId := int64(10)
@@ -31,6 +32,7 @@ func TestGetAllTodosSuccess(t *testing.T) {
 			Title: "Test todo1",
 			List: []models.TodoItem{
 				models.TodoItem{
+					ID:      1,
 					Item:    "sample data",
 					Checked: false,
 				},
@@ -39,12 +41,14 @@ func TestGetAllTodosSuccess(t *testing.T) {
 			CreatedAt: mockDate,
 			UpdatedAt: mockDate,
 			UserID:    10,
+			Version:   1,
 		},
 		models.Todo{
 			ID:    2,
 			Title: "Test todo2",
 			List: []models.TodoItem{
 				models.TodoItem{
+					ID:      2,
 					Item:    "sample data2",
 					Checked: false,
 				},
@@ -53,19 +57,20 @@ func TestGetAllTodosSuccess(t *testing.T) {
 			CreatedAt: mockDate,
 			UpdatedAt: mockDate,
 			UserID:    10,
+			Version:   1,
 		},
 	}
-	monkey.Patch(models.GetAllTodos, func(userId int64) ([]models.Todo, error) {
-		t.Log("userId", userId)
-		if userId == 10 {
-			return mockTodos, nil
+	store.getAllTodos = func(query models.TodoQuery) (models.TodoPage, error) {
+		t.Log("userId", query.UserID)
+		if query.UserID == 10 {
+			return models.TodoPage{Todos: mockTodos}, nil
 		}
-		return []models.Todo{}, nil
-	})
-	defer monkey.Unpatch(models.GetAllTodos)
+		return models.TodoPage{}, nil
+	}
 
 	c.Set("userId", mockUserId)
-	getAllTodos(c)
+	c.Request = httptest.NewRequest("GET", "/todos", nil)
+	h.getAllTodos(c)
 	assert.Equal(t, http.StatusOK, w.Code)
 	expectedResponse := `[
 		{
@@ -73,28 +78,34 @@ func TestGetAllTodosSuccess(t *testing.T) {
 		  "title": "Test todo1",
 		  "list": [
 			{
+			  "id": 1,
 			  "item": "sample data",
+			  "position": 0,
 			  "checked": false
 			}
 		  ],
 		  "isActive": true,
 		  "createdAt":  "` + mockDate.Format(time.RFC3339Nano) + `",
 		  "updatedAt":  "` + mockDate.Format(time.RFC3339Nano) + `",
-		  "userId": 10
+		  "userId": 10,
+		  "version": 1
 		},
 		{
 		  "id": 2,
 		  "title": "Test todo2",
 		  "list": [
 			{
+			  "id": 2,
 			  "item": "sample data2",
+			  "position": 0,
 			  "checked": false
 			}
 		  ],
 		  "isActive": true,
 		  "createdAt":  "` + mockDate.Format(time.RFC3339Nano) + `",
 		  "updatedAt":  "` + mockDate.Format(time.RFC3339Nano) + `",
-		  "userId": 10
+		  "userId": 10,
+		  "version": 1
 		}
 	  ]`
 	assert.JSONEq(t, expectedResponse, w.Body.String())
@@ -104,12 +115,13 @@ func TestGetAllTodos_FetchError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	// Patch models.GetAllTodos to simulate a fetch error
-	monkey.Patch(models.GetAllTodos, func(userId int64) ([]models.Todo, error) {
-		return nil, errors.New("fetch error")
-	})
-	defer monkey.Unpatch(models.GetAllTodos)
+	store.getAllTodos = func(query models.TodoQuery) (models.TodoPage, error) {
+		return models.TodoPage{}, errors.New("fetch error")
+	}
 
 	// Set the userId in the context
 	c.Set("userId", int64(10))
@@ -118,7 +130,7 @@ func TestGetAllTodos_FetchError(t *testing.T) {
 	c.Request = httptest.NewRequest("GET", "/todos", nil)
 
 	// Call the handler function
-	getAllTodos(c)
+	h.getAllTodos(c)
 
 	// Assert that the response status code is 500 Internal Server Error
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
@@ -132,29 +144,18 @@ func TestCreateTodoSuccess(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
-	// Mock current time to ensure consistency
-	mockTime := time.Date(2024, time.August, 26, 18, 2, 39, 0, time.Local)
-	monkey.Patch(time.Now, func() time.Time {
-		return mockTime
-	})
-
-	mockTodo := models.Todo{
-		Title:     "Test todo",
-		List:      []models.TodoItem{{Item: "sample data", Checked: false}},
-		IsActive:  true,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		UserID:    10,
-	}
-
-	// Patch the Save method
-	monkey.PatchInstanceMethod(reflect.TypeOf(&models.Todo{}), "Save", func(t *models.Todo) error {
+	// Capture what the handler hands to the store
+	var savedTodo models.Todo
+	store.createTodo = func(t *models.Todo) error {
 		// Simulate a successful save by setting the ID
 		t.ID = 1
+		t.Version = 1
+		savedTodo = *t
 		return nil
-	})
-	defer monkey.UnpatchInstanceMethod(reflect.TypeOf(&models.Todo{}), "Save")
+	}
 
 	// Set the userId in the context
 	c.Set("userId", int64(10))
@@ -167,7 +168,7 @@ func TestCreateTodoSuccess(t *testing.T) {
 	c.Request.Header.Set("Content-Type", "application/json")
 
 	// Call the handler function
-	createTodo(c)
+	h.createTodo(c)
 
 	// Assert that the response status code is 201 Created
 	assert.Equal(t, http.StatusCreated, w.Code)
@@ -178,20 +179,24 @@ func TestCreateTodoSuccess(t *testing.T) {
 			"todo": {
 				"id": 1,
 				"title": "Test todo",
-				"list": [{"item": "sample data", "checked": false}],
+				"list": [{"id": 0, "item": "sample data", "checked": false, "position": 0}],
 				"isActive": true,
-				"createdAt": "` + mockTodo.CreatedAt.Format(time.RFC3339Nano) + `",
-				"updatedAt": "` + mockTodo.UpdatedAt.Format(time.RFC3339Nano) + `",
-				"userId": 10
+				"createdAt": "` + savedTodo.CreatedAt.Format(time.RFC3339Nano) + `",
+				"updatedAt": "` + savedTodo.UpdatedAt.Format(time.RFC3339Nano) + `",
+				"userId": 10,
+				"version": 1
 			}
 		}`
 	assert.JSONEq(t, expectedResponse, w.Body.String())
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
 }
 
 func TestCreateTodo_BindJSONError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	// Simulate the request with invalid JSON body
 	c.Request = httptest.NewRequest("POST", "/todos", io.NopCloser(bytes.NewBufferString(`{
@@ -200,7 +205,7 @@ func TestCreateTodo_BindJSONError(t *testing.T) {
 	c.Request.Header.Set("Content-Type", "application/json")
 
 	// Call the handler function
-	createTodo(c)
+	h.createTodo(c)
 
 	// Assert that the response status code is 400 Bad Request
 	assert.Equal(t, http.StatusBadRequest, w.Code)
@@ -214,19 +219,13 @@ func TestCreateTodo_SaveError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
-	// Mock the current time
-	mockTime := time.Date(2024, time.August, 26, 0, 0, 0, 0, time.UTC)
-	monkey.Patch(time.Now, func() time.Time {
-		return mockTime
-	})
-	defer monkey.Unpatch(time.Now)
-
-	// Patch the Save method on *models.Todo to simulate a save error
-	monkey.PatchInstanceMethod(reflect.TypeOf(&models.Todo{}), "Save", func(t *models.Todo) error {
+	// Stub CreateTodo to simulate a save error
+	store.createTodo = func(t *models.Todo) error {
 		return errors.New("save error")
-	})
-	defer monkey.UnpatchInstanceMethod(reflect.TypeOf(&models.Todo{}), "Save")
+	}
 
 	// Set the userId in the context
 	c.Set("userId", int64(10))
@@ -239,7 +238,7 @@ func TestCreateTodo_SaveError(t *testing.T) {
 	c.Request.Header.Set("Content-Type", "application/json")
 
 	// Call the handler function
-	createTodo(c)
+	h.createTodo(c)
 
 	// Assert that the response status code is 500 Internal Server Error
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
@@ -253,6 +252,8 @@ func TestDeleteTodoById_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	// Mock Todo data
 	mockTodo := &models.Todo{
@@ -261,24 +262,24 @@ func TestDeleteTodoById_Success(t *testing.T) {
 		List:     []models.TodoItem{},
 		IsActive: true,
 		UserID:   10,
+		Version:  2,
 	}
 
 	// Patch models.GetTodoById
-	monkey.Patch(models.GetTodoById, func(todoId int64) (*models.Todo, error) {
+	store.getTodoById = func(todoId int64) (*models.Todo, error) {
 		t.Log("Actual todo id ", todoId)
 		if todoId == mockTodo.ID {
 			return mockTodo, nil
 		}
 		return nil, nil
-	})
-	defer monkey.Unpatch(models.GetTodoById)
+	}
 
-	// Patch the Delete method on *models.Todo
-	monkey.PatchInstanceMethod(reflect.TypeOf(models.Todo{}), "Delete", func(td models.Todo) error {
+	// Patch the Trash method on *models.Todo
+	store.trashTodo = func(td models.Todo) error {
 		// Simulate a successful deletion without accessing the database
+		assert.Equal(t, int64(2), td.Version)
 		return nil
-	})
-	defer monkey.UnpatchInstanceMethod(reflect.TypeOf(models.Todo{}), "Delete")
+	}
 
 	// Set the userId in the context
 	c.Set("userId", int64(10))
@@ -286,22 +287,27 @@ func TestDeleteTodoById_Success(t *testing.T) {
 	// Simulate the request
 	c.Params = gin.Params{{Key: "id", Value: "1"}}
 	c.Request = httptest.NewRequest("DELETE", "/todos/1", nil)
+	c.Request.Header.Set("If-Match", `"2"`)
 
 	// Call the handler function
-	deleteTodoById(c)
+	h.deleteTodoById(c)
 
 	// Assert that the response status code is 200 OK
 	assert.Equal(t, http.StatusOK, w.Code)
 
 	// Assert the response body
-	expectedResponse := `{"message":"Todo deleted successfully"}`
-	assert.JSONEq(t, expectedResponse, w.Body.String())
+	var response map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Todo moved to trash", response["message"])
+	assert.NotEmpty(t, response["undoToken"])
 }
 
 func TestDeleteTodoById_Unauthorized(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	// Mock Todo data
 	mockTodo := &models.Todo{
@@ -310,10 +316,9 @@ func TestDeleteTodoById_Unauthorized(t *testing.T) {
 	}
 
 	// Patch models.GetTodoById
-	monkey.Patch(models.GetTodoById, func(todoId int64) (*models.Todo, error) {
+	store.getTodoById = func(todoId int64) (*models.Todo, error) {
 		return mockTodo, nil
-	})
-	defer monkey.Unpatch(models.GetTodoById)
+	}
 
 	// Set the userId in the context to a different user
 	c.Set("userId", int64(10))
@@ -323,7 +328,7 @@ func TestDeleteTodoById_Unauthorized(t *testing.T) {
 	c.Request = httptest.NewRequest("DELETE", "/todos/1", nil)
 
 	// Call the handler function
-	deleteTodoById(c)
+	h.deleteTodoById(c)
 
 	// Assert that the response status code is 401 Unauthorized
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
@@ -337,6 +342,8 @@ func TestDeleteTodoById_Failure(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	// Mock Todo data
 	mockTodo := &models.Todo{
@@ -345,16 +352,14 @@ func TestDeleteTodoById_Failure(t *testing.T) {
 	}
 
 	// Patch models.GetTodoById
-	monkey.Patch(models.GetTodoById, func(todoId int64) (*models.Todo, error) {
+	store.getTodoById = func(todoId int64) (*models.Todo, error) {
 		return mockTodo, nil
-	})
-	defer monkey.Unpatch(models.GetTodoById)
+	}
 
-	// Patch the Delete method on *models.Todo to simulate an error
-	monkey.PatchInstanceMethod(reflect.TypeOf(models.Todo{}), "Delete", func(t models.Todo) error {
+	// Patch the Trash method on *models.Todo to simulate an error
+	store.trashTodo = func(t models.Todo) error {
 		return errors.New("delete error") // Simulate a delete failure
-	})
-	defer monkey.UnpatchInstanceMethod(reflect.TypeOf(models.Todo{}), "Delete")
+	}
 
 	// Set the userId in the context
 	c.Set("userId", int64(10))
@@ -362,9 +367,10 @@ func TestDeleteTodoById_Failure(t *testing.T) {
 	// Simulate the request
 	c.Params = gin.Params{{Key: "id", Value: strconv.FormatInt(mockTodo.ID, 10)}}
 	c.Request = httptest.NewRequest("DELETE", "/todos/1", nil)
+	c.Request.Header.Set("If-Match", "*")
 
 	// Call the handler function
-	deleteTodoById(c)
+	h.deleteTodoById(c)
 
 	// Assert that the response status code is 500 Internal Server Error
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
@@ -378,6 +384,8 @@ func TestDeleteTodoById_GetFailure(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	// Mock Todo data
 	mockTodo := &models.Todo{
@@ -386,10 +394,9 @@ func TestDeleteTodoById_GetFailure(t *testing.T) {
 	}
 
 	// Patch models.GetTodoById to return an error
-	monkey.Patch(models.GetTodoById, func(todoId int64) (*models.Todo, error) {
+	store.getTodoById = func(todoId int64) (*models.Todo, error) {
 		return nil, errors.New("Unable to fetch the todo")
-	})
-	defer monkey.Unpatch(models.GetTodoById)
+	}
 
 	// Set the userId in the context
 	c.Set("userId", int64(10))
@@ -399,7 +406,7 @@ func TestDeleteTodoById_GetFailure(t *testing.T) {
 	c.Request = httptest.NewRequest("DELETE", "/todos/1", nil)
 
 	// Call the handler function
-	deleteTodoById(c)
+	h.deleteTodoById(c)
 
 	// Assert that the response status code is 500 Internal Server Error
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
@@ -413,6 +420,8 @@ func TestDeleteTodoById_ParamError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	// Set the userId in the context
 	c.Set("userId", int64(10))
@@ -422,7 +431,7 @@ func TestDeleteTodoById_ParamError(t *testing.T) {
 	c.Request = httptest.NewRequest("DELETE", "/todos/abc", nil)
 
 	// Call the handler function
-	deleteTodoById(c)
+	h.deleteTodoById(c)
 
 	// Assert that the response status code is 500 Internal Server Error
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
@@ -436,6 +445,8 @@ func TestGetTodoById_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	mockTime := time.Date(2024, time.August, 26, 18, 2, 39, 0, time.Local)
 	// Mock Todo data
@@ -444,6 +455,7 @@ func TestGetTodoById_Success(t *testing.T) {
 		Title: "Test Todo",
 		List: []models.TodoItem{
 			models.TodoItem{
+				ID:      1,
 				Item:    "sample data",
 				Checked: true,
 			},
@@ -452,13 +464,13 @@ func TestGetTodoById_Success(t *testing.T) {
 		CreatedAt: mockTime,
 		UpdatedAt: mockTime,
 		UserID:    10,
+		Version:   4,
 	}
 
 	// Patch models.GetTodoById to return the mock todo
-	monkey.Patch(models.GetTodoById, func(todoId int64) (*models.Todo, error) {
+	store.getTodoById = func(todoId int64) (*models.Todo, error) {
 		return mockTodo, nil
-	})
-	defer monkey.Unpatch(models.GetTodoById)
+	}
 
 	// Set the userId in the context
 	c.Set("userId", int64(10))
@@ -468,7 +480,7 @@ func TestGetTodoById_Success(t *testing.T) {
 	c.Request = httptest.NewRequest("GET", "/todos/1", nil)
 
 	// Call the handler function
-	getTodoById(c)
+	h.getTodoById(c)
 
 	// Assert that the response status code is 200 OK
 	assert.Equal(t, http.StatusOK, w.Code)
@@ -479,23 +491,28 @@ func TestGetTodoById_Success(t *testing.T) {
 		"title": "Test Todo",
 		"list": [
 			{
+			  "id": 1,
 			  "item": "sample data",
+			  "position": 0,
 			  "checked": true
 			}
 		  ],
 		"isActive": true,
 		"createdAt":  "` + mockTime.Format(time.RFC3339Nano) + `",
 		"updatedAt":  "` + mockTime.Format(time.RFC3339Nano) + `",
-		"userId": 10
-		
+		"userId": 10,
+		"version": 4
 	}`
 	assert.JSONEq(t, expectedResponse, w.Body.String())
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
 }
 
 func TestGetTodoById_Unauthorized(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	// Mock Todo data
 	mockTodo := &models.Todo{
@@ -505,10 +522,9 @@ func TestGetTodoById_Unauthorized(t *testing.T) {
 	}
 
 	// Patch models.GetTodoById to return the mock todo
-	monkey.Patch(models.GetTodoById, func(todoId int64) (*models.Todo, error) {
+	store.getTodoById = func(todoId int64) (*models.Todo, error) {
 		return mockTodo, nil
-	})
-	defer monkey.Unpatch(models.GetTodoById)
+	}
 
 	// Set the userId in the context to a different user
 	c.Set("userId", int64(10))
@@ -518,7 +534,7 @@ func TestGetTodoById_Unauthorized(t *testing.T) {
 	c.Request = httptest.NewRequest("GET", "/todos/1", nil)
 
 	// Call the handler function
-	getTodoById(c)
+	h.getTodoById(c)
 
 	// Assert that the response status code is 401 Unauthorized
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
@@ -532,13 +548,15 @@ func TestGetTodoById_ParseError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	// Simulate the request with an invalid ID
 	c.Params = gin.Params{{Key: "id", Value: "invalid"}}
 	c.Request = httptest.NewRequest("GET", "/todos/invalid", nil)
 
 	// Call the handler function
-	getTodoById(c)
+	h.getTodoById(c)
 
 	// Assert that the response status code is 500 Internal Server Error
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
@@ -552,12 +570,13 @@ func TestGetTodoById_FetchError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	// Patch models.GetTodoById to simulate a fetch error
-	monkey.Patch(models.GetTodoById, func(todoId int64) (*models.Todo, error) {
+	store.getTodoById = func(todoId int64) (*models.Todo, error) {
 		return nil, errors.New("fetch error")
-	})
-	defer monkey.Unpatch(models.GetTodoById)
+	}
 
 	// Set the userId in the context
 	c.Set("userId", int64(10))
@@ -567,7 +586,7 @@ func TestGetTodoById_FetchError(t *testing.T) {
 	c.Request = httptest.NewRequest("GET", "/todos/1", nil)
 
 	// Call the handler function
-	getTodoById(c)
+	h.getTodoById(c)
 
 	// Assert that the response status code is 500 Internal Server Error
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
@@ -581,6 +600,8 @@ func TestUpdateTodoById_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	mockTime := time.Date(2024, time.August, 26, 18, 2, 39, 0, time.Local)
 	// Mock Todo data
@@ -597,20 +618,24 @@ func TestUpdateTodoById_Success(t *testing.T) {
 		CreatedAt: mockTime,
 		UpdatedAt: mockTime,
 		UserID:    10,
+		Version:   3,
 	}
 
 	// Patch models.GetTodoById to return the mock todo
-	monkey.Patch(models.GetTodoById, func(todoId int64) (*models.Todo, error) {
+	store.getTodoById = func(todoId int64) (*models.Todo, error) {
 		return mockTodo, nil
-	})
-	defer monkey.Unpatch(models.GetTodoById)
+	}
 
 	// Patch the Update method on *models.Todo
-	monkey.PatchInstanceMethod(reflect.TypeOf(models.Todo{}), "Update", func(t models.Todo) error {
-		// Simulate a successful update
+	var updatedTodo models.Todo
+	store.updateTodo = func(t models.Todo) error {
+		// Simulate a successful update, which moves the todo on a version
+		updatedTodo = t
+		stored := t
+		stored.Version++
+		mockTodo = &stored
 		return nil
-	})
-	defer monkey.UnpatchInstanceMethod(reflect.TypeOf(models.Todo{}), "Update")
+	}
 
 	// Set the userId in the context
 	c.Set("userId", int64(10))
@@ -621,29 +646,36 @@ func TestUpdateTodoById_Success(t *testing.T) {
 		"title": "Updated Title"
 	}`)))
 	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("If-Match", `"3"`)
 
 	// Call the handler function
-	updateTodoById(c)
+	h.updateTodoById(c)
 
 	// Assert that the response status code is 200 OK
 	assert.Equal(t, http.StatusOK, w.Code)
 
 	// Assert the response body
-	expectedResponse := `{"message":"Todo updated successfully"}`
-	assert.JSONEq(t, expectedResponse, w.Body.String())
+	var response map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Todo updated successfully", response["message"])
+	assert.NotEmpty(t, response["undoToken"])
+	assert.Equal(t, int64(3), updatedTodo.Version)
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
 }
 
 func TestUpdateTodoById_ParseError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	// Simulate the request with an invalid ID
 	c.Params = gin.Params{{Key: "id", Value: "invalid"}}
 	c.Request = httptest.NewRequest("PUT", "/todos/invalid", nil)
 
 	// Call the handler function
-	updateTodoById(c)
+	h.updateTodoById(c)
 
 	// Assert that the response status code is 500 Internal Server Error
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
@@ -657,6 +689,8 @@ func TestUpdateTodoById_Unauthorized(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	// Mock Todo data
 	mockTodo := &models.Todo{
@@ -666,10 +700,9 @@ func TestUpdateTodoById_Unauthorized(t *testing.T) {
 	}
 
 	// Patch models.GetTodoById to return the mock todo
-	monkey.Patch(models.GetTodoById, func(todoId int64) (*models.Todo, error) {
+	store.getTodoById = func(todoId int64) (*models.Todo, error) {
 		return mockTodo, nil
-	})
-	defer monkey.Unpatch(models.GetTodoById)
+	}
 
 	// Set the userId in the context to a different user
 	c.Set("userId", int64(10))
@@ -682,7 +715,7 @@ func TestUpdateTodoById_Unauthorized(t *testing.T) {
 	c.Request.Header.Set("Content-Type", "application/json")
 
 	// Call the handler function
-	updateTodoById(c)
+	h.updateTodoById(c)
 
 	// Assert that the response status code is 401 Unauthorized
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
@@ -696,12 +729,13 @@ func TestUpdateTodoById_FetchError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	// Patch models.GetTodoById to simulate a fetch error
-	monkey.Patch(models.GetTodoById, func(todoId int64) (*models.Todo, error) {
+	store.getTodoById = func(todoId int64) (*models.Todo, error) {
 		return nil, errors.New("fetch error")
-	})
-	defer monkey.Unpatch(models.GetTodoById)
+	}
 
 	// Set the userId in the context
 	c.Set("userId", int64(10))
@@ -714,7 +748,7 @@ func TestUpdateTodoById_FetchError(t *testing.T) {
 	c.Request.Header.Set("Content-Type", "application/json")
 
 	// Call the handler function
-	updateTodoById(c)
+	h.updateTodoById(c)
 
 	// Assert that the response status code is 500 Internal Server Error
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
@@ -728,6 +762,8 @@ func TestUpdateTodoById_BindJSONError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	// Mock Todo data
 	mockTodo := &models.Todo{
@@ -737,10 +773,9 @@ func TestUpdateTodoById_BindJSONError(t *testing.T) {
 	}
 
 	// Patch models.GetTodoById to return the mock todo
-	monkey.Patch(models.GetTodoById, func(todoId int64) (*models.Todo, error) {
+	store.getTodoById = func(todoId int64) (*models.Todo, error) {
 		return mockTodo, nil
-	})
-	defer monkey.Unpatch(models.GetTodoById)
+	}
 
 	// Set the userId in the context
 	c.Set("userId", int64(10))
@@ -751,9 +786,10 @@ func TestUpdateTodoById_BindJSONError(t *testing.T) {
 		"title": "Updated Title"  // Missing closing quotes
 	}`)))
 	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("If-Match", "*")
 
 	// Call the handler function
-	updateTodoById(c)
+	h.updateTodoById(c)
 
 	// Assert that the response status code is 400 Bad Request
 	assert.Equal(t, http.StatusBadRequest, w.Code)
@@ -767,6 +803,8 @@ func TestUpdateTodoById_UpdateError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	mockTime := time.Date(2024, time.August, 26, 18, 2, 39, 0, time.Local)
 	// Mock Todo data
@@ -783,19 +821,18 @@ func TestUpdateTodoById_UpdateError(t *testing.T) {
 		CreatedAt: mockTime,
 		UpdatedAt: mockTime,
 		UserID:    10,
+		Version:   3,
 	}
 
 	// Patch models.GetTodoById to return the mock todo
-	monkey.Patch(models.GetTodoById, func(todoId int64) (*models.Todo, error) {
+	store.getTodoById = func(todoId int64) (*models.Todo, error) {
 		return mockTodo, nil
-	})
-	defer monkey.Unpatch(models.GetTodoById)
+	}
 
 	// Patch the Update method on *models.Todo to simulate an update error
-	monkey.PatchInstanceMethod(reflect.TypeOf(models.Todo{}), "Update", func(t models.Todo) error {
+	store.updateTodo = func(t models.Todo) error {
 		return errors.New("update error")
-	})
-	defer monkey.UnpatchInstanceMethod(reflect.TypeOf(models.Todo{}), "Update")
+	}
 
 	// Set the userId in the context
 	c.Set("userId", int64(10))
@@ -806,9 +843,10 @@ func TestUpdateTodoById_UpdateError(t *testing.T) {
 		"title": "Updated Title"
 	}`)))
 	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("If-Match", `"3"`)
 
 	// Call the handler function
-	updateTodoById(c)
+	h.updateTodoById(c)
 
 	// Assert that the response status code is 500 Internal Server Error
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
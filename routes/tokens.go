@@ -0,0 +1,159 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"project_todo/models"
+	"project_todo/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type refreshRequest struct {
+	RefreshToken string `binding:"required" json:"refreshToken"`
+}
+
+// startSession starts a session for a new login of userId, from where the
+// request came.
+func (h *handler) startSession(context *gin.Context, userId int64) (*models.Session, error) {
+	familyId, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := models.Session{
+		UserID:     userId,
+		FamilyID:   familyId,
+		UserAgent:  context.Request.UserAgent(),
+		IP:         context.ClientIP(),
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}
+	err = h.store.CreateSession(&session)
+	return &session, err
+}
+
+// issueTokens hands the user a new access token and refresh token in
+// session, recording the access token's jti so revoking the session
+// revokes it too.
+func (h *handler) issueTokens(context *gin.Context, user *models.User, session *models.Session) (string, string, error) {
+	jti, err := utils.RandomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+	jwtToken, err := utils.GenerateToken(user.Email, user.ID, jti)
+	if err != nil {
+		return "", "", err
+	}
+	now := time.Now()
+	err = h.store.RecordToken(models.IssuedToken{
+		JTI:       jti,
+		SessionID: session.ID,
+		UserAgent: context.Request.UserAgent(),
+		IP:        context.ClientIP(),
+		IssuedAt:  now,
+		ExpiresAt: now.Add(utils.AccessTokenTTL()),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err := utils.RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	err = h.store.CreateRefreshToken(&models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  session.FamilyID,
+		TokenHash: utils.HashToken(refreshToken),
+		CreatedAt: now,
+		ExpiresAt: now.Add(utils.RefreshTokenTTL()),
+	})
+	return jwtToken, refreshToken, err
+}
+
+// refreshToken swaps a refresh token for a new access token and a new
+// refresh token. Each refresh token works once: presenting one that was
+// already swapped means someone else has a copy, so the whole login is
+// revoked and has to start over.
+func (h *handler) refreshToken(context *gin.Context) {
+	var request refreshRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Could not parse the request body"})
+		return
+	}
+	stored, err := h.store.GetRefreshToken(utils.HashToken(request.RefreshToken))
+	if errors.Is(err, models.ErrNotFound) || (err == nil && stored.RevokedAt != nil) {
+		context.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid refresh token"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to refresh the token"})
+		return
+	}
+	if stored.Expired(time.Now()) {
+		context.JSON(http.StatusUnauthorized, gin.H{"message": "Refresh token has expired"})
+		return
+	}
+	if stored.UsedAt == nil {
+		err = h.store.UseRefreshToken(stored.ID, time.Now())
+	}
+	if stored.UsedAt != nil || errors.Is(err, models.ErrNotFound) {
+		fmt.Println("Refresh token reused, revoking token family of user", stored.UserID)
+		err = h.store.RevokeRefreshFamily(stored.FamilyID, time.Now())
+		if err != nil {
+			fmt.Println("Unable to revoke token family", err)
+		}
+		h.revocations.Reset()
+		context.JSON(http.StatusUnauthorized, gin.H{"message": "Refresh token was already used, please log in again"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to refresh the token"})
+		return
+	}
+
+	user, err := h.store.GetUserById(stored.UserID)
+	if err != nil {
+		context.JSON(http.StatusUnauthorized, gin.H{"message": "Unable to authenticate the user"})
+		return
+	}
+	session, err := h.store.GetSessionByFamily(stored.FamilyID)
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid refresh token"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to refresh the token"})
+		return
+	}
+	jwtToken, refreshToken, err := h.issueTokens(context, user, session)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to refresh the token"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Token refreshed", "token": jwtToken, "refreshToken": refreshToken})
+}
+
+// logout ends the session the refresh token belongs to, along with the
+// access tokens handed out in it.
+func (h *handler) logout(context *gin.Context) {
+	var request refreshRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Could not parse the request body"})
+		return
+	}
+	stored, err := h.store.GetRefreshToken(utils.HashToken(request.RefreshToken))
+	if err == nil {
+		err = h.store.RevokeRefreshFamily(stored.FamilyID, time.Now())
+		h.revocations.Reset()
+	}
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to log out"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "User logged out successfully"})
+}
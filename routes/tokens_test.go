@@ -0,0 +1,98 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type tokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+func loginTokens(t *testing.T, server *gin.Engine, email string) tokenResponse {
+	signup(t, server, email)
+	w := doRequest(server, "POST", "/login", "", `{"email":"`+email+`","password":"password123"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tokens tokenResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+	return tokens
+}
+
+func refresh(t *testing.T, server *gin.Engine, refreshToken string) (int, tokenResponse) {
+	w := doRequest(server, "POST", "/token/refresh", "", `{"refreshToken":"`+refreshToken+`"}`)
+	var tokens tokenResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+	return w.Code, tokens
+}
+
+func signedToken(t *testing.T, claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(os.Getenv("JWT_SECRET")))
+	assert.NoError(t, err)
+	return token
+}
+
+func TestTokens_AccessTokensExpire(t *testing.T) {
+	server := newTestServer()
+	tokens := loginTokens(t, server, "john@example.com")
+	w := doRequest(server, "GET", "/todos", tokens.Token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	expired := signedToken(t, jwt.MapClaims{"email": "john@example.com", "userId": 1,
+		"iat": time.Now().Add(-time.Hour).Unix(), "exp": time.Now().Add(-time.Minute).Unix()})
+	w = doRequest(server, "GET", "/todos", expired, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	forever := signedToken(t, jwt.MapClaims{"email": "john@example.com", "userId": 1})
+	w = doRequest(server, "GET", "/todos", forever, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestTokens_RefreshRotation(t *testing.T) {
+	server := newTestServer()
+	first := loginTokens(t, server, "john@example.com")
+	assert.NotEmpty(t, first.RefreshToken)
+
+	code, second := refresh(t, server, first.RefreshToken)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	w := doRequest(server, "GET", "/todos", second.Token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	code, third := refresh(t, server, second.RefreshToken)
+	assert.Equal(t, http.StatusOK, code)
+
+	// Replaying a rotated token ends the login, the latest token included.
+	code, _ = refresh(t, server, first.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = refresh(t, server, third.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// Other logins of the same user are left alone.
+	other := loginFrom(t, server, "john@example.com", "Safari")
+	code, _ = refresh(t, server, other.RefreshToken)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = refresh(t, server, "unknown")
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestTokens_Logout(t *testing.T) {
+	server := newTestServer()
+	tokens := loginTokens(t, server, "john@example.com")
+	code, rotated := refresh(t, server, tokens.RefreshToken)
+	assert.Equal(t, http.StatusOK, code)
+
+	w := doRequest(server, "POST", "/logout", "", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(server, "POST", "/logout", "", `{"refreshToken":"`+tokens.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	code, _ = refresh(t, server, rotated.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	w = doRequest(server, "POST", "/logout", "", `{"refreshToken":"`+rotated.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"project_todo/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// trashedTodo is accessibleTodo for todos in the trash: anything not trashed is
+// reported as not found.
+func (h *handler) trashedTodo(context *gin.Context) *models.Todo {
+	todoId, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse todo id"})
+		return nil
+	}
+	todo, err := h.store.GetTodoById(todoId)
+	if errors.Is(err, models.ErrNotFound) || (err == nil && todo.DeletedAt == nil) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Todo not found in trash"})
+		return nil
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch todo"})
+		return nil
+	}
+	if !h.authorize(context, todo, models.ActionDelete, "Unauthorized access") {
+		return nil
+	}
+	return todo
+}
+
+func (h *handler) getTrash(context *gin.Context) {
+	var todos []models.Todo
+	var err error
+	if workspaceId := context.GetInt64("workspaceId"); workspaceId != 0 {
+		todos, err = h.store.GetWorkspaceTrash(workspaceId)
+	} else {
+		todos, err = h.store.GetTrash(context.GetInt64("userId"))
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch trash"})
+		return
+	}
+	if todos == nil {
+		todos = []models.Todo{}
+	}
+	context.JSON(http.StatusOK, todos)
+}
+
+func (h *handler) restoreTodo(context *gin.Context) {
+	todo := h.trashedTodo(context)
+	if todo == nil {
+		return
+	}
+	err := h.store.RestoreTodo(*todo, context.GetInt64("userId"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to restore todo"})
+		return
+	}
+	restored, err := h.store.GetTodoById(todo.ID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch todo"})
+		return
+	}
+	context.Header("ETag", etag(restored.Version))
+	context.JSON(http.StatusOK, gin.H{"message": "Todo restored", "todo": restored})
+}
+
+// purgeTodo permanently deletes one todo from the trash.
+func (h *handler) purgeTodo(context *gin.Context) {
+	todo := h.trashedTodo(context)
+	if todo == nil {
+		return
+	}
+	todo.Version = 0
+	err := h.store.DeleteTodo(*todo, context.GetInt64("userId"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to delete the todo"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Todo deleted permanently"})
+}
+
+func (h *handler) emptyTrash(context *gin.Context) {
+	count, err := h.store.EmptyTrash(context.GetInt64("userId"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to empty trash"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Trash emptied", "deleted": count})
+}
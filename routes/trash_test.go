@@ -0,0 +1,96 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"project_todo/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrash_DeleteAndRestore(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	doRequest(server, "POST", "/todos", token, `{"title":"Groceries","list":[{"item":"milk"}]}`)
+	doRequest(server, "POST", "/todos", token, `{"title":"Taxes"}`)
+
+	// An empty trash is an empty list, not null.
+	w := doRequest(server, "GET", "/todos/trash", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+
+	w = doRequestIfMatch(server, "DELETE", "/todos/1", token, `"1"`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"message":"Todo moved to trash"`)
+	assert.Contains(t, w.Body.String(), `"undoToken":"`)
+
+	w = doRequest(server, "GET", "/todos", token, "")
+	assert.Equal(t, []string{"Taxes"}, todoTitles(t, w.Body.Bytes()))
+
+	w = doRequest(server, "GET", "/todos/trash", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var trash []models.Todo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &trash))
+	assert.Len(t, trash, 1)
+	assert.Equal(t, "Groceries", trash[0].Title)
+	assert.NotNil(t, trash[0].DeletedAt)
+
+	// Trashed todos can't be edited until they are restored.
+	w = doRequestIfMatch(server, "PUT", "/todos/1", token, "*", `{"title":"Weekly groceries"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(server, "POST", "/todos/1/items", token, `{"item":"eggs"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequestIfMatch(server, "DELETE", "/todos/1", token, "*", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(server, "POST", "/todos/1/restore", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+	todo := getTodo(t, server, token, "/todos/1")
+	assert.Nil(t, todo.DeletedAt)
+	assert.Equal(t, []string{"milk"}, itemNames(todo))
+
+	w = doRequest(server, "POST", "/todos/1/restore", token, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestTrash_PermanentDelete(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	other := signupAndLogin(t, server, "jane@example.com")
+	for _, title := range []string{"One", "Two", "Three"} {
+		doRequest(server, "POST", "/todos", token, `{"title":"`+title+`"}`)
+	}
+	doRequest(server, "POST", "/todos", other, `{"title":"Theirs"}`)
+	for _, path := range []string{"/todos/1", "/todos/2", "/todos/4"} {
+		owner := token
+		if path == "/todos/4" {
+			owner = other
+		}
+		w := doRequestIfMatch(server, "DELETE", path, owner, "*", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	// Only trashed todos can be purged, and only by their owner.
+	w := doRequest(server, "DELETE", "/todos/trash/3", token, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(server, "DELETE", "/todos/trash/4", token, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doRequest(server, "DELETE", "/todos/trash/1", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "POST", "/todos/1/restore", token, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(server, "DELETE", "/todos/trash", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message":"Trash emptied","deleted":1}`, w.Body.String())
+
+	w = doRequest(server, "GET", "/todos/trash", token, "")
+	assert.JSONEq(t, `[]`, w.Body.String())
+	w = doRequest(server, "GET", "/todos/trash", other, "")
+	assert.Equal(t, []string{"Theirs"}, todoTitles(t, w.Body.Bytes()))
+	w = doRequest(server, "GET", "/todos", token, "")
+	assert.Equal(t, []string{"Three"}, todoTitles(t, w.Body.Bytes()))
+}
@@ -1,31 +1,54 @@
 package routes
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	netmail "net/mail"
 	"project_todo/models"
 	"project_todo/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func signup(context *gin.Context) {
+func (h *handler) signup(context *gin.Context) {
 	var user models.User
 	err := context.ShouldBindJSON(&user)
 	if err != nil {
 		context.JSON(http.StatusBadRequest, gin.H{"message": "Could not parse the request body"})
 		return
 	}
+	address, err := netmail.ParseAddress(user.Email)
+	if err != nil || address.Address != user.Email {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Invalid email address"})
+		return
+	}
 
-	err = user.Save()
+	// New accounts stay inactive until the user follows the link mailed to
+	// them.
+	now := time.Now()
+	user.IsActive = false
+	user.EmailVerifiedAt = nil
+	user.VerificationSentAt = nil
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	err = user.Save(h.store)
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to save the user"})
 		return
 	}
-	context.JSON(http.StatusOK, gin.H{"message": "User created successfully"})
+	_, err = h.store.MarkVerificationSent(user.ID, now, now)
+	if err == nil {
+		err = h.sendVerification(&user)
+	}
+	if err != nil {
+		fmt.Println("Unable to send the verification mail to user", user.ID, err)
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "User created successfully, check your email for a link to verify your address"})
 }
 
-func login(context *gin.Context) {
+func (h *handler) login(context *gin.Context) {
 	var user models.User
 	err := context.ShouldBindJSON(&user)
 	if err != nil {
@@ -33,15 +56,45 @@ func login(context *gin.Context) {
 		context.JSON(http.StatusBadRequest, gin.H{"message": "Could not parse the request body"})
 		return
 	}
-	err = user.ValidateCredentials()
+	err = user.ValidateCredentials(h.store)
 	if err != nil {
 		context.JSON(http.StatusUnauthorized, gin.H{"message": "Unable to authenticate the user"})
 		return
 	}
-	jwtToken, err := utils.GenerateToken(user.Email, user.ID)
+	if user.EmailVerifiedAt == nil && time.Since(user.CreatedAt) >= utils.UnverifiedLoginGrace() {
+		context.JSON(http.StatusForbidden, gin.H{"message": "Please verify your email address before logging in"})
+		return
+	}
+	// With two-factor authentication on, the password only gets a challenge
+	// to exchange with a code at POST /login/mfa.
+	factor, err := h.store.GetTOTP(user.ID)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to authenticate the user"})
+		return
+	}
+	if factor != nil && factor.Confirmed() {
+		mfaToken, err := utils.GenerateMFAToken(user.ID)
+		if err != nil {
+			context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to authenticate the user"})
+			return
+		}
+		context.JSON(http.StatusOK, gin.H{"message": "Enter the code from your authenticator app", "mfaRequired": true, "mfaToken": mfaToken})
+		return
+	}
+	h.completeLogin(context, &user)
+}
+
+// completeLogin starts a session for the user and hands out its tokens.
+func (h *handler) completeLogin(context *gin.Context, user *models.User) {
+	session, err := h.startSession(context, user.ID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to authenticate the user"})
+		return
+	}
+	jwtToken, refreshToken, err := h.issueTokens(context, user, session)
 	if err != nil {
 		context.JSON(http.StatusUnauthorized, gin.H{"message": "Unable to authenticate the user"})
 		return
 	}
-	context.JSON(http.StatusOK, gin.H{"message": "User logged in successfully", "token": jwtToken})
+	context.JSON(http.StatusOK, gin.H{"message": "User logged in successfully", "token": jwtToken, "refreshToken": refreshToken})
 }
@@ -2,38 +2,42 @@ package routes
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"project_todo/models"
 	"project_todo/utils"
-	"reflect"
 	"testing"
+	"time"
 
-	"bou.ke/monkey"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
+func hashForTest(t *testing.T, password string) string {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	assert.NoError(t, err)
+	return string(hashed)
+}
+
 func TestSignup_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store, mailer: discardMailer{}}
 
-	// Mock utils.HashPassword to return a dummy hashed password
-	monkey.Patch(utils.HashPassword, func(password string) (string, error) {
-		return "hashedPassword", nil
-	})
-	defer monkey.Unpatch(utils.HashPassword)
-
-	// Patch the Save method on *models.User
-	monkey.PatchInstanceMethod(reflect.TypeOf(&models.User{}), "Save", func(u *models.User) error {
+	// Stub store.CreateUser
+	var saved *models.User
+	store.createUser = func(u *models.User) error {
 		// Simulate a successful save
 		u.ID = 1
+		saved = u
 		return nil
-	})
-	defer monkey.UnpatchInstanceMethod(reflect.TypeOf(&models.User{}), "Save")
+	}
 
 	// Simulate the request with JSON body
 	c.Request = httptest.NewRequest("POST", "/signup", io.NopCloser(bytes.NewBufferString(`{
@@ -45,20 +49,26 @@ func TestSignup_Success(t *testing.T) {
 	c.Request.Header.Set("Content-Type", "application/json")
 
 	// Call the handler function
-	signup(c)
+	h.signup(c)
 
 	// Assert that the response status code is 200 OK
 	assert.Equal(t, http.StatusOK, w.Code)
 
 	// Assert the response body
-	expectedResponse := `{"message":"User created successfully"}`
+	expectedResponse := `{"message":"User created successfully, check your email for a link to verify your address"}`
 	assert.JSONEq(t, expectedResponse, w.Body.String())
+
+	// The password is stored hashed.
+	assert.NotEqual(t, "password123", saved.Password)
+	assert.True(t, utils.ComparePassword("password123", saved.Password))
 }
 
 func TestSignup_BindJSONError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store, mailer: discardMailer{}}
 
 	// Simulate the request with invalid JSON body
 	c.Request = httptest.NewRequest("POST", "/signup", io.NopCloser(bytes.NewBufferString(`{
@@ -70,7 +80,7 @@ func TestSignup_BindJSONError(t *testing.T) {
 	c.Request.Header.Set("Content-Type", "application/json")
 
 	// Call the handler function
-	signup(c)
+	h.signup(c)
 
 	// Assert that the response status code is 400 Bad Request
 	assert.Equal(t, http.StatusBadRequest, w.Code)
@@ -84,18 +94,13 @@ func TestSignup_SaveError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store, mailer: discardMailer{}}
 
-	// Mock utils.HashPassword to return a dummy hashed password
-	monkey.Patch(utils.HashPassword, func(password string) (string, error) {
-		return "hashedPassword", nil
-	})
-	defer monkey.Unpatch(utils.HashPassword)
-
-	// Patch the Save method on *models.User to simulate a save error
-	monkey.PatchInstanceMethod(reflect.TypeOf(&models.User{}), "Save", func(u *models.User) error {
+	// Stub store.CreateUser to simulate a save error
+	store.createUser = func(u *models.User) error {
 		return errors.New("save error")
-	})
-	defer monkey.UnpatchInstanceMethod(reflect.TypeOf(&models.User{}), "Save")
+	}
 
 	// Simulate the request with JSON body
 	c.Request = httptest.NewRequest("POST", "/signup", io.NopCloser(bytes.NewBufferString(`{
@@ -107,7 +112,7 @@ func TestSignup_SaveError(t *testing.T) {
 	c.Request.Header.Set("Content-Type", "application/json")
 
 	// Call the handler function
-	signup(c)
+	h.signup(c)
 
 	// Assert that the response status code is 500 Internal Server Error
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
@@ -121,18 +126,14 @@ func TestLogin_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
-	// Patch the ValidateCredentials method on *models.User to simulate successful validation
-	monkey.PatchInstanceMethod(reflect.TypeOf(&models.User{}), "ValidateCredentials", func(u *models.User) error {
-		return nil
-	})
-	defer monkey.UnpatchInstanceMethod(reflect.TypeOf(&models.User{}), "ValidateCredentials")
-
-	// Patch utils.GenerateToken to return a dummy token
-	monkey.Patch(utils.GenerateToken, func(email string, userID int64) (string, error) {
-		return "dummyToken", nil
-	})
-	defer monkey.Unpatch(utils.GenerateToken)
+	// Stub store.GetUserByEmail with a user whose password matches
+	store.getUserByEmail = func(email string) (*models.User, error) {
+		verified := time.Now()
+		return &models.User{ID: 1, Email: email, Password: hashForTest(t, "password123"), EmailVerifiedAt: &verified}, nil
+	}
 
 	// Simulate the request with JSON body
 	c.Request = httptest.NewRequest("POST", "/login", io.NopCloser(bytes.NewBufferString(`{
@@ -142,20 +143,27 @@ func TestLogin_Success(t *testing.T) {
 	c.Request.Header.Set("Content-Type", "application/json")
 
 	// Call the handler function
-	login(c)
+	h.login(c)
 
 	// Assert that the response status code is 200 OK
 	assert.Equal(t, http.StatusOK, w.Code)
 
 	// Assert the response body
-	expectedResponse := `{"message":"User logged in successfully","token":"dummyToken"}`
-	assert.JSONEq(t, expectedResponse, w.Body.String())
+	var response map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "User logged in successfully", response["message"])
+	claims, err := utils.VerifyToken(response["token"])
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), claims.UserID)
+	assert.NotEmpty(t, response["refreshToken"])
 }
 
 func TestLogin_BindJSONError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
 	// Simulate the request with invalid JSON body
 	c.Request = httptest.NewRequest("POST", "/login", io.NopCloser(bytes.NewBufferString(`{
@@ -165,7 +173,7 @@ func TestLogin_BindJSONError(t *testing.T) {
 	c.Request.Header.Set("Content-Type", "application/json")
 
 	// Call the handler function
-	login(c)
+	h.login(c)
 
 	// Assert that the response status code is 400 Bad Request
 	assert.Equal(t, http.StatusBadRequest, w.Code)
@@ -179,12 +187,13 @@ func TestLogin_ValidateCredentialsError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
-	// Patch the ValidateCredentials method on *models.User to simulate validation error
-	monkey.PatchInstanceMethod(reflect.TypeOf(&models.User{}), "ValidateCredentials", func(u *models.User) error {
-		return errors.New("Invalid Credentials")
-	})
-	defer monkey.UnpatchInstanceMethod(reflect.TypeOf(&models.User{}), "ValidateCredentials")
+	// Stub store.GetUserByEmail to simulate an unknown user
+	store.getUserByEmail = func(email string) (*models.User, error) {
+		return nil, models.ErrNotFound
+	}
 
 	// Simulate the request with JSON body
 	c.Request = httptest.NewRequest("POST", "/login", io.NopCloser(bytes.NewBufferString(`{
@@ -194,7 +203,7 @@ func TestLogin_ValidateCredentialsError(t *testing.T) {
 	c.Request.Header.Set("Content-Type", "application/json")
 
 	// Call the handler function
-	login(c)
+	h.login(c)
 
 	// Assert that the response status code is 401 Unauthorized
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
@@ -204,22 +213,23 @@ func TestLogin_ValidateCredentialsError(t *testing.T) {
 	assert.JSONEq(t, expectedResponse, w.Body.String())
 }
 
-func TestLogin_GenerateTokenError(t *testing.T) {
+func TestLogin_IssueTokenError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	store := newFakeStore()
+	h := &handler{store: store}
 
-	// Patch the ValidateCredentials method on *models.User to simulate successful validation
-	monkey.PatchInstanceMethod(reflect.TypeOf(&models.User{}), "ValidateCredentials", func(u *models.User) error {
-		return nil
-	})
-	defer monkey.UnpatchInstanceMethod(reflect.TypeOf(&models.User{}), "ValidateCredentials")
+	// Stub store.GetUserByEmail with a user whose password matches
+	store.getUserByEmail = func(email string) (*models.User, error) {
+		verified := time.Now()
+		return &models.User{ID: 1, Email: email, Password: hashForTest(t, "password123"), EmailVerifiedAt: &verified}, nil
+	}
 
-	// Patch utils.GenerateToken to simulate token generation error
-	monkey.Patch(utils.GenerateToken, func(email string, userID int64) (string, error) {
-		return "", errors.New("token generation error")
-	})
-	defer monkey.Unpatch(utils.GenerateToken)
+	// Stub store.RecordToken to simulate failing to hand out the token
+	store.recordToken = func(token models.IssuedToken) error {
+		return errors.New("record error")
+	}
 
 	// Simulate the request with JSON body
 	c.Request = httptest.NewRequest("POST", "/login", io.NopCloser(bytes.NewBufferString(`{
@@ -229,7 +239,7 @@ func TestLogin_GenerateTokenError(t *testing.T) {
 	c.Request.Header.Set("Content-Type", "application/json")
 
 	// Call the handler function
-	login(c)
+	h.login(c)
 
 	// Assert that the response status code is 401 Unauthorized
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
@@ -0,0 +1,117 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"project_todo/mail"
+	"project_todo/models"
+	"project_todo/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type resendVerificationRequest struct {
+	Email string `binding:"required" json:"email"`
+}
+
+// sendVerification mails the user a signed link that verifies their email.
+func (h *handler) sendVerification(user *models.User) error {
+	base, err := appURL()
+	if err != nil {
+		return err
+	}
+	token, err := utils.GenerateEmailToken(user.Email)
+	if err != nil {
+		return err
+	}
+	link := base + "/app-verify-email?token=" + url.QueryEscape(token)
+	return h.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nFollow this link within %s to verify your email address:\n\n%s\n\n"+
+			"If you didn't sign up, you can ignore this email.\n", user.FirstName, utils.EmailVerificationTTL(), link),
+	})
+}
+
+// verifyEmail activates the account a verification link was sent for.
+func (h *handler) verifyEmail(context *gin.Context) {
+	email, err := utils.VerifyEmailToken(context.Query("token"))
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Invalid or expired verification token"})
+		return
+	}
+	user, err := h.store.GetUserByEmail(email)
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Invalid or expired verification token"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to verify the email"})
+		return
+	}
+	if user.EmailVerifiedAt == nil {
+		err = h.store.VerifyEmail(user.ID, user.Email, time.Now())
+	}
+	if user.EmailVerifiedAt != nil || errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusOK, gin.H{"message": "Email already verified"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to verify the email"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// resendVerification mails a new verification link, at most once every
+// utils.VerificationResendInterval per user. Like forgotPassword it answers
+// the same for unknown emails, and for accounts already verified.
+func (h *handler) resendVerification(context *gin.Context) {
+	var request resendVerificationRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Could not parse the request body"})
+		return
+	}
+	_, err = appURL()
+	if err != nil {
+		fmt.Println("Unable to send verification mail:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to send the verification email"})
+		return
+	}
+	sent := gin.H{"message": "If the email belongs to an unverified account, a verification link has been sent to it"}
+	user, err := h.store.GetUserByEmail(strings.TrimSpace(request.Email))
+	if errors.Is(err, models.ErrNotFound) || (err == nil && user.EmailVerifiedAt != nil) {
+		context.JSON(http.StatusOK, sent)
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to send the verification email"})
+		return
+	}
+
+	now := time.Now()
+	interval := utils.VerificationResendInterval()
+	claimed, err := h.store.MarkVerificationSent(user.ID, now, now.Add(-interval))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to send the verification email"})
+		return
+	}
+	if !claimed {
+		// Answer as for any other address, so the throttle can't tell
+		// which emails have unverified accounts.
+		context.JSON(http.StatusOK, sent)
+		return
+	}
+	err = h.sendVerification(user)
+	if err != nil {
+		fmt.Println("Unable to send the verification mail", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to send the verification email"})
+		return
+	}
+	context.JSON(http.StatusOK, sent)
+}
@@ -0,0 +1,90 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const verifySubject = "Verify your email address"
+
+func TestVerification_Signup(t *testing.T) {
+	server, mailer := newMailServer(t)
+	w := doRequest(server, "POST", "/signup", "", `{"email":"not an email","firstName":"John","lastName":"Doe","password":"password123"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(server, "POST", "/signup", "", `{"email":"John <john@example.com>","firstName":"John","lastName":"Doe","password":"password123"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Signing up as verified doesn't skip the mail.
+	w = doRequest(server, "POST", "/signup", "", `{"email":"john@example.com","firstName":"John","lastName":"Doe","password":"password123",
+		"isActive":true,"emailVerifiedAt":"2024-01-01T00:00:00Z"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	sent := sentWith(t, mailer, verifySubject)
+	if assert.Len(t, sent, 1) {
+		assert.Equal(t, "john@example.com", sent[0].To)
+		assert.Contains(t, sent[0].Body, "/app-verify-email?token=")
+	}
+	w = doRequest(server, "POST", "/login", "", `{"email":"john@example.com","password":"password123"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	token := linkToken(t, mailer, verifySubject)
+	w = doRequest(server, "GET", "/verify-email?token="+token, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Email verified")
+	w = doRequest(server, "GET", "/verify-email?token="+token, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Email already verified")
+	w = doRequest(server, "POST", "/login", "", `{"email":"john@example.com","password":"password123"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestVerification_Tokens(t *testing.T) {
+	server, _ := newMailServer(t)
+	tokens := loginTokens(t, server, "john@example.com")
+
+	w := doRequest(server, "GET", "/verify-email?token=unknown", "", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(server, "GET", "/verify-email", "", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(server, "GET", "/verify-email?token="+tokens.Token, "", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestVerification_Resend(t *testing.T) {
+	server, mailer := newMailServer(t)
+	w := doRequest(server, "POST", "/signup", "", `{"email":"john@example.com","firstName":"John","lastName":"Doe","password":"password123"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doRequest(server, "POST", "/verify-email/resend", "", `{"email":"nobody@example.com"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "POST", "/verify-email/resend", "", `{"email":"john@example.com"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Retry-After"))
+	assert.Len(t, sentWith(t, mailer, verifySubject), 1)
+
+	t.Setenv("VERIFICATION_RESEND_INTERVAL", "1ns")
+	w = doRequest(server, "POST", "/verify-email/resend", "", `{"email":"john@example.com"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, sentWith(t, mailer, verifySubject), 2)
+
+	// Any of the links works, and verified accounts get no more mail.
+	w = doRequest(server, "GET", "/verify-email?token="+linkToken(t, mailer, verifySubject), "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "POST", "/verify-email/resend", "", `{"email":"john@example.com"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, sentWith(t, mailer, verifySubject), 2)
+}
+
+func TestVerification_LoginGrace(t *testing.T) {
+	t.Setenv("UNVERIFIED_LOGIN_GRACE", "1h")
+	server, _ := newMailServer(t)
+	w := doRequest(server, "POST", "/signup", "", `{"email":"john@example.com","firstName":"John","lastName":"Doe","password":"password123"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "POST", "/login", "", `{"email":"john@example.com","password":"password123"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	t.Setenv("UNVERIFIED_LOGIN_GRACE", "1ns")
+	w = doRequest(server, "POST", "/login", "", `{"email":"john@example.com","password":"password123"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
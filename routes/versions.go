@@ -0,0 +1,199 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"project_todo/models"
+	"project_todo/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type revertRequest struct {
+	Version *int64 `json:"version"`
+}
+
+type undoRequest struct {
+	Token string `json:"token"`
+}
+
+// issueUndo hands the authenticated user a token for taking back action,
+// which left the todo as after. Without one the change simply can't be
+// undone, so failures give an empty token.
+func (h *handler) issueUndo(context *gin.Context, action string, after *models.Todo) string {
+	if after == nil {
+		return ""
+	}
+	token, err := utils.RandomToken(32)
+	if err == nil {
+		now := time.Now()
+		err = h.store.CreateUndo(models.Undo{
+			Token:     token,
+			TodoID:    after.ID,
+			UserID:    context.GetInt64("userId"),
+			Action:    action,
+			Version:   after.Version,
+			CreatedAt: now,
+			ExpiresAt: now.Add(models.UndoTTL),
+		})
+	}
+	if err != nil {
+		fmt.Println("Unable to create undo token for todo", after.ID, err)
+		return ""
+	}
+	return token
+}
+
+// revert puts the title and checklist of snapshot back on todo, provided it
+// is still at expected (0 skips the check), leaving everything else as is.
+func (h *handler) revert(context *gin.Context, todo *models.Todo, snapshot *models.TodoVersion, expected int64) error {
+	reverted := *todo
+	reverted.Title = snapshot.Title
+	reverted.List = snapshot.List
+	reverted.UpdatedAt = time.Now()
+	reverted.Version = expected
+	return h.store.RevertTodo(reverted, context.GetInt64("userId"))
+}
+
+// getVersions lists the earlier versions of a todo, newest first.
+func (h *handler) getVersions(context *gin.Context) {
+	todo := h.accessibleTodo(context, models.ActionView)
+	if todo == nil {
+		return
+	}
+	versions, err := h.store.GetVersions(todo.ID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch versions"})
+		return
+	}
+	if versions == nil {
+		versions = []models.TodoVersion{}
+	}
+	context.JSON(http.StatusOK, versions)
+}
+
+// revertTodo restores the title and checklist of an earlier version. The
+// revert is itself a change, so it makes a new version and can be undone.
+func (h *handler) revertTodo(context *gin.Context) {
+	todo := h.accessibleTodo(context, models.ActionEdit)
+	if todo == nil {
+		return
+	}
+	expected, ok := ifMatch(context, todo)
+	if !ok {
+		return
+	}
+	var request revertRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil || request.Version == nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+		return
+	}
+	snapshot, err := h.store.GetVersion(todo.ID, *request.Version)
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Version not found"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch version"})
+		return
+	}
+	err = h.revert(context, todo, snapshot, expected)
+	if errors.Is(err, models.ErrVersionConflict) {
+		preconditionFailed(context)
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to revert todo"})
+		return
+	}
+	reverted, err := h.store.GetTodoById(todo.ID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch todo"})
+		return
+	}
+	context.Header("ETag", etag(reverted.Version))
+	context.JSON(http.StatusOK, gin.H{
+		"message":   "Todo reverted",
+		"todo":      reverted,
+		"undoToken": h.issueUndo(context, models.HistoryUpdated, reverted),
+	})
+}
+
+// undoChange takes back the update or delete an undo token was handed out
+// for. Tokens are single use, belong to the user who made the change and
+// only work while nobody has changed the todo since.
+func (h *handler) undoChange(context *gin.Context) {
+	todoId, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse todo id"})
+		return
+	}
+	var request undoRequest
+	err = context.ShouldBindJSON(&request)
+	if err != nil || request.Token == "" {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+		return
+	}
+	undo, err := h.store.TakeUndo(request.Token, context.GetInt64("userId"))
+	if errors.Is(err, models.ErrNotFound) || (err == nil && undo.TodoID != todoId) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Undo token not found"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch undo token"})
+		return
+	}
+	if undo.Expired(time.Now()) {
+		context.JSON(http.StatusGone, gin.H{"message": "Undo has expired"})
+		return
+	}
+	todo, err := h.store.GetTodoById(todoId)
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Todo not found"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch todo"})
+		return
+	}
+	action := models.ActionEdit
+	if undo.Action == models.HistoryTrashed {
+		action = models.ActionDelete
+	}
+	if !h.authorize(context, todo, action, "Unauthorized access") {
+		return
+	}
+	if todo.Version != undo.Version {
+		context.JSON(http.StatusConflict, gin.H{"message": "Todo has changed since, it can no longer be undone"})
+		return
+	}
+
+	if undo.Action == models.HistoryTrashed {
+		err = h.store.RestoreTodo(*todo, context.GetInt64("userId"))
+	} else {
+		var snapshot *models.TodoVersion
+		snapshot, err = h.store.GetVersion(todo.ID, undo.Version-1)
+		if err == nil {
+			err = h.revert(context, todo, snapshot, undo.Version)
+		}
+	}
+	if errors.Is(err, models.ErrVersionConflict) {
+		context.JSON(http.StatusConflict, gin.H{"message": "Todo has changed since, it can no longer be undone"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to undo change"})
+		return
+	}
+	undone, err := h.store.GetTodoById(todo.ID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch todo"})
+		return
+	}
+	context.Header("ETag", etag(undone.Version))
+	context.JSON(http.StatusOK, gin.H{"message": "Change undone", "todo": undone})
+}
@@ -0,0 +1,107 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"project_todo/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func undoToken(t *testing.T, body []byte) string {
+	var response struct {
+		UndoToken string `json:"undoToken"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &response))
+	assert.NotEmpty(t, response.UndoToken)
+	return response.UndoToken
+}
+
+func TestVersions_Revert(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	stranger := signupAndLogin(t, server, "jim@example.com")
+	doRequest(server, "POST", "/todos", token, `{"title":"Groceries","list":[{"item":"milk"},{"item":"eggs"}]}`)
+	doRequestIfMatch(server, "PUT", "/todos/1", token, `"1"`, `{"title":"Weekly groceries","list":[{"id":1,"item":"milk"}]}`)
+	doRequestIfMatch(server, "PUT", "/todos/1", token, `"2"`, `{"title":"Monthly groceries","list":[]}`)
+
+	w := doRequest(server, "GET", "/todos/1/versions", stranger, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(server, "GET", "/todos/1/versions", token, "")
+	var versions []models.TodoVersion
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &versions))
+	if assert.Len(t, versions, 2) {
+		assert.Equal(t, int64(2), versions[0].Version)
+		assert.Equal(t, "Weekly groceries", versions[0].Title)
+		assert.Equal(t, int64(1), versions[1].Version)
+		assert.Len(t, versions[1].List, 2)
+	}
+
+	w = doRequestIfMatch(server, "POST", "/todos/1/revert", token, `"2"`, `{"version":1}`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = doRequestIfMatch(server, "POST", "/todos/1/revert", token, `"3"`, `{"version":7}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequestIfMatch(server, "POST", "/todos/1/revert", token, `"3"`, `{"version":1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+
+	todo := getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, "Groceries", todo.Title)
+	if assert.Len(t, todo.List, 2) {
+		assert.Equal(t, "milk", todo.List[0].Item)
+		assert.Equal(t, "eggs", todo.List[1].Item)
+	}
+	w = doRequest(server, "GET", "/todos/1/history", token, "")
+	assert.Contains(t, w.Body.String(), `"action":"reverted"`)
+}
+
+func TestVersions_Undo(t *testing.T) {
+	server := newTestServer()
+	token := signupAndLogin(t, server, "john@example.com")
+	editor := signupAndLogin(t, server, "jane@example.com")
+	doRequest(server, "POST", "/todos", token, `{"title":"Groceries","list":[{"item":"milk"}]}`)
+	doRequest(server, "POST", "/todos/1/shares", token, `{"email":"jane@example.com","role":"editor"}`)
+
+	// Undoing an update puts the previous title and checklist back, once.
+	w := doRequestIfMatch(server, "PUT", "/todos/1", token, `"1"`, `{"title":"Taxes"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	undo := undoToken(t, w.Body.Bytes())
+	w = doRequest(server, "POST", "/todos/1/undo", editor, `{"token":"`+undo+`"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(server, "POST", "/todos/1/undo", token, `{"token":"`+undo+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	todo := getTodo(t, server, token, "/todos/1")
+	assert.Equal(t, "Groceries", todo.Title)
+	assert.Len(t, todo.List, 1)
+	w = doRequest(server, "POST", "/todos/1/undo", token, `{"token":"`+undo+`"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Once someone else changes the todo, the undo would clobber their work.
+	w = doRequestIfMatch(server, "PUT", "/todos/1", token, "*", `{"title":"Taxes"}`)
+	undo = undoToken(t, w.Body.Bytes())
+	w = doPatch(server, "/todos/1", editor, mergePatchType, "*", `{"title":"Tax return"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "POST", "/todos/1/undo", token, `{"token":"`+undo+`"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Undoing a delete takes the todo out of the trash.
+	w = doRequestIfMatch(server, "DELETE", "/todos/1", token, "*", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	undo = undoToken(t, w.Body.Bytes())
+	// A token presented for the wrong todo is used up all the same.
+	w = doRequest(server, "POST", "/todos/2/undo", token, `{"token":"`+undo+`"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(server, "POST", "/todos/1/undo", token, `{"token":"`+undo+`"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequestIfMatch(server, "POST", "/todos/1/restore", token, "*", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequestIfMatch(server, "DELETE", "/todos/1", token, "*", "")
+	undo = undoToken(t, w.Body.Bytes())
+	w = doRequest(server, "POST", "/todos/1/undo", token, `{"token":"`+undo+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	todo = getTodo(t, server, token, "/todos/1")
+	assert.Nil(t, todo.DeletedAt)
+	assert.Equal(t, "Tax return", todo.Title)
+}
@@ -0,0 +1,401 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"project_todo/models"
+	"project_todo/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxWorkspaceName = 100
+
+type workspaceRequest struct {
+	Name string `json:"name"`
+}
+
+type memberRequest struct {
+	Role string `json:"role"`
+}
+
+type inviteRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+func checkWorkspaceName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return "", errors.New("Workspace name is required")
+	case len(name) > maxWorkspaceName:
+		return "", errors.New("Workspace name is too long")
+	}
+	return name, nil
+}
+
+// memberParam reads the :userId param and looks up that member's role. On
+// failure it writes the response and returns "".
+func (h *handler) memberParam(context *gin.Context) (int64, string) {
+	memberId, err := strconv.ParseInt(context.Param("userId"), 10, 64)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse user id"})
+		return 0, ""
+	}
+	role, err := h.store.GetMemberRole(context.GetInt64("workspaceId"), memberId)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch member"})
+		return 0, ""
+	}
+	if role == "" {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Member not found"})
+		return 0, ""
+	}
+	return memberId, role
+}
+
+func (h *handler) getWorkspaces(context *gin.Context) {
+	workspaces, err := h.store.GetWorkspaces(context.GetInt64("userId"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch workspaces"})
+		return
+	}
+	if workspaces == nil {
+		workspaces = []models.Workspace{}
+	}
+	context.JSON(http.StatusOK, workspaces)
+}
+
+func (h *handler) createWorkspace(context *gin.Context) {
+	var request workspaceRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+		return
+	}
+	name, err := checkWorkspaceName(request.Name)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	workspace := models.Workspace{Name: name, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	err = h.store.CreateWorkspace(&workspace, context.GetInt64("userId"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to create workspace"})
+		return
+	}
+	context.JSON(http.StatusCreated, gin.H{"message": "Workspace created", "workspace": workspace})
+}
+
+func (h *handler) getWorkspace(context *gin.Context) {
+	workspace, err := h.store.GetWorkspace(context.GetInt64("workspaceId"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch workspace"})
+		return
+	}
+	workspace.Role = context.GetString("workspaceRole")
+	context.JSON(http.StatusOK, workspace)
+}
+
+func (h *handler) updateWorkspace(context *gin.Context) {
+	var request workspaceRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+		return
+	}
+	name, err := checkWorkspaceName(request.Name)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	workspace, err := h.store.GetWorkspace(context.GetInt64("workspaceId"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch workspace"})
+		return
+	}
+	workspace.Name = name
+	workspace.UpdatedAt = time.Now()
+	err = h.store.UpdateWorkspace(*workspace)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to update workspace"})
+		return
+	}
+	workspace.Role = context.GetString("workspaceRole")
+	context.JSON(http.StatusOK, gin.H{"message": "Workspace updated", "workspace": workspace})
+}
+
+// deleteWorkspace deletes the workspace for good, todos included; they do
+// not go to anyone's trash.
+func (h *handler) deleteWorkspace(context *gin.Context) {
+	err := h.store.DeleteWorkspace(context.GetInt64("workspaceId"), context.GetInt64("userId"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to delete workspace"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Workspace deleted"})
+}
+
+func (h *handler) getMembers(context *gin.Context) {
+	members, err := h.store.GetMembers(context.GetInt64("workspaceId"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch members"})
+		return
+	}
+	if members == nil {
+		members = []models.Member{}
+	}
+	context.JSON(http.StatusOK, members)
+}
+
+// updateMember changes a member's role. Admins manage members and guests;
+// only the owner makes or unmakes admins, and the owner's role is fixed.
+func (h *handler) updateMember(context *gin.Context) {
+	memberId, current := h.memberParam(context)
+	if current == "" {
+		return
+	}
+	var request memberRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+		return
+	}
+	request.Role = strings.ToLower(request.Role)
+	if !models.IsValidMemberRole(request.Role) {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Invalid role, use admin, member or guest"})
+		return
+	}
+	if current == models.WorkspaceOwner {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "The owner's role cannot be changed"})
+		return
+	}
+	isOwner := context.GetString("workspaceRole") == models.WorkspaceOwner
+	if (current == models.WorkspaceAdmin || request.Role == models.WorkspaceAdmin) && !isOwner {
+		context.JSON(http.StatusForbidden, gin.H{"message": "Only the owner can change admins"})
+		return
+	}
+	err = h.store.SetMemberRole(context.GetInt64("workspaceId"), memberId, request.Role)
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Member not found"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to update member"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Member updated"})
+}
+
+// removeMember takes a user out of the workspace. Anyone but the owner can
+// leave; removing others takes an admin, and removing an admin the owner.
+func (h *handler) removeMember(context *gin.Context) {
+	memberId, current := h.memberParam(context)
+	if current == "" {
+		return
+	}
+	role := context.GetString("workspaceRole")
+	switch {
+	case current == models.WorkspaceOwner:
+		context.JSON(http.StatusBadRequest, gin.H{"message": "The owner cannot leave the workspace"})
+		return
+	case memberId == context.GetInt64("userId"):
+	case !models.WorkspaceAllows(role, models.ActionManageWorkspace):
+		context.JSON(http.StatusForbidden, gin.H{"message": "Your workspace role does not allow that"})
+		return
+	case current == models.WorkspaceAdmin && role != models.WorkspaceOwner:
+		context.JSON(http.StatusForbidden, gin.H{"message": "Only the owner can change admins"})
+		return
+	}
+	err := h.store.RemoveMember(context.GetInt64("workspaceId"), memberId)
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Member not found"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to remove member"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+func (h *handler) getInvites(context *gin.Context) {
+	invites, err := h.store.GetInvites(context.GetInt64("workspaceId"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch invites"})
+		return
+	}
+	if invites == nil {
+		invites = []models.Invite{}
+	}
+	context.JSON(http.StatusOK, invites)
+}
+
+// createInvite invites an email address, which need not have an account
+// yet, as a member unless another role is given. The response carries the
+// token the invitee accepts with.
+func (h *handler) createInvite(context *gin.Context) {
+	var request inviteRequest
+	err := context.ShouldBindJSON(&request)
+	request.Email = strings.TrimSpace(request.Email)
+	if err != nil || request.Email == "" {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse request data"})
+		return
+	}
+	request.Role = strings.ToLower(request.Role)
+	if request.Role == "" {
+		request.Role = models.WorkspaceMember
+	}
+	if !models.IsValidMemberRole(request.Role) {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Invalid role, use admin, member or guest"})
+		return
+	}
+	if request.Role == models.WorkspaceAdmin && context.GetString("workspaceRole") != models.WorkspaceOwner {
+		context.JSON(http.StatusForbidden, gin.H{"message": "Only the owner can change admins"})
+		return
+	}
+	workspaceId := context.GetInt64("workspaceId")
+	user, err := h.store.GetUserByEmail(request.Email)
+	if err == nil {
+		role, err := h.store.GetMemberRole(workspaceId, user.ID)
+		if err != nil {
+			context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch member"})
+			return
+		}
+		if role != "" {
+			context.JSON(http.StatusConflict, gin.H{"message": "User is already a member"})
+			return
+		}
+	} else if !errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch user"})
+		return
+	}
+
+	token, err := utils.RandomToken(16)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to create invite"})
+		return
+	}
+	invite := models.Invite{
+		WorkspaceID: workspaceId,
+		Email:       request.Email,
+		Role:        request.Role,
+		Token:       token,
+		InvitedBy:   context.GetInt64("userId"),
+		CreatedAt:   time.Now(),
+	}
+	err = h.store.CreateInvite(&invite)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to create invite"})
+		return
+	}
+	context.JSON(http.StatusCreated, gin.H{"message": "Invite created", "invite": invite})
+}
+
+func (h *handler) deleteInvite(context *gin.Context) {
+	inviteId, err := strconv.ParseInt(context.Param("inviteId"), 10, 64)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"message": "Unable to parse invite id"})
+		return
+	}
+	err = h.store.DeleteInvite(context.GetInt64("workspaceId"), inviteId)
+	if errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Invite not found"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to delete invite"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Invite deleted"})
+}
+
+// verifiedUser loads the authenticated user, who must have verified their
+// email: invites go to an address, and until the user has shown they own
+// it anyone could have signed up with it. On failure it writes the
+// response and returns nil.
+func (h *handler) verifiedUser(context *gin.Context) *models.User {
+	user, err := h.store.GetUserById(context.GetInt64("userId"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch user"})
+		return nil
+	}
+	if user.EmailVerifiedAt == nil {
+		context.JSON(http.StatusForbidden, gin.H{"message": "Please verify your email address to see its invites"})
+		return nil
+	}
+	return user
+}
+
+// getMyInvites lists the pending invites addressed to the authenticated
+// user's email.
+func (h *handler) getMyInvites(context *gin.Context) {
+	user := h.verifiedUser(context)
+	if user == nil {
+		return
+	}
+	invites, err := h.store.GetInvitesForEmail(user.Email)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch invites"})
+		return
+	}
+	if invites == nil {
+		invites = []models.Invite{}
+	}
+	context.JSON(http.StatusOK, invites)
+}
+
+// pendingInvite loads the invite named by the :token param and checks it is
+// addressed to the authenticated user and still open. On failure it writes
+// the response and returns nil.
+func (h *handler) pendingInvite(context *gin.Context) *models.Invite {
+	user := h.verifiedUser(context)
+	if user == nil {
+		return nil
+	}
+	invite, err := h.store.GetInvite(context.Param("token"))
+	if errors.Is(err, models.ErrNotFound) ||
+		(err == nil && (invite.AcceptedAt != nil || !strings.EqualFold(invite.Email, user.Email))) {
+		context.JSON(http.StatusNotFound, gin.H{"message": "Invite not found"})
+		return nil
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to fetch invite"})
+		return nil
+	}
+	if invite.Expired(time.Now()) {
+		context.JSON(http.StatusGone, gin.H{"message": "Invite has expired"})
+		return nil
+	}
+	return invite
+}
+
+func (h *handler) acceptInvite(context *gin.Context) {
+	invite := h.pendingInvite(context)
+	if invite == nil {
+		return
+	}
+	err := h.store.AcceptInvite(*invite, context.GetInt64("userId"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to accept invite"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Invite accepted", "workspaceId": invite.WorkspaceID})
+}
+
+func (h *handler) declineInvite(context *gin.Context) {
+	invite := h.pendingInvite(context)
+	if invite == nil {
+		return
+	}
+	err := h.store.DeleteInvite(invite.WorkspaceID, invite.ID)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		context.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to decline invite"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Invite declined"})
+}
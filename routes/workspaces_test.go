@@ -0,0 +1,198 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"project_todo/utils"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// joinWorkspace invites email to workspace 1 with role and accepts the
+// invite as token.
+func joinWorkspace(t *testing.T, server *gin.Engine, owner, token, email, role string) {
+	w := doRequest(server, "POST", "/workspaces/1/invites", owner, `{"email":"`+email+`","role":"`+role+`"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var response struct {
+		Invite struct {
+			Token string `json:"token"`
+		} `json:"invite"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	w = doRequest(server, "POST", "/invites/"+response.Invite.Token+"/accept", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestWorkspaces_Invites(t *testing.T) {
+	server := newTestServer()
+	owner := signupAndLogin(t, server, "john@example.com")
+	jane := signupAndLogin(t, server, "jane@example.com")
+	stranger := signupAndLogin(t, server, "jim@example.com")
+
+	w := doRequest(server, "POST", "/workspaces", owner, `{"name":" "}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(server, "POST", "/workspaces", owner, `{"name":"Team"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"role":"owner"`)
+
+	w = doRequest(server, "POST", "/workspaces/1/invites", owner, `{"email":"Jane@Example.com","role":"owner"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(server, "POST", "/workspaces/1/invites", owner, `{"email":"john@example.com"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doRequest(server, "POST", "/workspaces/1/invites", stranger, `{"email":"jim@example.com"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(server, "POST", "/workspaces/1/invites", owner, `{"email":"Jane@Example.com"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var response struct {
+		Invite struct {
+			Token string `json:"token"`
+		} `json:"invite"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	// Only the invitee sees and accepts the invite, and only once.
+	w = doRequest(server, "GET", "/invites", jane, "")
+	assert.Contains(t, w.Body.String(), `"workspaceName":"Team"`)
+	w = doRequest(server, "GET", "/invites", stranger, "")
+	assert.Equal(t, "[]", w.Body.String())
+	w = doRequest(server, "POST", "/invites/"+response.Invite.Token+"/accept", stranger, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(server, "POST", "/invites/"+response.Invite.Token+"/accept", jane, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "POST", "/invites/"+response.Invite.Token+"/accept", jane, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(server, "GET", "/workspaces", jane, "")
+	assert.Contains(t, w.Body.String(), `"role":"member"`)
+	w = doRequest(server, "GET", "/workspaces/1/members", jane, "")
+	var members []map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &members))
+	assert.Len(t, members, 2)
+
+	// Members can't manage the workspace, invites included.
+	w = doRequest(server, "POST", "/workspaces/1/invites", jane, `{"email":"jim@example.com"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(server, "PATCH", "/workspaces/1", jane, `{"name":"Mine"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doRequest(server, "POST", "/workspaces/1/invites", owner, `{"email":"jim@example.com","role":"guest"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = doRequest(server, "GET", "/workspaces/1/invites", owner, "")
+	assert.Contains(t, w.Body.String(), "jim@example.com")
+	w = doRequest(server, "DELETE", "/workspaces/1/invites/2", owner, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "DELETE", "/workspaces/1/invites/2", owner, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestWorkspaces_InvitesNeedVerifiedEmail(t *testing.T) {
+	t.Setenv("UNVERIFIED_LOGIN_GRACE", "1h")
+	server := newTestServer()
+	owner := signupAndLogin(t, server, "john@example.com")
+	doRequest(server, "POST", "/workspaces", owner, `{"name":"Team"}`)
+	w := doRequest(server, "POST", "/workspaces/1/invites", owner, `{"email":"jane@example.com"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var response struct {
+		Invite struct {
+			Token string `json:"token"`
+		} `json:"invite"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	// Someone signing up with jane's address can log in during the grace
+	// period, but can't see or take her invites until they verify it.
+	w = doRequest(server, "POST", "/signup", "", `{"email":"jane@example.com","firstName":"Jane","lastName":"Doe","password":"password123"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	unverified := loginFrom(t, server, "jane@example.com", "Firefox").Token
+	w = doRequest(server, "GET", "/invites", unverified, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(server, "POST", "/invites/"+response.Invite.Token+"/accept", unverified, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(server, "POST", "/invites/"+response.Invite.Token+"/decline", unverified, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(server, "GET", "/workspaces/1", unverified, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	token, err := utils.GenerateEmailToken("jane@example.com")
+	assert.NoError(t, err)
+	doRequest(server, "GET", "/verify-email?token="+token, "", "")
+	w = doRequest(server, "POST", "/invites/"+response.Invite.Token+"/accept", unverified, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestWorkspaces_Roles(t *testing.T) {
+	server := newTestServer()
+	owner := signupAndLogin(t, server, "john@example.com")
+	admin := signupAndLogin(t, server, "jane@example.com")
+	member := signupAndLogin(t, server, "joe@example.com")
+	guest := signupAndLogin(t, server, "jim@example.com")
+	doRequest(server, "POST", "/workspaces", owner, `{"name":"Team"}`)
+	joinWorkspace(t, server, owner, admin, "jane@example.com", "admin")
+	joinWorkspace(t, server, owner, member, "joe@example.com", "member")
+	joinWorkspace(t, server, owner, guest, "jim@example.com", "guest")
+
+	// Only the owner hands out admin.
+	w := doRequest(server, "POST", "/workspaces/1/invites", admin, `{"email":"ann@example.com","role":"admin"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(server, "PATCH", "/workspaces/1/members/3", admin, `{"role":"admin"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(server, "PATCH", "/workspaces/1/members/1", admin, `{"role":"guest"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(server, "PATCH", "/workspaces/1/members/4", admin, `{"role":"member"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "PATCH", "/workspaces/1/members/4", admin, `{"role":"guest"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "PATCH", "/workspaces/1/members/9", admin, `{"role":"guest"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Members work on todos, guests only look; outsiders and personal
+	// routes don't reach workspace todos.
+	w = doRequest(server, "POST", "/workspaces/1/todos", member, `{"title":"Release"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = doRequest(server, "POST", "/workspaces/1/todos", guest, `{"title":"Mine"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	doRequest(server, "POST", "/todos", member, `{"title":"Personal"}`)
+	w = doRequest(server, "GET", "/workspaces/1/todos", guest, "")
+	assert.Equal(t, []string{"Release"}, todoTitles(t, w.Body.Bytes()))
+	w = doRequest(server, "GET", "/todos", member, "")
+	assert.Equal(t, []string{"Personal"}, todoTitles(t, w.Body.Bytes()))
+	w = doRequest(server, "GET", "/todos/1", member, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(server, "GET", "/workspaces/1/todos/2", member, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(server, "POST", "/workspaces/1/todos/1/items", guest, `{"item":"notes"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequestIfMatch(server, "POST", "/workspaces/1/todos/1/items", member, "*", `{"item":"notes"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = doRequest(server, "POST", "/workspaces/1/todos/1/shares", owner, `{"email":"ann@example.com"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequestIfMatch(server, "DELETE", "/workspaces/1/todos/1", guest, "*", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequestIfMatch(server, "DELETE", "/workspaces/1/todos/1", member, "*", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "GET", "/workspaces/1/todos/trash", admin, "")
+	assert.Equal(t, []string{"Release"}, todoTitles(t, w.Body.Bytes()))
+	w = doRequest(server, "GET", "/todos/trash", member, "")
+	assert.NotContains(t, w.Body.String(), "Release")
+
+	// Anyone but the owner can leave; only the owner removes admins.
+	w = doRequest(server, "DELETE", "/workspaces/1/members/2", member, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(server, "DELETE", "/workspaces/1/members/4", guest, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "GET", "/workspaces/1", guest, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(server, "DELETE", "/workspaces/1/members/1", admin, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(server, "DELETE", "/workspaces/1/members/3", admin, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doRequest(server, "DELETE", "/workspaces/1", admin, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(server, "DELETE", "/workspaces/1", owner, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(server, "GET", "/workspaces", admin, "")
+	assert.Equal(t, "[]", w.Body.String())
+}
@@ -2,7 +2,10 @@ document.addEventListener('DOMContentLoaded', function () {
     const signupForm = document.getElementById('signupForm');
     const loginForm = document.getElementById('loginForm');
     const todosList = document.getElementById('todosList');
+    // The todos currently listed, in their manual order.
+    let shownTodos = [];
     const addTodoBtn = document.getElementById('addTodoBtn');
+    const trashBtn = document.getElementById('trashBtn');
 
     // Handle Signup Form Submission
     if (signupForm) {
@@ -46,12 +49,145 @@ document.addEventListener('DOMContentLoaded', function () {
                     body: JSON.stringify({ email, password }),
                 });
 
+                const data = await response.json();
+                document.getElementById('message').textContent = data.message;
+                // 403 means the email address hasn't been verified yet.
+                document.getElementById('resendVerificationBtn').style.display = response.status === 403 ? '' : 'none';
+
+                if (response.ok && data.mfaRequired) {
+                    // Two-factor authentication: swap the challenge for
+                    // tokens with a code.
+                    mfaToken = data.mfaToken;
+                    loginForm.style.display = 'none';
+                    document.getElementById('mfaForm').style.display = '';
+                } else if (response.ok) {
+                    storeLogin(data);
+                }
+            } catch (error) {
+                console.error('Error:', error);
+            }
+        });
+    }
+
+    let mfaToken = null;
+    const mfaForm = document.getElementById('mfaForm');
+    if (mfaForm) {
+        mfaForm.addEventListener('submit', async function (e) {
+            e.preventDefault();
+            const value = document.getElementById('mfaCode').value.trim();
+            // Authenticator codes are six digits; anything else is taken
+            // for a recovery code.
+            const body = /^\d{6}$/.test(value) ? { mfaToken, code: value } : { mfaToken, recoveryCode: value };
+
+            try {
+                const response = await fetch('/login/mfa', {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/json',
+                    },
+                    body: JSON.stringify(body),
+                });
+
+                const data = await response.json();
+                document.getElementById('message').textContent = data.message;
+
+                if (response.ok) {
+                    storeLogin(data);
+                }
+            } catch (error) {
+                console.error('Error:', error);
+            }
+        });
+    }
+
+    function storeLogin(data) {
+        localStorage.setItem('token', data.token);
+        localStorage.setItem('refreshToken', data.refreshToken);
+        window.location.href = '/todolist';
+    }
+
+    const resendVerificationBtn = document.getElementById('resendVerificationBtn');
+    if (resendVerificationBtn) {
+        resendVerificationBtn.addEventListener('click', async function () {
+            const email = document.getElementById('loginEmail').value;
+
+            try {
+                const response = await fetch('/verify-email/resend', {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/json',
+                    },
+                    body: JSON.stringify({ email }),
+                });
+
+                const data = await response.json();
+                document.getElementById('message').textContent = data.message;
+            } catch (error) {
+                console.error('Error:', error);
+            }
+        });
+    }
+
+    // Handle Verify Email Page
+    const verifyMessage = document.getElementById('verifyMessage');
+    if (verifyMessage) {
+        const verifyToken = new URLSearchParams(window.location.search).get('token') || '';
+        fetch(`/verify-email?token=${encodeURIComponent(verifyToken)}`)
+            .then(response => response.json())
+            .then(data => {
+                verifyMessage.textContent = data.message;
+            })
+            .catch(error => console.error('Error:', error));
+    }
+
+    // Handle Password Reset Page
+    const forgotForm = document.getElementById('forgotForm');
+    const resetForm = document.getElementById('resetForm');
+    if (forgotForm && resetForm) {
+        const resetToken = new URLSearchParams(window.location.search).get('token');
+        if (resetToken) {
+            forgotForm.style.display = 'none';
+            resetForm.style.display = '';
+        }
+
+        forgotForm.addEventListener('submit', async function (e) {
+            e.preventDefault();
+            const email = document.getElementById('forgotEmail').value;
+
+            try {
+                const response = await fetch('/password/forgot', {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/json',
+                    },
+                    body: JSON.stringify({ email }),
+                });
+
+                const data = await response.json();
+                document.getElementById('message').textContent = data.message;
+            } catch (error) {
+                console.error('Error:', error);
+            }
+        });
+
+        resetForm.addEventListener('submit', async function (e) {
+            e.preventDefault();
+            const password = document.getElementById('newPassword').value;
+
+            try {
+                const response = await fetch('/password/reset', {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/json',
+                    },
+                    body: JSON.stringify({ token: resetToken, password }),
+                });
+
                 const data = await response.json();
                 document.getElementById('message').textContent = data.message;
 
                 if (response.ok) {
-                    localStorage.setItem('token', data.token);
-                    window.location.href = '/todolist';
+                    resetForm.style.display = 'none';
                 }
             } catch (error) {
                 console.error('Error:', error);
@@ -64,19 +200,64 @@ document.addEventListener('DOMContentLoaded', function () {
         addTodoBtn.addEventListener('click', function () {
             renderTodoForm();
         });
+        trashBtn.addEventListener('click', loadTrash);
 
         loadTodos();
     }
 
-    async function loadTodos() {
-        const token = localStorage.getItem('token');
-        const response = await fetch('/todos', {
+    // apiFetch is fetch with the access token. Access tokens are
+    // short-lived, so on a 401 it swaps the refresh token for new tokens
+    // once and retries; if that fails too the user has to log in again.
+    async function apiFetch(url, options = {}) {
+        const send = () => fetch(url, {
+            ...options,
+            headers: { ...options.headers, 'Authorization': `${localStorage.getItem('token')}` },
+        });
+        let response = await send();
+        if (response.status === 401 && await refreshTokens()) {
+            response = await send();
+        }
+        if (response.status === 401) {
+            window.location.href = '/app-login';
+        }
+        return response;
+    }
+
+    async function refreshTokens() {
+        const response = await fetch('/token/refresh', {
+            method: 'POST',
             headers: {
-                'Authorization': `${token}`,
+                'Content-Type': 'application/json',
             },
+            body: JSON.stringify({ refreshToken: localStorage.getItem('refreshToken') }),
         });
-        const todos = await response.json();
-        todos.forEach(todo => renderTodoItem(todo));
+        if (!response.ok) {
+            return false;
+        }
+        const data = await response.json();
+        localStorage.setItem('token', data.token);
+        localStorage.setItem('refreshToken', data.refreshToken);
+        return true;
+    }
+
+    async function loadTodos() {
+        const token = localStorage.getItem('token');
+        let cursor = '';
+        shownTodos = [];
+        do {
+            const url = cursor ? `/todos?cursor=${encodeURIComponent(cursor)}` : '/todos';
+            const response = await apiFetch(url, {
+                headers: {
+                    'Authorization': `${token}`,
+                },
+            });
+            const todos = await response.json();
+            (todos || []).forEach(todo => {
+                shownTodos.push(todo);
+                renderTodoItem(todo);
+            });
+            cursor = response.headers.get('X-Next-Cursor');
+        } while (cursor);
     }
 
     function renderTodoItem(todo) {
@@ -88,6 +269,36 @@ document.addEventListener('DOMContentLoaded', function () {
 
         const title = document.createElement('span');
         title.textContent = todo.title;
+        if (todo.priority) {
+            const priority = document.createElement('span');
+            priority.className = `priority priority-${todo.priority.toLowerCase()}`;
+            priority.textContent = todo.priority;
+            title.prepend(priority);
+        }
+        if (todo.dueAt) {
+            title.appendChild(dueLabel(todo.dueAt));
+        }
+        (todo.tags || []).forEach(tag => {
+            const chip = document.createElement('span');
+            chip.className = 'tag';
+            chip.textContent = tag.name;
+            if (tag.color) {
+                chip.style.backgroundColor = tag.color;
+            }
+            title.appendChild(chip);
+        });
+        if (todo.recurrence) {
+            const repeat = document.createElement('span');
+            repeat.className = 'due';
+            repeat.textContent = ` (repeats ${todo.recurrence})`;
+            title.appendChild(repeat);
+        }
+        if (todo.shared) {
+            const shared = document.createElement('span');
+            shared.className = 'shared';
+            shared.textContent = `shared, ${todo.role}`;
+            title.appendChild(shared);
+        }
 
         const actions = document.createElement('div');
         actions.className = 'todo-actions';
@@ -101,26 +312,63 @@ document.addEventListener('DOMContentLoaded', function () {
         const deleteBtn = document.createElement('button');
         deleteBtn.textContent = 'Delete';
         deleteBtn.addEventListener('click', function () {
-            deleteTodo(todo.id);
+            deleteTodo(todo);
+        });
+
+        const linkBtn = document.createElement('button');
+        linkBtn.textContent = 'Link';
+        linkBtn.title = 'Create a public read-only link';
+        linkBtn.addEventListener('click', function () {
+            createShareLink(todo);
+        });
+
+        const upBtn = document.createElement('button');
+        upBtn.textContent = '↑';
+        upBtn.title = 'Move up';
+        upBtn.addEventListener('click', function () {
+            moveTodo(todo, -1);
         });
 
-        actions.appendChild(editBtn);
-        actions.appendChild(deleteBtn);
+        const downBtn = document.createElement('button');
+        downBtn.textContent = '↓';
+        downBtn.title = 'Move down';
+        downBtn.addEventListener('click', function () {
+            moveTodo(todo, 1);
+        });
+
+        // Only the owner orders and deletes a todo; viewers can't edit it.
+        if (!todo.shared) {
+            actions.appendChild(upBtn);
+            actions.appendChild(downBtn);
+        }
+        if (todo.role !== 'viewer') {
+            actions.appendChild(editBtn);
+        }
+        if (!todo.shared) {
+            actions.appendChild(linkBtn);
+            actions.appendChild(deleteBtn);
+        }
         todoHeader.appendChild(title);
         todoHeader.appendChild(actions);
         li.appendChild(todoHeader);
 
-        todo.list.forEach(item => {
+        (todo.list || []).forEach(item => {
             const checklistItem = document.createElement('div');
             checklistItem.className = 'checklist-item';
 
             const checkbox = document.createElement('input');
             checkbox.type = 'checkbox';
             checkbox.checked = item.checked;
-            checkbox.disabled = true;
+            checkbox.disabled = todo.role === 'viewer';
+            checkbox.addEventListener('change', function () {
+                toggleItem(todo, item.id, checkbox);
+            });
 
             const itemText = document.createElement('span');
             itemText.textContent = item.item;
+            if (item.dueAt && !item.checked) {
+                itemText.appendChild(dueLabel(item.dueAt));
+            }
 
             checklistItem.appendChild(checkbox);
             checklistItem.appendChild(itemText);
@@ -130,6 +378,24 @@ document.addEventListener('DOMContentLoaded', function () {
         todosList.appendChild(li);
     }
 
+    function dueLabel(dueAt) {
+        const label = document.createElement('span');
+        const due = new Date(dueAt);
+        label.className = due < new Date() ? 'due overdue' : 'due';
+        label.textContent = ` (due ${due.toLocaleString()})`;
+        return label;
+    }
+
+    // datetime-local inputs work in the browser's zone without an offset.
+    function toLocalInput(dueAt) {
+        if (!dueAt) {
+            return '';
+        }
+        const due = new Date(dueAt);
+        due.setMinutes(due.getMinutes() - due.getTimezoneOffset());
+        return due.toISOString().slice(0, 16);
+    }
+
     function renderTodoForm(todo = { title: '', list: [] }) {
         todosList.innerHTML = '';
 
@@ -140,9 +406,21 @@ document.addEventListener('DOMContentLoaded', function () {
         const titleInput = formClone.querySelector('#todoTitle');
         titleInput.value = todo.title;
 
+        const dueInput = formClone.querySelector('#todoDue');
+        dueInput.value = toLocalInput(todo.dueAt);
+
+        const recurrenceInput = formClone.querySelector('#todoRecurrence');
+        recurrenceInput.value = todo.recurrence || '';
+
+        const priorityInput = formClone.querySelector('#todoPriority');
+        priorityInput.value = todo.priority || 'P2';
+
+        const projectInput = formClone.querySelector('#todoProject');
+        loadProjectOptions(projectInput, todo.projectId);
+
         const checklistItems = formClone.querySelector('#checklistItems');
-        todo.list.forEach(item => {
-            addChecklistItem(checklistItems, item.item, item.checked);
+        (todo.list || []).forEach(item => {
+            addChecklistItem(checklistItems, item.item, item.checked, item.id, item.dueAt);
         });
 
         const newChecklistItem = formClone.querySelector('#newChecklistItem');
@@ -157,7 +435,15 @@ document.addEventListener('DOMContentLoaded', function () {
         saveTodoBtn.addEventListener('click', function () {
             saveTodo({
                 id: todo.id,
+                version: todo.version,
                 title: titleInput.value,
+                // PUT replaces the whole todo, so keep it open unless it was done.
+                isActive: todo.isActive !== false,
+                recurrence: recurrenceInput.value.trim(),
+                priority: priorityInput.value,
+                projectId: projectInput.value ? Number(projectInput.value) : undefined,
+                dueAt: dueInput.value ? new Date(dueInput.value).toISOString() : undefined,
+                timeZone: Intl.DateTimeFormat().resolvedOptions().timeZone,
                 list: getChecklistItems(checklistItems),
             });
         });
@@ -172,9 +458,37 @@ document.addEventListener('DOMContentLoaded', function () {
         todosList.appendChild(formClone);
     }
 
-    function addChecklistItem(container, item = '', checked = false) {
+    // loadProjectOptions fills the project select. Archived projects can't
+    // take new todos, so only the todo's own project is offered if archived.
+    async function loadProjectOptions(select, projectId) {
+        const token = localStorage.getItem('token');
+        const response = await apiFetch('/projects?includeArchived=true', {
+            headers: {
+                'Authorization': `${token}`,
+            },
+        });
+        const projects = response.ok ? await response.json() : [];
+        projects.forEach(project => {
+            if (project.archivedAt && project.id !== projectId) {
+                return;
+            }
+            const option = document.createElement('option');
+            option.value = project.id;
+            option.textContent = project.archivedAt ? `${project.name} (archived)` : project.name;
+            select.appendChild(option);
+        });
+        select.value = projectId || '';
+    }
+
+    function addChecklistItem(container, item = '', checked = false, id = null, dueAt = null) {
         const li = document.createElement('li');
         li.className = 'checklist-item';
+        if (id) {
+            li.dataset.id = id;
+        }
+        if (dueAt) {
+            li.dataset.dueAt = dueAt;
+        }
 
         const checkbox = document.createElement('input');
         checkbox.type = 'checkbox';
@@ -195,41 +509,251 @@ document.addEventListener('DOMContentLoaded', function () {
         listItems.forEach(li => {
             const item = li.querySelector('input[type="text"]').value;
             const checked = li.querySelector('input[type="checkbox"]').checked;
-            items.push({ item, checked });
+            // Sending the id back keeps existing items instead of recreating them.
+            const id = li.dataset.id ? Number(li.dataset.id) : undefined;
+            items.push({ id, item, checked, dueAt: li.dataset.dueAt });
         });
         return items;
     }
 
+    // Writes to an existing todo send the version it was loaded at; the
+    // server answers 412 if it has been changed since, e.g. in another tab.
+    function ifMatch(todo) {
+        return `"${todo.version}"`;
+    }
+
+    function reloadStale() {
+        alert('This todo was changed somewhere else. Reloading the latest version.');
+        todosList.innerHTML = '';
+        loadTodos();
+    }
+
     async function saveTodo(todo) {
         const token = localStorage.getItem('token');
         const method = todo.id ? 'PUT' : 'POST';
         const url = todo.id ? `/todos/${todo.id}` : '/todos';
+        const headers = {
+            'Authorization': `${token}`,
+            'Content-Type': 'application/json',
+        };
+        if (todo.id) {
+            headers['If-Match'] = ifMatch(todo);
+        }
 
-        const response = await fetch(url, {
+        const response = await apiFetch(url, {
             method,
+            headers,
+            body: JSON.stringify(todo),
+        });
+
+        if (response.status === 412) {
+            reloadStale();
+        } else if (response.ok) {
+            const data = await response.json();
+            todosList.innerHTML = '';
+            loadTodos();
+            if (todo.id) {
+                offerUndo('Todo updated', todo.id, data.undoToken);
+            }
+        }
+    }
+
+    async function toggleItem(todo, itemId, checkbox) {
+        const token = localStorage.getItem('token');
+        const response = await apiFetch(`/todos/${todo.id}/items/${itemId}`, {
+            method: 'PATCH',
             headers: {
                 'Authorization': `${token}`,
                 'Content-Type': 'application/json',
+                'If-Match': ifMatch(todo),
             },
-            body: JSON.stringify(todo),
+            body: JSON.stringify({ checked: checkbox.checked }),
         });
+        if (response.status === 412) {
+            reloadStale();
+            return;
+        }
+        if (!response.ok) {
+            checkbox.checked = !checkbox.checked;
+            return;
+        }
+        // Every item change moves the todo on by one version.
+        todo.version++;
+    }
 
+    // moveTodo swaps the todo with its neighbour above (-1) or below (1).
+    // Only the moved todo gets a new rank on the server.
+    async function moveTodo(todo, direction) {
+        // Shared todos are ordered by their owners, so skip over them.
+        const own = shownTodos.filter(shown => !shown.shared);
+        const index = own.findIndex(shown => shown.id === todo.id);
+        const neighbour = own[index + direction];
+        if (index < 0 || !neighbour) {
+            return;
+        }
+        const token = localStorage.getItem('token');
+        const response = await apiFetch('/todos/reorder', {
+            method: 'POST',
+            headers: {
+                'Authorization': `${token}`,
+                'Content-Type': 'application/json',
+                'If-Match': ifMatch(todo),
+            },
+            body: JSON.stringify(direction < 0
+                ? { id: todo.id, before: neighbour.id }
+                : { id: todo.id, after: neighbour.id }),
+        });
+        if (response.status === 412) {
+            reloadStale();
+            return;
+        }
+        todosList.innerHTML = '';
+        loadTodos();
+    }
+
+    async function deleteTodo(todo) {
+        const token = localStorage.getItem('token');
+        const response = await apiFetch(`/todos/${todo.id}`, {
+            method: 'DELETE',
+            headers: {
+                'Authorization': `${token}`,
+                'If-Match': ifMatch(todo),
+            },
+        });
+        if (response.status === 412) {
+            reloadStale();
+            return;
+        }
+        const data = await response.json();
+        todosList.innerHTML = '';
+        loadTodos();
         if (response.ok) {
+            offerUndo('Todo moved to trash', todo.id, data.undoToken);
+        }
+    }
+
+    // offerUndo shows message with an Undo button above the list until the
+    // server would refuse the undo token anyway, or the next change replaces
+    // it.
+    let undoBar = null;
+    function offerUndo(message, todoId, undoToken) {
+        if (undoBar) {
+            undoBar.remove();
+            undoBar = null;
+        }
+        if (!undoToken) {
+            return;
+        }
+        const bar = document.createElement('div');
+        bar.className = 'undo-bar';
+        const text = document.createElement('span');
+        text.textContent = message;
+        const undoBtn = document.createElement('button');
+        undoBtn.textContent = 'Undo';
+        undoBtn.addEventListener('click', async function () {
+            bar.remove();
+            const token = localStorage.getItem('token');
+            const response = await apiFetch(`/todos/${todoId}/undo`, {
+                method: 'POST',
+                headers: {
+                    'Authorization': `${token}`,
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ token: undoToken }),
+            });
+            if (!response.ok) {
+                const data = await response.json();
+                alert(data.message);
+            }
             todosList.innerHTML = '';
             loadTodos();
+        });
+        bar.appendChild(text);
+        bar.appendChild(undoBtn);
+        todosList.parentNode.insertBefore(bar, todosList);
+        undoBar = bar;
+        setTimeout(function () {
+            bar.remove();
+        }, 5 * 60 * 1000);
+    }
+
+    async function createShareLink(todo) {
+        const token = localStorage.getItem('token');
+        const response = await apiFetch(`/todos/${todo.id}/links`, {
+            method: 'POST',
+            headers: {
+                'Authorization': `${token}`,
+            },
+        });
+        const data = await response.json();
+        if (!response.ok) {
+            alert(data.message);
+            return;
         }
+        window.prompt('Anyone with this link can view the todo:', window.location.origin + data.url);
     }
 
-    async function deleteTodo(id) {
+    async function loadTrash() {
         const token = localStorage.getItem('token');
-        await fetch(`/todos/${id}`, {
-            method: 'DELETE',
+        const response = await apiFetch('/todos/trash', {
             headers: {
                 'Authorization': `${token}`,
             },
         });
+        const todos = await response.json();
         todosList.innerHTML = '';
-        loadTodos();
+
+        const backBtn = document.createElement('button');
+        backBtn.textContent = 'Back';
+        backBtn.addEventListener('click', function () {
+            todosList.innerHTML = '';
+            loadTodos();
+        });
+        todosList.appendChild(backBtn);
+
+        (todos || []).forEach(todo => {
+            const li = document.createElement('li');
+            li.className = 'todo-item';
+
+            const todoHeader = document.createElement('div');
+            todoHeader.className = 'todo-header';
+
+            const title = document.createElement('span');
+            title.textContent = todo.title;
+
+            const actions = document.createElement('div');
+            actions.className = 'todo-actions';
+
+            const restoreBtn = document.createElement('button');
+            restoreBtn.textContent = 'Restore';
+            restoreBtn.addEventListener('click', function () {
+                trashAction('POST', `/todos/${todo.id}/restore`);
+            });
+
+            const purgeBtn = document.createElement('button');
+            purgeBtn.textContent = 'Delete forever';
+            purgeBtn.addEventListener('click', function () {
+                trashAction('DELETE', `/todos/trash/${todo.id}`);
+            });
+
+            actions.appendChild(restoreBtn);
+            actions.appendChild(purgeBtn);
+            todoHeader.appendChild(title);
+            todoHeader.appendChild(actions);
+            li.appendChild(todoHeader);
+            todosList.appendChild(li);
+        });
+    }
+
+    async function trashAction(method, url) {
+        const token = localStorage.getItem('token');
+        await apiFetch(url, {
+            method,
+            headers: {
+                'Authorization': `${token}`,
+            },
+        });
+        loadTrash();
     }
 
     const logoutBtn = document.getElementById('logoutBtn');
@@ -237,8 +761,16 @@ document.addEventListener('DOMContentLoaded', function () {
         logoutBtn.addEventListener('click', logout);
     }
 
-    function logout() {
+    async function logout() {
+        await fetch('/logout', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify({ refreshToken: localStorage.getItem('refreshToken') }),
+        });
         localStorage.removeItem('token');
+        localStorage.removeItem('refreshToken');
         window.location.href = '/app-login';
     }
 });
\ No newline at end of file
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// DurationEnv reads a Go duration such as "15m" from the named variable,
+// falling back when it is unset, malformed or not positive.
+func DurationEnv(name string, fallback time.Duration) time.Duration {
+	return durationEnv(name, fallback, false)
+}
+
+// OptionalDurationEnv is DurationEnv for settings that zero turns off: an
+// explicit zero is kept, negative values fall back.
+func OptionalDurationEnv(name string, fallback time.Duration) time.Duration {
+	return durationEnv(name, fallback, true)
+}
+
+func durationEnv(name string, fallback time.Duration, allowZero bool) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 || (duration == 0 && !allowZero) {
+		fmt.Println("Invalid", name, value, "- using", fallback)
+		return fallback
+	}
+	return duration
+}
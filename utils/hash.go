@@ -2,10 +2,11 @@ package utils
 
 import "golang.org/x/crypto/bcrypt"
 
-const hashCost = 14
+// HashCost is the bcrypt work factor. Tests lower it to keep signups fast.
+var HashCost = 14
 
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), hashCost)
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), HashCost)
 	return string(bytes), err
 }
 
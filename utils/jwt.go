@@ -2,49 +2,195 @@ package utils
 
 import (
 	"errors"
-	"fmt"
 	"os"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 var secretKey = os.Getenv("JWT_SECRET")
 
-func GenerateToken(email string, userId int64) (string, error) {
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+	// DefaultRevocationCacheTTL is short so that other servers catch up
+	// with revoked sessions quickly.
+	DefaultRevocationCacheTTL         = 30 * time.Second
+	DefaultEmailVerificationTTL       = 24 * time.Hour
+	DefaultVerificationResendInterval = time.Minute
+	DefaultMFAChallengeTTL            = 5 * time.Minute
+)
+
+// AccessTokenTTL is how long a JWT from GenerateToken is valid, from
+// ACCESS_TOKEN_TTL.
+func AccessTokenTTL() time.Duration {
+	return DurationEnv("ACCESS_TOKEN_TTL", DefaultAccessTokenTTL)
+}
+
+// RefreshTokenTTL is how long a refresh token can be exchanged for new
+// tokens, from REFRESH_TOKEN_TTL.
+func RefreshTokenTTL() time.Duration {
+	return DurationEnv("REFRESH_TOKEN_TTL", DefaultRefreshTokenTTL)
+}
+
+// RevocationCacheTTL is how long Authenticate trusts what it looked up
+// about an access token's session, from REVOCATION_CACHE_TTL. Sessions
+// revoked through another server stop working within that time.
+func RevocationCacheTTL() time.Duration {
+	return DurationEnv("REVOCATION_CACHE_TTL", DefaultRevocationCacheTTL)
+}
+
+// EmailVerificationTTL is how long the link mailed on signup works, from
+// EMAIL_VERIFICATION_TTL.
+func EmailVerificationTTL() time.Duration {
+	return DurationEnv("EMAIL_VERIFICATION_TTL", DefaultEmailVerificationTTL)
+}
+
+// VerificationResendInterval is how long a user has to wait before another
+// verification email is sent, from VERIFICATION_RESEND_INTERVAL.
+func VerificationResendInterval() time.Duration {
+	return DurationEnv("VERIFICATION_RESEND_INTERVAL", DefaultVerificationResendInterval)
+}
+
+// UnverifiedLoginGrace is how long after signup users can log in without
+// having verified their email, from UNVERIFIED_LOGIN_GRACE. Unset or 0,
+// they can't log in until they have.
+func UnverifiedLoginGrace() time.Duration {
+	return OptionalDurationEnv("UNVERIFIED_LOGIN_GRACE", 0)
+}
+
+// MFAChallengeTTL is how long users have to enter their second factor
+// after their password, from MFA_CHALLENGE_TTL.
+func MFAChallengeTTL() time.Duration {
+	return DurationEnv("MFA_CHALLENGE_TTL", DefaultMFAChallengeTTL)
+}
+
+// TokenClaims is what Authenticate needs from an access token.
+type TokenClaims struct {
+	UserID int64
+	// JTI identifies the token among those handed out in its session.
+	JTI string
+}
+
+// GenerateToken signs a short-lived access token for the user, identified
+// by jti.
+func GenerateToken(email string, userId int64, jti string) (string, error) {
+	now := time.Now()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"email":  email,
 		"userId": userId,
+		"iat":    now.Unix(),
+		"exp":    now.Add(AccessTokenTTL()).Unix(),
+		"jti":    jti,
 	})
 	return token.SignedString([]byte(secretKey))
 }
 
-func VerifyToken(token string) (int64, error) {
-	fmt.Println("before parsing")
-
+// VerifyToken checks an access token and returns its claims. Tokens
+// without an expiry or jti, as handed out before tokens expired, are
+// rejected.
+func VerifyToken(token string) (*TokenClaims, error) {
 	parsedToken, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
 		_, ok := t.Method.(*jwt.SigningMethodHMAC)
 		if !ok {
 			return nil, errors.New("Unexpected signing method")
 		}
 		return []byte(secretKey), nil
+	}, jwt.WithExpirationRequired(), jwt.WithIssuedAt())
+
+	if err != nil {
+		return nil, errors.New("Could not parse token")
+	}
+	if !parsedToken.Valid {
+		return nil, errors.New("Invalid token!")
+	}
+	claims, ok := parsedToken.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("Invalid token claims")
+	}
+	userId, ok := claims["userId"].(float64)
+	jti, hasJti := claims["jti"].(string)
+	if !ok || !hasJti || jti == "" {
+		return nil, errors.New("Invalid token claims")
+	}
+	return &TokenClaims{UserID: int64(userId), JTI: jti}, nil
+}
+
+const verifyEmailPurpose = "verify-email"
+
+// GenerateEmailToken signs a token that proves whoever holds it received
+// mail at email. It can't be used as an access token.
+func GenerateEmailToken(email string) (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"email":   email,
+		"purpose": verifyEmailPurpose,
+		"iat":     now.Unix(),
+		"exp":     now.Add(EmailVerificationTTL()).Unix(),
 	})
-	fmt.Println("after parsing", parsedToken)
+	return token.SignedString([]byte(secretKey))
+}
 
+// VerifyEmailToken checks a token from GenerateEmailToken and returns the
+// email it was made for.
+func VerifyEmailToken(token string) (string, error) {
+	claims, err := verifyPurpose(token, verifyEmailPurpose)
 	if err != nil {
-		return 0, errors.New("Could not parse token")
+		return "", err
+	}
+	email, ok := claims["email"].(string)
+	if !ok || email == "" {
+		return "", errors.New("Invalid token claims")
 	}
-	isTokenValid := parsedToken.Valid
-	fmt.Println("isTokenValid", isTokenValid)
+	return email, nil
+}
 
-	if !isTokenValid {
-		return 0, errors.New("Invalid token!")
+const mfaPurpose = "mfa"
+
+// GenerateMFAToken signs the challenge handed out when a user with two-factor
+// authentication enters their password. It has no jti, so it can't be used
+// as an access token.
+func GenerateMFAToken(userId int64) (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"userId":  userId,
+		"purpose": mfaPurpose,
+		"iat":     now.Unix(),
+		"exp":     now.Add(MFAChallengeTTL()).Unix(),
+	})
+	return token.SignedString([]byte(secretKey))
+}
+
+// VerifyMFAToken checks a token from GenerateMFAToken and returns the user
+// it was made for.
+func VerifyMFAToken(token string) (int64, error) {
+	claims, err := verifyPurpose(token, mfaPurpose)
+	if err != nil {
+		return 0, err
 	}
-	claims, ok := parsedToken.Claims.(jwt.MapClaims)
-	fmt.Println("claims", claims)
+	userId, ok := claims["userId"].(float64)
 	if !ok {
 		return 0, errors.New("Invalid token claims")
 	}
-	fmt.Println("UserId.... ", claims["userId"].(float64))
-	userId := int64(claims["userId"].(float64))
-	return userId, nil
+	return int64(userId), nil
+}
+
+// verifyPurpose checks a token signed for purpose and returns its claims.
+func verifyPurpose(token, purpose string) (jwt.MapClaims, error) {
+	parsedToken, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		_, ok := t.Method.(*jwt.SigningMethodHMAC)
+		if !ok {
+			return nil, errors.New("Unexpected signing method")
+		}
+		return []byte(secretKey), nil
+	}, jwt.WithExpirationRequired(), jwt.WithIssuedAt())
+
+	if err != nil || !parsedToken.Valid {
+		return nil, errors.New("Invalid token!")
+	}
+	claims, ok := parsedToken.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != purpose {
+		return nil, errors.New("Invalid token claims")
+	}
+	return claims, nil
 }
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+const DefaultPasswordResetTTL = time.Hour
+
+// PasswordResetTTL is how long a password reset link works, from
+// PASSWORD_RESET_TTL.
+func PasswordResetTTL() time.Duration {
+	return DurationEnv("PASSWORD_RESET_TTL", DefaultPasswordResetTTL)
+}
+
+// RandomToken returns a hex string of n random bytes, for tokens handed out
+// in links and invites.
+func RandomToken(n int) (string, error) {
+	token := make([]byte, n)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// HashToken returns the hex SHA-256 of a token, for tokens that are stored
+// server-side and must not be usable if the database leaks.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
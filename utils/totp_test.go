@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The SHA1 test vectors of RFC 6238, appendix B, truncated to 6 digits.
+func TestTOTPCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		code, err := TOTPCode(secret, TOTPStep(time.Unix(unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, want, code, unix)
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	assert.NoError(t, err)
+	now := time.Now()
+	code, err := TOTPCode(secret, TOTPStep(now.Add(-TOTPPeriod)))
+	assert.NoError(t, err)
+
+	step, ok := MatchTOTP(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, TOTPStep(now)-1, step)
+	_, ok = MatchTOTP(secret, code, now.Add(2*TOTPPeriod))
+	assert.False(t, ok)
+	_, ok = MatchTOTP(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("Todo Checklist", "john@example.com", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Todo%20Checklist:john@example.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=Todo+Checklist")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	code, err := NewRecoveryCode()
+	assert.NoError(t, err)
+	assert.Len(t, code, 19)
+	assert.Equal(t, NormalizeRecoveryCode(code), NormalizeRecoveryCode(strings.ToLower(strings.ReplaceAll(code, "-", " "))))
+}